@@ -3,6 +3,7 @@ package commands
 import (
 	"context"
 	"os"
+	"strings"
 
 	"github.com/chaisql/chai/cmd/chai/dbutil"
 	"github.com/chaisql/chai/cmd/chai/shell"
@@ -15,18 +16,34 @@ func NewApp() *cli.Command {
 	cmd.Name = "chai"
 	cmd.Usage = "Shell for the ChaiSQL database"
 	cmd.EnableShellCompletion = true
+	cmd.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:      "format",
+			Usage:     "output format of query results: " + strings.Join(dbutil.Formats, ", ") + ". Defaults to list when reading from STDIN and to table in the shell.",
+			Validator: dbutil.ValidateFormat,
+		},
+	}
 
 	cmd.Commands = []*cli.Command{
 		NewVersionCommand(),
 		NewDumpCommand(),
 		NewRestoreCommand(),
+		NewBackupCommand(),
+		NewWatchCommand(),
+		NewPurgeChangesCommand(),
+		NewRekeyCommand(),
 		NewBenchCommand(),
 		NewPebbleCommand(),
+		NewMigrateCommand(),
+		NewDiffCommand(),
+		NewCheckCommand(),
+		NewServeCommand(),
 	}
 
 	// Root command
 	cmd.Action = func(ctx context.Context, cmd *cli.Command) error {
 		dbpath := cmd.Args().First()
+		format := cmd.String("format")
 
 		if dbutil.CanReadFromStandardInput() {
 			db, err := dbutil.OpenDB(dbpath)
@@ -35,11 +52,19 @@ func NewApp() *cli.Command {
 			}
 			defer db.Close()
 
-			return dbutil.ExecSQL(ctx, db, os.Stdin, os.Stdout)
+			if format == "" {
+				format = dbutil.FormatList
+			}
+
+			return dbutil.ExecSQL(ctx, db, os.Stdin, os.Stdout, &dbutil.ExecOptions{
+				Format:    format,
+				NullValue: dbutil.DefaultNullValue,
+			})
 		}
 
 		return shell.Run(ctx, &shell.Options{
 			DBPath: dbpath,
+			Format: format,
 		})
 	}
 
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/chaisql/chai/cmd/chai/dbutil"
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v3"
+)
+
+// NewBackupCommand returns a cli.Command for "chai backup".
+func NewBackupCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "backup",
+		Usage:     "Create a binary backup of a database",
+		UsageText: `chai backup dbPath backupDir`,
+		Description: `The backup command creates a consistent binary copy of a database.
+
+	$ chai backup mydb backups/mydb
+
+If the backup directory already contains a backup of the database,
+only the data written since the previous backup is copied.
+
+The backup can be restored with:
+
+	$ chai restore --from-backup backups/mydb mydb`,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args()
+			if args.Len() != 2 {
+				return errors.New(cmd.UsageText)
+			}
+
+			return dbutil.Backup(ctx, args.First(), args.Get(1))
+		},
+	}
+}
@@ -3,6 +3,8 @@ package commands
 import (
 	"context"
 	"errors"
+	"os"
+	"time"
 
 	"github.com/chaisql/chai/cmd/chai/dbutil"
 	"github.com/urfave/cli/v3"
@@ -12,26 +14,22 @@ import (
 func NewBenchCommand() *cli.Command {
 	cmd := cli.Command{
 		Name:      "bench",
-		Usage:     "Simple load testing command",
-		UsageText: `chai bench query`,
-		Description: `The bench command runs a query repeatedly (100 times by default, -n option) and outputs a series of results.
-Each result represent the average time for a given sample of queries (10 by default, -s/--sample option).
+		Usage:     "Load testing command",
+		UsageText: `chai bench [options] [query]`,
+		Description: `The bench command runs a query, or the statements of a workload file, repeatedly (100 times by default, -n option)
+and reports the throughput and the latency percentiles of the queries.
 
-$ chai bench -n 200 -s 5 "SELECT 1"
-{
-	"totalQueries": 5,
-	"sampleSpeed": "2.191µs"
-}
-{
-	"totalQueries": 10,
-	"sampleSpeed": "1.941µs"
-}
-{
-	"totalQueries": 15,
-	"sampleSpeed": "2.237µs"
-}
+$ chai bench -n 10000 "SELECT 1"
+Clients: 1, duration: 23.38ms, queries: 10000, throughput: 427715.1 q/s
+
+STATEMENT  COUNT       Q/S   MEAN    P50    P95    P99    P999     MAX
+ SELECT 1  10000  427715.1  2.2µs  2.1µs  2.6µs  4.9µs  18.3µs  84.5µs
+    total  10000  427715.1  2.2µs  2.1µs  2.6µs  4.9µs  18.3µs  84.5µs
 ...
 
+With --csv, the stats of the statements are printed as semicolon separated values, with latencies in milliseconds.
+The -s/--sample option, which printed the average latency of every sample of queries, has been removed:
+use --interval to measure the throughput over time.
 
 By default, queries are run in-memory. To write them on disk, use the -p/--path options.
 The database will be created if it doesn't exist.
@@ -42,8 +40,29 @@ To prepare the database before running a query, use the -i/--init option
 
 $ chai bench -i "CREATE TABLE foo(a INT PRIMARY KEY); INSERT INTO foo(a) VALUES (1), (2), (3)" "SELECT * FROM foo"
 
+A workload file is a JSON file describing statements, picked at random according to their weight,
+and how to generate their parameters: "int" and "float" between min and max, "text" of a given length,
+"sequence" starting at min, or "choice" among values.
+
+{
+  "init": "CREATE TABLE users(id INT PRIMARY KEY, name TEXT)",
+  "statements": [
+    {"name": "insert", "weight": 1, "query": "INSERT INTO users VALUES ($1, $2)",
+     "params": [{"type": "sequence", "min": 1}, {"type": "text", "length": 20}]},
+    {"name": "get", "weight": 9, "query": "SELECT * FROM users WHERE id = $1",
+     "params": [{"type": "int", "min": 1, "max": 1000}]}
+  ]
+}
+
+$ chai bench -w workload.json --clients 8 --duration 30s -o before.json
+
+The -o/--output option saves the results to a file, which can be compared with another one.
+With --threshold, the command fails if a latency increased, or a throughput decreased, by more than the given percentage.
+
+$ chai bench --compare before.json after.json --threshold 10
+
 By default, each query is run in a separate transaction. To run everything, including the setup,
-in the same transaction, use -t`,
+in the same transaction, use -x/--tx`,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
 				Name:    "path",
@@ -55,6 +74,11 @@ in the same transaction, use -t`,
 				Aliases: []string{"i"},
 				Usage:   "Queries to run to initialize the database before running the benchmark.",
 			},
+			&cli.StringFlag{
+				Name:    "workload",
+				Aliases: []string{"w"},
+				Usage:   "Path of a workload file describing the statements to run.",
+			},
 			&cli.BoolFlag{
 				Name:    "tx",
 				Aliases: []string{"x"},
@@ -66,26 +90,85 @@ in the same transaction, use -t`,
 				Value:   100,
 				Usage:   "Total number of queries to run.",
 			},
+			&cli.DurationFlag{
+				Name:    "duration",
+				Aliases: []string{"d"},
+				Usage:   "Run the benchmark for the given duration instead of a number of queries.",
+			},
 			&cli.IntFlag{
-				Name:    "sample",
-				Aliases: []string{"s"},
-				Value:   10,
-				Usage:   "Number of queries to use to determine the average speed of the query.",
+				Name:    "clients",
+				Aliases: []string{"c"},
+				Value:   1,
+				Usage:   "Number of connections running queries concurrently.",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Value: time.Second,
+				Usage: "Period of the throughput measures.",
+			},
+			&cli.Uint64Flag{
+				Name:  "seed",
+				Value: 1,
+				Usage: "Seed of the random generators, to run the same queries across runs.",
 			},
 			&cli.BoolFlag{
 				Name:  "prepare",
 				Usage: "Prepare the query before running the benchmark",
 			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Save the results to a JSON file.",
+			},
 			&cli.BoolFlag{
 				Name:  "csv",
-				Usage: "Output the results in csv",
+				Usage: "Output the stats of the statements in CSV.",
+			},
+			&cli.BoolFlag{
+				Name:  "compare",
+				Usage: "Compare two result files instead of running a benchmark.",
+			},
+			&cli.FloatFlag{
+				Name:  "threshold",
+				Usage: "With --compare, fail if a metric is worse by more than the given percentage.",
 			},
 		},
 	}
 
 	cmd.Action = func(ctx context.Context, cmd *cli.Command) error {
+		if cmd.Bool("compare") {
+			if cmd.Args().Len() != 2 {
+				return errors.New("chai bench --compare old.json new.json")
+			}
+
+			a, err := dbutil.ReadBenchResult(cmd.Args().Get(0))
+			if err != nil {
+				return err
+			}
+			b, err := dbutil.ReadBenchResult(cmd.Args().Get(1))
+			if err != nil {
+				return err
+			}
+
+			return dbutil.CompareBench(os.Stdout, a, b, cmd.Float("threshold"))
+		}
+
+		var w *dbutil.Workload
 		query := cmd.Args().First()
-		if query == "" {
+		switch {
+		case cmd.String("workload") != "" && query != "":
+			return errors.New("cannot use both a query and a workload file")
+		case cmd.String("workload") != "":
+			var err error
+			w, err = dbutil.LoadWorkload(cmd.String("workload"))
+			if err != nil {
+				return err
+			}
+		case query != "":
+			w = &dbutil.Workload{
+				Statements: []dbutil.WorkloadStatement{{Query: query}},
+			}
+		default:
 			return errors.New(cmd.UsageText)
 		}
 
@@ -97,14 +180,37 @@ in the same transaction, use -t`,
 		}
 		defer db.Close()
 
-		return dbutil.Bench(ctx, db, query, dbutil.BenchOptions{
-			Init:       cmd.String("init"),
-			N:          cmd.Int("number"),
-			SampleSize: cmd.Int("sample"),
-			SameTx:     cmd.Bool("tx"),
-			Prepare:    cmd.Bool("prepare"),
-			CSV:        cmd.Bool("csv"),
+		n := cmd.Int("number")
+		if cmd.IsSet("duration") && !cmd.IsSet("number") {
+			n = 0
+		}
+
+		res, err := dbutil.Bench(ctx, db, w, dbutil.BenchOptions{
+			Init:     cmd.String("init"),
+			N:        n,
+			Duration: cmd.Duration("duration"),
+			Clients:  cmd.Int("clients"),
+			Interval: cmd.Duration("interval"),
+			Seed:     cmd.Uint64("seed"),
+			SameTx:   cmd.Bool("tx"),
+			Prepare:  cmd.Bool("prepare"),
 		})
+		if err != nil {
+			return err
+		}
+
+		if path := cmd.String("output"); path != "" {
+			err = dbutil.WriteBenchResult(path, res)
+			if err != nil {
+				return err
+			}
+		}
+
+		if cmd.Bool("csv") {
+			return dbutil.WriteBenchCSV(os.Stdout, res)
+		}
+
+		return dbutil.WriteBenchReport(os.Stdout, res)
 	}
 
 	return &cmd
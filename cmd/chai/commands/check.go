@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"context"
+	"os"
+
+	"github.com/chaisql/chai/cmd/chai/dbutil"
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v3"
+)
+
+// NewCheckCommand returns a cli.Command for "chai check".
+func NewCheckCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "check",
+		Usage:     "Verify the integrity of the tables and indexes of a database",
+		UsageText: `chai check [--table TABLE] [--repair] dbPath`,
+		Description: `The check command verifies that a database is internally consistent,
+for example after a crash:
+
+	$ chai check mydb
+	users_name_idx: row (1): missing index entry ('foo')
+
+Every row must decode against the schema of its table and respect its NOT NULL
+constraints, every index must contain exactly one valid entry per row and respect
+its UNIQUE constraint, and the leases of the sequences must be within their bounds.
+
+With --repair, the indexes with missing or invalid entries are rebuilt,
+the way REINDEX does. The database must not be in use.`,
+		Flags: []cli.Flag{
+			&cli.StringSliceFlag{
+				Name:    "table",
+				Aliases: []string{"t"},
+				Usage:   "Name of a table to check, with its indexes and sequences. Defaults to all tables.",
+			},
+			&cli.BoolFlag{
+				Name:  "repair",
+				Usage: "Rebuild the indexes with missing or invalid entries.",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args()
+			if args.Len() != 1 {
+				return errors.New(cmd.UsageText)
+			}
+
+			return dbutil.Check(ctx, args.First(), cmd.StringSlice("table"), cmd.Bool("repair"), os.Stdout)
+		},
+	}
+}
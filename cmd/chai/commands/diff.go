@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"context"
+	"os"
+
+	"github.com/chaisql/chai/cmd/chai/dbutil"
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v3"
+)
+
+// NewDiffCommand returns a cli.Command for "chai diff".
+func NewDiffCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "diff",
+		Usage:     "Compare the schema and data of two databases",
+		UsageText: `chai diff [--data] [--sql] dbA dbB`,
+		Description: `The diff command compares the tables, indexes and sequences of two databases:
+
+	$ chai diff staging production
+	+ table users: CREATE TABLE users (id INTEGER NOT NULL, CONSTRAINT users_pk PRIMARY KEY (id))
+
+Objects only in dbA are prefixed with "-", objects only in dbB with "+"
+and objects that differ with "~". With --data, the rows of the tables are also
+compared by primary key. With --sql, a SQL script transforming dbA into dbB
+is written instead of the report:
+
+	$ chai diff --data --sql staging production > changes.sql
+	$ cat changes.sql | chai staging
+
+Tables whose changes can't be made with ALTER TABLE are created again.
+Without --data, the script copies the rows of the columns they keep.`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "data",
+				Usage: "Compare the rows of the tables.",
+			},
+			&cli.BoolFlag{
+				Name:  "sql",
+				Usage: "Output a SQL script transforming dbA into dbB.",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args()
+			if args.Len() != 2 {
+				return errors.New(cmd.UsageText)
+			}
+
+			a, err := dbutil.OpenReadOnly(args.First())
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			b, err := dbutil.OpenReadOnly(args.Get(1))
+			if err != nil {
+				return err
+			}
+			defer b.Close()
+
+			return dbutil.Diff(a, b, os.Stdout, dbutil.DiffOptions{
+				Data: cmd.Bool("data"),
+				SQL:  cmd.Bool("sql"),
+			})
+		},
+	}
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/chaisql/chai/cmd/chai/dbutil"
+	"github.com/chaisql/chai/migrate"
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v3"
+)
+
+// NewMigrateCommand returns a cli.Command for "chai migrate".
+func NewMigrateCommand() *cli.Command {
+	dirFlag := &cli.StringFlag{
+		Name:  "dir",
+		Value: "migrations",
+		Usage: "Directory containing the migration files.",
+	}
+
+	// withDB opens the database passed as the only argument of the command
+	withDB := func(fn func(ctx context.Context, cmd *cli.Command, db *sql.DB) error) cli.ActionFunc {
+		return func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args()
+			if args.Len() != 1 {
+				return errors.New(cmd.UsageText)
+			}
+
+			db, err := dbutil.OpenDB(args.First())
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			return fn(ctx, cmd, db)
+		}
+	}
+
+	return &cli.Command{
+		Name:      "migrate",
+		Usage:     "Apply versioned migration files to a database",
+		UsageText: `chai migrate up|down|status|create`,
+		Description: `The migrate command applies the migration files of a directory, in the order of their versions.
+Migrations are pairs of files named NNN_name.up.sql and NNN_name.down.sql:
+
+	$ chai migrate create add_users
+	$ chai migrate up mydb
+	$ chai migrate status mydb
+
+Each migration is applied in its own transaction and recorded in the __chai_migrations table.
+This table is included by chai dump and chai diff, so restored databases keep their migrations.
+Applying migrations fails if the up file of an applied migration has been edited.`,
+		Commands: []*cli.Command{
+			{
+				Name:      "up",
+				Usage:     "Apply the pending migrations",
+				UsageText: `chai migrate up [--dir DIR] [-n N] dbPath`,
+				Flags: []cli.Flag{
+					dirFlag,
+					&cli.IntFlag{
+						Name:  "n",
+						Usage: "Number of migrations to apply. Defaults to all the pending migrations.",
+					},
+				},
+				Action: withDB(func(ctx context.Context, cmd *cli.Command, db *sql.DB) error {
+					return dbutil.MigrateUp(ctx, db, cmd.String("dir"), cmd.Int("n"), os.Stdout)
+				}),
+			},
+			{
+				Name:      "down",
+				Usage:     "Revert the last applied migrations",
+				UsageText: `chai migrate down [--dir DIR] [-n N] dbPath`,
+				Flags: []cli.Flag{
+					dirFlag,
+					&cli.IntFlag{
+						Name:  "n",
+						Value: 1,
+						Usage: "Number of migrations to revert.",
+					},
+				},
+				Action: withDB(func(ctx context.Context, cmd *cli.Command, db *sql.DB) error {
+					return dbutil.MigrateDown(ctx, db, cmd.String("dir"), cmd.Int("n"), os.Stdout)
+				}),
+			},
+			{
+				Name:      "status",
+				Usage:     "List the migrations and whether they have been applied",
+				UsageText: `chai migrate status [--dir DIR] dbPath`,
+				Flags:     []cli.Flag{dirFlag},
+				Action: withDB(func(ctx context.Context, cmd *cli.Command, db *sql.DB) error {
+					return dbutil.MigrateStatus(ctx, db, cmd.String("dir"), os.Stdout)
+				}),
+			},
+			{
+				Name:      "create",
+				Usage:     "Create the files of a new migration",
+				UsageText: `chai migrate create [--dir DIR] name`,
+				Flags:     []cli.Flag{dirFlag},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					args := cmd.Args()
+					if args.Len() != 1 {
+						return errors.New(cmd.UsageText)
+					}
+
+					up, down, err := migrate.Create(cmd.String("dir"), args.First())
+					if err != nil {
+						return err
+					}
+
+					fmt.Printf("Created %s\nCreated %s\n", up, down)
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/chaisql/chai/internal/database"
 	"github.com/chaisql/chai/internal/database/catalogstore"
 	"github.com/chaisql/chai/internal/kv"
+	"github.com/cockroachdb/errors"
 	"github.com/urfave/cli/v3"
 )
 
@@ -42,7 +43,10 @@ func NewPebbleCommand() *cli.Command {
 		}
 		defer db.Close()
 
-		ng := db.Engine.(*kv.PebbleEngine)
+		ng, ok := db.Engine.(*kv.PebbleEngine)
+		if !ok {
+			return errors.New("the database is not stored in Pebble")
+		}
 		return dbutil.DumpPebble(ctx, ng.DB(), dbutil.DumpPebbleOptions{
 			KeysOnly: cmd.Bool("keys-only"),
 		})
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/chaisql/chai/cmd/chai/dbutil"
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v3"
+)
+
+// NewRekeyCommand returns a cli.Command for "chai rekey".
+func NewRekeyCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "rekey",
+		Usage:     "Change the key used to encrypt a database",
+		UsageText: `chai rekey [--key KEY] [--new-key KEY] dbPath`,
+		Description: `The rekey command re-encrypts the files of a database with a new key.
+Keys are hex encoded and must be 16, 24 or 32 bytes long.
+
+	$ chai rekey --key $OLD_KEY --new-key $NEW_KEY mydb
+
+If --key is omitted, the database must not be encrypted and is encrypted
+with the new key. If --new-key is omitted, the database is decrypted.
+Keys can also be passed with the CHAI_ENCRYPTION_KEY and CHAI_NEW_ENCRYPTION_KEY
+environment variables. The database must not be in use.
+
+An encrypted database is opened by passing its key in the data source name:
+
+	$ chai "mydb?encryption_key=$NEW_KEY"`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "key",
+				Usage:   "Key currently used to encrypt the database.",
+				Sources: cli.EnvVars("CHAI_ENCRYPTION_KEY"),
+			},
+			&cli.StringFlag{
+				Name:    "new-key",
+				Usage:   "Key used to encrypt the database.",
+				Sources: cli.EnvVars("CHAI_NEW_ENCRYPTION_KEY"),
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args()
+			if args.Len() != 1 {
+				return errors.New(cmd.UsageText)
+			}
+
+			return dbutil.Rekey(args.First(), cmd.String("key"), cmd.String("new-key"))
+		},
+	}
+}
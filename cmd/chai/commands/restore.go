@@ -12,17 +12,32 @@ import (
 func NewRestoreCommand() (cmd *cli.Command) {
 	return &cli.Command{
 		Name:      "restore",
-		Usage:     "Restore a database from a file created by chai dump",
-		UsageText: `chai restore dumpFile dbPath`,
+		Usage:     "Restore a database from a file created by chai dump or a directory created by chai backup",
+		UsageText: `chai restore [--from-backup] source dbPath`,
 		Description: `The restore command can restore a database from a text file.
 
-	$ chai restore dump.sql mydb`,
-		Flags: []cli.Flag{},
+	$ chai restore dump.sql mydb
+
+With the --from-backup flag, the database is replaced by a binary backup
+created by chai backup. The backup is validated before the database is replaced.
+
+	$ chai restore --from-backup backups/mydb mydb`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "from-backup",
+				Usage: "Restore from a backup directory created by chai backup.",
+			},
+		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			args := cmd.Args()
 			if args.Len() != 2 {
 				return errors.New(cmd.UsageText)
 			}
+
+			if cmd.Bool("from-backup") {
+				return dbutil.RestoreBackup(ctx, args.First(), args.Get(1))
+			}
+
 			return dbutil.Restore(ctx, nil, args.First(), args.Get(args.Len()-1))
 		},
 	}
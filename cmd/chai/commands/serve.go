@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/chaisql/chai/cmd/chai/pgwire"
+	"github.com/chaisql/chai/httpapi"
+	"github.com/chaisql/chai/internal/sql/driver"
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v3"
+)
+
+// NewServeCommand returns a cli.Command for "chai serve".
+func NewServeCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "serve",
+		Usage:     "Serve a database over the PostgreSQL wire protocol",
+		UsageText: `chai serve [--listen ADDR] [--http ADDR] [--allow-copy] [dbPath]`,
+		Description: `The serve command opens a database and lets PostgreSQL clients and drivers,
+like psql or pgx, connect to it:
+
+	$ chai serve --listen localhost:5432 mydb
+	$ psql -h localhost -p 5432
+
+Each client connection runs on its own database connection, with its own transactions.
+Both the simple and the extended query protocols are supported, with parameters sent
+as text or binary. There is no authentication and no TLS, so the server should only
+listen on trusted networks.
+
+COPY statements read and write files with the permissions of the server,
+so they are rejected unless --allow-copy is set.
+
+With --http, the database is also served over HTTP by the handler of the httpapi package,
+which runs queries sent to POST /query and streams the changes read by chai watch.
+
+If dbPath is omitted, an in-memory database is served.`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "listen",
+				Aliases: []string{"l"},
+				Usage:   "Address to listen on.",
+				Value:   "localhost:5432",
+			},
+			&cli.StringFlag{
+				Name:  "http",
+				Usage: "Address to serve the HTTP API on.",
+			},
+			&cli.BoolFlag{
+				Name:  "allow-copy",
+				Usage: "Allow COPY statements to read and write files on the server.",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args()
+			if args.Len() > 1 {
+				return errors.New(cmd.UsageText)
+			}
+
+			dsn := args.First()
+			if dsn == "" {
+				dsn = ":memory:"
+			}
+
+			return runServeCmd(ctx, dsn, cmd.String("listen"), cmd.String("http"), &pgwire.Options{
+				AllowCopy: cmd.Bool("allow-copy"),
+			})
+		},
+	}
+}
+
+func runServeCmd(ctx context.Context, dsn, addr, httpAddr string, srvOpts *pgwire.Options) error {
+	path, opts, err := driver.ParseDSN(dsn)
+	if err != nil {
+		return err
+	}
+
+	c, err := driver.NewConnector(path, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	srv := pgwire.NewServer(c.DB(), srvOpts)
+
+	var httpSrv *http.Server
+	if httpAddr != "" {
+		hln, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			_ = ln.Close()
+			return err
+		}
+
+		httpSrv = &http.Server{
+			Handler: httpapi.NewHandler(sql.OpenDB(c), &httpapi.Options{
+				AllowCopy: srvOpts.AllowCopy,
+			}),
+		}
+		defer httpSrv.Close()
+
+		fmt.Fprintf(os.Stderr, "Serving HTTP on %s\n", hln.Addr())
+		go func() {
+			_ = httpSrv.Serve(hln)
+		}()
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+		if httpSrv != nil {
+			_ = httpSrv.Close()
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", ln.Addr())
+	err = srv.Serve(ln)
+	if errors.Is(err, pgwire.ErrServerClosed) {
+		return nil
+	}
+	_ = srv.Close()
+	return err
+}
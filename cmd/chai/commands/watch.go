@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"strconv"
+
+	"github.com/chaisql/chai/cmd/chai/dbutil"
+	"github.com/cockroachdb/errors"
+	"github.com/urfave/cli/v3"
+)
+
+// NewWatchCommand returns a cli.Command for "chai watch".
+func NewWatchCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "watch",
+		Usage:     "Print the changes committed to a table as JSON lines",
+		UsageText: `chai watch [--cursor N] [--follow] dbPath|URL tableName`,
+		Description: `The watch command prints the inserts, updates and deletes committed to a table,
+in commit order, one JSON object per line:
+
+	$ chai watch mydb users
+	{"cursor": 1, "table": "users", "op": "insert", "key": [1], "before": null, "after": {"id": 1, "name": "foo"}}
+
+Changes are only recorded if the database is opened with the changelog=on option.
+Each line contains a cursor that can be passed to --cursor to only print
+the changes committed after it.
+
+A database cannot be read by another process while it is opened for writing.
+To watch a database in use, pass the URL of the process serving it,
+either with chai serve --http or by mounting the handler of the httpapi package:
+
+	$ chai serve --http localhost:8080 "mydb?changelog=on"
+	$ chai watch --follow http://localhost:8080 users
+
+With --follow, the command keeps printing the changes as they are committed.
+It requires a URL.`,
+		Flags: []cli.Flag{
+			&cli.Uint64Flag{
+				Name:  "cursor",
+				Usage: "Only print the changes committed after this cursor.",
+			},
+			&cli.BoolFlag{
+				Name:    "follow",
+				Aliases: []string{"f"},
+				Usage:   "Keep printing the changes as they are committed.",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args()
+			if args.Len() != 2 {
+				return errors.New(cmd.UsageText)
+			}
+
+			target, tableName, cursor := args.First(), args.Get(1), cmd.Uint64("cursor")
+			if dbutil.IsURL(target) {
+				return dbutil.WatchURL(ctx, target, os.Stdout, tableName, cursor, cmd.Bool("follow"))
+			}
+
+			if cmd.Bool("follow") {
+				return errors.New("--follow requires the URL of a server: the database cannot be written while it is watched")
+			}
+
+			db, err := dbutil.OpenChangelog(target)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			return dbutil.Watch(db, os.Stdout, tableName, cursor)
+		},
+	}
+}
+
+// NewPurgeChangesCommand returns a cli.Command for "chai purge-changes".
+func NewPurgeChangesCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "purge-changes",
+		Usage:     "Remove the changes printed by chai watch up to a cursor",
+		UsageText: `chai purge-changes dbPath|URL cursor`,
+		Description: `The purge-changes command removes the changes up to the given cursor, included,
+from the changelog of a database, once they have been consumed:
+
+	$ chai purge-changes mydb 42
+
+As with chai watch, a database in use is purged through the URL of the process serving it:
+
+	$ chai purge-changes http://localhost:8080 42`,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args()
+			if args.Len() != 2 {
+				return errors.New(cmd.UsageText)
+			}
+
+			cursor, err := strconv.ParseUint(args.Get(1), 10, 64)
+			if err != nil {
+				return errors.Errorf("invalid cursor %q", args.Get(1))
+			}
+
+			if dbutil.IsURL(args.First()) {
+				return dbutil.PurgeChangesURL(ctx, args.First(), cursor)
+			}
+
+			return dbutil.PurgeChanges(args.First(), cursor)
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package dbutil
+
+import (
+	"context"
+
+	"github.com/chaisql/chai"
+	"github.com/cockroachdb/errors"
+)
+
+// Backup creates a binary backup of the database located at dbPath in backupDir.
+// If backupDir already contains a backup of the database, it is updated incrementally.
+func Backup(ctx context.Context, dbPath, backupDir string) error {
+	if dbPath == "" {
+		return errors.New("database path expected")
+	}
+
+	if backupDir == "" {
+		return errors.New("backup directory expected")
+	}
+
+	db, err := OpenDB(dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return chai.Backup(ctx, db, backupDir)
+}
+
+// RestoreBackup replaces the database located at dbPath with the backup stored in backupDir.
+func RestoreBackup(ctx context.Context, backupDir, dbPath string) error {
+	if dbPath == "" {
+		return errors.New("database path expected")
+	}
+
+	if backupDir == "" {
+		return errors.New("backup directory expected")
+	}
+
+	return chai.Restore(ctx, backupDir, dbPath, chai.Options{})
+}
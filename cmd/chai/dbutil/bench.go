@@ -5,159 +5,688 @@ import (
 	"database/sql"
 	"encoding/csv"
 	"encoding/json"
+	"fmt"
 	"io"
+	"math"
+	"math/bits"
+	"math/rand/v2"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"text/tabwriter"
 	"time"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/sync/errgroup"
 )
 
+// A Workload is a set of statements run by the bench command.
+// Each query picks one of the statements at random, according to their weights.
+type Workload struct {
+	// Init is run once before the benchmark.
+	Init       string              `json:"init"`
+	Statements []WorkloadStatement `json:"statements"`
+}
+
+// A WorkloadStatement is a statement of a workload.
+// Its parameters are generated for each query.
+type WorkloadStatement struct {
+	Name   string           `json:"name"`
+	Query  string           `json:"query"`
+	Weight int              `json:"weight"`
+	Params []ParamGenerator `json:"params"`
+}
+
+// A ParamGenerator describes how to generate the value of a parameter.
+//
+//   - int: an integer between Min and Max, included
+//   - float: a double between Min and Max
+//   - text: a random text of Length letters, 10 by default
+//   - sequence: an integer incremented for each query, starting at Min
+//   - choice: one of Values
+type ParamGenerator struct {
+	Type   string  `json:"type"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Length int     `json:"length"`
+	Values []any   `json:"values"`
+}
+
+// LoadWorkload reads a workload from a JSON file.
+func LoadWorkload(path string) (*Workload, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var w Workload
+	err = json.Unmarshal(data, &w)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid workload file %q", path)
+	}
+
+	return &w, nil
+}
+
 type BenchOptions struct {
-	Init       string
-	N          int
-	SampleSize int
-	SameTx     bool
-	Prepare    bool
-	CSV        bool
+	Init string
+	// N is the total number of queries to run.
+	N int
+	// Duration is the maximum duration of the benchmark.
+	Duration time.Duration
+	// Clients is the number of connections running queries concurrently.
+	Clients int
+	// Interval is the period of the throughput measures.
+	Interval time.Duration
+	// Seed initializes the generators of parameters and the choice of statements.
+	Seed    uint64
+	SameTx  bool
+	Prepare bool
 }
 
-type preparer interface {
-	PrepareContext(ctx context.Context, q string) (*sql.Stmt, error)
+// A BenchResult is the result of a benchmark. It can be saved to a file
+// and compared with another result.
+type BenchResult struct {
+	Clients  int           `json:"clients"`
+	Duration time.Duration `json:"duration"`
+	Interval time.Duration `json:"interval"`
+	Total    BenchStats    `json:"total"`
+	// Statements are the stats of each statement of the workload.
+	Statements []BenchStats `json:"statements"`
+	// Throughput is the number of queries per second during each interval.
+	Throughput []float64 `json:"throughput"`
+}
+
+// BenchStats are the latency stats of a statement.
+type BenchStats struct {
+	Name  string        `json:"name"`
+	Count int           `json:"count"`
+	QPS   float64       `json:"qps"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+	P999  time.Duration `json:"p999"`
+	Max   time.Duration `json:"max"`
+	// Histogram counts the queries by latency, in buckets whose
+	// upper bounds double from 1µs.
+	Histogram []BenchBucket `json:"histogram"`
+}
+
+// A BenchBucket counts the queries whose latency is lower or equal to Le
+// and greater than the bound of the previous bucket.
+type BenchBucket struct {
+	Le    time.Duration `json:"le"`
+	Count int           `json:"count"`
+}
+
+type benchStatement struct {
+	WorkloadStatement
+	params []func(r *rand.Rand) any
+}
+
+// benchClient runs the queries of one connection and records their latencies.
+type benchClient struct {
+	// tx is the transaction running all the queries, with the SameTx option.
+	tx        *sql.Tx
+	exec      func(ctx context.Context, stmt int, args ...any) error
+	rng       *rand.Rand
+	latencies [][]time.Duration
+	counts    []int
 }
 
-type execer func(query string, args ...any) (sql.Result, error)
+// Bench runs the statements of the workload on opt.Clients connections, until opt.N queries are run
+// or opt.Duration is elapsed, and returns the latency of the queries.
+func Bench(ctx context.Context, db *sql.DB, w *Workload, opt BenchOptions) (*BenchResult, error) {
+	if len(w.Statements) == 0 {
+		return nil, errors.New("workload has no statements")
+	}
+	if opt.N <= 0 && opt.Duration <= 0 {
+		return nil, errors.New("number of queries or duration expected")
+	}
+	if opt.Clients <= 0 {
+		opt.Clients = 1
+	}
+	if opt.SameTx && opt.Clients > 1 {
+		return nil, errors.New("cannot run multiple clients in the same transaction")
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = time.Second
+	}
 
-// Bench takes a database and dumps its content as SQL queries in the given writer.
-// If tables is provided, only selected tables will be outputted.
-func Bench(ctx context.Context, db *sql.DB, query string, opt BenchOptions) error {
-	var tx *sql.Tx
-	conn, err := db.Conn(ctx)
+	stmts, err := compileWorkload(w)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer conn.Close()
 
-	var p preparer = conn
-	var e execer = db.Exec
+	var totalWeight int
+	for _, s := range stmts {
+		totalWeight += s.Weight
+	}
 
-	if opt.SameTx {
-		tx, err = conn.BeginTx(ctx, nil)
+	init := strings.TrimSpace(w.Init + "\n" + opt.Init)
+	if init != "" && !opt.SameTx {
+		_, err := db.ExecContext(ctx, init)
 		if err != nil {
-			return err
+			return nil, errors.Wrap(err, "init")
 		}
-		defer tx.Rollback()
-		p = tx
-		e = tx.Exec
 	}
 
-	if opt.Init != "" {
-		_, err := e(opt.Init)
+	clients := make([]*benchClient, opt.Clients)
+	for i := range clients {
+		conn, err := db.Conn(ctx)
 		if err != nil {
-			return err
+			return nil, err
 		}
-	}
+		defer conn.Close()
 
-	if opt.Prepare {
-		stmt, err := p.PrepareContext(ctx, query)
+		c, err := newBenchClient(ctx, conn, stmts, init, opt)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		e = func(query string, args ...any) (sql.Result, error) {
-			return stmt.ExecContext(ctx, args...)
+		if c.tx != nil {
+			defer c.tx.Rollback()
 		}
+		c.rng = rand.New(rand.NewPCG(opt.Seed, uint64(i)))
+		clients[i] = c
 	}
 
-	var enc encoder
-	if opt.CSV {
-		enc = newCSVWriter(os.Stdout)
-	} else {
-		enc = newJSONWriter(os.Stdout)
-	}
+	var remaining atomic.Int64
+	remaining.Store(int64(opt.N))
+
+	g, ctx := errgroup.WithContext(ctx)
+	start := time.Now()
+	for _, c := range clients {
+		g.Go(func() error {
+			for {
+				if opt.N > 0 && remaining.Add(-1) < 0 {
+					return nil
+				}
+				if opt.Duration > 0 && time.Since(start) >= opt.Duration {
+					return nil
+				}
+
+				idx := pickStatement(c.rng, stmts, totalWeight)
+				args := make([]any, len(stmts[idx].params))
+				for i, gen := range stmts[idx].params {
+					args[i] = gen(c.rng)
+				}
 
-	var totalDuration time.Duration
-	for i := 0; i < opt.N; i += opt.SampleSize {
-		var total time.Duration
+				qstart := time.Now()
+				err := c.exec(ctx, idx, args...)
+				end := time.Now()
+				if err != nil {
+					return errors.Wrapf(err, "statement %q", stmts[idx].Name)
+				}
 
-		for j := 0; j < opt.SampleSize; j++ {
-			start := time.Now()
+				c.latencies[idx] = append(c.latencies[idx], end.Sub(qstart))
+				interval := int(end.Sub(start) / opt.Interval)
+				for len(c.counts) <= interval {
+					c.counts = append(c.counts, 0)
+				}
+				c.counts[interval]++
+			}
+		})
+	}
+
+	err = g.Wait()
+	duration := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
 
-			_, err := e(query)
-			total += time.Since(start)
+	for _, c := range clients {
+		if c.tx != nil {
+			err = c.tx.Commit()
 			if err != nil {
-				return err
+				return nil, err
 			}
 		}
+	}
 
-		totalDuration += total
-		avg := total / time.Duration(opt.SampleSize)
-		qps := int(time.Second / avg)
+	res := BenchResult{
+		Clients:  opt.Clients,
+		Duration: duration,
+		Interval: opt.Interval,
+	}
 
-		err := enc(map[string]interface{}{
-			"totalQueries":     i + opt.SampleSize,
-			"averageDuration":  avg.String(),
-			"queriesPerSecond": qps,
-			"totalDuration":    totalDuration.String(),
-		})
-		if err != nil {
-			return err
+	var all []time.Duration
+	for i, s := range stmts {
+		var latencies []time.Duration
+		for _, c := range clients {
+			latencies = append(latencies, c.latencies[i]...)
+		}
+		all = append(all, latencies...)
+		res.Statements = append(res.Statements, computeBenchStats(s.Name, latencies, duration))
+	}
+	res.Total = computeBenchStats("total", all, duration)
+
+	for _, c := range clients {
+		for i, n := range c.counts {
+			for len(res.Throughput) <= i {
+				res.Throughput = append(res.Throughput, 0)
+			}
+			res.Throughput[i] += float64(n)
+		}
+	}
+	// the last interval is usually shorter than the others: if it is too short
+	// to give a meaningful measure, it is merged with the previous one
+	last := len(res.Throughput) - 1
+	lastLength := duration - time.Duration(last)*opt.Interval
+	if last > 0 && lastLength < opt.Interval/2 {
+		res.Throughput[last-1] += res.Throughput[last]
+		res.Throughput = res.Throughput[:last]
+		last--
+		lastLength += opt.Interval
+	}
+	for i := range res.Throughput {
+		length := opt.Interval
+		if i == last {
+			length = lastLength
 		}
+		res.Throughput[i] /= length.Seconds()
 	}
 
+	return &res, nil
+}
+
+func newBenchClient(ctx context.Context, conn *sql.Conn, stmts []benchStatement, init string, opt BenchOptions) (_ *benchClient, err error) {
+	c := benchClient{
+		latencies: make([][]time.Duration, len(stmts)),
+	}
+	defer func() {
+		if err != nil && c.tx != nil {
+			_ = c.tx.Rollback()
+		}
+	}()
+
+	var p preparer = conn
+	var e execer = conn.ExecContext
+
 	if opt.SameTx {
-		err = tx.Commit()
+		tx, err := conn.BeginTx(ctx, nil)
 		if err != nil {
+			return nil, err
+		}
+		c.tx = tx
+		p = tx
+		e = tx.ExecContext
+
+		if init != "" {
+			_, err := tx.ExecContext(ctx, init)
+			if err != nil {
+				return nil, errors.Wrap(err, "init")
+			}
+		}
+	}
+
+	if !opt.Prepare {
+		c.exec = func(ctx context.Context, stmt int, args ...any) error {
+			_, err := e(ctx, stmts[stmt].Query, args...)
 			return err
 		}
+		return &c, nil
 	}
 
-	return nil
+	prepared := make([]*sql.Stmt, len(stmts))
+	for i, s := range stmts {
+		stmt, err := p.PrepareContext(ctx, s.Query)
+		if err != nil {
+			return nil, errors.Wrapf(err, "statement %q", s.Name)
+		}
+		prepared[i] = stmt
+	}
+	c.exec = func(ctx context.Context, stmt int, args ...any) error {
+		_, err := prepared[stmt].ExecContext(ctx, args...)
+		return err
+	}
+
+	return &c, nil
+}
+
+type preparer interface {
+	PrepareContext(ctx context.Context, q string) (*sql.Stmt, error)
 }
 
-type encoder func(map[string]interface{}) error
+type execer func(ctx context.Context, query string, args ...any) (sql.Result, error)
+
+func compileWorkload(w *Workload) ([]benchStatement, error) {
+	stmts := make([]benchStatement, len(w.Statements))
+	for i, s := range w.Statements {
+		if s.Query == "" {
+			return nil, errors.Errorf("statement %d has no query", i+1)
+		}
+		if s.Name == "" {
+			s.Name = s.Query
+		}
+		if s.Weight < 0 {
+			return nil, errors.Errorf("statement %q: negative weight", s.Name)
+		}
+		if s.Weight == 0 {
+			s.Weight = 1
+		}
 
-func newJSONWriter(w io.Writer) func(map[string]interface{}) error {
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "  ")
-	return func(m map[string]interface{}) error {
-		return enc.Encode(m)
+		stmts[i].WorkloadStatement = s
+		for j, p := range s.Params {
+			gen, err := p.generator()
+			if err != nil {
+				return nil, errors.Wrapf(err, "statement %q: parameter %d", s.Name, j+1)
+			}
+			stmts[i].params = append(stmts[i].params, gen)
+		}
 	}
+
+	return stmts, nil
 }
 
-func newCSVWriter(w io.Writer) func(map[string]interface{}) error {
+func (p *ParamGenerator) generator() (func(r *rand.Rand) any, error) {
+	switch p.Type {
+	case "int":
+		lo, hi := int64(p.Min), int64(p.Max)
+		if hi < lo {
+			return nil, errors.Errorf("max %d lower than min %d", hi, lo)
+		}
+		return func(r *rand.Rand) any {
+			return lo + r.Int64N(hi-lo+1)
+		}, nil
+	case "float":
+		lo, hi := p.Min, p.Max
+		if hi < lo {
+			return nil, errors.Errorf("max %g lower than min %g", hi, lo)
+		}
+		return func(r *rand.Rand) any {
+			return lo + r.Float64()*(hi-lo)
+		}, nil
+	case "text":
+		n := p.Length
+		if n <= 0 {
+			n = 10
+		}
+		return func(r *rand.Rand) any {
+			b := make([]byte, n)
+			for i := range b {
+				b[i] = byte('a' + r.IntN(26))
+			}
+			return string(b)
+		}, nil
+	case "sequence":
+		var seq atomic.Int64
+		seq.Store(int64(p.Min) - 1)
+		return func(r *rand.Rand) any {
+			return seq.Add(1)
+		}, nil
+	case "choice":
+		if len(p.Values) == 0 {
+			return nil, errors.New("no values to choose from")
+		}
+		values := make([]any, len(p.Values))
+		for i, v := range p.Values {
+			// JSON numbers are decoded as floats
+			if f, ok := v.(float64); ok && f == math.Trunc(f) {
+				v = int64(f)
+			}
+			values[i] = v
+		}
+		return func(r *rand.Rand) any {
+			return values[r.IntN(len(values))]
+		}, nil
+	}
+
+	return nil, errors.Errorf("unknown generator type %q, expected int, float, text, sequence or choice", p.Type)
+}
+
+func pickStatement(r *rand.Rand, stmts []benchStatement, totalWeight int) int {
+	n := r.IntN(totalWeight)
+	for i, s := range stmts {
+		if n < s.Weight {
+			return i
+		}
+		n -= s.Weight
+	}
+	return len(stmts) - 1
+}
+
+func computeBenchStats(name string, latencies []time.Duration, duration time.Duration) BenchStats {
+	s := BenchStats{
+		Name:  name,
+		Count: len(latencies),
+		QPS:   float64(len(latencies)) / duration.Seconds(),
+	}
+	if len(latencies) == 0 {
+		return s
+	}
+
+	slices.Sort(latencies)
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+
+		var b int
+		if us := (l + time.Microsecond - 1) / time.Microsecond; us > 1 {
+			b = bits.Len64(uint64(us - 1))
+		}
+		for len(s.Histogram) <= b {
+			s.Histogram = append(s.Histogram, BenchBucket{Le: time.Microsecond << len(s.Histogram)})
+		}
+		s.Histogram[b].Count++
+	}
+
+	s.Mean = total / time.Duration(len(latencies))
+	s.P50 = percentile(latencies, 0.5)
+	s.P95 = percentile(latencies, 0.95)
+	s.P99 = percentile(latencies, 0.99)
+	s.P999 = percentile(latencies, 0.999)
+	s.Max = latencies[len(latencies)-1]
+
+	return s
+}
+
+// percentile returns the p-th percentile of sorted latencies,
+// using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(idx, 0)]
+}
+
+// WriteBenchReport prints the stats of each statement, the latency histogram
+// of all the queries and the throughput over time.
+func WriteBenchReport(w io.Writer, res *BenchResult) error {
+	fmt.Fprintf(w, "Clients: %d, duration: %s, queries: %d, throughput: %.1f q/s\n\n",
+		res.Clients, roundDuration(res.Duration), res.Total.Count, res.Total.QPS)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "STATEMENT\tCOUNT\tQ/S\tMEAN\tP50\tP95\tP99\tP999\tMAX\t")
+	for _, s := range append(res.Statements, res.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t%s\t\n", s.Name, s.Count, s.QPS,
+			roundDuration(s.Mean), roundDuration(s.P50), roundDuration(s.P95),
+			roundDuration(s.P99), roundDuration(s.P999), roundDuration(s.Max))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nLatency histogram:")
+	var maxCount int
+	for _, b := range res.Total.Histogram {
+		maxCount = max(maxCount, b.Count)
+	}
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	first := slices.IndexFunc(res.Total.Histogram, func(b BenchBucket) bool { return b.Count > 0 })
+	for _, b := range res.Total.Histogram[max(first, 0):] {
+		bar := strings.Repeat("#", int(math.Ceil(float64(b.Count)*40/float64(maxCount))))
+		fmt.Fprintf(tw, "  <= %s\t%d\t%s\n", b.Le, b.Count, bar)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w, "\nThroughput:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, qps := range res.Throughput {
+		end := time.Duration(i+1) * res.Interval
+		if i == len(res.Throughput)-1 {
+			end = res.Duration
+		}
+		fmt.Fprintf(tw, "  %s\t%.1f q/s\n", roundDuration(end), qps)
+	}
+	return tw.Flush()
+}
+
+// WriteBenchCSV prints the stats of each statement and of all the queries
+// as semicolon separated values, with latencies in milliseconds.
+func WriteBenchCSV(w io.Writer, res *BenchResult) error {
 	enc := csv.NewWriter(w)
 	enc.Comma = ';'
-	header := []string{"totalQueries", "averageDuration", "queriesPerSecond", "totalDuration"}
-	var headerWritten bool
 
-	return func(m map[string]interface{}) error {
-		if !headerWritten {
-			err := enc.Write(header)
-			if err != nil {
-				return err
-			}
-			headerWritten = true
-		}
-		err := enc.Write([]string{
-			strconv.Itoa(m["totalQueries"].(int)),
-			durationToString(m["averageDuration"].(time.Duration)),
-			strconv.Itoa(m["queriesPerSecond"].(int)),
-			durationToString(m["totalDuration"].(time.Duration)),
+	err := enc.Write([]string{"statement", "count", "queriesPerSecond", "mean", "p50", "p95", "p99", "p999", "max"})
+	if err != nil {
+		return err
+	}
+	for _, s := range append(res.Statements, res.Total) {
+		err = enc.Write([]string{
+			s.Name,
+			strconv.Itoa(s.Count),
+			csvFloat(s.QPS),
+			csvFloat(durationToMilliseconds(s.Mean)),
+			csvFloat(durationToMilliseconds(s.P50)),
+			csvFloat(durationToMilliseconds(s.P95)),
+			csvFloat(durationToMilliseconds(s.P99)),
+			csvFloat(durationToMilliseconds(s.P999)),
+			csvFloat(durationToMilliseconds(s.Max)),
 		})
 		if err != nil {
 			return err
 		}
-		enc.Flush()
-		return enc.Error()
 	}
+
+	enc.Flush()
+	return enc.Error()
 }
 
 func durationToMilliseconds(d time.Duration) float64 {
-	m := d / time.Millisecond
-	nsec := d % time.Millisecond
-	return float64(m) + float64(nsec)/1e6
+	return float64(d) / float64(time.Millisecond)
+}
+
+// csvFloat formats f with a decimal comma, for spreadsheets
+// expecting semicolon separated values.
+func csvFloat(f float64) string {
+	return strings.Replace(strconv.FormatFloat(f, 'f', -1, 64), ".", ",", 1)
+}
+
+// roundDuration keeps the 4 most significant digits of d.
+func roundDuration(d time.Duration) time.Duration {
+	m := time.Duration(1)
+	for d/m >= 10000 {
+		m *= 10
+	}
+	return d.Round(m)
+}
+
+// WriteBenchResult saves res to a JSON file.
+func WriteBenchResult(path string, res *BenchResult) error {
+	data, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ReadBenchResult reads a result saved by WriteBenchResult.
+func ReadBenchResult(path string) (*BenchResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var res BenchResult
+	err = json.Unmarshal(data, &res)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid result file %q", path)
+	}
+
+	return &res, nil
+}
+
+// CompareBench prints the difference between the stats of two results, statement by statement.
+// If threshold is positive, it returns an error if the latency of a statement increased,
+// or its throughput decreased, by more than threshold percent.
+func CompareBench(w io.Writer, a, b *BenchResult, threshold float64) error {
+	var regressions int
+	var added []string
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "STATEMENT\tMETRIC\tOLD\tNEW\tDELTA\t\t")
+
+	for _, sb := range append(b.Statements, b.Total) {
+		var sa *BenchStats
+		if sb.Name == b.Total.Name {
+			sa = &a.Total
+		} else if idx := slices.IndexFunc(a.Statements, func(s BenchStats) bool { return s.Name == sb.Name }); idx >= 0 {
+			sa = &a.Statements[idx]
+		}
+		if sa == nil {
+			added = append(added, sb.Name)
+			continue
+		}
+
+		metrics := []struct {
+			name     string
+			old, new float64
+			str      func(float64) string
+			// higherIsBetter is true for the throughput
+			higherIsBetter bool
+		}{
+			{"q/s", sa.QPS, sb.QPS, func(f float64) string { return fmt.Sprintf("%.1f", f) }, true},
+			{"p50", float64(sa.P50), float64(sb.P50), durationStr, false},
+			{"p95", float64(sa.P95), float64(sb.P95), durationStr, false},
+			{"p99", float64(sa.P99), float64(sb.P99), durationStr, false},
+			{"p999", float64(sa.P999), float64(sb.P999), durationStr, false},
+		}
+
+		for _, m := range metrics {
+			var delta float64
+			if m.old != 0 {
+				delta = (m.new - m.old) / m.old * 100
+			}
+
+			var mark string
+			worse := delta
+			if m.higherIsBetter {
+				worse = -delta
+			}
+			if threshold > 0 && worse > threshold {
+				regressions++
+				mark = "!"
+			}
+
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%+.1f%%\t%s\t\n", sb.Name, m.name, m.str(m.old), m.str(m.new), delta, mark)
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, name := range added {
+		fmt.Fprintf(w, "Statement %q is only in the new result\n", name)
+	}
+	for _, sa := range a.Statements {
+		if !slices.ContainsFunc(b.Statements, func(s BenchStats) bool { return s.Name == sa.Name }) {
+			fmt.Fprintf(w, "Statement %q is only in the old result\n", sa.Name)
+		}
+	}
+
+	if regressions > 0 {
+		return errors.Errorf("found %d regressions above %g%%", regressions, threshold)
+	}
+
+	return nil
 }
 
-func durationToString(d time.Duration) string {
-	ms := durationToMilliseconds(d)
-	return strings.Replace(strconv.FormatFloat(ms, 'f', -1, 64), ".", ",", 1)
+func durationStr(f float64) string {
+	return roundDuration(time.Duration(f)).String()
 }
@@ -0,0 +1,160 @@
+package dbutil
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBench(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workload.json")
+	err := os.WriteFile(path, []byte(`{
+		"init": "CREATE TABLE test(a INT PRIMARY KEY, b TEXT, c INT)",
+		"statements": [
+			{"name": "insert", "weight": 1, "query": "INSERT INTO test (a, b, c) VALUES ($1, $2, $3)",
+			 "params": [{"type": "sequence", "min": 1}, {"type": "text", "length": 5}, {"type": "choice", "values": [1, 2, 3]}]},
+			{"name": "get", "weight": 3, "query": "SELECT * FROM test WHERE a = $1",
+			 "params": [{"type": "int", "min": 1, "max": 100}]}
+		]
+	}`), 0o644)
+	require.NoError(t, err)
+
+	w, err := LoadWorkload(path)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		opt  BenchOptions
+	}{
+		{"clients", BenchOptions{N: 1000, Clients: 4}},
+		{"prepare", BenchOptions{N: 1000, Clients: 4, Prepare: true}},
+		{"tx", BenchOptions{N: 1000, SameTx: true, Prepare: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, err := sql.Open("chai", ":memory:")
+			require.NoError(t, err)
+			defer db.Close()
+
+			res, err := Bench(t.Context(), db, w, test.opt)
+			require.NoError(t, err)
+
+			require.Equal(t, 1000, res.Total.Count)
+			require.Len(t, res.Statements, 2)
+			require.Equal(t, res.Total.Count, res.Statements[0].Count+res.Statements[1].Count)
+			require.NotZero(t, res.Statements[0].Count)
+			require.NotZero(t, res.Statements[1].Count)
+
+			var histogram int
+			for _, b := range res.Total.Histogram {
+				histogram += b.Count
+			}
+			require.Equal(t, res.Total.Count, histogram)
+
+			require.LessOrEqual(t, res.Total.P50, res.Total.P95)
+			require.LessOrEqual(t, res.Total.P95, res.Total.P99)
+			require.LessOrEqual(t, res.Total.P99, res.Total.P999)
+			require.LessOrEqual(t, res.Total.P999, res.Total.Max)
+			require.NotEmpty(t, res.Throughput)
+
+			// the sequence generates a new primary key for each insert
+			var count int
+			err = db.QueryRow("SELECT COUNT(*) FROM test").Scan(&count)
+			require.NoError(t, err)
+			require.Equal(t, res.Statements[0].Count, count)
+
+			var buf bytes.Buffer
+			require.NoError(t, WriteBenchReport(&buf, res))
+			require.Contains(t, buf.String(), "queries: 1000")
+
+			buf.Reset()
+			require.NoError(t, WriteBenchCSV(&buf, res))
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, len(res.Statements)+2)
+			require.Equal(t, "statement;count;queriesPerSecond;mean;p50;p95;p99;p999;max", lines[0])
+			require.True(t, strings.HasPrefix(lines[len(lines)-1], "total;1000;"), lines[len(lines)-1])
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		db, err := sql.Open("chai", ":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		_, err = Bench(t.Context(), db, &Workload{}, BenchOptions{N: 10})
+		require.Error(t, err)
+
+		_, err = Bench(t.Context(), db, w, BenchOptions{N: 10, Clients: 2, SameTx: true})
+		require.Error(t, err)
+
+		bad := Workload{Statements: []WorkloadStatement{{Query: "SELECT $1", Params: []ParamGenerator{{Type: "unknown"}}}}}
+		_, err = Bench(t.Context(), db, &bad, BenchOptions{N: 10})
+		require.ErrorContains(t, err, `unknown generator type "unknown"`)
+
+		bad = Workload{Statements: []WorkloadStatement{{Query: "SELECT * FROM unknown"}}}
+		_, err = Bench(t.Context(), db, &bad, BenchOptions{N: 10, Clients: 2})
+		require.Error(t, err)
+	})
+}
+
+func TestComputeBenchStats(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1000; i > 0; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Microsecond)
+	}
+
+	s := computeBenchStats("test", latencies, time.Second)
+	require.Equal(t, 1000, s.Count)
+	require.Equal(t, 1000.0, s.QPS)
+	require.Equal(t, 500*time.Microsecond, s.P50)
+	require.Equal(t, 950*time.Microsecond, s.P95)
+	require.Equal(t, 990*time.Microsecond, s.P99)
+	require.Equal(t, 999*time.Microsecond, s.P999)
+	require.Equal(t, 1000*time.Microsecond, s.Max)
+
+	require.Equal(t, BenchBucket{Le: time.Microsecond, Count: 1}, s.Histogram[0])
+	require.Equal(t, BenchBucket{Le: 2 * time.Microsecond, Count: 1}, s.Histogram[1])
+	require.Equal(t, BenchBucket{Le: 4 * time.Microsecond, Count: 2}, s.Histogram[2])
+	require.Equal(t, BenchBucket{Le: 1024 * time.Microsecond, Count: 1000 - 512}, s.Histogram[10])
+}
+
+func TestCompareBench(t *testing.T) {
+	dir := t.TempDir()
+
+	a := BenchResult{
+		Total:      BenchStats{Name: "total", QPS: 1000, P50: time.Millisecond, P95: time.Millisecond, P99: time.Millisecond, P999: time.Millisecond},
+		Statements: []BenchStats{{Name: "get", QPS: 1000, P50: time.Millisecond, P95: time.Millisecond, P99: time.Millisecond, P999: time.Millisecond}},
+	}
+	require.NoError(t, WriteBenchResult(filepath.Join(dir, "a.json"), &a))
+
+	b := a
+	b.Total.P99 = 2 * time.Millisecond
+	b.Statements = []BenchStats{{Name: "put", QPS: 1000}}
+	require.NoError(t, WriteBenchResult(filepath.Join(dir, "b.json"), &b))
+
+	ra, err := ReadBenchResult(filepath.Join(dir, "a.json"))
+	require.NoError(t, err)
+	require.Equal(t, &a, ra)
+	rb, err := ReadBenchResult(filepath.Join(dir, "b.json"))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, CompareBench(&buf, ra, ra, 10))
+	require.Contains(t, buf.String(), "+0.0%")
+
+	buf.Reset()
+	require.NoError(t, CompareBench(&buf, ra, rb, 0))
+	require.Contains(t, buf.String(), "+100.0%")
+	require.Contains(t, buf.String(), `Statement "put" is only in the new result`)
+	require.Contains(t, buf.String(), `Statement "get" is only in the old result`)
+
+	err = CompareBench(&buf, ra, rb, 10)
+	require.EqualError(t, err, "found 1 regressions above 10%")
+}
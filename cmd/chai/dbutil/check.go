@@ -0,0 +1,49 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/database/catalogstore"
+	"github.com/cockroachdb/errors"
+)
+
+// Check verifies the consistency of the database located at dbPath and prints
+// the inconsistencies it finds. If tables is not empty, only these tables are checked.
+// With repair, the broken indexes are rebuilt.
+// It returns an error if inconsistencies were found.
+func Check(ctx context.Context, dbPath string, tables []string, repair bool, w io.Writer) error {
+	if dbPath == "" {
+		return errors.New("database path expected")
+	}
+
+	db, err := database.Open(dbPath, &database.Options{
+		CatalogLoader: catalogstore.LoadCatalog,
+		ReadOnly:      !repair,
+	})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	report, err := db.Check(ctx, &database.CheckOptions{Tables: tables, Repair: repair})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range report.Errors {
+		fmt.Fprintln(w, e.Error())
+	}
+	for _, name := range report.Repaired {
+		fmt.Fprintf(w, "Rebuilt index %s\n", name)
+	}
+
+	if len(report.Errors) == 0 {
+		fmt.Fprintln(w, "No inconsistencies found.")
+		return nil
+	}
+
+	return errors.Errorf("found %d inconsistencies", len(report.Errors))
+}
@@ -0,0 +1,34 @@
+package dbutil
+
+import (
+	"bytes"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+
+	db, err := sql.Open("chai", path)
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE test(a INT PRIMARY KEY, b TEXT UNIQUE);
+		INSERT INTO test (a, b) VALUES (1, 'foo'), (2, 'bar');
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	var buf bytes.Buffer
+	require.NoError(t, Check(t.Context(), path, nil, false, &buf))
+	require.Equal(t, "No inconsistencies found.\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, Check(t.Context(), path, []string{"test"}, true, &buf))
+	require.Equal(t, "No inconsistencies found.\n", buf.String())
+
+	err = Check(t.Context(), path, []string{"unknown"}, false, &buf)
+	require.Error(t, err)
+}
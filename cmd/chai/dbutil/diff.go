@@ -0,0 +1,686 @@
+package dbutil
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/database/catalogstore"
+	"github.com/chaisql/chai/internal/encoding"
+	"github.com/chaisql/chai/internal/row"
+	"github.com/chaisql/chai/internal/stringutil"
+	"github.com/chaisql/chai/internal/tree"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/chaisql/chai/migrate"
+	"github.com/cockroachdb/errors"
+)
+
+// DiffOptions configures the comparison of two databases.
+type DiffOptions struct {
+	// Data compares the rows of the tables by primary key.
+	Data bool
+	// SQL outputs a script transforming the first database into the second one,
+	// instead of a report.
+	SQL bool
+}
+
+// OpenReadOnly opens the database located at dbPath in read-only mode.
+func OpenReadOnly(dbPath string) (*database.Database, error) {
+	return database.Open(dbPath, &database.Options{
+		CatalogLoader: catalogstore.LoadCatalog,
+		ReadOnly:      true,
+	})
+}
+
+// Diff compares the schemas of a and b and, optionally, their data.
+// It writes a report of the differences to w, or a SQL script that transforms a into b.
+//
+// In the report, objects and rows that only exist in a are prefixed with "-",
+// the ones that only exist in b with "+" and the ones that differ with "~".
+func Diff(a, b *database.Database, w io.Writer, opts DiffOptions) error {
+	txA, err := beginReadOnly(a)
+	if err != nil {
+		return err
+	}
+	defer txA.Rollback()
+
+	txB, err := beginReadOnly(b)
+	if err != nil {
+		return err
+	}
+	defer txB.Rollback()
+
+	d := differ{w: w, opts: opts, txA: txA, txB: txB}
+
+	if opts.SQL {
+		if _, err := fmt.Fprintln(w, "BEGIN TRANSACTION;"); err != nil {
+			return err
+		}
+	}
+
+	err = d.diffSchema()
+	if err != nil {
+		return err
+	}
+
+	if opts.Data {
+		err = d.diffData()
+		if err != nil {
+			return err
+		}
+	}
+
+	if opts.SQL {
+		_, err = fmt.Fprintln(w, "COMMIT;")
+		return err
+	}
+
+	if d.changes == 0 {
+		_, err = fmt.Fprintln(w, "No differences.")
+	}
+	return err
+}
+
+func beginReadOnly(db *database.Database) (*database.Transaction, error) {
+	conn, err := db.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := conn.BeginTx(&database.TxOptions{ReadOnly: true})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tx.OnRollbackHooks = append(tx.OnRollbackHooks, func() { conn.Close() })
+
+	return tx, nil
+}
+
+type differ struct {
+	w        io.Writer
+	opts     DiffOptions
+	txA, txB *database.Transaction
+
+	// tables dropped and created again. Their rows are all copied from b
+	// when comparing data, or copied from the previous table otherwise.
+	recreated []string
+	// temporary names of the recreated tables whose rows are copied
+	renamed map[string]string
+	changes int
+}
+
+// emit writes a line of the report, or the SQL statements of a change.
+// Statements without a report line are only written to SQL scripts.
+func (d *differ) emit(report string, statements ...string) error {
+	if !d.opts.SQL {
+		if report == "" {
+			return nil
+		}
+
+		d.changes++
+		_, err := fmt.Fprintln(d.w, report)
+		return err
+	}
+
+	for _, stmt := range statements {
+		if _, err := fmt.Fprintf(d.w, "%s;\n", stmt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *differ) diffSchema() error {
+	ca, cb := d.txA.Catalog, d.txB.Catalog
+
+	tablesA, tablesB := userTables(ca), userTables(cb)
+	indexesA, indexesB := userIndexes(ca), userIndexes(cb)
+	sequencesA, sequencesB := userSequences(ca), userSequences(cb)
+
+	// tables whose schema changed are altered if columns were only added,
+	// or dropped and created again
+	var altered []string
+	for _, name := range tablesA {
+		if !slices.Contains(tablesB, name) {
+			continue
+		}
+
+		ta, err := ca.GetTableInfo(name)
+		if err != nil {
+			return err
+		}
+		tb, err := cb.GetTableInfo(name)
+		if err != nil {
+			return err
+		}
+
+		if ta.String() == tb.String() {
+			continue
+		}
+
+		if isAddColumnsOnly(ta, tb) {
+			altered = append(altered, name)
+		} else {
+			d.recreated = append(d.recreated, name)
+		}
+	}
+
+	// drop the indexes that were removed or changed, unless their table is dropped
+	for _, name := range indexesA {
+		ia, _ := ca.GetIndexInfo(name)
+		ib, err := cb.GetIndexInfo(name)
+		if err == nil && ia.String() == ib.String() {
+			continue
+		}
+
+		var statements []string
+		if slices.Contains(tablesB, ia.Owner.TableName) && !slices.Contains(d.recreated, ia.Owner.TableName) {
+			statements = append(statements, "DROP INDEX "+ident(name))
+		}
+
+		err = d.emit("- index "+name+": "+ia.String(), statements...)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range tablesA {
+		if slices.Contains(tablesB, name) {
+			continue
+		}
+
+		info, err := ca.GetTableInfo(name)
+		if err != nil {
+			return err
+		}
+
+		err = d.emit("- table "+name+": "+info.String(), "DROP TABLE "+ident(name))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range d.recreated {
+		stmt := "DROP TABLE " + ident(name)
+		if !d.opts.Data {
+			// keep the table to copy its rows once the new one is created
+			tmp := tempTableName(name, tablesA, tablesB)
+			if d.renamed == nil {
+				d.renamed = make(map[string]string)
+			}
+			d.renamed[name] = tmp
+			stmt = fmt.Sprintf("ALTER TABLE %s RENAME TO %s", ident(name), ident(tmp))
+		}
+
+		err := d.emitTableChanges(name, stmt)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sequencesA {
+		sa, _ := ca.GetSequence(name)
+		sb, err := cb.GetSequence(name)
+		if err == nil && sa.Info.String() == sb.Info.String() {
+			continue
+		}
+
+		if err != nil {
+			err = d.emit("- sequence "+name+": "+sa.Info.String(), "DROP SEQUENCE "+ident(name))
+		} else {
+			err = d.emit("~ sequence "+name+": "+sa.Info.String()+" -> "+sb.Info.String(), "DROP SEQUENCE "+ident(name), sb.Info.String())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range sequencesB {
+		if slices.Contains(sequencesA, name) {
+			continue
+		}
+
+		s, err := cb.GetSequence(name)
+		if err != nil {
+			return err
+		}
+
+		err = d.emit("+ sequence "+name+": "+s.Info.String(), s.Info.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range tablesB {
+		if slices.Contains(tablesA, name) {
+			continue
+		}
+
+		info, err := cb.GetTableInfo(name)
+		if err != nil {
+			return err
+		}
+
+		err = d.emit("+ table "+name+": "+info.String(), info.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range d.recreated {
+		info, err := cb.GetTableInfo(name)
+		if err != nil {
+			return err
+		}
+
+		statements := []string{info.String()}
+		if tmp, ok := d.renamed[name]; ok {
+			old, err := ca.GetTableInfo(name)
+			if err != nil {
+				return err
+			}
+
+			var columns []string
+			for _, cc := range info.ColumnConstraints.Ordered {
+				if old.ColumnConstraints.GetColumnConstraint(cc.Column) != nil {
+					columns = append(columns, ident(cc.Column))
+				}
+			}
+			if len(columns) > 0 {
+				list := strings.Join(columns, ", ")
+				statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", ident(name), list, list, ident(tmp)))
+			}
+			statements = append(statements, "DROP TABLE "+ident(tmp))
+		}
+
+		// the changes were reported when the table was dropped
+		if err := d.emit("", statements...); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range altered {
+		ta, err := ca.GetTableInfo(name)
+		if err != nil {
+			return err
+		}
+		tb, err := cb.GetTableInfo(name)
+		if err != nil {
+			return err
+		}
+
+		var statements []string
+		for _, cc := range tb.ColumnConstraints.Ordered[len(ta.ColumnConstraints.Ordered):] {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", ident(name), cc.String()))
+		}
+
+		if err := d.emitTableChanges(name, statements...); err != nil {
+			return err
+		}
+	}
+
+	// create the indexes that were added or changed, or whose table was created again
+	for _, name := range indexesB {
+		ib, _ := cb.GetIndexInfo(name)
+		report := "+ index " + name + ": " + ib.String()
+		ia, err := ca.GetIndexInfo(name)
+		if err == nil && ia.String() == ib.String() {
+			if !slices.Contains(d.recreated, ib.Owner.TableName) {
+				continue
+			}
+			report = ""
+		}
+
+		err = d.emit(report, ib.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// emitTableChanges reports the differences between the columns and
+// constraints of a table, or emits the given statements.
+func (d *differ) emitTableChanges(name string, statements ...string) error {
+	if d.opts.SQL {
+		return d.emit("", statements...)
+	}
+
+	ta, err := d.txA.Catalog.GetTableInfo(name)
+	if err != nil {
+		return err
+	}
+	tb, err := d.txB.Catalog.GetTableInfo(name)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{"~ table " + name + ":"}
+	for _, cc := range ta.ColumnConstraints.Ordered {
+		other := tb.ColumnConstraints.GetColumnConstraint(cc.Column)
+		switch {
+		case other == nil:
+			lines = append(lines, "    - column "+cc.String())
+		case other.String() != cc.String():
+			lines = append(lines, "    ~ column "+cc.String()+" -> "+other.String())
+		}
+	}
+	for _, cc := range tb.ColumnConstraints.Ordered {
+		if ta.ColumnConstraints.GetColumnConstraint(cc.Column) == nil {
+			lines = append(lines, "    + column "+cc.String())
+		}
+	}
+
+	constraints := func(info *database.TableInfo) []string {
+		var list []string
+		for _, tc := range info.TableConstraints {
+			list = append(list, tc.String())
+		}
+		return list
+	}
+	ca, cb := constraints(ta), constraints(tb)
+	for _, c := range ca {
+		if !slices.Contains(cb, c) {
+			lines = append(lines, "    - "+c)
+		}
+	}
+	for _, c := range cb {
+		if !slices.Contains(ca, c) {
+			lines = append(lines, "    + "+c)
+		}
+	}
+
+	// other differences, such as the options of the table
+	if len(lines) == 1 {
+		lines = append(lines, "    - "+ta.String(), "    + "+tb.String())
+	}
+
+	return d.emit(strings.Join(lines, "\n"))
+}
+
+// tempTableName returns a name for a table recreated as name,
+// which is not used by any table of both databases.
+func tempTableName(name string, tablesA, tablesB []string) string {
+	tmp := name + "_old"
+	for i := 2; slices.Contains(tablesA, tmp) || slices.Contains(tablesB, tmp); i++ {
+		tmp = fmt.Sprintf("%s_old%d", name, i)
+	}
+	return tmp
+}
+
+// isAddColumnsOnly returns true if b only differs from a by the columns added after the columns of a.
+func isAddColumnsOnly(a, b *database.TableInfo) bool {
+	if len(b.ColumnConstraints.Ordered) <= len(a.ColumnConstraints.Ordered) {
+		return false
+	}
+
+	for i, cc := range a.ColumnConstraints.Ordered {
+		if cc.String() != b.ColumnConstraints.Ordered[i].String() {
+			return false
+		}
+	}
+
+	// compare the rest of the definitions without the added columns
+	cp := b.Clone()
+	cp.ColumnConstraints.Ordered = cp.ColumnConstraints.Ordered[:len(a.ColumnConstraints.Ordered)]
+	return cp.String() == a.String()
+}
+
+func (d *differ) diffData() error {
+	tablesA, tablesB := userTables(d.txA.Catalog), userTables(d.txB.Catalog)
+
+	for _, name := range tablesB {
+		tb, err := d.txB.Catalog.GetTable(d.txB, name)
+		if err != nil {
+			return err
+		}
+
+		// rows of new tables are all copied
+		if !slices.Contains(tablesA, name) || slices.Contains(d.recreated, name) {
+			err = iterateTable(tb, func(r database.Row) error {
+				return d.emitInsert(tb.Info, r)
+			})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		ta, err := d.txA.Catalog.GetTable(d.txA, name)
+		if err != nil {
+			return err
+		}
+
+		err = d.diffRows(ta, tb)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// diffRows compares the rows of two tables with the same primary key.
+// Both tables are iterated in the order of their keys.
+func (d *differ) diffRows(ta, tb *database.Table) error {
+	itA, err := ta.Iterator(nil)
+	if err != nil {
+		return err
+	}
+	defer itA.Close()
+
+	itB, err := tb.Iterator(nil)
+	if err != nil {
+		return err
+	}
+	defer itB.Close()
+
+	itA.First()
+	itB.First()
+	for itA.Valid() || itB.Valid() {
+		var ra, rb database.Row
+		if itA.Valid() {
+			if ra, err = itA.Value(); err != nil {
+				return err
+			}
+		}
+		if itB.Valid() {
+			if rb, err = itB.Value(); err != nil {
+				return err
+			}
+		}
+
+		var cmp int
+		switch {
+		case ra == nil:
+			cmp = 1
+		case rb == nil:
+			cmp = -1
+		default:
+			// keys are compared without the namespace of their table
+			ka, kb := ra.Key().Encoded, rb.Key().Encoded
+			cmp = bytes.Compare(ka[encoding.Skip(ka):], kb[encoding.Skip(kb):])
+		}
+
+		switch {
+		case cmp < 0:
+			err = d.emitDelete(ta.Info, ra)
+			itA.Next()
+		case cmp > 0:
+			err = d.emitInsert(tb.Info, rb)
+			itB.Next()
+		default:
+			err = d.emitUpdate(tb.Info, ra, rb)
+			itA.Next()
+			itB.Next()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := itA.Error(); err != nil {
+		return err
+	}
+	return itB.Error()
+}
+
+func iterateTable(t *database.Table, fn func(r database.Row) error) error {
+	it, err := t.Iterator(nil)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.First(); it.Valid(); it.Next() {
+		r, err := it.Value()
+		if err != nil {
+			return err
+		}
+
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+
+	return it.Error()
+}
+
+func (d *differ) emitInsert(info *database.TableInfo, r database.Row) error {
+	data, err := row.MarshalJSON(r)
+	if err != nil {
+		return err
+	}
+
+	var columns, values []string
+	err = r.Iterate(func(c string, v types.Value) error {
+		columns = append(columns, ident(c))
+		values = append(values, sqlLiteral(v))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return d.emit(fmt.Sprintf("+ %s %s: %s", info.TableName, r.Key(), data),
+		fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", ident(info.TableName), strings.Join(columns, ", "), strings.Join(values, ", ")))
+}
+
+func (d *differ) emitDelete(info *database.TableInfo, r database.Row) error {
+	data, err := row.MarshalJSON(r)
+	if err != nil {
+		return err
+	}
+
+	where, err := primaryKeyCondition(info, r.Key())
+	if err != nil {
+		return err
+	}
+
+	return d.emit(fmt.Sprintf("- %s %s: %s", info.TableName, r.Key(), data),
+		fmt.Sprintf("DELETE FROM %s WHERE %s", ident(info.TableName), where))
+}
+
+// emitUpdate compares the columns of b with the ones of a.
+// Columns missing from a, which were added to the table, are NULL.
+func (d *differ) emitUpdate(info *database.TableInfo, a, b database.Row) error {
+	var changes, set []string
+	err := b.Iterate(func(c string, vb types.Value) error {
+		va, err := a.Get(c)
+		if err != nil && !errors.Is(err, types.ErrColumnNotFound) {
+			return err
+		}
+		if va == nil {
+			va = types.NewNullValue()
+		}
+
+		if valuesEqual(va, vb) {
+			return nil
+		}
+
+		changes = append(changes, fmt.Sprintf("%s: %s -> %s", c, va, vb))
+		set = append(set, ident(c)+" = "+sqlLiteral(vb))
+		return nil
+	})
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	where, err := primaryKeyCondition(info, b.Key())
+	if err != nil {
+		return err
+	}
+
+	return d.emit(fmt.Sprintf("~ %s %s: %s", info.TableName, b.Key(), strings.Join(changes, ", ")),
+		fmt.Sprintf("UPDATE %s SET %s WHERE %s", ident(info.TableName), strings.Join(set, ", "), where))
+}
+
+func primaryKeyCondition(info *database.TableInfo, key *tree.Key) (string, error) {
+	values, err := key.Decode()
+	if err != nil {
+		return "", err
+	}
+
+	conds := make([]string, len(values))
+	for i, v := range values {
+		conds[i] = ident(info.PrimaryKey.Columns[i]) + " = " + sqlLiteral(v)
+	}
+
+	return strings.Join(conds, " AND "), nil
+}
+
+func valuesEqual(a, b types.Value) bool {
+	if a.Type() == types.TypeNull || b.Type() == types.TypeNull {
+		return a.Type() == b.Type()
+	}
+
+	eq, err := a.EQ(b)
+	return err == nil && eq
+}
+
+// sqlLiteral returns the representation of v in a SQL statement.
+func sqlLiteral(v types.Value) string {
+	if v.Type() == types.TypeTimestamp {
+		return types.NewTextValue(types.AsTime(v).Format(time.RFC3339Nano)).String()
+	}
+
+	return v.String()
+}
+
+func ident(name string) string {
+	return stringutil.NormalizeIdentifier(name, '`')
+}
+
+func userTables(c *database.Catalog) []string {
+	return slices.DeleteFunc(c.ListTables(), isInternal)
+}
+
+// userIndexes returns the indexes that are not created by a table constraint.
+func userIndexes(c *database.Catalog) []string {
+	return slices.DeleteFunc(c.ListIndexes(""), func(name string) bool {
+		info, err := c.GetIndexInfo(name)
+		return err != nil || len(info.Owner.Columns) > 0 || isInternal(info.Owner.TableName)
+	})
+}
+
+// userSequences returns the sequences that are not owned by a table.
+func userSequences(c *database.Catalog) []string {
+	return slices.DeleteFunc(c.ListSequences(), func(name string) bool {
+		s, err := c.GetSequence(name)
+		return err != nil || s.Info.Owner.TableName != "" || isInternal(name)
+	})
+}
+
+// isInternal returns whether name is an internal table, index or sequence.
+// The migrations table is compared like the tables of the user,
+// since it depends on the schema of the database.
+func isInternal(name string) bool {
+	return strings.HasPrefix(name, database.InternalPrefix) && name != migrate.TableName
+}
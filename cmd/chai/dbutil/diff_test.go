@@ -0,0 +1,153 @@
+package dbutil
+
+import (
+	"bytes"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	pathA, pathB := filepath.Join(dir, "a"), filepath.Join(dir, "b")
+
+	exec := func(path, q string) {
+		t.Helper()
+
+		db, err := sql.Open("chai", path)
+		require.NoError(t, err)
+		defer db.Close()
+
+		_, err = db.Exec(q)
+		require.NoError(t, err)
+	}
+
+	exec(pathA, `
+		CREATE SEQUENCE s;
+		CREATE SEQUENCE old_seq;
+		CREATE TABLE users(id INT PRIMARY KEY, name TEXT);
+		CREATE INDEX users_name_idx ON users(name);
+		CREATE TABLE old(id INT PRIMARY KEY);
+		CREATE TABLE items(id INT PRIMARY KEY, price INT);
+		INSERT INTO users (id, name) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+		INSERT INTO items (id, price) VALUES (1, 10);
+	`)
+	exec(pathB, `
+		CREATE SEQUENCE s INCREMENT BY 2;
+		CREATE TABLE users(id INT PRIMARY KEY, name TEXT, age INT);
+		CREATE INDEX users_age_idx ON users(age);
+		CREATE TABLE items(id INT PRIMARY KEY, price DOUBLE PRECISION);
+		CREATE TABLE events(id INT PRIMARY KEY, at TIMESTAMP UNIQUE);
+		INSERT INTO users (id, name) VALUES (1, 'a'), (3, 'c');
+		INSERT INTO users (id, name, age) VALUES (2, 'b', 20), (4, 'it\'s', 40);
+		INSERT INTO items (id, price) VALUES (1, 10.5);
+		INSERT INTO events (id, at) VALUES (1, '2025-01-02T03:04:05Z');
+	`)
+
+	diff := func(opts DiffOptions) string {
+		t.Helper()
+
+		a, err := OpenReadOnly(pathA)
+		require.NoError(t, err)
+		defer a.Close()
+
+		b, err := OpenReadOnly(pathB)
+		require.NoError(t, err)
+		defer b.Close()
+
+		var buf bytes.Buffer
+		require.NoError(t, Diff(a, b, &buf, opts))
+		return buf.String()
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		require.Equal(t, `- index users_name_idx: CREATE INDEX users_name_idx ON users (name)
+- table old: CREATE TABLE old (id INTEGER NOT NULL, CONSTRAINT old_pk PRIMARY KEY (id))
+~ table items:
+    ~ column price INTEGER -> price DOUBLE PRECISION
+- sequence old_seq: CREATE SEQUENCE old_seq
+~ sequence s: CREATE SEQUENCE s -> CREATE SEQUENCE s INCREMENT BY 2
++ table events: CREATE TABLE events (id INTEGER NOT NULL, at TIMESTAMP, CONSTRAINT events_pk PRIMARY KEY (id), CONSTRAINT events_at_unique UNIQUE (at))
+~ table users:
+    + column age INTEGER
++ index users_age_idx: CREATE INDEX users_age_idx ON users (age)
+`, diff(DiffOptions{}))
+	})
+
+	t.Run("Data", func(t *testing.T) {
+		// rows of created tables are all inserted
+		require.Equal(t, `+ events (1): {"id": 1, "at": "2025-01-02T03:04:05Z"}
++ items (1): {"id": 1, "price": 10.5}
+~ users (2): age: NULL -> 20
++ users (4): {"id": 4, "name": "it's", "age": 40}
+`, diff(DiffOptions{Data: true})[len(diff(DiffOptions{})):])
+	})
+
+	t.Run("SQL", func(t *testing.T) {
+		script := diff(DiffOptions{Data: true, SQL: true})
+		require.Contains(t, script, "ALTER TABLE users ADD COLUMN age INTEGER;\n")
+		require.Contains(t, script, "DROP TABLE items;\n")
+		require.Contains(t, script, "INSERT INTO items (id, price) VALUES (1, 10.5);\n")
+
+		exec(pathA, script)
+		require.Equal(t, "No differences.\n", diff(DiffOptions{Data: true}))
+	})
+
+	t.Run("Recreated tables keep their rows", func(t *testing.T) {
+		pathA, pathB := filepath.Join(dir, "c"), filepath.Join(dir, "d")
+		exec(pathA, `
+			CREATE TABLE t(id INT PRIMARY KEY, a INT, b TEXT);
+			CREATE INDEX t_a_idx ON t(a);
+			INSERT INTO t (id, a, b) VALUES (1, 10, 'x'), (2, 20, 'y');
+		`)
+		exec(pathB, `
+			CREATE TABLE t(id INT PRIMARY KEY, a INT NOT NULL);
+			CREATE INDEX t_a_idx ON t(a);
+			INSERT INTO t (id, a) VALUES (1, 10), (2, 20);
+		`)
+
+		a, err := OpenReadOnly(pathA)
+		require.NoError(t, err)
+		b, err := OpenReadOnly(pathB)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		err = Diff(a, b, &buf, DiffOptions{SQL: true})
+		require.NoError(t, a.Close())
+		require.NoError(t, b.Close())
+		require.NoError(t, err)
+
+		require.Equal(t, `BEGIN TRANSACTION;
+ALTER TABLE t RENAME TO t_old;
+CREATE TABLE t (id INTEGER NOT NULL, a INTEGER NOT NULL, CONSTRAINT t_pk PRIMARY KEY (id));
+INSERT INTO t (id, a) SELECT id, a FROM t_old;
+DROP TABLE t_old;
+CREATE INDEX t_a_idx ON t (a);
+COMMIT;
+`, buf.String())
+
+		exec(pathA, buf.String())
+
+		db, err := sql.Open("chai", pathA)
+		require.NoError(t, err)
+		defer db.Close()
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM t WHERE a >= 10").Scan(&n))
+		require.Equal(t, 2, n)
+	})
+
+	t.Run("Deleted rows", func(t *testing.T) {
+		exec(pathA, `INSERT INTO users (id, name) VALUES (5, 'e'); UPDATE users SET name = 'z' WHERE id = 1`)
+
+		require.Equal(t, `~ users (1): name: 'z' -> 'a'
+- users (5): {"id": 5, "name": "e", "age": null}
+`, diff(DiffOptions{Data: true}))
+
+		require.Equal(t, `BEGIN TRANSACTION;
+UPDATE users SET name = 'a' WHERE id = 1;
+DELETE FROM users WHERE id = 5;
+COMMIT;
+`, diff(DiffOptions{Data: true, SQL: true}))
+	})
+}
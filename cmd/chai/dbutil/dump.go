@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 )
 
 // Dump takes a database and dumps its content as SQL queries in the given writer.
@@ -110,6 +111,12 @@ func dumpTable(ctx context.Context, tx *sql.Tx, w io.Writer, query, tableName st
 				sb.WriteString(strings.ReplaceAll(v, "'", "\\'"))
 				sb.WriteByte('\'')
 				continue
+			case time.Time:
+				// Display as single quoted RFC 3339 timestamp.
+				sb.WriteByte('\'')
+				sb.WriteString(v.UTC().Format(time.RFC3339Nano))
+				sb.WriteByte('\'')
+				continue
 			default:
 				fmt.Fprintf(&sb, "%v", v)
 			}
@@ -1,11 +1,9 @@
 package dbutil
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"io"
-	"strings"
 
 	"github.com/chaisql/chai/internal/database"
 	"github.com/chaisql/chai/internal/query"
@@ -16,16 +14,28 @@ import (
 )
 
 // ExecSQL reads SQL queries from reader and executes them until the reader is exhausted.
-// If the query has results, they will be outputted to w.
-func ExecSQL(ctx context.Context, db *sql.DB, r io.Reader, w io.Writer) error {
+// If the query has results, they will be outputted to w, formatted according to opts.
+// If opts is nil, results are outputted as pipe-separated values.
+// Queries returning no rows still output the header of their result, if any.
+func ExecSQL(ctx context.Context, db *sql.DB, r io.Reader, w io.Writer, opts *ExecOptions) error {
+	if opts == nil {
+		opts = &ExecOptions{Format: FormatList, NullValue: DefaultNullValue}
+	}
+	if opts.Format == "" {
+		o := *opts
+		o.Format = FormatList
+		opts = &o
+	}
+	if err := ValidateFormat(opts.Format); err != nil {
+		return err
+	}
+
 	conn, err := db.Conn(ctx)
 	if err != nil {
 		return err
 	}
 	defer conn.Close()
 
-	var buf bytes.Buffer
-
 	return conn.Raw(func(driverConn any) error {
 		conn := driverConn.(*driver.Conn)
 
@@ -46,47 +56,71 @@ func ExecSQL(ctx context.Context, db *sql.DB, r io.Reader, w io.Writer) error {
 				return err
 			}
 
-			var headerPrinted bool
+			var rw resultWriter
+			values := make([]types.Value, 0, len(cols))
+
+			start := func() (err error) {
+				// separate the results of each statement,
+				// except for JSON lines which must only contain objects.
+				if stmtWithOutputCount > 0 && opts.Format != FormatJSONL {
+					if _, err := io.WriteString(w, "\n"); err != nil {
+						return err
+					}
+				}
+				stmtWithOutputCount++
+
+				rw, err = newResultWriter(w, opts, cols)
+				return err
+			}
 
-			return res.Iterate(func(r database.Row) error {
-				buf.Reset()
+			err = res.Iterate(func(r database.Row) error {
 				if err := ctx.Err(); err != nil {
 					return err
 				}
 
-				if !headerPrinted {
-					if stmtWithOutputCount > 0 {
-						buf.WriteString("\n")
+				if rw == nil {
+					if err := start(); err != nil {
+						return err
 					}
-					stmtWithOutputCount++
-
-					buf.WriteString(strings.Join(cols, "|"))
-					buf.WriteString("\n")
-					headerPrinted = true
 				}
 
-				var i int
+				values = values[:0]
 				err = r.Iterate(func(column string, value types.Value) error {
-					if i > 0 {
-						buf.WriteString("|")
-					}
-					if value == nil {
-						buf.WriteString("NULL")
-					} else {
-						buf.WriteString(value.String())
-					}
-					i++
+					values = append(values, value)
 					return nil
 				})
 				if err != nil {
 					return err
 				}
 
-				buf.WriteString("\n")
-
-				_, err = w.Write(buf.Bytes())
-				return err
+				return rw.WriteRow(values)
 			})
+			if err != nil {
+				return err
+			}
+
+			if rw == nil {
+				if !returnsRows(s) {
+					return nil
+				}
+				if err := start(); err != nil {
+					return err
+				}
+			}
+
+			return rw.Close()
 		})
 	})
 }
+
+// returnsRows returns whether stmt returns rows, even if there are none.
+func returnsRows(stmt statement.Statement) bool {
+	switch st := stmt.(type) {
+	case *statement.SelectStmt, *statement.ExplainStmt:
+		return true
+	case *statement.InsertStmt:
+		return len(st.Returning) > 0
+	}
+
+	return false
+}
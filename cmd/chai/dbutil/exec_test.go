@@ -3,6 +3,7 @@ package dbutil
 import (
 	"bytes"
 	"database/sql"
+	"io"
 	"strings"
 	"testing"
 
@@ -27,7 +28,7 @@ func TestExecSQL(t *testing.T) {
 		SELECT * FROM test;
 		COMMIT;
 		SELECT b, a FROM test;
-	`), &got)
+	`), &got, nil)
 	require.NoError(t, err)
 
 	require.Equal(t, "a|b\n1|'a'\n2|'b'\n3|'c'\n\nb|a\n'a'|1\n'b'|2\n'c'|3\n", got.String())
@@ -43,3 +44,117 @@ func TestExecSQL(t *testing.T) {
 	require.Equal(t, 1, res.A)
 	require.Equal(t, "a", res.B)
 }
+
+func TestExecSQLFormats(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE test(a INT PRIMARY KEY, b TEXT, c BYTEA);
+		INSERT INTO test (a, b, c) VALUES (1, 'hello, "world"', '\xaabb'), (20, NULL, NULL);
+	`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO test (a, b) VALUES (3, $1)`, "a|b\nc")
+	require.NoError(t, err)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{FormatList, "a|b|c\n1|'hello, \"world\"'|'\\xaabb'\n3|'a|b\nc'|-\n20|-|-\n"},
+		{FormatTable, `+----+----------------+--------+
+| a  | b              | c      |
++----+----------------+--------+
+|  1 | hello, "world" | \xaabb |
+|  3 | a|b\nc         | -      |
+| 20 | -              | -      |
++----+----------------+--------+
+`},
+		{FormatMarkdown, `| a  | b              | c      |
+|---:|----------------|--------|
+|  1 | hello, "world" | \xaabb |
+|  3 | a\|b\nc        | -      |
+| 20 | -              | -      |
+`},
+		{FormatCSV, "a,b,c\n1,\"hello, \"\"world\"\"\",\\xaabb\n3,\"a|b\nc\",-\n20,-,-\n"},
+		{FormatJSON, `[
+  {"a": 1, "b": "hello, \"world\"", "c": "qrs="},
+  {"a": 3, "b": "a|b\nc", "c": null},
+  {"a": 20, "b": null, "c": null}
+]
+`},
+		{FormatJSONL, `{"a": 1, "b": "hello, \"world\"", "c": "qrs="}
+{"a": 3, "b": "a|b\nc", "c": null}
+{"a": 20, "b": null, "c": null}
+`},
+		{FormatLine, `a = 1
+b = hello, "world"
+c = \xaabb
+
+a = 3
+b = a|b
+    c
+c = -
+
+a = 20
+b = -
+c = -
+`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var got bytes.Buffer
+			err := ExecSQL(t.Context(), db, strings.NewReader("SELECT * FROM test"), &got, &ExecOptions{
+				Format:    test.format,
+				NullValue: "-",
+			})
+			require.NoError(t, err)
+			require.Equal(t, test.want, got.String())
+		})
+	}
+
+	err = ExecSQL(t.Context(), db, strings.NewReader("SELECT * FROM test"), io.Discard, &ExecOptions{Format: "xml"})
+	require.Error(t, err)
+}
+
+func TestExecSQLEmptyResult(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE test(a INT PRIMARY KEY, b TEXT)")
+	require.NoError(t, err)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", "a|b\n"},
+		{FormatList, "a|b\n"},
+		{FormatTable, `+---+---+
+| a | b |
++---+---+
++---+---+
+`},
+		{FormatMarkdown, `| a | b |
+|---|---|
+`},
+		{FormatCSV, "a,b\n"},
+		{FormatJSON, "[]\n"},
+		{FormatJSONL, ""},
+		{FormatLine, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var got bytes.Buffer
+			err := ExecSQL(t.Context(), db, strings.NewReader("UPDATE test SET b = 'a'; SELECT * FROM test"), &got, &ExecOptions{
+				Format: test.format,
+			})
+			require.NoError(t, err)
+			require.Equal(t, test.want, got.String())
+		})
+	}
+}
@@ -0,0 +1,390 @@
+package dbutil
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chaisql/chai/internal/types"
+)
+
+// Output formats of query results.
+const (
+	// FormatList outputs values as SQL literals separated by pipes.
+	FormatList = "list"
+	// FormatTable outputs an aligned table.
+	FormatTable = "table"
+	// FormatCSV outputs RFC 4180 CSV, with a header.
+	FormatCSV = "csv"
+	// FormatJSON outputs a JSON array of objects per query.
+	FormatJSON = "json"
+	// FormatJSONL outputs one JSON object per line.
+	FormatJSONL = "jsonl"
+	// FormatMarkdown outputs a Markdown table.
+	FormatMarkdown = "markdown"
+	// FormatLine outputs one line per column, with a blank line between rows.
+	FormatLine = "line"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{FormatList, FormatTable, FormatCSV, FormatJSON, FormatJSONL, FormatMarkdown, FormatLine}
+
+// DefaultNullValue is the text displayed for NULL values by default.
+const DefaultNullValue = "NULL"
+
+// ExecOptions controls how the results of queries are written.
+type ExecOptions struct {
+	// Format of the results. Defaults to FormatList.
+	Format string
+	// NullValue is the text displayed for NULL values.
+	// It is ignored by the JSON formats.
+	NullValue string
+}
+
+// ValidateFormat returns an error if format is not a supported output format.
+func ValidateFormat(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown format %q, expected one of %s", format, strings.Join(Formats, ", "))
+}
+
+// resultWriter writes the rows of a query result.
+type resultWriter interface {
+	WriteRow(values []types.Value) error
+	// Close writes what remains of the result.
+	Close() error
+}
+
+func newResultWriter(w io.Writer, opts *ExecOptions, columns []string) (resultWriter, error) {
+	switch opts.Format {
+	case "", FormatList:
+		return newListWriter(w, opts.NullValue, columns), nil
+	case FormatTable:
+		return &tableWriter{w: w, null: opts.NullValue, columns: columns}, nil
+	case FormatMarkdown:
+		return &tableWriter{w: w, null: opts.NullValue, columns: columns, markdown: true}, nil
+	case FormatCSV:
+		return newCSVResultWriter(w, opts.NullValue, columns)
+	case FormatJSON, FormatJSONL:
+		return &jsonWriter{w: bufio.NewWriter(w), columns: columns, lines: opts.Format == FormatJSONL}, nil
+	case FormatLine:
+		return newLineWriter(w, opts.NullValue, columns), nil
+	}
+
+	return nil, ValidateFormat(opts.Format)
+}
+
+// formatValue returns the text representation of a value, without the quotes
+// of SQL literals.
+func formatValue(v types.Value, null string) string {
+	if v == nil {
+		return null
+	}
+
+	switch v.Type() {
+	case types.TypeNull:
+		return null
+	case types.TypeText:
+		return types.AsString(v)
+	case types.TypeBytea:
+		return `\x` + hex.EncodeToString(types.AsByteSlice(v))
+	case types.TypeTimestamp:
+		return types.AsTime(v).Format(time.RFC3339Nano)
+	case types.TypeUUID:
+		return v.(types.UUIDValue).Canonical()
+	}
+
+	return v.String()
+}
+
+// escapeControl escapes the characters that would break the layout of a table.
+var escapeControl = strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+type listWriter struct {
+	w    io.Writer
+	null string
+	buf  strings.Builder
+}
+
+func newListWriter(w io.Writer, null string, columns []string) *listWriter {
+	lw := listWriter{w: w, null: null}
+	lw.buf.WriteString(strings.Join(columns, "|"))
+	lw.buf.WriteString("\n")
+	return &lw
+}
+
+func (lw *listWriter) WriteRow(values []types.Value) error {
+	for i, v := range values {
+		if i > 0 {
+			lw.buf.WriteString("|")
+		}
+		if v == nil || v.Type() == types.TypeNull {
+			lw.buf.WriteString(lw.null)
+		} else {
+			lw.buf.WriteString(v.String())
+		}
+	}
+	lw.buf.WriteString("\n")
+
+	_, err := io.WriteString(lw.w, lw.buf.String())
+	lw.buf.Reset()
+	return err
+}
+
+// Close writes the header if no row was written.
+func (lw *listWriter) Close() error {
+	if lw.buf.Len() == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(lw.w, lw.buf.String())
+	lw.buf.Reset()
+	return err
+}
+
+// tableWriter buffers the rows to compute the width of the columns.
+type tableWriter struct {
+	w        io.Writer
+	null     string
+	columns  []string
+	markdown bool
+	rows     [][]string
+	// numeric columns are right aligned
+	numeric []bool
+}
+
+func (tw *tableWriter) WriteRow(values []types.Value) error {
+	if tw.numeric == nil {
+		tw.numeric = make([]bool, len(values))
+		for i, v := range values {
+			tw.numeric[i] = v != nil && v.Type().IsNumber()
+		}
+	}
+
+	row := make([]string, len(values))
+	for i, v := range values {
+		s := escapeControl.Replace(formatValue(v, tw.null))
+		if tw.markdown {
+			s = strings.ReplaceAll(s, "|", `\|`)
+		}
+		row[i] = s
+	}
+
+	tw.rows = append(tw.rows, row)
+	return nil
+}
+
+func (tw *tableWriter) Close() error {
+	if tw.numeric == nil {
+		tw.numeric = make([]bool, len(tw.columns))
+	}
+
+	widths := make([]int, len(tw.columns))
+	for i, c := range tw.columns {
+		widths[i] = utf8.RuneCountInString(c)
+	}
+	for _, row := range tw.rows {
+		for i, s := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(s))
+		}
+	}
+
+	w := bufio.NewWriter(tw.w)
+
+	separator := func() {
+		if tw.markdown {
+			w.WriteString("|")
+			for i := range tw.columns {
+				if tw.numeric[i] {
+					w.WriteString(strings.Repeat("-", widths[i]+1) + ":|")
+				} else {
+					w.WriteString(strings.Repeat("-", widths[i]+2) + "|")
+				}
+			}
+		} else {
+			w.WriteString("+")
+			for i := range tw.columns {
+				w.WriteString(strings.Repeat("-", widths[i]+2) + "+")
+			}
+		}
+		w.WriteString("\n")
+	}
+
+	line := func(row []string, header bool) {
+		w.WriteString("|")
+		for i, s := range row {
+			pad := strings.Repeat(" ", widths[i]-utf8.RuneCountInString(s))
+			if tw.numeric[i] && !header {
+				w.WriteString(" " + pad + s + " |")
+			} else {
+				w.WriteString(" " + s + pad + " |")
+			}
+		}
+		w.WriteString("\n")
+	}
+
+	if !tw.markdown {
+		separator()
+	}
+	line(tw.columns, true)
+	separator()
+	for _, row := range tw.rows {
+		line(row, false)
+	}
+	if !tw.markdown {
+		separator()
+	}
+
+	return w.Flush()
+}
+
+type csvResultWriter struct {
+	w      *csv.Writer
+	null   string
+	record []string
+}
+
+func newCSVResultWriter(w io.Writer, null string, columns []string) (*csvResultWriter, error) {
+	cw := csvResultWriter{w: csv.NewWriter(w), null: null}
+	if err := cw.w.Write(columns); err != nil {
+		return nil, err
+	}
+
+	return &cw, nil
+}
+
+func (cw *csvResultWriter) WriteRow(values []types.Value) error {
+	cw.record = cw.record[:0]
+	for _, v := range values {
+		cw.record = append(cw.record, formatValue(v, cw.null))
+	}
+
+	if err := cw.w.Write(cw.record); err != nil {
+		return err
+	}
+
+	// flush every row so that results are displayed as they come
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+func (cw *csvResultWriter) Close() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonWriter struct {
+	w       *bufio.Writer
+	columns []string
+	lines   bool
+	count   int
+}
+
+func (jw *jsonWriter) WriteRow(values []types.Value) error {
+	if !jw.lines {
+		if jw.count == 0 {
+			jw.w.WriteString("[\n  ")
+		} else {
+			jw.w.WriteString(",\n  ")
+		}
+	}
+	jw.count++
+
+	jw.w.WriteString("{")
+	for i, v := range values {
+		if i > 0 {
+			jw.w.WriteString(", ")
+		}
+
+		k, err := json.Marshal(jw.columns[i])
+		if err != nil {
+			return err
+		}
+		jw.w.Write(k)
+		jw.w.WriteString(": ")
+
+		if v == nil {
+			v = types.NewNullValue()
+		}
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		jw.w.Write(data)
+	}
+	jw.w.WriteString("}")
+
+	if jw.lines {
+		jw.w.WriteString("\n")
+	}
+
+	return jw.w.Flush()
+}
+
+func (jw *jsonWriter) Close() error {
+	if !jw.lines {
+		if jw.count > 0 {
+			jw.w.WriteString("\n]\n")
+		} else {
+			jw.w.WriteString("[]\n")
+		}
+	}
+
+	return jw.w.Flush()
+}
+
+// lineWriter writes each column on its own line, which keeps wide rows readable.
+type lineWriter struct {
+	w       io.Writer
+	null    string
+	columns []string
+	width   int
+	count   int
+	buf     strings.Builder
+}
+
+func newLineWriter(w io.Writer, null string, columns []string) *lineWriter {
+	lw := lineWriter{w: w, null: null, columns: columns}
+	for _, c := range columns {
+		lw.width = max(lw.width, utf8.RuneCountInString(c))
+	}
+
+	return &lw
+}
+
+func (lw *lineWriter) WriteRow(values []types.Value) error {
+	if lw.count > 0 {
+		lw.buf.WriteString("\n")
+	}
+	lw.count++
+
+	// multi-line values are indented under the first line
+	indent := "\n" + strings.Repeat(" ", lw.width+3)
+
+	for i, v := range values {
+		c := lw.columns[i]
+		lw.buf.WriteString(strings.Repeat(" ", lw.width-utf8.RuneCountInString(c)))
+		lw.buf.WriteString(c)
+		lw.buf.WriteString(" = ")
+		lw.buf.WriteString(strings.ReplaceAll(formatValue(v, lw.null), "\n", indent))
+		lw.buf.WriteString("\n")
+	}
+
+	_, err := io.WriteString(lw.w, lw.buf.String())
+	lw.buf.Reset()
+	return err
+}
+
+func (lw *lineWriter) Close() error {
+	return nil
+}
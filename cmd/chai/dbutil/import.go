@@ -0,0 +1,868 @@
+package dbutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/chaisql/chai/internal/query/statement"
+	"github.com/chaisql/chai/internal/row"
+	"github.com/chaisql/chai/internal/sql/parser"
+	"github.com/chaisql/chai/internal/stringutil"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+// Formats of imported files.
+const (
+	// ImportCSV files must have a header.
+	ImportCSV = "csv"
+	// ImportJSON files contain an array of objects.
+	ImportJSON = "json"
+	// ImportNDJSON files contain one object per line.
+	ImportNDJSON = "ndjson"
+)
+
+// Policies applied to the records that cannot be imported.
+const (
+	// OnErrorAbort stops the import and rolls it back.
+	OnErrorAbort = "abort"
+	// OnErrorSkip ignores the record and reports its error.
+	OnErrorSkip = "skip"
+	// OnErrorReject writes the record to the reject file.
+	OnErrorReject = "reject"
+)
+
+const (
+	// number of records read to infer the types of the columns
+	importSampleSize = 1000
+	// number of records inserted per statement
+	importBatchSize = 1000
+	// name of the primary key generated for tables without an id column
+	importRowID = "rowid"
+)
+
+// ImportOptions controls how files are imported.
+type ImportOptions struct {
+	// Format of the file. Defaults to ImportCSV.
+	Format string
+	// Schema is the list of column definitions of the table,
+	// i.e "id INT PRIMARY KEY, name TEXT".
+	// If empty and the table doesn't exist, the columns and their types
+	// are inferred from the first records.
+	Schema string
+	// OnError is the policy applied to the records that cannot be imported.
+	// Defaults to OnErrorAbort.
+	OnError string
+	// RejectFile receives the records that cannot be imported,
+	// in the format of the imported file. Required by OnErrorReject.
+	RejectFile io.Writer
+}
+
+// ImportReport summarizes an import.
+type ImportReport struct {
+	// number of imported records
+	Imported int
+	// errors of the records that were skipped or rejected
+	Errors []*ImportError
+}
+
+// ImportError is the error of a record that couldn't be imported.
+type ImportError struct {
+	// Record is the position of the record in the file, starting at 1.
+	Record int
+	Err    error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("record %d: %v", e.Record, e.Err)
+}
+
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+// Import reads the records of r and inserts them in the given table,
+// in a single transaction. The table is created if it doesn't exist.
+func Import(ctx context.Context, db *sql.DB, r io.Reader, table string, opts *ImportOptions) (*ImportReport, error) {
+	if opts == nil {
+		opts = new(ImportOptions)
+	}
+
+	var rr recordReader
+	switch strings.ToLower(opts.Format) {
+	case "", ImportCSV:
+		rr = newCSVRecordReader(r)
+	case ImportJSON:
+		rr = newJSONRecordReader(r)
+	case ImportNDJSON:
+		rr = newNDJSONRecordReader(r)
+	default:
+		return nil, errors.Errorf("unknown format %q, expected one of csv, json, ndjson", opts.Format)
+	}
+
+	onError := opts.OnError
+	switch onError {
+	case "":
+		onError = OnErrorAbort
+	case OnErrorAbort, OnErrorSkip:
+	case OnErrorReject:
+		if opts.RejectFile == nil {
+			return nil, errors.New("a reject file is required to reject records")
+		}
+	default:
+		return nil, errors.Errorf("unknown error policy %q, expected one of abort, skip, reject", onError)
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// read a sample of the records to infer the types of the columns
+	var sample []*importRecord
+	for len(sample) < importSampleSize {
+		rec, err := rr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		sample = append(sample, rec)
+	}
+
+	columns, err := importColumns(ctx, tx, table, opts.Schema, sample, rr.IsText())
+	if err != nil {
+		return nil, err
+	}
+
+	im := importer{
+		tx:      tx,
+		table:   table,
+		columns: columns,
+		text:    rr.IsText(),
+		onError: onError,
+		reject:  opts.RejectFile,
+		rr:      rr,
+	}
+	defer func() {
+		if im.stmt != nil {
+			im.stmt.Close()
+		}
+	}()
+
+	for _, rec := range sample {
+		if err := im.add(ctx, rec); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		rec, err := rr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := im.add(ctx, rec); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := im.flush(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &im.report, nil
+}
+
+type importColumn struct {
+	name string
+	typ  types.Type
+}
+
+// importColumns returns the columns of the table, creating it if needed.
+func importColumns(ctx context.Context, tx *sql.Tx, table, schema string, sample []*importRecord, text bool) (map[string]types.Type, error) {
+	columns, err := tableColumns(ctx, tx, table)
+	if err != nil {
+		return nil, err
+	}
+	if columns != nil {
+		if schema != "" {
+			return nil, errors.Errorf("cannot use a schema, table %s already exists", table)
+		}
+
+		return columns, nil
+	}
+
+	if schema == "" {
+		inferred := inferColumns(sample, text)
+		if len(inferred) == 0 {
+			return nil, errors.New("cannot infer the columns of the table from an empty file")
+		}
+
+		pk := inferPrimaryKey(sample, inferred)
+
+		var defs []string
+		for _, c := range inferred {
+			def := stringutil.NormalizeIdentifier(c.name, '`') + " " + strings.ToUpper(c.typ.String())
+			if c.name == pk {
+				def += " PRIMARY KEY"
+			}
+			defs = append(defs, def)
+		}
+
+		// without a suitable column, rows are identified by a generated rowid
+		if pk == "" {
+			if slices.ContainsFunc(inferred, func(c importColumn) bool { return strings.EqualFold(c.name, importRowID) }) {
+				return nil, errors.Errorf("cannot infer the primary key of the table, use a schema")
+			}
+
+			seq := table + "_" + importRowID + "_seq"
+			_, err = tx.ExecContext(ctx, "CREATE SEQUENCE "+stringutil.NormalizeIdentifier(seq, '`'))
+			if err != nil {
+				return nil, err
+			}
+
+			defs = append([]string{fmt.Sprintf("%s BIGINT PRIMARY KEY DEFAULT nextval(%s)", importRowID, types.NewTextValue(seq).String())}, defs...)
+		}
+
+		schema = strings.Join(defs, ", ")
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", stringutil.NormalizeIdentifier(table, '`'), schema))
+	if err != nil {
+		return nil, err
+	}
+
+	return tableColumns(ctx, tx, table)
+}
+
+// tableColumns returns the type of every column of the table,
+// or nil if the table doesn't exist.
+func tableColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]types.Type, error) {
+	var columns map[string]types.Type
+
+	err := QueryTables(ctx, tx, []string{table}, func(name, query string) error {
+		stmts, err := parser.ParseQuery(query)
+		if err != nil {
+			return err
+		}
+
+		columns = make(map[string]types.Type)
+		for _, cc := range stmts[0].(*statement.CreateTableStmt).Info.ColumnConstraints.Ordered {
+			columns[cc.Column] = cc.Type
+		}
+		return nil
+	})
+
+	return columns, err
+}
+
+// inferColumns returns the columns of the records, in order of appearance,
+// with the narrowest type that can store all their values.
+// If text is true, the types are inferred from the content of text values.
+func inferColumns(records []*importRecord, text bool) []importColumn {
+	var columns []importColumn
+	positions := make(map[string]int)
+
+	for _, rec := range records {
+		if rec.err != nil {
+			continue
+		}
+
+		_ = rec.row.Iterate(func(column string, v types.Value) error {
+			i, ok := positions[column]
+			if !ok {
+				i = len(columns)
+				positions[column] = i
+				columns = append(columns, importColumn{name: column})
+			}
+
+			columns[i].typ = mergeTypes(columns[i].typ, inferType(v, text))
+			return nil
+		})
+	}
+
+	// columns with only NULL values
+	for i := range columns {
+		if columns[i].typ == types.TypeAny {
+			columns[i].typ = types.TypeText
+		}
+	}
+
+	return columns
+}
+
+// inferPrimaryKey returns the id column of the records if its values are
+// not NULL and unique in the sample, or an empty string.
+func inferPrimaryKey(records []*importRecord, columns []importColumn) string {
+	i := slices.IndexFunc(columns, func(c importColumn) bool { return strings.EqualFold(c.name, "id") })
+	if i < 0 {
+		return ""
+	}
+	switch columns[i].typ {
+	case types.TypeInteger, types.TypeBigint, types.TypeText:
+	default:
+		return ""
+	}
+
+	name := columns[i].name
+	seen := make(map[string]struct{}, len(records))
+	for _, rec := range records {
+		if rec.err != nil {
+			continue
+		}
+
+		v, err := rec.row.Get(name)
+		if err != nil || v.Type() == types.TypeNull {
+			return ""
+		}
+		if v.Type() == types.TypeText && types.AsString(v) == "" {
+			return ""
+		}
+
+		k := v.String()
+		if _, ok := seen[k]; ok {
+			return ""
+		}
+		seen[k] = struct{}{}
+	}
+
+	return name
+}
+
+// inferType returns the narrowest type that can store the value,
+// or TypeAny if it is NULL.
+func inferType(v types.Value, text bool) types.Type {
+	switch v.Type() {
+	case types.TypeNull:
+		return types.TypeAny
+	case types.TypeText:
+	default:
+		return v.Type()
+	}
+
+	s := types.AsString(v)
+	if !text {
+		if isTimestamp(s) {
+			return types.TypeTimestamp
+		}
+		return types.TypeText
+	}
+
+	switch {
+	case s == "":
+		return types.TypeAny
+	case strings.EqualFold(s, "true") || strings.EqualFold(s, "false"):
+		return types.TypeBoolean
+	case isTimestamp(s):
+		return types.TypeTimestamp
+	// ParseFloat accepts words like "inf" or "nan"
+	case !strings.ContainsAny(s, "0123456789"):
+		return types.TypeText
+	}
+
+	if _, err := strconv.ParseInt(s, 10, 32); err == nil {
+		return types.TypeInteger
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return types.TypeBigint
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return types.TypeDoublePrecision
+	}
+
+	return types.TypeText
+}
+
+// isTimestamp returns whether s is a date, with an optional time, i.e. 2025-01-31 or 2025-01-31T10:00:00Z.
+// Other formats accepted by the database are not recognized, to avoid mistaking text for timestamps.
+func isTimestamp(s string) bool {
+	if len(s) < 10 || s[4] != '-' || s[7] != '-' {
+		return false
+	}
+
+	_, err := types.ParseTimestamp(s)
+	return err == nil
+}
+
+// mergeTypes returns the narrowest type that can store values of both types.
+func mergeTypes(a, b types.Type) types.Type {
+	switch {
+	case a == types.TypeAny:
+		return b
+	case b == types.TypeAny || a == b:
+		return a
+	case isImportNumber(a) && isImportNumber(b):
+		return max(a, b)
+	}
+
+	return types.TypeText
+}
+
+func isImportNumber(t types.Type) bool {
+	return t == types.TypeInteger || t == types.TypeBigint || t == types.TypeDoublePrecision
+}
+
+type importer struct {
+	tx      *sql.Tx
+	table   string
+	columns map[string]types.Type
+	text    bool
+	onError string
+	reject  io.Writer
+	rr      recordReader
+	report  ImportReport
+
+	batch []*importRecord
+	args  []any
+	stmt  *sql.Stmt
+	query string
+}
+
+// add converts the record to the types of the columns
+// and adds it to the current batch.
+func (im *importer) add(ctx context.Context, rec *importRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if rec.err == nil {
+		rec.err = im.convert(rec)
+	}
+	if rec.err != nil {
+		return im.fail(rec, rec.err)
+	}
+
+	im.batch = append(im.batch, rec)
+	if len(im.batch) < importBatchSize {
+		return nil
+	}
+
+	return im.flush(ctx)
+}
+
+func (im *importer) convert(rec *importRecord) error {
+	cb := row.NewColumnBuffer()
+
+	err := rec.row.Iterate(func(column string, v types.Value) error {
+		t, ok := im.columns[column]
+		if !ok {
+			return errors.Errorf("unknown column %q", column)
+		}
+
+		switch {
+		case v.Type() == types.TypeNull:
+		// empty fields of non text columns are NULL
+		case im.text && t != types.TypeText && v.Type() == types.TypeText && types.AsString(v) == "":
+			v = types.NewNullValue()
+		default:
+			var err error
+			v, err = v.CastAs(t)
+			if err != nil {
+				return errors.Wrapf(err, "column %q", column)
+			}
+		}
+
+		cb.Add(column, v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rec.row = cb
+	return nil
+}
+
+// fail applies the error policy to the record.
+func (im *importer) fail(rec *importRecord, err error) error {
+	ierr := &ImportError{Record: rec.n, Err: err}
+
+	switch im.onError {
+	case OnErrorSkip:
+	case OnErrorReject:
+		if err := im.rr.Reject(im.reject, rec); err != nil {
+			return err
+		}
+	default:
+		return ierr
+	}
+
+	im.report.Errors = append(im.report.Errors, ierr)
+	return nil
+}
+
+// flush inserts the records of the current batch.
+// If the batch fails and records can be skipped,
+// they are inserted one by one to find the ones that fail.
+func (im *importer) flush(ctx context.Context) error {
+	if len(im.batch) == 0 {
+		return nil
+	}
+	defer func() {
+		im.batch = im.batch[:0]
+	}()
+
+	if im.onError == OnErrorAbort {
+		if err := im.insert(ctx, im.batch); err != nil {
+			return errors.Wrapf(err, "records %d to %d", im.batch[0].n, im.batch[len(im.batch)-1].n)
+		}
+
+		im.report.Imported += len(im.batch)
+		return nil
+	}
+
+	err := im.savepoint(ctx, func() error {
+		return im.insert(ctx, im.batch)
+	})
+	if err == nil {
+		im.report.Imported += len(im.batch)
+		return nil
+	}
+
+	for _, rec := range im.batch {
+		err := im.savepoint(ctx, func() error {
+			return im.insert(ctx, []*importRecord{rec})
+		})
+		if err != nil {
+			if err := im.fail(rec, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		im.report.Imported++
+	}
+
+	return nil
+}
+
+// savepoint runs fn and rolls back its changes if it fails.
+func (im *importer) savepoint(ctx context.Context, fn func() error) error {
+	if _, err := im.tx.ExecContext(ctx, "SAVEPOINT chai_import"); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rerr := im.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT chai_import"); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+
+	_, err := im.tx.ExecContext(ctx, "RELEASE SAVEPOINT chai_import")
+	return err
+}
+
+// insert inserts the records. Consecutive records with the same columns
+// are inserted by the same statement.
+func (im *importer) insert(ctx context.Context, records []*importRecord) error {
+	for len(records) > 0 {
+		columns := recordColumns(records[0])
+		n := 1
+		for n < len(records) && slices.Equal(columns, recordColumns(records[n])) {
+			n++
+		}
+
+		if err := im.insertRows(ctx, columns, records[:n]); err != nil {
+			return err
+		}
+		records = records[n:]
+	}
+
+	return nil
+}
+
+func (im *importer) insertRows(ctx context.Context, columns []string, records []*importRecord) error {
+	im.args = im.args[:0]
+	for _, rec := range records {
+		err := rec.row.Iterate(func(_ string, v types.Value) error {
+			im.args = append(im.args, importArg(v))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "INSERT INTO %s (", stringutil.NormalizeIdentifier(im.table, '`'))
+	for i, c := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(stringutil.NormalizeIdentifier(c, '`'))
+	}
+	sb.WriteString(") VALUES ")
+	var param int
+	for i := range records {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("(")
+		for j := range columns {
+			if j > 0 {
+				sb.WriteString(", ")
+			}
+			param++
+			sb.WriteString("$")
+			sb.WriteString(strconv.Itoa(param))
+		}
+		sb.WriteString(")")
+	}
+	q := sb.String()
+
+	// the statement of the last batch is reused, as files
+	// usually contain records with the same columns
+	if im.stmt == nil || im.query != q {
+		if im.stmt != nil {
+			im.stmt.Close()
+		}
+
+		stmt, err := im.tx.PrepareContext(ctx, q)
+		if err != nil {
+			im.stmt = nil
+			return err
+		}
+		im.stmt, im.query = stmt, q
+	}
+
+	_, err := im.stmt.ExecContext(ctx, im.args...)
+	return err
+}
+
+// recordColumns returns the columns of the record, in order.
+func recordColumns(rec *importRecord) []string {
+	var columns []string
+	_ = rec.row.Iterate(func(column string, _ types.Value) error {
+		columns = append(columns, column)
+		return nil
+	})
+	return columns
+}
+
+// importArg converts a value to a parameter of the insert statement.
+func importArg(v types.Value) any {
+	switch v.Type() {
+	case types.TypeNull:
+		return nil
+	case types.TypeUUID:
+		return v.(types.UUIDValue).Canonical()
+	}
+
+	return v.V()
+}
+
+// importRecord is a record read from an imported file.
+type importRecord struct {
+	// position of the record in the file
+	n   int
+	row *row.ColumnBuffer
+	// content of the record, as read from the file
+	raw any
+	// error that occurred while reading the record
+	err error
+}
+
+type recordReader interface {
+	// Read returns the next record, or io.EOF.
+	// Records that cannot be decoded are returned with their error.
+	Read() (*importRecord, error)
+	// IsText returns whether all the values are read as text.
+	IsText() bool
+	// Reject writes the record to w, in the format of the file.
+	Reject(w io.Writer, rec *importRecord) error
+}
+
+type csvRecordReader struct {
+	r       *csv.Reader
+	headers []string
+	n       int
+	// csv writer of the reject file
+	w *csv.Writer
+}
+
+func newCSVRecordReader(r io.Reader) *csvRecordReader {
+	return &csvRecordReader{r: csv.NewReader(r)}
+}
+
+func (cr *csvRecordReader) Read() (*importRecord, error) {
+	if cr.headers == nil {
+		headers, err := cr.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		cr.headers = headers
+	}
+
+	record, err := cr.r.Read()
+	cr.n++
+	rec := importRecord{n: cr.n, raw: record}
+
+	var perr *csv.ParseError
+	switch {
+	case errors.As(err, &perr):
+		rec.err = err
+	case err != nil:
+		return nil, err
+	case len(record) != len(cr.headers):
+		rec.err = errors.Errorf("expected %d fields, got %d", len(cr.headers), len(record))
+	default:
+		rec.row = row.NewColumnBuffer()
+		rec.row.ScanCSV(cr.headers, record)
+	}
+
+	return &rec, nil
+}
+
+func (cr *csvRecordReader) IsText() bool {
+	return true
+}
+
+func (cr *csvRecordReader) Reject(w io.Writer, rec *importRecord) error {
+	if cr.w == nil {
+		cr.w = csv.NewWriter(w)
+		if err := cr.w.Write(cr.headers); err != nil {
+			return err
+		}
+	}
+
+	record, _ := rec.raw.([]string)
+	if err := cr.w.Write(record); err != nil {
+		return err
+	}
+
+	cr.w.Flush()
+	return cr.w.Error()
+}
+
+// jsonRecordReader reads a JSON array of objects.
+type jsonRecordReader struct {
+	dec     *json.Decoder
+	started bool
+	n       int
+}
+
+func newJSONRecordReader(r io.Reader) *jsonRecordReader {
+	return &jsonRecordReader{dec: json.NewDecoder(r)}
+}
+
+func (jr *jsonRecordReader) Read() (*importRecord, error) {
+	if !jr.started {
+		tok, err := jr.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		if d, ok := tok.(json.Delim); !ok || d != '[' {
+			return nil, errors.New("expected a JSON array of objects")
+		}
+		jr.started = true
+	}
+
+	if !jr.dec.More() {
+		return nil, io.EOF
+	}
+
+	var raw json.RawMessage
+	if err := jr.dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	jr.n++
+	return decodeJSONRecord(jr.n, raw), nil
+}
+
+func (jr *jsonRecordReader) IsText() bool {
+	return false
+}
+
+func (jr *jsonRecordReader) Reject(w io.Writer, rec *importRecord) error {
+	return rejectJSONRecord(w, rec)
+}
+
+// ndjsonRecordReader reads one JSON object per line.
+type ndjsonRecordReader struct {
+	r *bufio.Reader
+	n int
+}
+
+func newNDJSONRecordReader(r io.Reader) *ndjsonRecordReader {
+	return &ndjsonRecordReader{r: bufio.NewReader(r)}
+}
+
+func (nr *ndjsonRecordReader) Read() (*importRecord, error) {
+	for {
+		line, err := nr.r.ReadBytes('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || len(line) == 0) {
+			return nil, err
+		}
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		nr.n++
+		return decodeJSONRecord(nr.n, line), nil
+	}
+}
+
+func (nr *ndjsonRecordReader) IsText() bool {
+	return false
+}
+
+func (nr *ndjsonRecordReader) Reject(w io.Writer, rec *importRecord) error {
+	return rejectJSONRecord(w, rec)
+}
+
+func decodeJSONRecord(n int, data []byte) *importRecord {
+	rec := importRecord{n: n, raw: data}
+
+	cb := row.NewColumnBuffer()
+	if err := cb.UnmarshalJSON(data); err != nil {
+		rec.err = errors.Wrap(err, "invalid JSON object")
+	} else {
+		rec.row = cb
+	}
+
+	return &rec
+}
+
+// rejectJSONRecord writes the object on a single line.
+func rejectJSONRecord(w io.Writer, rec *importRecord) error {
+	var buf bytes.Buffer
+	data := rec.raw.([]byte)
+	if err := json.Compact(&buf, data); err != nil {
+		buf.Reset()
+		buf.Write(data)
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
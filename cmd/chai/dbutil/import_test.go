@@ -0,0 +1,137 @@
+package dbutil
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "github.com/chaisql/chai"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImport(t *testing.T) {
+	query := func(t *testing.T, db *sql.DB, q string) string {
+		t.Helper()
+
+		var got bytes.Buffer
+		err := ExecSQL(t.Context(), db, strings.NewReader(q), &got, nil)
+		require.NoError(t, err)
+		return got.String()
+	}
+
+	open := func(t *testing.T) *sql.DB {
+		db, err := sql.Open("chai", ":memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	t.Run("CSV inference", func(t *testing.T) {
+		db := open(t)
+
+		report, err := Import(t.Context(), db, strings.NewReader(
+			"a,b,c,d,e\n1,1.5,true,2024-01-02 10:00:00,foo\n5000000000,2,false,,bar\n,3,,2024-01-03,\n",
+		), "test", &ImportOptions{Format: ImportCSV})
+		require.NoError(t, err)
+		require.Equal(t, 3, report.Imported)
+		require.Empty(t, report.Errors)
+
+		require.Contains(t, query(t, db, "SELECT sql FROM __chai_catalog WHERE name = 'test'"),
+			"a BIGINT, b DOUBLE PRECISION, c BOOLEAN, d TIMESTAMP, e TEXT")
+		require.Equal(t, "a|b|c|e\n1|1.5|true|'foo'\n5000000000|2.0|false|'bar'\nNULL|3.0|NULL|''\n",
+			query(t, db, "SELECT a, b, c, e FROM test"))
+	})
+
+	t.Run("Primary key", func(t *testing.T) {
+		db := open(t)
+
+		_, err := Import(t.Context(), db, strings.NewReader("name,id\nfoo,2\nbar,1\n"), "test", &ImportOptions{Format: ImportCSV})
+		require.NoError(t, err)
+
+		require.Equal(t, "name|id\n'bar'|1\n'foo'|2\n", query(t, db, "SELECT * FROM test"))
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		db := open(t)
+
+		report, err := Import(t.Context(), db, strings.NewReader(
+			`[{"a": 1, "b": "foo"}, {"a": 2, "b": "bar", "c": true}]`,
+		), "test", &ImportOptions{Format: ImportJSON})
+		require.NoError(t, err)
+		require.Equal(t, 2, report.Imported)
+
+		require.Equal(t, "a|b|c\n1|'foo'|NULL\n2|'bar'|true\n", query(t, db, "SELECT a, b, c FROM test"))
+	})
+
+	t.Run("NDJSON existing table", func(t *testing.T) {
+		db := open(t)
+		query(t, db, "CREATE TABLE test(a INT PRIMARY KEY, b TEXT)")
+
+		report, err := Import(t.Context(), db, strings.NewReader(
+			"{\"a\": 1, \"b\": \"foo\"}\n\n{\"a\": 2}\n",
+		), "test", &ImportOptions{Format: ImportNDJSON})
+		require.NoError(t, err)
+		require.Equal(t, 2, report.Imported)
+
+		require.Equal(t, "a|b\n1|'foo'\n2|NULL\n", query(t, db, "SELECT * FROM test"))
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		db := open(t)
+
+		_, err := Import(t.Context(), db, strings.NewReader("a,b\n1,2\n"), "test", &ImportOptions{
+			Format: ImportCSV,
+			Schema: "a INT PRIMARY KEY, b TEXT",
+		})
+		require.NoError(t, err)
+
+		require.Equal(t, "a|b\n1|'2'\n", query(t, db, "SELECT * FROM test"))
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		db := open(t)
+		query(t, db, "CREATE TABLE test(a INT PRIMARY KEY)")
+
+		_, err := Import(t.Context(), db, strings.NewReader("a\n1\nfoo\n"), "test", &ImportOptions{Format: ImportCSV})
+		require.Error(t, err)
+
+		// nothing is imported
+		require.Equal(t, "a\n", query(t, db, "SELECT * FROM test"))
+	})
+
+	t.Run("Skip", func(t *testing.T) {
+		db := open(t)
+		query(t, db, "CREATE TABLE test(a INT PRIMARY KEY)")
+
+		report, err := Import(t.Context(), db, strings.NewReader("a\n1\nfoo\n1\n2\n"), "test", &ImportOptions{
+			Format:  ImportCSV,
+			OnError: OnErrorSkip,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, report.Imported)
+		require.Len(t, report.Errors, 2)
+		require.Equal(t, 2, report.Errors[0].Record)
+		require.Equal(t, 3, report.Errors[1].Record)
+
+		require.Equal(t, "a\n1\n2\n", query(t, db, "SELECT * FROM test"))
+	})
+
+	t.Run("Reject", func(t *testing.T) {
+		db := open(t)
+		query(t, db, "CREATE TABLE test(a INT PRIMARY KEY, b TEXT NOT NULL)")
+
+		var rejected bytes.Buffer
+		report, err := Import(t.Context(), db, strings.NewReader(
+			"{\"a\": 1, \"b\": \"x\"}\n{\"a\": 2}\n{\"a\": 3, \"b\": \"y\"}\n",
+		), "test", &ImportOptions{
+			Format:     ImportNDJSON,
+			OnError:    OnErrorReject,
+			RejectFile: &rejected,
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, report.Imported)
+		require.Len(t, report.Errors, 1)
+		require.Equal(t, "{\"a\":2}\n", rejected.String())
+	})
+}
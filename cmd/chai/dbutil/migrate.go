@@ -0,0 +1,66 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/chaisql/chai/migrate"
+)
+
+// MigrateUp applies at most n pending migrations of dir, or all of them if n is 0,
+// and prints the applied migrations.
+func MigrateUp(ctx context.Context, db *sql.DB, dir string, n int, w io.Writer) error {
+	list, err := migrate.Up(ctx, db, os.DirFS(dir), n)
+	for _, m := range list {
+		fmt.Fprintf(w, "Applied %03d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No pending migrations.")
+	}
+	return nil
+}
+
+// MigrateDown reverts the last n applied migrations of dir and prints the reverted migrations.
+func MigrateDown(ctx context.Context, db *sql.DB, dir string, n int, w io.Writer) error {
+	list, err := migrate.Down(ctx, db, os.DirFS(dir), n)
+	for _, m := range list {
+		fmt.Fprintf(w, "Reverted %03d_%s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(list) == 0 {
+		fmt.Fprintln(w, "No applied migrations.")
+	}
+	return nil
+}
+
+// MigrateStatus prints the migrations of dir and whether they have been applied.
+func MigrateStatus(ctx context.Context, db *sql.DB, dir string, w io.Writer) error {
+	list, err := migrate.GetStatus(ctx, db, os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range list {
+		appliedAt := "pending"
+		if s.Applied {
+			appliedAt = s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%03d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,95 @@
+package dbutil
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"001_users.up.sql":    "CREATE TABLE users(id INT PRIMARY KEY);",
+		"001_users.down.sql":  "DROP TABLE users;",
+		"002_orders.up.sql":   "CREATE TABLE orders(id INT PRIMARY KEY);",
+		"002_orders.down.sql": "DROP TABLE orders;",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	var buf bytes.Buffer
+	require.NoError(t, MigrateUp(t.Context(), db, dir, 1, &buf))
+	require.Equal(t, "Applied 001_users\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, MigrateStatus(t.Context(), db, dir, &buf))
+	require.Regexp(t, `^VERSION  NAME    APPLIED AT
+001      users   \d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ
+002      orders  pending
+$`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, MigrateUp(t.Context(), db, dir, 0, &buf))
+	require.NoError(t, MigrateUp(t.Context(), db, dir, 0, &buf))
+	require.Equal(t, "Applied 002_orders\nNo pending migrations.\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, MigrateDown(t.Context(), db, dir, 5, &buf))
+	require.NoError(t, MigrateDown(t.Context(), db, dir, 1, &buf))
+	require.Equal(t, "Reverted 002_orders\nReverted 001_users\nNo applied migrations.\n", buf.String())
+}
+
+func TestMigrateDump(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "001_users.up.sql"), []byte("CREATE TABLE users(id INT PRIMARY KEY);"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "001_users.down.sql"), []byte("DROP TABLE users;"), 0o644))
+
+	path := filepath.Join(t.TempDir(), "db")
+	db, err := sql.Open("chai", path)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, MigrateUp(t.Context(), db, dir, 0, &buf))
+
+	var dump bytes.Buffer
+	require.NoError(t, Dump(t.Context(), db, &dump))
+	require.NoError(t, db.Close())
+	require.Contains(t, dump.String(), "CREATE TABLE __chai_migrations")
+
+	// restored databases keep their applied migrations
+	restored, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer restored.Close()
+	_, err = restored.Exec(dump.String())
+	require.NoError(t, err)
+
+	buf.Reset()
+	require.NoError(t, MigrateUp(t.Context(), restored, dir, 0, &buf))
+	require.Equal(t, "No pending migrations.\n", buf.String())
+
+	// diff compares the applied migrations
+	empty := filepath.Join(t.TempDir(), "empty")
+	edb, err := sql.Open("chai", empty)
+	require.NoError(t, err)
+	require.NoError(t, edb.Close())
+
+	a, err := OpenReadOnly(empty)
+	require.NoError(t, err)
+	defer a.Close()
+	b, err := OpenReadOnly(path)
+	require.NoError(t, err)
+	defer b.Close()
+
+	buf.Reset()
+	require.NoError(t, Diff(a, b, &buf, DiffOptions{Data: true}))
+	require.Contains(t, buf.String(), "+ table __chai_migrations:")
+	require.Contains(t, buf.String(), "+ __chai_migrations (1):")
+}
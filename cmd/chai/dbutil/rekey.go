@@ -0,0 +1,33 @@
+package dbutil
+
+import (
+	"encoding/hex"
+
+	"github.com/chaisql/chai"
+	"github.com/cockroachdb/errors"
+)
+
+// Rekey re-encrypts the database located at dbPath with newKey.
+// Keys are hex encoded. An empty oldKey means the database is not encrypted,
+// an empty newKey decrypts it.
+func Rekey(dbPath, oldKey, newKey string) error {
+	if dbPath == "" {
+		return errors.New("database path expected")
+	}
+
+	if oldKey == "" && newKey == "" {
+		return errors.New("at least one of the current and new keys expected")
+	}
+
+	oldk, err := hex.DecodeString(oldKey)
+	if err != nil {
+		return errors.Wrap(err, "invalid current key")
+	}
+
+	newk, err := hex.DecodeString(newKey)
+	if err != nil {
+		return errors.Wrap(err, "invalid new key")
+	}
+
+	return chai.Rekey(dbPath, oldk, newk)
+}
@@ -0,0 +1,39 @@
+package dbutil
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaisql/chai"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRekey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+	oldKey := strings.Repeat("01", 32)
+	newKey := strings.Repeat("02", 32)
+
+	db, err := sql.Open("chai", path+"?encryption_key="+oldKey)
+	require.NoError(t, err)
+	_, err = db.Exec("CREATE TABLE test(a INT PRIMARY KEY); INSERT INTO test (a) VALUES (1)")
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	err = Rekey(path, newKey, oldKey)
+	require.True(t, chai.IsInvalidKeyError(err))
+
+	require.NoError(t, Rekey(path, oldKey, newKey))
+
+	_, err = sql.Open("chai", path+"?encryption_key="+oldKey)
+	require.True(t, chai.IsInvalidKeyError(err))
+
+	db, err = sql.Open("chai", path+"?encryption_key="+newKey)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var a int
+	require.NoError(t, db.QueryRow("SELECT a FROM test").Scan(&a))
+	require.Equal(t, 1, a)
+}
@@ -35,5 +35,5 @@ func Restore(ctx context.Context, db *sql.DB, dumpFile, dbPath string) error {
 		defer db.Close()
 	}
 
-	return ExecSQL(ctx, db, file, io.Discard)
+	return ExecSQL(ctx, db, file, io.Discard, nil)
 }
@@ -7,10 +7,14 @@ import (
 
 	"github.com/chaisql/chai/internal/query/statement"
 	"github.com/chaisql/chai/internal/sql/parser"
+	"github.com/chaisql/chai/migrate"
 )
 
+// QueryTables calls fn with the name and the CREATE TABLE statement of the given tables,
+// or of all the tables if none is given. Internal tables are skipped, except the migrations
+// table, so that dumped databases keep the list of their applied migrations.
 func QueryTables(ctx context.Context, tx *sql.Tx, tables []string, fn func(name, query string) error) error {
-	query := "SELECT name, sql FROM __chai_catalog WHERE type = 'table' AND name NOT LIKE '__chai_%'"
+	query := "SELECT name, sql FROM __chai_catalog WHERE type = 'table' AND (name NOT LIKE '__chai_%' OR name = '" + migrate.TableName + "')"
 	var args []any
 	if len(tables) > 0 {
 		var arg string
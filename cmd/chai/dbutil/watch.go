@@ -0,0 +1,161 @@
+package dbutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/database/catalogstore"
+	"github.com/cockroachdb/errors"
+)
+
+// IsURL returns whether s is the URL of a server exposing a database
+// with the httpapi package, rather than the path of a database.
+func IsURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// OpenChangelog opens the database located at dbPath in read-only mode,
+// to read its changelog.
+func OpenChangelog(dbPath string) (*database.Database, error) {
+	return OpenReadOnly(dbPath)
+}
+
+// Watch writes the changes committed to the given table after cursor
+// as JSON lines, in commit order.
+func Watch(db *database.Database, w io.Writer, tableName string, cursor uint64) error {
+	_, err := db.Catalog().GetTableInfo(tableName)
+	if err != nil {
+		return err
+	}
+
+	return db.ReadChanges(tableName, cursor, func(e *database.ChangeEvent) error {
+		data, err := e.MarshalJSON()
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// WatchURL writes the changes committed to the given table after cursor
+// as JSON lines, reading them from the server at baseURL.
+// If follow is true, it keeps writing the changes as they are committed
+// until ctx is canceled or the server closes the connection.
+func WatchURL(ctx context.Context, baseURL string, w io.Writer, tableName string, cursor uint64, follow bool) error {
+	q := url.Values{}
+	q.Set("table", tableName)
+	q.Set("cursor", strconv.FormatUint(cursor, 10))
+	if follow {
+		q.Set("follow", "true")
+	}
+
+	resp, err := changesRequest(ctx, http.MethodGet, baseURL, q)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	r := bufio.NewReader(resp.Body)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			// errors occurring after the first change are sent as the last line
+			if bytes.HasPrefix(line, []byte(`{"error"`)) {
+				return responseError(line)
+			}
+
+			if _, err := w.Write(line); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// PurgeChanges removes the changes up to the given cursor, included,
+// from the changelog of the database located at dbPath.
+func PurgeChanges(dbPath string, cursor uint64) error {
+	db, err := database.Open(dbPath, &database.Options{
+		CatalogLoader: catalogstore.LoadCatalog,
+	})
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.PurgeChanges(cursor)
+}
+
+// PurgeChangesURL removes the changes up to the given cursor, included,
+// from the changelog of the database served at baseURL.
+func PurgeChangesURL(ctx context.Context, baseURL string, cursor uint64) error {
+	q := url.Values{}
+	q.Set("cursor", strconv.FormatUint(cursor, 10))
+
+	resp, err := changesRequest(ctx, http.MethodDelete, baseURL, q)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// changesRequest sends a request to the changes endpoint of the server at baseURL.
+// It returns an error if the server doesn't respond with 200 OK.
+func changesRequest(ctx context.Context, method, baseURL string, q url.Values) (*http.Response, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	u = u.JoinPath("changes")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		err = responseError(data)
+		return nil, errors.Wrapf(err, "%s %s", method, u.Redacted())
+	}
+
+	return resp, nil
+}
+
+// responseError returns the error reported by the server in data.
+func responseError(data []byte) error {
+	var res struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(data, &res) != nil || res.Error == "" {
+		return errors.Errorf("unexpected response: %s", bytes.TrimSpace(data))
+	}
+
+	return errors.New(res.Error)
+}
@@ -0,0 +1,131 @@
+package dbutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaisql/chai/httpapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+
+	db, err := sql.Open("chai", path+"?changelog=on")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE test(a INT PRIMARY KEY, b TEXT);
+		INSERT INTO test (a, b) VALUES (1, 'foo'), (2, 'bar');
+		DELETE FROM test WHERE a = 1;
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	ng, err := OpenChangelog(path)
+	require.NoError(t, err)
+	defer ng.Close()
+
+	var buf bytes.Buffer
+	err = Watch(ng, &buf, "test", 1)
+	require.NoError(t, err)
+
+	require.Equal(t, `{"cursor": 2, "table": "test", "op": "insert", "key": [2], "before": null, "after": {"a": 2, "b": "bar"}}
+{"cursor": 3, "table": "test", "op": "delete", "key": [1], "before": {"a": 1, "b": "foo"}, "after": null}
+`, buf.String())
+
+	err = Watch(ng, &buf, "unknown", 0)
+	require.Error(t, err)
+}
+
+func TestWatchURL(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:?changelog=on")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE test(a INT PRIMARY KEY, b TEXT);
+		INSERT INTO test (a, b) VALUES (1, 'foo'), (2, 'bar');
+	`)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(http.StripPrefix("/db", httpapi.NewHandler(db, nil)))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	err = WatchURL(context.Background(), srv.URL+"/db", &buf, "test", 1, false)
+	require.NoError(t, err)
+	require.Equal(t, `{"cursor": 2, "table": "test", "op": "insert", "key": [2], "before": null, "after": {"a": 2, "b": "bar"}}
+`, buf.String())
+
+	err = WatchURL(context.Background(), srv.URL+"/db", &buf, "unknown", 0, false)
+	require.ErrorContains(t, err, "unknown")
+
+	t.Run("follow", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		r, w := io.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			done <- WatchURL(ctx, srv.URL+"/db", w, "test", 2, true)
+			w.Close()
+		}()
+
+		_, err := db.Exec("UPDATE test SET b = 'baz' WHERE a = 1")
+		require.NoError(t, err)
+
+		line, err := bufio.NewReader(r).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, `{"cursor": 3, "table": "test", "op": "update", "key": [1], "before": {"a": 1, "b": "foo"}, "after": {"a": 1, "b": "baz"}}
+`, line)
+
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		err := PurgeChangesURL(context.Background(), srv.URL+"/db", 2)
+		require.NoError(t, err)
+
+		buf.Reset()
+		err = WatchURL(context.Background(), srv.URL+"/db", &buf, "test", 0, false)
+		require.NoError(t, err)
+		require.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	})
+}
+
+func TestPurgeChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+
+	db, err := sql.Open("chai", path+"?changelog=on")
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE test(a INT PRIMARY KEY);
+		INSERT INTO test (a) VALUES (1), (2), (3);
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	err = PurgeChanges(path, 2)
+	require.NoError(t, err)
+
+	ng, err := OpenChangelog(path)
+	require.NoError(t, err)
+	defer ng.Close()
+
+	var buf bytes.Buffer
+	err = Watch(ng, &buf, "test", 0)
+	require.NoError(t, err)
+	require.Equal(t, `{"cursor": 3, "table": "test", "op": "insert", "key": [3], "before": null, "after": {"a": 3}}
+`, buf.String())
+}
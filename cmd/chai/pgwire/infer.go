@@ -0,0 +1,255 @@
+package pgwire
+
+import (
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/expr"
+	"github.com/chaisql/chai/internal/expr/functions"
+	"github.com/chaisql/chai/internal/query"
+	"github.com/chaisql/chai/internal/query/statement"
+	"github.com/chaisql/chai/internal/stream"
+	"github.com/chaisql/chai/internal/types"
+)
+
+// A column is a column of the rows returned by a statement.
+type column struct {
+	name string
+	typ  types.Type
+}
+
+// typer infers the types of the parameters and of the columns of a statement,
+// which must have been prepared.
+type typer struct {
+	tx *database.Transaction
+}
+
+// paramTypes returns the types of the n parameters of stmt.
+// The types of the parameters that can't be inferred are types.TypeAny.
+func (t *typer) paramTypes(stmt statement.Statement, n int) []types.Type {
+	list := make([]types.Type, n)
+	set := func(e expr.Expr, tp types.Type) {
+		p, ok := e.(expr.PositionalParam)
+		if !ok || tp.IsAny() || tp == types.TypeNull {
+			return
+		}
+		if i := int(p) - 1; i >= 0 && i < n && list[i].IsAny() {
+			list[i] = tp
+		}
+	}
+
+	if ps, ok := stmt.(query.PreparedStatement); ok {
+		for e, tp := range ps.ParamTypes {
+			set(e, tp)
+		}
+		stmt = ps.Statement
+	}
+
+	var exprs []expr.Expr
+	switch s := stmt.(type) {
+	case *statement.SelectStmt:
+		for _, core := range s.CompoundSelect {
+			exprs = append(exprs, core.WhereExpr)
+			exprs = append(exprs, core.ProjectionExprs...)
+		}
+		set(s.LimitExpr, types.TypeBigint)
+		set(s.OffsetExpr, types.TypeBigint)
+	case *statement.UpdateStmt:
+		exprs = append(exprs, s.WhereExpr)
+	case *statement.DeleteStmt:
+		exprs = append(exprs, s.WhereExpr)
+		set(s.LimitExpr, types.TypeBigint)
+		set(s.OffsetExpr, types.TypeBigint)
+	}
+
+	for _, e := range exprs {
+		t.inferParams(e, set)
+	}
+
+	return list
+}
+
+// inferParams calls set for each parameter of e whose type can be deduced
+// from the expression it is compared with, or from a cast.
+func (t *typer) inferParams(e expr.Expr, set func(expr.Expr, types.Type)) {
+	// assign gives the type tp to e, if it is a parameter or a list of parameters
+	assign := func(e expr.Expr, tp types.Type) {
+		if l, ok := e.(expr.LiteralExprList); ok {
+			for _, e := range l {
+				set(e, tp)
+			}
+			return
+		}
+		set(e, tp)
+	}
+
+	switch e := e.(type) {
+	case nil:
+	case expr.Parentheses:
+		t.inferParams(e.E, set)
+	case *expr.NamedExpr:
+		t.inferParams(e.Expr, set)
+	case *expr.Cast:
+		set(e.Expr, e.CastAs)
+		t.inferParams(e.Expr, set)
+	case *expr.BetweenOperator:
+		xt := t.exprType(e.X)
+		assign(e.LeftHand(), xt)
+		assign(e.RightHand(), xt)
+		assign(e.X, t.exprType(e.LeftHand()))
+		t.inferParams(e.X, set)
+		t.inferParams(e.LeftHand(), set)
+		t.inferParams(e.RightHand(), set)
+	case expr.Operator:
+		l, r := e.LeftHand(), e.RightHand()
+		if expr.IsComparisonOperator(e) || expr.IsArithmeticOperator(e) {
+			assign(l, t.exprType(r))
+			assign(r, t.exprType(l))
+		}
+		t.inferParams(l, set)
+		t.inferParams(r, set)
+	case expr.LiteralExprList:
+		for _, e := range e {
+			t.inferParams(e, set)
+		}
+	case expr.Function:
+		for _, p := range e.Params() {
+			t.inferParams(p, set)
+		}
+	}
+}
+
+// exprType returns the type of the values returned by e, or types.TypeAny
+// if it can't be known before evaluating e.
+func (t *typer) exprType(e expr.Expr) types.Type {
+	switch e := e.(type) {
+	case expr.Parentheses:
+		return t.exprType(e.E)
+	case *expr.NamedExpr:
+		return t.exprType(e.Expr)
+	case *expr.Column:
+		if e.Type != 0 {
+			return e.Type
+		}
+		// columns created by the planner are not bound
+		if e.Table != "" && t.tx != nil {
+			info, err := t.tx.Catalog.GetTableInfo(e.Table)
+			if err != nil {
+				return types.TypeAny
+			}
+			if cc := info.ColumnConstraints.GetColumnConstraint(e.Name); cc != nil {
+				return cc.Type
+			}
+		}
+	case expr.LiteralValue:
+		return e.Value.Type()
+	case *expr.Cast:
+		return e.CastAs
+	case *expr.AndOp, *expr.OrOp, *expr.NotOp:
+		return types.TypeBoolean
+	case *expr.ConcatOperator:
+		return types.TypeText
+	case expr.Operator:
+		if expr.IsComparisonOperator(e) {
+			return types.TypeBoolean
+		}
+		if expr.IsArithmeticOperator(e) {
+			l, r := t.exprType(e.LeftHand()), t.exprType(e.RightHand())
+			switch {
+			case !l.IsNumber() || !r.IsNumber():
+				return types.TypeAny
+			case l == types.TypeDoublePrecision || r == types.TypeDoublePrecision:
+				return types.TypeDoublePrecision
+			default:
+				return types.TypeBigint
+			}
+		}
+	case *functions.Count, *functions.Len, *functions.NextVal:
+		return types.TypeBigint
+	case *functions.Avg:
+		return types.TypeDoublePrecision
+	case *functions.Sum:
+		if tp := t.exprType(e.Expr); tp.IsInteger() {
+			return types.TypeBigint
+		} else if tp == types.TypeDoublePrecision {
+			return tp
+		}
+	case *functions.Min:
+		return t.exprType(e.Expr)
+	case *functions.Max:
+		return t.exprType(e.Expr)
+	case *functions.Now:
+		return types.TypeTimestamp
+	case *functions.TypeOf:
+		return types.TypeText
+	case *functions.Coalesce:
+		for _, e := range e.Exprs {
+			if tp := t.exprType(e); !tp.IsAny() && tp != types.TypeNull {
+				return tp
+			}
+		}
+	}
+
+	return types.TypeAny
+}
+
+// columns returns the columns of the rows returned by stmt,
+// or nil if it doesn't return rows.
+func (t *typer) columns(stmt statement.Statement, params []environment.Param) ([]column, error) {
+	if ps, ok := stmt.(query.PreparedStatement); ok {
+		stmt = ps.Statement
+	}
+
+	var s *stream.Stream
+	var tableName string
+	var exprs []expr.Expr
+	switch st := stmt.(type) {
+	case *statement.SelectStmt:
+		s = st.Stream
+		tableName = st.CompoundSelect[0].TableName
+		exprs = st.CompoundSelect[0].ProjectionExprs
+	case *statement.InsertStmt:
+		if len(st.Returning) == 0 {
+			return nil, nil
+		}
+		s = st.Stream
+		tableName = st.TableName
+		exprs = st.Returning
+	case *statement.ExplainStmt:
+		return []column{{name: "plan", typ: types.TypeText}}, nil
+	default:
+		return nil, nil
+	}
+
+	names, err := s.Columns(environment.New(nil, t.tx, params, nil))
+	if err != nil {
+		return nil, err
+	}
+
+	var list []types.Type
+	for _, e := range exprs {
+		if _, ok := e.(expr.Wildcard); !ok {
+			list = append(list, t.exprType(e))
+			continue
+		}
+
+		info, err := t.tx.Catalog.GetTableInfo(tableName)
+		if err != nil {
+			return nil, err
+		}
+		for _, cc := range info.ColumnConstraints.Ordered {
+			list = append(list, cc.Type)
+		}
+	}
+
+	cols := make([]column, len(names))
+	for i, name := range names {
+		cols[i] = column{name: name, typ: types.TypeAny}
+		// the types of the columns can't be known if the wildcard
+		// was expanded differently
+		if len(list) == len(names) {
+			cols[i].typ = list[i]
+		}
+	}
+
+	return cols, nil
+}
@@ -0,0 +1,212 @@
+// Package pgwire implements a server speaking the PostgreSQL frontend/backend protocol (v3),
+// so that PostgreSQL clients, such as psql or pgx, can run queries on a chai database.
+//
+// Each client connection is a session running on its own database connection.
+// Both the simple and the extended query protocols are supported, but there is no authentication
+// and no TLS: the server is meant to be used locally, for debugging.
+package pgwire
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"sync"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/cockroachdb/errors"
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// ErrServerClosed is returned by Serve after a call to Close.
+var ErrServerClosed = errors.New("pgwire: server closed")
+
+// Options configure a Server.
+type Options struct {
+	// AllowCopy allows COPY statements, which read and write
+	// files on the server with its permissions.
+	AllowCopy bool
+}
+
+// A Server serves the clients of a database.
+type Server struct {
+	db   *database.Database
+	opts Options
+
+	mu       sync.Mutex
+	listener net.Listener
+	// connections accepted by the server, including those
+	// that haven't finished their startup yet.
+	conns    map[net.Conn]struct{}
+	sessions map[uint32]*session
+	nextPID  uint32
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a server running the queries of its clients on db.
+// COPY statements are rejected unless opts allow them.
+func NewServer(db *database.Database, opts *Options) *Server {
+	s := Server{
+		db:       db,
+		conns:    make(map[net.Conn]struct{}),
+		sessions: make(map[uint32]*session),
+	}
+	if opts != nil {
+		s.opts = *opts
+	}
+	return &s
+}
+
+// Serve accepts connections on ln until the server is closed.
+// It always returns a non-nil error.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = c.Close()
+			return ErrServerClosed
+		}
+		s.conns[c] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(c)
+		}()
+	}
+}
+
+// Close stops accepting connections, closes the connections of the clients
+// and waits for their sessions to end.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for _, sess := range s.sessions {
+		sess.cancelQuery()
+	}
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serveConn(c net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		_ = c.Close()
+	}()
+
+	backend := pgproto3.NewBackend(c, c)
+	params, ok := s.startup(c, backend)
+	if !ok {
+		return
+	}
+
+	conn, err := s.db.Connect()
+	if err != nil {
+		backend.Send(&pgproto3.ErrorResponse{Severity: "FATAL", SeverityUnlocalized: "FATAL", Code: "XX000", Message: err.Error()})
+		_ = backend.Flush()
+		return
+	}
+	defer conn.Close()
+
+	sess := newSession(s, c, backend, conn, params)
+	if !s.register(sess) {
+		return
+	}
+	defer s.unregister(sess)
+
+	_ = sess.run()
+}
+
+// startup reads the startup message of the client and returns its parameters.
+// It returns false if the connection must be closed.
+func (s *Server) startup(c net.Conn, backend *pgproto3.Backend) (map[string]string, bool) {
+	for {
+		msg, err := backend.ReceiveStartupMessage()
+		if err != nil {
+			return nil, false
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.SSLRequest, *pgproto3.GSSEncRequest:
+			// encryption is not supported
+			if _, err := c.Write([]byte{'N'}); err != nil {
+				return nil, false
+			}
+		case *pgproto3.CancelRequest:
+			s.cancel(m.ProcessID, m.SecretKey)
+			return nil, false
+		case *pgproto3.StartupMessage:
+			return m.Parameters, true
+		default:
+			return nil, false
+		}
+	}
+}
+
+func (s *Server) register(sess *session) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	s.nextPID++
+	sess.pid = s.nextPID
+	var b [4]byte
+	_, _ = rand.Read(b[:])
+	sess.secret = binary.BigEndian.Uint32(b[:])
+	s.sessions[sess.pid] = sess
+	return true
+}
+
+func (s *Server) unregister(sess *session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, sess.pid)
+}
+
+// cancel cancels the running query of the session with the given process id,
+// if the secret key matches.
+func (s *Server) cancel(pid, secret uint32) {
+	s.mu.Lock()
+	sess, ok := s.sessions[pid]
+	s.mu.Unlock()
+
+	if ok && sess.secret == secret {
+		sess.cancelQuery()
+	}
+}
@@ -0,0 +1,313 @@
+package pgwire
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chaisql/chai/internal/sql/driver"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, opts *Options) string {
+	t.Helper()
+
+	c, err := driver.NewConnector(":memory:", driver.Options{})
+	require.NoError(t, err)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := NewServer(c.DB(), opts)
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ln)
+	}()
+
+	t.Cleanup(func() {
+		require.NoError(t, srv.Close())
+		require.ErrorIs(t, <-done, ErrServerClosed)
+		require.NoError(t, c.Close())
+	})
+
+	return "postgres://chai@" + ln.Addr().String() + "/chai?sslmode=disable"
+}
+
+func connect(t *testing.T, url string) *pgx.Conn {
+	t.Helper()
+
+	conn, err := pgx.Connect(t.Context(), url)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close(context.Background()) })
+	return conn
+}
+
+func TestServer(t *testing.T) {
+	url := startServer(t, nil)
+	conn := connect(t, url)
+	ctx := t.Context()
+
+	require.NoError(t, conn.Ping(ctx))
+
+	_, err := conn.Exec(ctx, "CREATE TABLE test(a INT PRIMARY KEY, b TEXT, c DOUBLE PRECISION, d BOOL, e TIMESTAMP, f BYTEA)")
+	require.NoError(t, err)
+
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)
+	for i := 1; i <= 10; i++ {
+		tag, err := conn.Exec(ctx, "INSERT INTO test (a, b, c, d, e, f) VALUES ($1, $2, $3, $4, $5, $6)", i, "foo", float64(i)/2, i%2 == 0, ts, []byte{byte(i)})
+		require.NoError(t, err)
+		require.Equal(t, "INSERT 0 1", tag.String())
+	}
+
+	t.Run("extended", func(t *testing.T) {
+		var b string
+		var c float64
+		var d bool
+		var e time.Time
+		var f []byte
+		err := conn.QueryRow(ctx, "SELECT b, c, d, e, f FROM test WHERE a = $1", 4).Scan(&b, &c, &d, &e, &f)
+		require.NoError(t, err)
+		require.Equal(t, "foo", b)
+		require.Equal(t, 2.0, c)
+		require.True(t, d)
+		require.True(t, ts.Equal(e))
+		require.Equal(t, []byte{4}, f)
+
+		var n int64
+		err = conn.QueryRow(ctx, "SELECT COUNT(*) FROM test WHERE a > $1 AND d = $2", 5, true).Scan(&n)
+		require.NoError(t, err)
+		require.EqualValues(t, 3, n)
+	})
+
+	t.Run("untyped columns", func(t *testing.T) {
+		// the type of abs(a) is unknown, so the column is described as text
+		// and its values are sent as text, even in binary format
+		res := conn.PgConn().ExecParams(ctx, "SELECT abs(a) FROM test WHERE a = 4", nil, nil, nil, []int16{1}).Read()
+		require.NoError(t, res.Err)
+		require.EqualValues(t, oidText, res.FieldDescriptions[0].DataTypeOID)
+		require.Equal(t, [][][]byte{{[]byte("4")}}, res.Rows)
+	})
+
+	t.Run("timestamps", func(t *testing.T) {
+		// more than 292 years away from the epoch of PostgreSQL,
+		// which overflow a time.Duration
+		for i, want := range []time.Time{
+			time.Date(1600, 2, 3, 4, 5, 6, 7000, time.UTC),
+			time.Date(2400, 2, 3, 4, 5, 6, 7000, time.UTC),
+		} {
+			_, err := conn.Exec(ctx, "INSERT INTO test (a, e) VALUES ($1, $2)", 300+i, want)
+			require.NoError(t, err)
+
+			var got time.Time
+			err = conn.QueryRow(ctx, "SELECT e FROM test WHERE a = $1", 300+i).Scan(&got)
+			require.NoError(t, err)
+			require.True(t, want.Equal(got), got)
+
+			_, err = conn.Exec(ctx, "DELETE FROM test WHERE a = $1", 300+i)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("simple", func(t *testing.T) {
+		rows, err := conn.Query(ctx, "SELECT a, b FROM test WHERE a <= 3 ORDER BY a", pgx.QueryExecModeSimpleProtocol)
+		require.NoError(t, err)
+		var got []int32
+		for rows.Next() {
+			var a int32
+			var b string
+			require.NoError(t, rows.Scan(&a, &b))
+			got = append(got, a)
+		}
+		require.NoError(t, rows.Err())
+		require.Equal(t, []int32{1, 2, 3}, got)
+
+		fields := rows.FieldDescriptions()
+		require.Equal(t, "a", fields[0].Name)
+		require.EqualValues(t, oidInt4, fields[0].DataTypeOID)
+		require.EqualValues(t, oidText, fields[1].DataTypeOID)
+
+		var name string
+		err = conn.QueryRow(ctx, "SHOW server_encoding", pgx.QueryExecModeSimpleProtocol).Scan(&name)
+		require.NoError(t, err)
+		require.Equal(t, "UTF8", name)
+
+		results, err := conn.PgConn().Exec(ctx, "UPDATE test SET b = 'bar' WHERE a > 8; DELETE FROM test WHERE a = 10").ReadAll()
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+		require.Equal(t, "UPDATE 2", results[0].CommandTag.String())
+		require.Equal(t, "DELETE 1", results[1].CommandTag.String())
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		tx, err := conn.Begin(ctx)
+		require.NoError(t, err)
+		require.Equal(t, byte('T'), conn.PgConn().TxStatus())
+
+		_, err = tx.Exec(ctx, "INSERT INTO test (a) VALUES (100)")
+		require.NoError(t, err)
+		require.NoError(t, tx.Rollback(ctx))
+		require.Equal(t, byte('I'), conn.PgConn().TxStatus())
+
+		var n int64
+		require.NoError(t, conn.QueryRow(ctx, "SELECT COUNT(*) FROM test WHERE a = 100").Scan(&n))
+		require.Zero(t, n)
+
+		// errors make the transaction fail until it is rolled back
+		tx, err = conn.Begin(ctx)
+		require.NoError(t, err)
+		_, err = tx.Exec(ctx, "INSERT INTO test (a) VALUES (1)")
+		var pgErr *pgconn.PgError
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, "23505", pgErr.Code)
+		require.Equal(t, byte('E'), conn.PgConn().TxStatus())
+
+		_, err = tx.Exec(ctx, "SELECT 1")
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, "25P02", pgErr.Code)
+
+		err = tx.Commit(ctx)
+		require.ErrorIs(t, err, pgx.ErrTxCommitRollback)
+		require.Equal(t, byte('I'), conn.PgConn().TxStatus())
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := conn.Exec(ctx, "SELECT * FROM unknown")
+		var pgErr *pgconn.PgError
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, "42P01", pgErr.Code)
+
+		_, err = conn.Exec(ctx, "SELEC 1")
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, "42601", pgErr.Code)
+
+		// the session is still usable
+		require.NoError(t, conn.Ping(ctx))
+	})
+
+	t.Run("sessions", func(t *testing.T) {
+		other := connect(t, url)
+
+		tx, err := conn.Begin(ctx)
+		require.NoError(t, err)
+		_, err = tx.Exec(ctx, "INSERT INTO test (a) VALUES (200)")
+		require.NoError(t, err)
+		require.NoError(t, tx.Commit(ctx))
+
+		var n int64
+		require.NoError(t, other.QueryRow(ctx, "SELECT COUNT(*) FROM test WHERE a = 200").Scan(&n))
+		require.EqualValues(t, 1, n)
+	})
+}
+
+func TestServerCopy(t *testing.T) {
+	ctx := t.Context()
+	path := filepath.Join(t.TempDir(), "test.csv")
+
+	conn := connect(t, startServer(t, nil))
+	_, err := conn.Exec(ctx, "CREATE TABLE test(a INT PRIMARY KEY)")
+	require.NoError(t, err)
+
+	for _, q := range []string{
+		"COPY test TO '" + path + "'",
+		"COPY test FROM '" + path + "'",
+	} {
+		_, err = conn.Exec(ctx, q)
+		var pgErr *pgconn.PgError
+		require.True(t, errors.As(err, &pgErr), q)
+		require.Equal(t, "42501", pgErr.Code)
+
+		_, err = conn.Exec(ctx, q, pgx.QueryExecModeSimpleProtocol)
+		require.True(t, errors.As(err, &pgErr), q)
+		require.Equal(t, "42501", pgErr.Code)
+	}
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	conn = connect(t, startServer(t, &Options{AllowCopy: true}))
+	_, err = conn.Exec(ctx, "CREATE TABLE test(a INT PRIMARY KEY)")
+	require.NoError(t, err)
+	_, err = conn.Exec(ctx, "COPY test TO '"+path+"'")
+	require.NoError(t, err)
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+}
+
+func TestServerCloseDuringStartup(t *testing.T) {
+	c, err := driver.NewConnector(":memory:", driver.Options{})
+	require.NoError(t, err)
+	defer c.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := NewServer(c.DB(), nil)
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ln)
+	}()
+
+	// the client connects but never sends its startup message
+	client, err := net.Dial("tcp", ln.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	// wait for the connection to be accepted
+	require.Eventually(t, func() bool {
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		return len(srv.conns) == 1
+	}, time.Second, time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() {
+		closed <- srv.Close()
+	}()
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close is blocked by a connection in startup")
+	}
+	require.ErrorIs(t, <-done, ErrServerClosed)
+}
+
+func TestServerCancel(t *testing.T) {
+	url := startServer(t, nil)
+	conn := connect(t, url)
+	ctx := t.Context()
+
+	_, err := conn.Exec(ctx, "CREATE TABLE test(a INT PRIMARY KEY, b TEXT)")
+	require.NoError(t, err)
+	tx, err := conn.Begin(ctx)
+	require.NoError(t, err)
+	batch := &pgx.Batch{}
+	for i := range 20_000 {
+		batch.Queue("INSERT INTO test (a, b) VALUES ($1, $2)", i, strings.Repeat("a", 200))
+	}
+	require.NoError(t, tx.SendBatch(ctx, batch).Close())
+	require.NoError(t, tx.Commit(ctx))
+
+	// the server blocks on sending the rows until they are read,
+	// so the query is still running when it is canceled
+	rr := conn.PgConn().Exec(ctx, "SELECT * FROM test")
+	require.True(t, rr.NextResult())
+	res := rr.ResultReader()
+	require.True(t, res.NextRow())
+	require.NoError(t, conn.PgConn().CancelRequest(ctx))
+	for res.NextRow() {
+	}
+	_, err = res.Close()
+	var pgErr *pgconn.PgError
+	require.True(t, errors.As(err, &pgErr), err)
+	require.Equal(t, "57014", pgErr.Code)
+	require.Error(t, rr.Close())
+
+	// the session is still usable
+	require.NoError(t, conn.Ping(ctx))
+}
@@ -0,0 +1,879 @@
+package pgwire
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/environment"
+	errs "github.com/chaisql/chai/internal/errors"
+	"github.com/chaisql/chai/internal/query"
+	"github.com/chaisql/chai/internal/query/statement"
+	"github.com/chaisql/chai/internal/sql/parser"
+	"github.com/chaisql/chai/internal/stream"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+	"github.com/jackc/pgx/v5/pgproto3"
+)
+
+// flushRows is the number of rows after which the buffered messages
+// are sent to the client, to avoid buffering large results.
+const flushRows = 1000
+
+var (
+	setRe  = regexp.MustCompile(`(?is)^\s*SET\s+(?:SESSION\s+|LOCAL\s+)?(\w+)\s*(?:=|\s+TO\s+)\s*([^;]*?)\s*;?\s*$`)
+	showRe = regexp.MustCompile(`(?is)^\s*SHOW\s+(\w+)\s*;?\s*$`)
+)
+
+// A pgError is an error returned to the client with a specific SQLSTATE code.
+type pgError struct {
+	code string
+	msg  string
+}
+
+func (e *pgError) Error() string {
+	return e.msg
+}
+
+var errTxAborted = &pgError{code: "25P02", msg: "current transaction is aborted, commands ignored until end of transaction block"}
+
+var errCopyNotAllowed = &pgError{code: "42501", msg: "COPY is not allowed: the server was started without --allow-copy"}
+
+// A session handles the messages of a client, on its own database connection.
+type session struct {
+	srv     *Server
+	netConn net.Conn
+	backend *pgproto3.Backend
+	conn    *database.Connection
+	params  map[string]string
+
+	pid    uint32
+	secret uint32
+
+	stmts   map[string]*preparedStmt
+	portals map[string]*portal
+
+	// failed is true if a statement failed in the current transaction.
+	// Other statements are rejected until the transaction is rolled back.
+	failed bool
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+// A preparedStmt is a statement parsed by a Parse message or a simple query.
+type preparedStmt struct {
+	// stmt is nil for empty queries and session commands.
+	stmt       statement.Statement
+	paramTypes []types.Type
+	columns    []column
+
+	// rows and tag are the result of session commands, like SET or SHOW.
+	rows [][]types.Value
+	tag  string
+}
+
+// A portal is a prepared statement bound to its parameters.
+type portal struct {
+	stmt    *preparedStmt
+	params  []environment.Param
+	formats []int16
+
+	res     *statement.Result
+	it      database.Iterator
+	tag     string
+	count   int
+	started bool
+	done    bool
+}
+
+func newSession(srv *Server, c net.Conn, backend *pgproto3.Backend, conn *database.Connection, startup map[string]string) *session {
+	params := map[string]string{
+		"server_version":              "16.0",
+		"server_encoding":             "UTF8",
+		"client_encoding":             "UTF8",
+		"datestyle":                   "ISO, MDY",
+		"timezone":                    "UTC",
+		"integer_datetimes":           "on",
+		"standard_conforming_strings": "on",
+		"intervalstyle":               "postgres",
+		"application_name":            startup["application_name"],
+		"session_authorization":       startup["user"],
+	}
+
+	return &session{
+		srv:     srv,
+		netConn: c,
+		backend: backend,
+		conn:    conn,
+		params:  params,
+		stmts:   make(map[string]*preparedStmt),
+		portals: make(map[string]*portal),
+	}
+}
+
+// parameterNames are the names of the parameters reported to the client after
+// authentication, as spelled by PostgreSQL.
+var parameterNames = map[string]string{
+	"server_version":              "server_version",
+	"server_encoding":             "server_encoding",
+	"client_encoding":             "client_encoding",
+	"datestyle":                   "DateStyle",
+	"timezone":                    "TimeZone",
+	"integer_datetimes":           "integer_datetimes",
+	"standard_conforming_strings": "standard_conforming_strings",
+	"intervalstyle":               "IntervalStyle",
+	"application_name":            "application_name",
+	"session_authorization":       "session_authorization",
+}
+
+// run handles the messages of the client until it terminates the session
+// or the connection is closed.
+func (s *session) run() error {
+	defer s.closePortals()
+
+	s.backend.Send(&pgproto3.AuthenticationOk{})
+	for key, name := range parameterNames {
+		s.backend.Send(&pgproto3.ParameterStatus{Name: name, Value: s.params[key]})
+	}
+	s.backend.Send(&pgproto3.BackendKeyData{ProcessID: s.pid, SecretKey: s.secret})
+	s.readyForQuery()
+	if err := s.backend.Flush(); err != nil {
+		return err
+	}
+
+	// after an error, the messages of the extended protocol are ignored until Sync
+	var skip bool
+	for {
+		msg, err := s.backend.Receive()
+		if err != nil {
+			return err
+		}
+
+		switch m := msg.(type) {
+		case *pgproto3.Query:
+			if err := s.simpleQuery(m.String); err != nil {
+				s.sendError(err)
+			}
+			s.readyForQuery()
+		case *pgproto3.Sync:
+			skip = false
+			s.sync()
+			s.readyForQuery()
+		case *pgproto3.Terminate:
+			return nil
+		case *pgproto3.Flush:
+		default:
+			if skip {
+				continue
+			}
+			if err := s.extended(msg); err != nil {
+				s.sendError(err)
+				skip = true
+			}
+		}
+
+		switch msg.(type) {
+		case *pgproto3.Parse, *pgproto3.Bind, *pgproto3.Describe, *pgproto3.Execute, *pgproto3.Close:
+			// responses to the extended protocol are sent on Sync or Flush
+		default:
+			if err := s.backend.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// extended handles a message of the extended query protocol.
+func (s *session) extended(msg pgproto3.FrontendMessage) error {
+	switch m := msg.(type) {
+	case *pgproto3.Parse:
+		if _, ok := s.stmts[m.Name]; ok && m.Name != "" {
+			return &pgError{code: "42P05", msg: "prepared statement \"" + m.Name + "\" already exists"}
+		}
+		ps, err := s.parse(m.Query, m.ParameterOIDs)
+		if err != nil {
+			return err
+		}
+		s.stmts[m.Name] = ps
+		s.backend.Send(&pgproto3.ParseComplete{})
+	case *pgproto3.Bind:
+		if err := s.bind(m); err != nil {
+			return err
+		}
+		s.backend.Send(&pgproto3.BindComplete{})
+	case *pgproto3.Describe:
+		return s.describe(m.ObjectType, m.Name)
+	case *pgproto3.Execute:
+		p, ok := s.portals[m.Portal]
+		if !ok {
+			return &pgError{code: "34000", msg: "portal \"" + m.Portal + "\" does not exist"}
+		}
+		return s.execute(p, int(m.MaxRows), false)
+	case *pgproto3.Close:
+		if m.ObjectType == 'S' {
+			delete(s.stmts, m.Name)
+		} else if p, ok := s.portals[m.Name]; ok {
+			delete(s.portals, m.Name)
+			if err := s.closePortal(p, nil); err != nil {
+				return err
+			}
+		}
+		s.backend.Send(&pgproto3.CloseComplete{})
+	default:
+		return &pgError{code: "08P01", msg: "unsupported message type " + strings.TrimPrefix(typeName(msg), "pgproto3.")}
+	}
+
+	return nil
+}
+
+// simpleQuery runs the statements of a query sent with the simple query protocol.
+// Execution stops at the first error.
+func (s *session) simpleQuery(q string) error {
+	if ps, ok := s.sessionCommand(q); ok {
+		return s.execute(&portal{stmt: ps}, 0, true)
+	}
+
+	stmts, err := parser.ParseQuery(q)
+	if err != nil {
+		return err
+	}
+	if len(stmts) == 0 {
+		s.backend.Send(&pgproto3.EmptyQueryResponse{})
+		return nil
+	}
+
+	for _, stmt := range stmts {
+		ps, err := s.prepare(stmt, 0)
+		if err != nil {
+			return err
+		}
+
+		if err := s.execute(&portal{stmt: ps}, 0, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parse prepares a query sent with a Parse message.
+// The types of the parameters given by the client take precedence over the inferred ones.
+func (s *session) parse(q string, oids []uint32) (*preparedStmt, error) {
+	if ps, ok := s.sessionCommand(q); ok {
+		return ps, nil
+	}
+
+	p := parser.NewParser(strings.NewReader(q))
+	stmts, err := p.ParseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if len(stmts) == 0 {
+		return &preparedStmt{}, nil
+	}
+	if len(stmts) > 1 {
+		return nil, &pgError{code: "42601", msg: "cannot insert multiple commands into a prepared statement"}
+	}
+
+	ps, err := s.prepare(stmts[0], max(p.NumParams(), len(oids)))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, oid := range oids {
+		if tp := oidType(oid); !tp.IsAny() {
+			ps.paramTypes[i] = tp
+		}
+	}
+
+	return ps, nil
+}
+
+// prepare binds stmt and infers the types of its n parameters and of its columns.
+func (s *session) prepare(stmt statement.Statement, n int) (*preparedStmt, error) {
+	if s.failed && !endsFailedTx(stmt) {
+		return nil, errTxAborted
+	}
+	if _, ok := stmt.(*statement.CopyStmt); ok && !s.srv.opts.AllowCopy {
+		return nil, errCopyNotAllowed
+	}
+
+	tx := s.conn.GetTx()
+	if tx == nil {
+		var err error
+		tx, err = s.conn.BeginTx(&database.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = tx.Rollback() }()
+	}
+
+	stmt, err := query.Prepare(&statement.Context{DB: s.srv.db, Conn: s.conn}, stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	t := typer{tx: tx}
+	cols, err := t.columns(stmt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &preparedStmt{
+		stmt:       stmt,
+		paramTypes: t.paramTypes(stmt, n),
+		columns:    cols,
+	}, nil
+}
+
+// sessionCommand returns the statement of the SET and SHOW commands,
+// which are handled by the session rather than by the database.
+func (s *session) sessionCommand(q string) (*preparedStmt, bool) {
+	if m := setRe.FindStringSubmatch(q); m != nil {
+		value := strings.Trim(m[2], `'"`)
+		s.params[strings.ToLower(m[1])] = value
+		return &preparedStmt{tag: "SET"}, true
+	}
+
+	if m := showRe.FindStringSubmatch(q); m != nil {
+		name := strings.ToLower(m[1])
+		value, ok := s.params[name]
+		if !ok {
+			return nil, false
+		}
+		return &preparedStmt{
+			columns: []column{{name: name, typ: types.TypeText}},
+			rows:    [][]types.Value{{types.NewTextValue(value)}},
+			tag:     "SHOW",
+		}, true
+	}
+
+	return nil, false
+}
+
+func (s *session) bind(m *pgproto3.Bind) error {
+	ps, ok := s.stmts[m.PreparedStatement]
+	if !ok {
+		return &pgError{code: "26000", msg: "prepared statement \"" + m.PreparedStatement + "\" does not exist"}
+	}
+
+	if old, ok := s.portals[m.DestinationPortal]; ok {
+		if m.DestinationPortal != "" {
+			return &pgError{code: "42P03", msg: "portal \"" + m.DestinationPortal + "\" already exists"}
+		}
+		delete(s.portals, "")
+		if err := s.closePortal(old, nil); err != nil {
+			return err
+		}
+	}
+
+	if len(m.Parameters) != len(ps.paramTypes) {
+		return &pgError{code: "08P01", msg: "bind message supplies " + strconv.Itoa(len(m.Parameters)) +
+			" parameters, but prepared statement requires " + strconv.Itoa(len(ps.paramTypes))}
+	}
+
+	params := make([]environment.Param, len(m.Parameters))
+	for i, data := range m.Parameters {
+		v, err := decodeParam(data, ps.paramTypes[i], formatCode(m.ParameterFormatCodes, i))
+		if err != nil {
+			return &pgError{code: "22P02", msg: "invalid value for parameter $" + strconv.Itoa(i+1) + ": " + err.Error()}
+		}
+		params[i] = environment.Param{Value: v}
+	}
+
+	// the message is reused by the backend
+	s.portals[m.DestinationPortal] = &portal{
+		stmt:    ps,
+		params:  params,
+		formats: append([]int16(nil), m.ResultFormatCodes...),
+	}
+
+	return nil
+}
+
+func (s *session) describe(objectType byte, name string) error {
+	if objectType == 'S' {
+		ps, ok := s.stmts[name]
+		if !ok {
+			return &pgError{code: "26000", msg: "prepared statement \"" + name + "\" does not exist"}
+		}
+
+		oids := make([]uint32, len(ps.paramTypes))
+		for i, tp := range ps.paramTypes {
+			// parameters of unknown type are left unspecified
+			// so that clients send them as text
+			if !tp.IsAny() {
+				oids[i] = typeOID(tp)
+			}
+		}
+		s.backend.Send(&pgproto3.ParameterDescription{ParameterOIDs: oids})
+		s.rowDescription(ps, nil)
+		return nil
+	}
+
+	p, ok := s.portals[name]
+	if !ok {
+		return &pgError{code: "34000", msg: "portal \"" + name + "\" does not exist"}
+	}
+	s.rowDescription(p.stmt, p.formats)
+	return nil
+}
+
+// rowDescription describes the rows returned by ps,
+// or sends NoData if it doesn't return rows.
+func (s *session) rowDescription(ps *preparedStmt, formats []int16) {
+	if ps.columns == nil {
+		s.backend.Send(&pgproto3.NoData{})
+		return
+	}
+
+	fields := make([]pgproto3.FieldDescription, len(ps.columns))
+	for i, c := range ps.columns {
+		fields[i] = pgproto3.FieldDescription{
+			Name:         []byte(c.name),
+			DataTypeOID:  typeOID(c.typ),
+			DataTypeSize: typeSize(c.typ),
+			TypeModifier: -1,
+			Format:       formatCode(formats, i),
+		}
+	}
+	s.backend.Send(&pgproto3.RowDescription{Fields: fields})
+}
+
+// execute runs the statement of the portal, if it was not started yet, and sends
+// at most maxRows rows to the client, or all of them if maxRows is 0.
+// If describe is true, the rows are described first, as in the simple query protocol.
+func (s *session) execute(p *portal, maxRows int, describe bool) (err error) {
+	ps := p.stmt
+	if ps.stmt == nil && ps.tag == "" {
+		s.backend.Send(&pgproto3.EmptyQueryResponse{})
+		return nil
+	}
+
+	if describe {
+		s.rowDescription(ps, nil)
+	}
+
+	if ps.stmt == nil {
+		for _, row := range ps.rows {
+			if err := s.sendRow(p, row); err != nil {
+				return err
+			}
+		}
+		s.backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(ps.tag)})
+		return nil
+	}
+
+	if p.done {
+		s.backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(p.commandTag())})
+		return nil
+	}
+
+	ctx := s.beginQuery()
+	defer s.endQuery()
+
+	defer func() {
+		if err != nil {
+			err = s.closePortal(p, err)
+		}
+	}()
+
+	if !p.started {
+		p.started = true
+		if err := s.start(ctx, p); err != nil {
+			return err
+		}
+	}
+
+	for n := 0; p.it != nil && (maxRows <= 0 || n < maxRows); n++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if !p.it.Next() {
+			if err := p.it.Error(); err != nil {
+				return err
+			}
+			p.done = true
+			break
+		}
+
+		r, err := p.it.Row()
+		if err != nil {
+			return err
+		}
+
+		var values []types.Value
+		err = r.Iterate(func(_ string, v types.Value) error {
+			values = append(values, v)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.sendRow(p, values); err != nil {
+			return err
+		}
+		p.count++
+
+		if p.count%flushRows == 0 {
+			if err := s.backend.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.it != nil && !p.done {
+		s.backend.Send(&pgproto3.PortalSuspended{})
+		return nil
+	}
+
+	p.done = true
+	if err := s.closePortal(p, nil); err != nil {
+		return err
+	}
+
+	s.backend.Send(&pgproto3.CommandComplete{CommandTag: []byte(p.commandTag())})
+	return nil
+}
+
+// start runs the statement of the portal. Statements that don't return rows
+// are run to completion, the others are left to be iterated.
+func (s *session) start(ctx context.Context, p *portal) error {
+	stmt := p.stmt.stmt
+	if s.failed {
+		switch stmt.(type) {
+		case query.RollbackStmt, query.RollbackToSavepointStmt:
+		case query.CommitStmt:
+			// committing a failed transaction rolls it back
+			stmt = query.RollbackStmt{}
+			p.tag = "ROLLBACK"
+		default:
+			return errTxAborted
+		}
+	}
+
+	switch stmt.(type) {
+	case query.CommitStmt, query.RollbackStmt, query.RollbackToSavepointStmt:
+		// the rows of the other portals can't be read after the end of the transaction
+		for name, other := range s.portals {
+			if other != p && other.it != nil {
+				delete(s.portals, name)
+				_ = s.closePortal(other, nil)
+			}
+		}
+	}
+
+	res, err := query.New(stmt).Run(&query.Context{
+		Ctx:    ctx,
+		DB:     s.srv.db,
+		Conn:   s.conn,
+		Params: p.params,
+	})
+	if err != nil {
+		return err
+	}
+	p.res = res
+
+	if p.stmt.columns != nil {
+		p.it, err = res.Iterator()
+		return err
+	}
+
+	p.count, err = countRows(ctx, res)
+	if err != nil {
+		return err
+	}
+
+	switch stmt.(type) {
+	case query.RollbackStmt, query.RollbackToSavepointStmt:
+		s.failed = false
+	}
+
+	return nil
+}
+
+// countRows runs res to completion and returns the number of rows
+// written by the statement, or returned if it doesn't write any.
+func countRows(ctx context.Context, res *statement.Result) (int, error) {
+	if res.Result == nil {
+		return 0, nil
+	}
+
+	var it stream.Iterator
+	var err error
+	if sr, ok := res.Result.(*statement.StreamStmtResult); ok && sr.Stream.Op != nil {
+		// the rows written by INSERT, UPDATE and DELETE are discarded by the last operator
+		if d, ok := sr.Stream.Op.(*stream.DiscardOperator); ok && d.GetPrev() != nil {
+			env := environment.New(sr.Context.DB, sr.Context.Conn.GetTx(), sr.Context.Params, nil)
+			it, err = d.GetPrev().Iterator(env)
+		}
+	}
+	if it == nil && err == nil {
+		it, err = res.Iterator()
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	var n int
+	for it.Next() {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		n++
+	}
+
+	return n, it.Error()
+}
+
+func (s *session) sendRow(p *portal, row []types.Value) error {
+	cols := p.stmt.columns
+	if len(row) != len(cols) {
+		return errors.Errorf("expected %d columns, got %d", len(cols), len(row))
+	}
+
+	values := make([][]byte, len(row))
+	for i, v := range row {
+		var err error
+		values[i], err = encodeValue(v, cols[i].typ, formatCode(p.formats, i))
+		if err != nil {
+			return err
+		}
+	}
+
+	s.backend.Send(&pgproto3.DataRow{Values: values})
+	return nil
+}
+
+// closePortal releases the iterator and the result of the portal.
+// If err is not nil, the transaction opened for the statement is rolled back.
+func (s *session) closePortal(p *portal, err error) error {
+	if p.it != nil {
+		if cerr := p.it.Close(); err == nil {
+			err = cerr
+		}
+		p.it = nil
+	}
+
+	if p.res != nil {
+		if err != nil && p.res.Tx != nil {
+			_ = p.res.Tx.Rollback()
+			p.res.Tx = nil
+		}
+		if cerr := p.res.Close(); err == nil {
+			err = cerr
+		}
+		p.res = nil
+	}
+
+	return err
+}
+
+func (s *session) closePortals() {
+	for name, p := range s.portals {
+		delete(s.portals, name)
+		_ = s.closePortal(p, nil)
+	}
+}
+
+// sync ends the implicit transactions of the portals
+// and releases the unnamed portal.
+func (s *session) sync() {
+	for name, p := range s.portals {
+		if name == "" || (p.res != nil && p.res.Tx != nil) {
+			delete(s.portals, name)
+			_ = s.closePortal(p, nil)
+		}
+	}
+}
+
+func (s *session) readyForQuery() {
+	status := byte('I')
+	switch {
+	case s.conn.GetTx() == nil:
+		s.failed = false
+	case s.failed:
+		status = 'E'
+	default:
+		status = 'T'
+	}
+
+	s.backend.Send(&pgproto3.ReadyForQuery{TxStatus: status})
+}
+
+// sendError reports err to the client. An error in an explicit transaction
+// makes it fail.
+func (s *session) sendError(err error) {
+	if s.conn.GetTx() != nil {
+		s.failed = true
+	}
+
+	code := errorCode(err)
+	msg := err.Error()
+	if code == "57014" {
+		msg = "canceling statement due to user request"
+	}
+
+	s.backend.Send(&pgproto3.ErrorResponse{
+		Severity:            "ERROR",
+		SeverityUnlocalized: "ERROR",
+		Code:                code,
+		Message:             msg,
+	})
+}
+
+func (s *session) beginQuery() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	return ctx
+}
+
+func (s *session) endQuery() {
+	s.cancelQuery()
+
+	s.mu.Lock()
+	s.cancel = nil
+	s.mu.Unlock()
+}
+
+// cancelQuery cancels the running query, if any.
+// It can be called from any goroutine.
+func (s *session) cancelQuery() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+// commandTag returns the tag sent to the client once the statement of the portal completed.
+func (p *portal) commandTag() string {
+	if p.tag != "" {
+		return p.tag
+	}
+
+	stmt := p.stmt.stmt
+	if ps, ok := stmt.(query.PreparedStatement); ok {
+		stmt = ps.Statement
+	}
+
+	n := strconv.Itoa(p.count)
+	switch stmt.(type) {
+	case *statement.SelectStmt:
+		return "SELECT " + n
+	case *statement.InsertStmt:
+		return "INSERT 0 " + n
+	case *statement.UpdateStmt:
+		return "UPDATE " + n
+	case *statement.DeleteStmt:
+		return "DELETE " + n
+	case query.BeginStmt:
+		return "BEGIN"
+	case query.CommitStmt:
+		return "COMMIT"
+	case query.RollbackStmt, query.RollbackToSavepointStmt:
+		return "ROLLBACK"
+	case query.SavepointStmt:
+		return "SAVEPOINT"
+	case query.ReleaseSavepointStmt:
+		return "RELEASE"
+	case *statement.CreateTableStmt:
+		return "CREATE TABLE"
+	case *statement.CreateIndexStmt:
+		return "CREATE INDEX"
+	case *statement.CreateSequenceStmt:
+		return "CREATE SEQUENCE"
+	case *statement.DropTableStmt:
+		return "DROP TABLE"
+	case *statement.DropIndexStmt:
+		return "DROP INDEX"
+	case *statement.DropSequenceStmt:
+		return "DROP SEQUENCE"
+	case *statement.ReIndexStmt:
+		return "REINDEX"
+	case *statement.ExplainStmt:
+		return "EXPLAIN"
+	case *statement.CopyStmt:
+		return "COPY " + n
+	}
+
+	if strings.Contains(typeName(stmt), "AlterTable") {
+		return "ALTER TABLE"
+	}
+
+	return "OK"
+}
+
+// endsFailedTx returns whether stmt can be run in a failed transaction.
+func endsFailedTx(stmt statement.Statement) bool {
+	switch stmt.(type) {
+	case query.RollbackStmt, query.RollbackToSavepointStmt, query.CommitStmt:
+		return true
+	}
+
+	return false
+}
+
+// formatCode returns the format of the i-th value, given the format codes of a message.
+// A single format code applies to all the values.
+func formatCode(codes []int16, i int) int16 {
+	switch {
+	case len(codes) == 0:
+		return textFormat
+	case len(codes) == 1:
+		return codes[0]
+	case i < len(codes):
+		return codes[i]
+	}
+
+	return textFormat
+}
+
+// errorCode returns the SQLSTATE code of err.
+func errorCode(err error) string {
+	var pgErr *pgError
+	var parseErr *parser.ParseError
+	var cerr *database.ConstraintViolationError
+
+	switch {
+	case errors.As(err, &pgErr):
+		return pgErr.code
+	case errors.Is(err, context.Canceled):
+		return "57014"
+	case errors.As(err, &parseErr):
+		return "42601"
+	case errors.As(err, &cerr):
+		switch cerr.Constraint {
+		case "NOT NULL":
+			return "23502"
+		case "UNIQUE", "PRIMARY KEY":
+			return "23505"
+		}
+		return "23000"
+	case errs.IsNotFoundError(err):
+		return "42P01"
+	case errs.IsAlreadyExistsError(err):
+		return "42P07"
+	case errors.Is(err, database.ErrReadOnly):
+		return "25006"
+	}
+
+	return "XX000"
+}
+
+func typeName(v any) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", v), "*")
+}
@@ -0,0 +1,265 @@
+package pgwire
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+// OIDs of the PostgreSQL types chai types are mapped to.
+const (
+	oidBool        = 16
+	oidBytea       = 17
+	oidName        = 19
+	oidInt8        = 20
+	oidInt2        = 21
+	oidInt4        = 23
+	oidText        = 25
+	oidFloat4      = 700
+	oidFloat8      = 701
+	oidBPChar      = 1042
+	oidVarchar     = 1043
+	oidDate        = 1082
+	oidTimestamp   = 1114
+	oidTimestamptz = 1184
+	oidNumeric     = 1700
+	oidUUID        = 2950
+)
+
+const (
+	textFormat   = 0
+	binaryFormat = 1
+)
+
+// pgEpoch is the origin of the binary representation of timestamps.
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// typeOID returns the OID of the PostgreSQL type a chai type is sent as.
+// Values of unknown types are sent as text.
+func typeOID(t types.Type) uint32 {
+	switch t {
+	case types.TypeBoolean:
+		return oidBool
+	case types.TypeInteger:
+		return oidInt4
+	case types.TypeBigint:
+		return oidInt8
+	case types.TypeDoublePrecision:
+		return oidFloat8
+	case types.TypeTimestamp:
+		return oidTimestamp
+	case types.TypeBytea:
+		return oidBytea
+	case types.TypeUUID:
+		return oidUUID
+	}
+
+	return oidText
+}
+
+// typeSize returns the size of the binary representation of t,
+// or -1 if it has a variable size.
+func typeSize(t types.Type) int16 {
+	switch t {
+	case types.TypeBoolean:
+		return 1
+	case types.TypeInteger:
+		return 4
+	case types.TypeBigint, types.TypeDoublePrecision, types.TypeTimestamp:
+		return 8
+	case types.TypeUUID:
+		return 16
+	}
+
+	return -1
+}
+
+// oidType returns the chai type of the PostgreSQL type with the given OID,
+// or types.TypeAny if it is not supported.
+func oidType(oid uint32) types.Type {
+	switch oid {
+	case oidBool:
+		return types.TypeBoolean
+	case oidInt2, oidInt4:
+		return types.TypeInteger
+	case oidInt8:
+		return types.TypeBigint
+	case oidFloat4, oidFloat8, oidNumeric:
+		return types.TypeDoublePrecision
+	case oidDate, oidTimestamp, oidTimestamptz:
+		return types.TypeTimestamp
+	case oidText, oidName, oidBPChar, oidVarchar:
+		return types.TypeText
+	case oidBytea:
+		return types.TypeBytea
+	case oidUUID:
+		return types.TypeUUID
+	}
+
+	return types.TypeAny
+}
+
+// encodeValue returns the representation of v sent to the client, as a value of type t.
+// NULL is returned as a nil slice. Columns of unknown types are described as text,
+// so their values are always sent as text.
+func encodeValue(v types.Value, t types.Type, format int16) ([]byte, error) {
+	if v.Type() == types.TypeNull {
+		return nil, nil
+	}
+
+	if !t.IsAny() && t != types.TypeNull && v.Type() != t {
+		var err error
+		v, err = v.CastAs(t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if format == textFormat || typeOID(t) == oidText {
+		return []byte(textValue(v)), nil
+	}
+
+	switch v.Type() {
+	case types.TypeBoolean:
+		if types.AsBool(v) {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case types.TypeInteger:
+		return binary.BigEndian.AppendUint32(nil, uint32(types.AsInt32(v))), nil
+	case types.TypeBigint:
+		return binary.BigEndian.AppendUint64(nil, uint64(types.AsInt64(v))), nil
+	case types.TypeDoublePrecision:
+		return binary.BigEndian.AppendUint64(nil, math.Float64bits(types.AsFloat64(v))), nil
+	case types.TypeTimestamp:
+		return binary.BigEndian.AppendUint64(nil, uint64(types.AsTime(v).UnixMicro()-pgEpoch.UnixMicro())), nil
+	case types.TypeBytea:
+		return types.AsByteSlice(v), nil
+	case types.TypeUUID:
+		u := v.(types.UUIDValue)
+		return u[:], nil
+	}
+
+	return []byte(textValue(v)), nil
+}
+
+// textValue returns the text representation of v used by PostgreSQL.
+func textValue(v types.Value) string {
+	switch v.Type() {
+	case types.TypeBoolean:
+		if types.AsBool(v) {
+			return "t"
+		}
+		return "f"
+	case types.TypeInteger, types.TypeBigint:
+		return strconv.FormatInt(types.AsInt64(v), 10)
+	case types.TypeDoublePrecision:
+		f := types.AsFloat64(v)
+		switch {
+		case math.IsInf(f, 1):
+			return "Infinity"
+		case math.IsInf(f, -1):
+			return "-Infinity"
+		case math.IsNaN(f):
+			return "NaN"
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	case types.TypeTimestamp:
+		return types.AsTime(v).UTC().Format("2006-01-02 15:04:05.999999")
+	case types.TypeText:
+		return types.AsString(v)
+	case types.TypeBytea:
+		return `\x` + hex.EncodeToString(types.AsByteSlice(v))
+	case types.TypeUUID:
+		return v.(types.UUIDValue).Canonical()
+	}
+
+	return v.String()
+}
+
+// decodeParam returns the value of a parameter of type t sent by the client.
+// Parameters of unknown type sent as text are passed as TEXT values.
+func decodeParam(data []byte, t types.Type, format int16) (types.Value, error) {
+	if data == nil {
+		return types.NewNullValue(), nil
+	}
+
+	if format == textFormat {
+		s := string(data)
+		switch t {
+		case types.TypeAny, types.TypeText:
+			return types.NewTextValue(s), nil
+		case types.TypeBytea:
+			if b, ok := strings.CutPrefix(s, `\x`); ok {
+				return hexValue(b)
+			}
+			return types.NewByteaValue([]byte(s)), nil
+		}
+
+		return types.NewTextValue(s).CastAs(t)
+	}
+
+	switch t {
+	case types.TypeBoolean:
+		if len(data) != 1 {
+			return nil, errors.Errorf("invalid binary boolean of %d bytes", len(data))
+		}
+		return types.NewBooleanValue(data[0] != 0), nil
+	case types.TypeInteger, types.TypeBigint:
+		var i int64
+		switch len(data) {
+		case 2:
+			i = int64(int16(binary.BigEndian.Uint16(data)))
+		case 4:
+			i = int64(int32(binary.BigEndian.Uint32(data)))
+		case 8:
+			i = int64(binary.BigEndian.Uint64(data))
+		default:
+			return nil, errors.Errorf("invalid binary integer of %d bytes", len(data))
+		}
+		return types.NewBigintValue(i).CastAs(t)
+	case types.TypeDoublePrecision:
+		switch len(data) {
+		case 4:
+			return types.NewDoublePrecisionValue(float64(math.Float32frombits(binary.BigEndian.Uint32(data)))), nil
+		case 8:
+			return types.NewDoublePrecisionValue(math.Float64frombits(binary.BigEndian.Uint64(data))), nil
+		}
+		return nil, errors.Errorf("invalid binary double of %d bytes", len(data))
+	case types.TypeTimestamp:
+		switch len(data) {
+		case 4:
+			// dates are a number of days
+			days := int32(binary.BigEndian.Uint32(data))
+			return types.NewTimestampValue(pgEpoch.AddDate(0, 0, int(days))), nil
+		case 8:
+			us := int64(binary.BigEndian.Uint64(data))
+			return types.NewTimestampValue(time.UnixMicro(pgEpoch.UnixMicro() + us).UTC()), nil
+		}
+		return nil, errors.Errorf("invalid binary timestamp of %d bytes", len(data))
+	case types.TypeBytea:
+		return types.NewByteaValue(append([]byte(nil), data...)), nil
+	case types.TypeUUID:
+		if len(data) != 16 {
+			return nil, errors.Errorf("invalid binary uuid of %d bytes", len(data))
+		}
+		return types.NewUUIDValue([16]byte(data)), nil
+	}
+
+	return types.NewTextValue(string(data)), nil
+}
+
+func hexValue(s string) (types.Value, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bytea")
+	}
+
+	return types.NewByteaValue(b), nil
+}
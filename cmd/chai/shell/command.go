@@ -4,17 +4,17 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
-	"encoding/csv"
 	"fmt"
 	"io"
 	"os"
-	"strconv"
+	"path/filepath"
 	"strings"
+	"unicode"
 
 	"github.com/cockroachdb/errors"
 
 	"github.com/chaisql/chai/cmd/chai/dbutil"
-	"github.com/chaisql/chai/internal/row"
+	"github.com/chaisql/chai/internal/types"
 )
 
 type command struct {
@@ -73,9 +73,15 @@ var commands = []command{
 	},
 	{
 		Name:        ".import",
-		Options:     "TYPE FILE table",
+		Options:     "[--schema DEFS] [--on-error abort|skip|reject] [--reject-file FILE] csv|json|ndjson FILE TABLE",
 		DisplayName: ".import",
-		Description: "Import data from a file. Only supported type is 'csv'",
+		Description: "Import data from a file. Column types are inferred if the table doesn't exist.",
+	},
+	{
+		Name:        ".export",
+		Options:     "[--format csv|json] FILE TABLE|QUERY",
+		DisplayName: ".export",
+		Description: "Export a table or the result of a query to a CSV file with a header, or a JSON file with one object per line.",
 	},
 	{
 		Name:        ".timer",
@@ -83,6 +89,18 @@ var commands = []command{
 		DisplayName: ".timer",
 		Description: "Display the execution time after each query or hide it.",
 	},
+	{
+		Name:        ".mode",
+		Options:     "[" + strings.Join(dbutil.Formats, "|") + "]",
+		DisplayName: ".mode",
+		Description: "Set the output format of query results or show the current one.",
+	},
+	{
+		Name:        ".nullvalue",
+		Options:     "STRING",
+		DisplayName: ".nullvalue",
+		Description: "Set the text displayed for NULL values.",
+	},
 	{
 		Name:        ".restore",
 		Options:     "[dumpFile]",
@@ -91,6 +109,16 @@ var commands = []command{
 	},
 }
 
+// parseCommandString removes the quotes around a command argument, if any,
+// so that empty strings and strings with spaces can be passed.
+func parseCommandString(s string) string {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
+
 func getUsage(cmdName string) string {
 	for _, c := range commands {
 		if c.Name == cmdName {
@@ -192,109 +220,186 @@ func runSaveCmd(ctx context.Context, db *sql.DB, dbPath string) error {
 	return err
 }
 
-const csvBatchSize = 1000
+// runImportCmd imports a file in a table. args are the arguments of the .import command:
+//
+//	[--schema DEFINITIONS] [--on-error abort|skip|reject] [--reject-file FILE] FORMAT FILE TABLE
+func runImportCmd(ctx context.Context, db *sql.DB, args []string, out io.Writer) error {
+	var opts dbutil.ImportOptions
+	var rejectPath string
+
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		if len(args) < 2 {
+			return errors.New(getUsage(".import"))
+		}
+
+		switch args[0] {
+		case "--schema":
+			opts.Schema = args[1]
+		case "--on-error":
+			opts.OnError = args[1]
+		case "--reject-file":
+			rejectPath = args[1]
+		default:
+			return errors.Errorf("unknown option %s\n%s", args[0], getUsage(".import"))
+		}
+
+		args = args[2:]
+	}
+	if len(args) != 3 {
+		return errors.New(getUsage(".import"))
+	}
 
-func runImportCmd(ctx context.Context, db *sql.DB, fileType, path, table string) (err error) {
-	if strings.ToLower(fileType) != "csv" {
-		return errors.New("TYPE should be csv")
+	opts.Format = args[0]
+	if rejectPath != "" && opts.OnError == "" {
+		opts.OnError = dbutil.OnErrorReject
 	}
 
-	f, err := os.Open(path)
+	f, err := os.Open(args[1])
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		return err
+	if rejectPath != "" {
+		rf, err := os.Create(rejectPath)
+		if err != nil {
+			return err
+		}
+		defer rf.Close()
+		opts.RejectFile = rf
 	}
-	defer conn.Close()
 
-	tx, err := conn.BeginTx(ctx, nil)
+	report, err := dbutil.Import(ctx, db, f, args[2], &opts)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	r := csv.NewReader(f)
+	for _, err := range report.Errors {
+		fmt.Fprintln(out, err)
+	}
 
-	headers, err := r.Read()
-	if err != nil {
-		return err
+	fmt.Fprintf(out, "Imported %d records", report.Imported)
+	switch {
+	case len(report.Errors) == 0:
+	case opts.OnError == dbutil.OnErrorReject:
+		fmt.Fprintf(out, ", rejected %d to %s", len(report.Errors), rejectPath)
+	default:
+		fmt.Fprintf(out, ", skipped %d", len(report.Errors))
 	}
+	fmt.Fprintln(out, ".")
 
-	_, err = tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", table, strings.Join(headers, " TEXT, ")))
-	if err != nil {
-		return err
+	return nil
+}
+
+// splitCommandArgs splits the arguments of a command on spaces.
+// Arguments can be quoted to contain spaces.
+func splitCommandArgs(s string) ([]string, error) {
+	var args []string
+
+	for {
+		arg, rest, ok, err := nextCommandArg(s)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return args, nil
+		}
+
+		args = append(args, arg)
+		s = rest
 	}
+}
 
-	baseQ := fmt.Sprintf("INSERT INTO %s VALUES ", table)
+// nextCommandArg returns the first argument of s and the rest of the string.
+// It returns false if s doesn't contain any argument.
+func nextCommandArg(s string) (arg, rest string, ok bool, err error) {
+	var cur strings.Builder
+	var quote rune
+
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	if s == "" {
+		return "", "", false, nil
+	}
 
-	buf := make([][]string, csvBatchSize)
-	fbs := make([]*row.ColumnBuffer, csvBatchSize)
-	for i := range fbs {
-		fbs[i] = row.NewColumnBuffer()
+	for i, c := range s {
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			cur.WriteRune(c)
+		case c == '\'' || c == '"':
+			quote = c
+		case unicode.IsSpace(c):
+			return cur.String(), s[i:], true, nil
+		default:
+			cur.WriteRune(c)
+		}
 	}
-	args := make([]any, csvBatchSize)
-	for i := range args {
-		args[i] = fbs[i]
+	if quote != 0 {
+		return "", "", false, errors.New("unterminated quoted string")
 	}
 
-	var sb strings.Builder
-	var stop bool
-	var stmt *sql.Stmt
+	return cur.String(), "", true, nil
+}
 
-	for !stop {
-		sb.Reset()
-		n, err := csvReadN(r, csvBatchSize, buf)
-		if errors.Is(err, io.EOF) {
-			stop = true
-		} else if err != nil {
+// runExportCmd exports a table or the result of a query to a file
+// with a COPY statement. in is the input of the .export command, without its name:
+//
+//	[--format csv|json] FILE TABLE|QUERY
+//
+// The format defaults to json for files with a .json extension, csv otherwise.
+func runExportCmd(ctx context.Context, db *sql.DB, in string) error {
+	var format string
+
+	for {
+		arg, rest, ok, err := nextCommandArg(in)
+		if err != nil {
 			return err
 		}
-
-		if n == 0 {
+		if !ok || arg != "--format" {
 			break
 		}
 
-		for i := 0; i < n; i++ {
-			fbs[i].Reset()
-			fbs[i].ScanCSV(headers, buf[i])
-		}
-
-		if stmt == nil || n < csvBatchSize {
-			sb.WriteString(baseQ)
-			for i := 0; i < n; i++ {
-				if i > 0 {
-					sb.WriteString(",")
-				}
-				sb.WriteString("$")
-				sb.WriteString(strconv.Itoa(i + 1))
-			}
-
-			stmt, err = tx.Prepare(sb.String())
-			if err != nil {
-				return err
-			}
-		}
-
-		_, err = stmt.Exec(args[:n]...)
+		format, in, ok, err = nextCommandArg(rest)
 		if err != nil {
 			return err
 		}
+		if !ok {
+			return errors.New(getUsage(".export"))
+		}
 	}
 
-	return tx.Commit()
-}
+	path, source, ok, err := nextCommandArg(in)
+	if err != nil {
+		return err
+	}
+	source = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(source), ";"))
+	if !ok || source == "" {
+		return errors.New(getUsage(".export"))
+	}
 
-func csvReadN(r *csv.Reader, n int, dst [][]string) (int, error) {
-	for i := 0; i < n; i++ {
-		record, err := r.Read()
-		if err != nil {
-			return i, err
+	if format == "" {
+		format = "csv"
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			format = "json"
 		}
-		dst[i] = record
 	}
-	return n, nil
+
+	var options string
+	switch format {
+	case "csv":
+		options = "FORMAT csv, HEADER"
+	case "json":
+		options = "FORMAT json"
+	default:
+		return errors.Errorf("unknown format %q, expected csv or json", format)
+	}
+
+	// a single word is a table name, anything else is a query
+	if strings.ContainsFunc(source, unicode.IsSpace) {
+		source = "(" + source + ")"
+	}
+
+	_, err = db.ExecContext(ctx, fmt.Sprintf("COPY %s TO %s WITH (%s)", source, types.NewTextValue(path).String(), options))
+	return err
 }
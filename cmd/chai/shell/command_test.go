@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/csv"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -163,7 +164,7 @@ func BenchmarkImportCSV(b *testing.B) {
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		err = runImportCmd(b.Context(), db, "csv", fp, "foo")
+		err = runImportCmd(b.Context(), db, []string{"csv", fp, "foo"}, io.Discard)
 		require.NoError(b, err)
 
 		b.StopTimer()
@@ -172,3 +173,82 @@ func BenchmarkImportCSV(b *testing.B) {
 		b.StartTimer()
 	}
 }
+
+func TestModeCmd(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	sh := Shell{db: db, mode: dbutil.FormatTable, nullValue: dbutil.DefaultNullValue}
+
+	var buf bytes.Buffer
+	for _, in := range []string{
+		".mode csv",
+		".nullvalue ''",
+		"SELECT 1 AS a, NULL AS b;",
+		".mode",
+	} {
+		require.NoError(t, sh.executeInput(t.Context(), in, &buf))
+	}
+	require.Equal(t, "a,b\n1,\ncsv\n", buf.String())
+
+	require.Error(t, sh.runCommand(t.Context(), ".mode xml", &buf))
+}
+
+func TestImportCmd(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	dir := t.TempDir()
+	fp := filepath.Join(dir, "data.ndjson")
+	require.NoError(t, os.WriteFile(fp, []byte("{\"a\": 1}\n{\"a\": \"x\"}\n{\"a\": 2}\n"), 0o644))
+	rejected := filepath.Join(dir, "rejected.ndjson")
+
+	sh := Shell{db: db, mode: dbutil.FormatList, nullValue: dbutil.DefaultNullValue}
+
+	var buf bytes.Buffer
+	require.NoError(t, sh.executeInput(t.Context(), `.import --schema "a INT PRIMARY KEY" --reject-file `+rejected+` ndjson `+fp+` test`, &buf))
+	require.Contains(t, buf.String(), "Imported 2 records, rejected 1 to "+rejected+".\n")
+
+	data, err := os.ReadFile(rejected)
+	require.NoError(t, err)
+	require.Equal(t, "{\"a\":\"x\"}\n", string(data))
+
+	require.Error(t, sh.runCommand(t.Context(), ".import ndjson "+fp, &buf))
+	require.Error(t, sh.runCommand(t.Context(), ".import --on-error maybe ndjson "+fp+" test2", &buf))
+}
+
+func TestExportCmd(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE test(a INT PRIMARY KEY, b TEXT); INSERT INTO test VALUES (1, 'foo'), (2, 'bar baz')`)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	sh := Shell{db: db, mode: dbutil.FormatList, nullValue: dbutil.DefaultNullValue}
+
+	var buf bytes.Buffer
+	csvPath := filepath.Join(dir, "test.csv")
+	require.NoError(t, sh.runCommand(t.Context(), ".export "+csvPath+" test", &buf))
+	data, err := os.ReadFile(csvPath)
+	require.NoError(t, err)
+	require.Equal(t, "a,b\n1,foo\n2,bar baz\n", string(data))
+
+	jsonPath := filepath.Join(dir, "test.json")
+	require.NoError(t, sh.runCommand(t.Context(), ".export "+jsonPath+" SELECT b FROM test WHERE b = 'bar baz';", &buf))
+	data, err = os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	require.Equal(t, "{\"b\": \"bar baz\"}\n", string(data))
+
+	txtPath := filepath.Join(dir, "test.txt")
+	require.NoError(t, sh.runCommand(t.Context(), `.export --format json "`+txtPath+`" test`, &buf))
+	data, err = os.ReadFile(txtPath)
+	require.NoError(t, err)
+	require.Equal(t, "{\"a\": 1, \"b\": \"foo\"}\n{\"a\": 2, \"b\": \"bar baz\"}\n", string(data))
+
+	require.Error(t, sh.runCommand(t.Context(), ".export "+csvPath, &buf))
+	require.Error(t, sh.runCommand(t.Context(), ".export --format xml "+csvPath+" test", &buf))
+}
@@ -0,0 +1,230 @@
+package shell
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/expr/functions"
+	"github.com/chaisql/chai/internal/sql/driver"
+	"github.com/chaisql/chai/internal/sql/scanner"
+	"github.com/cockroachdb/errors"
+)
+
+// completionSource holds the names that can be completed in the shell.
+type completionSource struct {
+	tables    []string
+	columns   map[string][]string
+	indexes   []string
+	sequences []string
+	functions []string
+	keywords  []string
+}
+
+// loadCompletionSource reads the names of the objects of the database from its catalog,
+// so that completions reflect the changes made during the session.
+func loadCompletionSource(ctx context.Context, db *sql.DB) (*completionSource, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var catalog *database.Catalog
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*driver.Conn)
+		if !ok {
+			return errors.Errorf("unexpected connection type %T", driverConn)
+		}
+
+		catalog = c.DB().Catalog()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	src := completionSource{
+		tables:    catalog.ListTables(),
+		columns:   make(map[string][]string),
+		indexes:   catalog.ListIndexes(""),
+		sequences: catalog.ListSequences(),
+		functions: functions.Names(),
+	}
+
+	for _, table := range src.tables {
+		info, err := catalog.GetTableInfo(table)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cc := range info.ColumnConstraints.Ordered {
+			src.columns[table] = append(src.columns[table], cc.Column)
+		}
+	}
+
+	for _, tok := range scanner.AllKeywords() {
+		src.keywords = append(src.keywords, tok.String())
+	}
+	sort.Strings(src.keywords)
+
+	return &src, nil
+}
+
+// keywords followed by a table name
+var tableKeywords = map[string]bool{
+	"FROM":   true,
+	"JOIN":   true,
+	"INTO":   true,
+	"UPDATE": true,
+	"TABLE":  true,
+	"COPY":   true,
+}
+
+// complete returns the word being typed before the cursor, and the names it can be
+// completed with. text is the input before the cursor, after is the input after it.
+func (src *completionSource) complete(text, after string) (string, []string) {
+	word := text[len(strings.TrimRightFunc(text, isIdentRune)):]
+	before := strings.TrimSuffix(text, word)
+
+	// dot-commands take table names as arguments
+	if line := strings.TrimLeftFunc(text, unicode.IsSpace); strings.HasPrefix(line, ".") {
+		if !strings.ContainsFunc(line, unicode.IsSpace) {
+			var names []string
+			for _, c := range commands {
+				names = append(names, c.Name)
+			}
+			return line, filterCompletions(line, names)
+		}
+
+		return word, filterCompletions(word, src.tables)
+	}
+
+	// the current statement starts after the last semicolon
+	// before the cursor and ends at the first one after it
+	if i := strings.LastIndexByte(before, ';'); i >= 0 {
+		before = before[i+1:]
+	}
+	if i := strings.IndexByte(after, ';'); i >= 0 {
+		after = after[:i]
+	}
+	words := identWords(before)
+	var prev, first string
+	if len(words) > 0 {
+		first, prev = words[0], words[len(words)-1]
+	}
+
+	switch {
+	case strings.HasSuffix(strings.TrimRightFunc(before, unicode.IsSpace), "nextval('"):
+		return word, filterCompletions(word, src.sequences)
+	case strings.HasSuffix(before, "'"):
+		// no completion inside strings
+		return word, nil
+	case prev == "ON" && first == "CREATE":
+		// CREATE INDEX idx ON table
+		return word, filterCompletions(word, src.tables)
+	case first == "CREATE":
+		// new objects are named by the user
+		if word == "" {
+			return word, nil
+		}
+		return word, filterCompletions(word, src.keywordsLike(word, src.functions))
+	case tableKeywords[prev]:
+		return word, filterCompletions(word, src.tables)
+	case prev == "REINDEX":
+		return word, filterCompletions(word, slices.Concat(src.tables, src.indexes))
+	case prev == "INDEX":
+		return word, filterCompletions(word, src.indexes)
+	case prev == "SEQUENCE":
+		return word, filterCompletions(word, src.sequences)
+	}
+
+	// columns of the tables referenced by the statement
+	var names []string
+	words = append(words, identWords(after)...)
+	for i := 1; i < len(words); i++ {
+		if !tableKeywords[words[i-1]] {
+			continue
+		}
+		for table, columns := range src.columns {
+			if strings.EqualFold(table, words[i]) {
+				names = append(names, columns...)
+			}
+		}
+	}
+
+	if word == "" {
+		return word, filterCompletions(word, names)
+	}
+
+	return word, filterCompletions(word, src.keywordsLike(word, slices.Concat(names, src.functions)))
+}
+
+// keywordsLike appends the keywords to names, in the case of the word being typed.
+func (src *completionSource) keywordsLike(word string, names []string) []string {
+	lower := strings.ToLower(word) == word
+
+	for _, k := range src.keywords {
+		if lower {
+			k = strings.ToLower(k)
+		}
+		names = append(names, k)
+	}
+
+	return names
+}
+
+// identWords returns the identifiers and keywords of s, in upper case.
+func identWords(s string) []string {
+	return strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool { return !isIdentRune(r) })
+}
+
+// filterCompletions returns the sorted names that start with prefix,
+// ignoring case. Internal tables are only returned if they are explicitly typed.
+func filterCompletions(prefix string, names []string) []string {
+	seen := make(map[string]bool)
+	var list []string
+
+	for _, name := range names {
+		if seen[name] || !strings.HasPrefix(strings.ToLower(name), strings.ToLower(prefix)) {
+			continue
+		}
+		if strings.HasPrefix(name, "__chai_") && !strings.HasPrefix(prefix, "_") {
+			continue
+		}
+
+		seen[name] = true
+		list = append(list, name)
+	}
+
+	sort.Strings(list)
+	return list
+}
+
+// commonPrefix returns the longest prefix shared by all the names, ignoring case.
+// The prefix is taken from the first name.
+func commonPrefix(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	prefix := []rune(names[0])
+	for _, name := range names[1:] {
+		r := []rune(name)
+		n := 0
+		for n < len(prefix) && n < len(r) && unicode.ToLower(prefix[n]) == unicode.ToLower(r[n]) {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+
+	return string(prefix)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,84 @@
+package shell
+
+import (
+	"database/sql"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplete(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE users(id INT PRIMARY KEY, name TEXT, age INT);
+		CREATE TABLE orders(id INT PRIMARY KEY, user_id INT, amount DOUBLE PRECISION);
+		CREATE INDEX users_name_idx ON users(name);
+		CREATE SEQUENCE user_seq;
+	`)
+	require.NoError(t, err)
+
+	src, err := loadCompletionSource(t.Context(), db)
+	require.NoError(t, err)
+
+	tests := []struct {
+		before, after string
+		word          string
+		expected      []string
+	}{
+		{"SELECT * FROM ", "", "", []string{"orders", "users"}},
+		{"SELECT * FROM u", "", "u", []string{"users"}},
+		{"SELECT * FROM __chai_c", "", "__chai_c", []string{"__chai_catalog"}},
+		{"SELECT na", " FROM users", "na", []string{"name"}},
+		{"SELECT * FROM users WHERE ", "", "", []string{"age", "id", "name"}},
+		{"SELECT * FROM orders WHERE am", "", "am", []string{"amount"}},
+		{"SELECT * FROM orders WHERE al", "", "al", []string{"all", "alter"}},
+		{"SEL", "", "SEL", []string{"SELECT"}},
+		{"UPDATE users SET ag", "", "ag", []string{"age"}},
+		{"INSERT INTO users (na", "", "na", []string{"name"}},
+		{"DROP INDEX ", "", "", []string{"users_name_idx"}},
+		{"REINDEX u", "", "u", []string{"users", "users_name_idx"}},
+		{"SELECT nextval('u", "", "u", []string{"user_seq"}},
+		{"DROP SEQUENCE ", "", "", []string{"user_seq"}},
+		{"CREATE INDEX idx ON o", "", "o", []string{"orders"}},
+		{"CREATE TABLE ", "", "", nil},
+		{"SELECT 'o", "", "o", nil},
+		{"SELECT 1; SELECT * FROM o", "", "o", []string{"orders"}},
+		{".ta", "", ".ta", []string{".tables"}},
+		{".dump u", "", "u", []string{"users"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.before, func(t *testing.T) {
+			word, completions := src.complete(test.before, test.after)
+			require.Equal(t, test.word, word)
+			require.Equal(t, test.expected, completions)
+		})
+	}
+
+	// tables created during the session are completed
+	_, err = db.Exec(`CREATE TABLE products(id INT PRIMARY KEY)`)
+	require.NoError(t, err)
+
+	m := newQueryInputModel(&Shell{db: db})
+	m.textArea.SetValue("select * from pro")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	require.Equal(t, "select * from products", m.textArea.Value())
+
+	m.textArea.SetValue("SELECT * FROM ")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	require.Equal(t, "SELECT * FROM ", m.textArea.Value())
+	require.Equal(t, []string{"orders", "products", "users"}, m.suggestions)
+
+	m.textArea.SetValue("select * from users where user_i")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	require.Equal(t, "select * from users where user_i", m.textArea.Value())
+
+	m.textArea.SetValue("SELECT * FROM users WHERE NA")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	require.Equal(t, "SELECT * FROM users WHERE name", m.textArea.Value())
+	require.Nil(t, m.suggestions)
+}
@@ -39,6 +39,11 @@ type Shell struct {
 
 	displayTime bool
 
+	// output format of query results
+	mode string
+	// text displayed for NULL values
+	nullValue string
+
 	history []string
 
 	// context used for execution cancellation,
@@ -54,6 +59,8 @@ type Options struct {
 	// Path of the database directory that will be created.
 	// If empty, the database will be in-memory.
 	DBPath string
+	// Format of query results. Defaults to table.
+	Format string
 }
 
 type queryTask struct {
@@ -71,6 +78,11 @@ func Run(ctx context.Context, opts *Options) error {
 	var sh Shell
 
 	sh.opts = opts
+	sh.mode = dbutil.FormatTable
+	if opts.Format != "" {
+		sh.mode = opts.Format
+	}
+	sh.nullValue = dbutil.DefaultNullValue
 
 	db, err := dbutil.OpenDB(sh.opts.DBPath)
 	if err != nil {
@@ -312,6 +324,27 @@ func (sh *Shell) runCommand(ctx context.Context, in string, out io.Writer) error
 
 		sh.displayTime = cmd[1] == "on"
 		return nil
+	case ".mode":
+		if len(cmd) > 2 {
+			return errors.New(getUsage(".mode"))
+		}
+		if len(cmd) == 1 {
+			_, err := fmt.Fprintln(out, sh.mode)
+			return err
+		}
+		if err := dbutil.ValidateFormat(cmd[1]); err != nil {
+			return err
+		}
+
+		sh.mode = cmd[1]
+		return nil
+	case ".nullvalue":
+		if len(cmd) == 1 {
+			return errors.New(getUsage(".nullvalue"))
+		}
+
+		sh.nullValue = parseCommandString(strings.TrimSpace(strings.TrimPrefix(in, cmd[0])))
+		return nil
 	case ".help":
 		return runHelpCmd(out)
 	case ".tables":
@@ -341,11 +374,14 @@ func (sh *Shell) runCommand(ctx context.Context, in string, out io.Writer) error
 	case ".schema":
 		return dbutil.DumpSchema(ctx, sh.db, out, cmd[1:]...)
 	case ".import":
-		if len(cmd) != 4 {
-			return errors.New(getUsage(".import"))
+		args, err := splitCommandArgs(strings.TrimPrefix(in, cmd[0]))
+		if err != nil {
+			return err
 		}
 
-		return runImportCmd(ctx, sh.db, cmd[1], cmd[2], cmd[3])
+		return runImportCmd(ctx, sh.db, args, out)
+	case ".export":
+		return runExportCmd(ctx, sh.db, strings.TrimPrefix(in, cmd[0]))
 	case ".restore":
 		if len(cmd) != 2 {
 			return errors.New(getUsage(".restore"))
@@ -357,7 +393,10 @@ func (sh *Shell) runCommand(ctx context.Context, in string, out io.Writer) error
 }
 
 func (sh *Shell) runQuery(ctx context.Context, q string, out io.Writer) error {
-	err := dbutil.ExecSQL(ctx, sh.db, strings.NewReader(q), out)
+	err := dbutil.ExecSQL(ctx, sh.db, strings.NewReader(q), out, &dbutil.ExecOptions{
+		Format:    sh.mode,
+		NullValue: sh.nullValue,
+	})
 	if errors.Is(err, context.Canceled) {
 		return errors.New("interrupted")
 	}
@@ -3,8 +3,10 @@ package shell
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/bubbles/cursor"
 	"github.com/charmbracelet/bubbles/spinner"
@@ -72,6 +74,8 @@ type queryInputModel struct {
 	err           error
 	historyOffset int
 	currentQuery  *string
+	// completions displayed after pressing Tab
+	suggestions []string
 }
 
 func newQueryInputModel(shell *Shell) queryInputModel {
@@ -125,7 +129,12 @@ func (m queryInputModel) Update(msg tea.Msg) (queryInputModel, tea.Cmd) {
 	case tea.WindowSizeMsg:
 		m.textArea.SetWidth(msg.Width - 1)
 	case tea.KeyMsg:
+		m.suggestions = nil
+
 		switch msg.Type {
+		case tea.KeyTab:
+			m.complete()
+			return m, nil
 		case tea.KeyCtrlC:
 			freeze := m.freezeAndReset()
 			return m, tea.Println(freeze)
@@ -222,6 +231,9 @@ func (m queryInputModel) View() string {
 	if m.err != nil {
 		return "Error: " + m.err.Error() + "\n" + m.textArea.View() + "\n"
 	}
+	if len(m.suggestions) > 0 {
+		return m.textArea.View() + "\n" + m.suggestionsView() + "\n"
+	}
 	if !m.debug {
 		return m.textArea.View() + "\n"
 	}
@@ -242,6 +254,64 @@ func (m queryInputModel) View() string {
 		"HistoryOffset: " + strconv.Itoa(m.historyOffset) + "\n"
 }
 
+// maximum number of completions displayed
+const maxSuggestions = 50
+
+func (m queryInputModel) suggestionsView() string {
+	s := m.suggestions
+	var more string
+	if len(s) > maxSuggestions {
+		more = "  (" + strconv.Itoa(len(s)-maxSuggestions) + " more)"
+		s = s[:maxSuggestions]
+	}
+
+	return lipgloss.NewStyle().Width(m.textArea.Width()).Render(strings.Join(s, "  ") + more)
+}
+
+// complete completes the word before the cursor. If there are several completions,
+// their common prefix is inserted and they are displayed under the input.
+func (m *queryInputModel) complete() {
+	src, err := loadCompletionSource(context.Background(), m.shell.db)
+	if err != nil {
+		m.err = err
+		return
+	}
+
+	before, after := m.splitAtCursor()
+	word, completions := src.complete(before, after)
+	if len(completions) == 0 {
+		return
+	}
+
+	completion := completions[0]
+	if len(completions) > 1 {
+		m.suggestions = completions
+		completion = commonPrefix(completions)
+		if utf8.RuneCountInString(completion) <= utf8.RuneCountInString(word) {
+			return
+		}
+	}
+
+	// replace the word, as the completion may use a different case
+	for range utf8.RuneCountInString(word) {
+		m.textArea, _ = m.textArea.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	}
+	m.textArea.InsertString(completion)
+}
+
+// splitAtCursor returns the input before and after the cursor.
+func (m *queryInputModel) splitAtCursor() (string, string) {
+	lines := strings.Split(m.textArea.Value(), "\n")
+	row := m.textArea.Line()
+	info := m.textArea.LineInfo()
+	line := []rune(lines[row])
+	col := min(info.StartColumn+info.ColumnOffset, len(line))
+
+	before := strings.Join(append(lines[:row:row], string(line[:col])), "\n")
+	after := strings.Join(append([]string{string(line[col:])}, lines[row+1:]...), "\n")
+	return before, after
+}
+
 func (m *queryInputModel) freezeAndReset() string {
 	m.textArea.Cursor.SetMode(cursor.CursorHide)
 	freeze := strings.TrimSuffix(m.View(), "\n")
@@ -1,11 +1,132 @@
 package chai
 
 import (
+	"context"
 	"database/sql"
+	sqldriver "database/sql/driver"
+	"io"
 
+	"github.com/chaisql/chai/internal/kv"
+	"github.com/chaisql/chai/internal/row"
 	"github.com/chaisql/chai/internal/sql/driver"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
 )
 
 func init() {
 	sql.Register("chai", driver.Driver{})
 }
+
+// Options are used to tune the database when it is opened.
+// The same options can be passed as query parameters of the
+// data source name given to sql.Open:
+//
+//	sql.Open("chai", "mydb?cache_size=512MB&sync=off&compression=zstd")
+type Options = driver.Options
+
+// Open opens the database located at path with the given options.
+// The returned connector must be passed to sql.OpenDB:
+//
+//	connector, err := chai.Open("mydb", chai.Options{CacheSize: 512 << 20})
+//	...
+//	db := sql.OpenDB(connector)
+func Open(path string, opts Options) (sqldriver.Connector, error) {
+	return driver.NewConnector(path, opts)
+}
+
+// Savepoint creates a savepoint with the given name in the transaction.
+// It is equivalent to running SAVEPOINT name.
+func Savepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	return driver.Savepoint(ctx, tx, name)
+}
+
+// RollbackToSavepoint discards the changes made in the transaction
+// after the given savepoint. It is equivalent to running ROLLBACK TO SAVEPOINT name.
+func RollbackToSavepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	return driver.RollbackToSavepoint(ctx, tx, name)
+}
+
+// ReleaseSavepoint destroys the given savepoint, keeping the changes made after it.
+// It is equivalent to running RELEASE SAVEPOINT name.
+func ReleaseSavepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	return driver.ReleaseSavepoint(ctx, tx, name)
+}
+
+// Backup writes a consistent copy of the database to dir, without blocking
+// the other transactions. If dir contains a previous backup of the database,
+// only the data written since is copied.
+// The backup can be restored with Restore.
+func Backup(ctx context.Context, db *sql.DB, dir string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*driver.Conn)
+		if !ok {
+			return errors.New("not a chai database")
+		}
+
+		return c.DB().Backup(ctx, dir)
+	})
+}
+
+// OpenReader returns a reader on the value of a TEXT or BYTEA column of the row
+// of table whose primary key is pk. Large values are read chunk by chunk rather
+// than loaded in memory. The reader reads from a snapshot of the database taken
+// when it is opened and must be closed.
+//
+// Large values can also be written chunk by chunk by passing an io.Reader
+// as a query parameter, which is stored as BYTEA.
+func OpenReader(ctx context.Context, db *sql.DB, table, column string, pk ...any) (io.ReadSeekCloser, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	values := make([]types.Value, len(pk))
+	for i, v := range pk {
+		values[i], err = row.NewValue(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var r io.ReadSeekCloser
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*driver.Conn)
+		if !ok {
+			return errors.New("not a chai database")
+		}
+
+		r, err = c.DB().OpenReader(table, column, values)
+		return err
+	})
+
+	return r, err
+}
+
+// Restore replaces the database located at path with the backup stored in dir.
+// The backup is validated before replacing the database, which must not be in use.
+func Restore(ctx context.Context, dir, path string, opts Options) error {
+	return driver.Restore(ctx, dir, path, opts)
+}
+
+// Rekey encrypts the files of the database located at path with newKey.
+// oldKey is the key currently used to encrypt the database, or nil if it is not encrypted.
+// If newKey is nil, the database is decrypted.
+// The database must not be in use.
+func Rekey(path string, oldKey, newKey []byte) error {
+	return kv.Rekey(path, keyProvider(oldKey), keyProvider(newKey))
+}
+
+func keyProvider(key []byte) kv.KeyProvider {
+	if len(key) == 0 {
+		return nil
+	}
+
+	return kv.StaticKey(key)
+}
@@ -1,14 +1,17 @@
 package chai_test
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
 	"testing"
 
-	_ "github.com/chaisql/chai"
+	"github.com/chaisql/chai"
 	"github.com/chaisql/chai/internal/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -379,3 +382,291 @@ func BenchmarkOrderBy(b *testing.B) {
 		}
 	}
 }
+
+func TestSavepoint(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+
+	_, err = db.Exec("CREATE TABLE test(a INT PRIMARY KEY, b INT NOT NULL)")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	// skip the invalid records without losing the others
+	for i, b := range []any{1, nil, 3, nil, 5} {
+		err = chai.Savepoint(ctx, tx, "record")
+		require.NoError(t, err)
+
+		_, err = tx.Exec("INSERT INTO test (a, b) VALUES ($1, $2)", i, b)
+		if err != nil {
+			err = chai.RollbackToSavepoint(ctx, tx, "record")
+			require.NoError(t, err)
+			continue
+		}
+
+		err = chai.ReleaseSavepoint(ctx, tx, "record")
+		require.NoError(t, err)
+	}
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	var count int
+	err = db.QueryRow("SELECT COUNT(*) FROM test").Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 3, count)
+
+	tx, err = db.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	err = chai.RollbackToSavepoint(ctx, tx, "unknown")
+	require.Error(t, err)
+}
+
+func TestOpenWithOptions(t *testing.T) {
+	dir := t.TempDir()
+
+	connector, err := chai.Open(filepath.Join(dir, "a"), chai.Options{
+		CacheSize:    1 << 20,
+		MemTableSize: 1 << 20,
+		NoSync:       true,
+		MaxBatchSize: 1 << 10,
+		Compression:  "zstd",
+	})
+	require.NoError(t, err)
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE test(a INT PRIMARY KEY, b TEXT); INSERT INTO test (a, b) VALUES (1, 'foo')")
+	require.NoError(t, err)
+
+	var b string
+	err = db.QueryRow("SELECT b FROM test WHERE a = 1").Scan(&b)
+	require.NoError(t, err)
+	require.Equal(t, "foo", b)
+
+	_, err = chai.Open(filepath.Join(dir, "b"), chai.Options{Compression: "unknown"})
+	require.Error(t, err)
+
+	// options can also be passed in the DSN
+	db2, err := sql.Open("chai", filepath.Join(dir, "c")+"?cache_size=1MB&sync=off&compression=snappy")
+	require.NoError(t, err)
+	defer db2.Close()
+
+	_, err = db2.Exec("CREATE TABLE test(a INT PRIMARY KEY)")
+	require.NoError(t, err)
+
+	_, err = sql.Open("chai", filepath.Join(dir, "d")+"?unknown=1")
+	require.Error(t, err)
+}
+
+func TestReadOnly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "db")
+
+	// a read-only database must exist
+	_, err := chai.Open(dir, chai.Options{ReadOnly: true})
+	require.Error(t, err)
+
+	db, err := sql.Open("chai", dir)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE test(a INT PRIMARY KEY, b TEXT);
+		CREATE SEQUENCE seq;
+		INSERT INTO test (a, b) VALUES (1, 'foo'), (2, 'bar');
+	`)
+	require.NoError(t, err)
+
+	// the database is locked by the writer
+	_, err = chai.Open(dir, chai.Options{})
+	require.True(t, chai.IsLockedError(err))
+	_, err = chai.Open(dir, chai.Options{ReadOnly: true})
+	require.True(t, chai.IsLockedError(err))
+
+	require.NoError(t, db.Close())
+
+	// multiple readers can share the database
+	ro1, err := sql.Open("chai", dir+"?read_only=true")
+	require.NoError(t, err)
+	defer ro1.Close()
+
+	ro2, err := sql.Open("chai", dir+"?read_only=true")
+	require.NoError(t, err)
+	defer ro2.Close()
+
+	// but writers cannot open it
+	_, err = chai.Open(dir, chai.Options{})
+	require.True(t, chai.IsLockedError(err))
+
+	// reads are allowed, including the ones requiring temporary storage
+	rows, err := ro1.Query("SELECT b FROM test ORDER BY b")
+	require.NoError(t, err)
+	testutil.RequireRowsEq(t, `{"b": 'bar'} {"b": 'foo'}`, rows)
+	rows.Close()
+
+	tx, err := ro2.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	require.NoError(t, err)
+	var count int
+	err = tx.QueryRow("SELECT COUNT(*) FROM test").Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.NoError(t, tx.Rollback())
+
+	// writes are rejected
+	for _, q := range []string{
+		"INSERT INTO test (a, b) VALUES (3, 'baz')",
+		"CREATE TABLE foo(a INT PRIMARY KEY)",
+		"SELECT nextval('seq')",
+		"BEGIN",
+	} {
+		_, err = ro1.Exec(q)
+		require.True(t, chai.IsReadOnlyError(err), "%s: %v", q, err)
+	}
+
+	require.NoError(t, ro1.Close())
+	require.NoError(t, ro2.Close())
+
+	// the lock is released once all readers are closed
+	db, err = sql.Open("chai", dir)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+}
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "backup")
+
+	db, err := sql.Open("chai", filepath.Join(dir, "db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE SEQUENCE seq;
+		CREATE TABLE test(a INT PRIMARY KEY DEFAULT nextval('seq'), b TEXT);
+		INSERT INTO test (b) VALUES ('foo'), ('bar');
+	`)
+	require.NoError(t, err)
+
+	// uncommitted changes are not part of the backup
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	_, err = tx.Exec("INSERT INTO test (b) VALUES ('baz')")
+	require.NoError(t, err)
+
+	err = chai.Backup(ctx, db, backupDir)
+	require.NoError(t, err)
+
+	require.NoError(t, tx.Commit())
+
+	count := func(t *testing.T, db *sql.DB) int {
+		var n int
+		err := db.QueryRow("SELECT COUNT(*) FROM test").Scan(&n)
+		require.NoError(t, err)
+		return n
+	}
+
+	restore := func(t *testing.T, path string) *sql.DB {
+		err := chai.Restore(ctx, backupDir, path, chai.Options{})
+		require.NoError(t, err)
+
+		db, err := sql.Open("chai", path)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	restored := restore(t, filepath.Join(dir, "restored1"))
+	require.Equal(t, 2, count(t, restored))
+
+	// sequences are restored
+	_, err = restored.Exec("INSERT INTO test (b) VALUES ('qux')")
+	require.NoError(t, err)
+	var a int
+	err = restored.QueryRow("SELECT a FROM test WHERE b = 'qux'").Scan(&a)
+	require.NoError(t, err)
+	require.Equal(t, 3, a)
+
+	// incremental backup in the same directory
+	_, err = db.Exec("INSERT INTO test (b) VALUES ('a'), ('b')")
+	require.NoError(t, err)
+
+	err = chai.Backup(ctx, db, backupDir)
+	require.NoError(t, err)
+
+	restored = restore(t, filepath.Join(dir, "restored2"))
+	require.Equal(t, 5, count(t, restored))
+
+	// restoring over an existing database replaces it
+	require.NoError(t, restored.Close())
+	restored = restore(t, filepath.Join(dir, "restored2"))
+	require.Equal(t, 5, count(t, restored))
+
+	// databases in use cannot be replaced
+	err = chai.Restore(ctx, backupDir, filepath.Join(dir, "restored2"), chai.Options{})
+	require.True(t, chai.IsLockedError(err), "%v", err)
+	require.Equal(t, 5, count(t, restored))
+
+	// invalid backups are rejected
+	err = chai.Restore(ctx, filepath.Join(dir, "unknown"), filepath.Join(dir, "restored3"), chai.Options{})
+	require.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "restored3"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestLargeValues(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "db")
+
+	db, err := sql.Open("chai", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	data := bytes.Repeat([]byte("chai"), 100_000)
+
+	_, err = db.Exec("CREATE TABLE test(a INT PRIMARY KEY, b BYTEA)")
+	require.NoError(t, err)
+
+	// readers are stored in chunks
+	_, err = db.Exec("INSERT INTO test (a, b) VALUES ($1, $2)", 1, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	var b []byte
+	err = db.QueryRow("SELECT b FROM test WHERE a = 1").Scan(&b)
+	require.NoError(t, err)
+	require.Equal(t, data, b)
+
+	// large readers can be selected
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	err = tx.QueryRow("SELECT $1", bytes.NewReader(data)).Scan(&b)
+	require.NoError(t, err)
+	require.Equal(t, data, b)
+	require.NoError(t, tx.Rollback())
+
+	// the values are kept when the database is reopened
+	require.NoError(t, db.Close())
+	db, err = sql.Open("chai", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	r, err := chai.OpenReader(ctx, db, "test", "b", 1)
+	require.NoError(t, err)
+	defer r.Close()
+
+	_, err = r.Seek(int64(len(data)-4), io.SeekStart)
+	require.NoError(t, err)
+	b, err = io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, []byte("chai"), b)
+
+	_, err = chai.OpenReader(ctx, db, "test", "b", 2)
+	require.Error(t, err)
+}
@@ -3,6 +3,7 @@ package chai
 import (
 	"github.com/chaisql/chai/internal/database"
 	errs "github.com/chaisql/chai/internal/errors"
+	"github.com/chaisql/chai/internal/kv"
 	"github.com/cockroachdb/errors"
 )
 
@@ -34,3 +35,22 @@ func IsAlreadyExistsError(err error) bool {
 
 	return false
 }
+
+// IsLockedError determines if the database could not be opened
+// because it is already opened by another process.
+func IsLockedError(err error) bool {
+	return errors.Is(err, kv.ErrDatabaseLocked)
+}
+
+// IsReadOnlyError determines if the error is returned as a result of
+// writing to a database opened in read-only mode.
+func IsReadOnlyError(err error) bool {
+	return errors.Is(err, database.ErrReadOnly)
+}
+
+// IsInvalidKeyError determines if the database could not be opened
+// because it is encrypted with a different key, or because a key
+// was expected or provided when it was not.
+func IsInvalidKeyError(err error) bool {
+	return errors.Is(err, kv.ErrInvalidEncryptionKey)
+}
@@ -0,0 +1,498 @@
+// Package httpapi provides an HTTP handler running SQL queries on a chai database,
+// for applications that need to expose a database to tools that don't link Go code,
+// like admin dashboards.
+//
+// The handler serves a single endpoint, POST /query, whose body is a JSON object
+// with the query and its parameters:
+//
+//	{"query": "SELECT * FROM users WHERE age > $1", "params": [18]}
+//
+// Named parameters are passed as an object:
+//
+//	{"query": "SELECT * FROM users WHERE age > :age", "params": {"age": 18}}
+//
+// The rows returned by the last statement are streamed as newline delimited JSON objects,
+// one per row. Errors are returned as a JSON object with an "error" field. If an error
+// occurs after rows were sent, it is written as the last line of the response.
+//
+// Each request runs on its own connection: transactions started with BEGIN
+// must be committed or rolled back by the same request, otherwise it is rejected.
+//
+// COPY statements read and write files on the server, so they are rejected
+// unless Options.AllowCopy is set.
+//
+// The changes recorded by the changelog of the database are read with GET /changes,
+// as newline delimited JSON objects, one per change. The table and cursor query parameters
+// only return the changes of a table committed after a cursor. With follow=true, the response
+// doesn't end after the last change and streams the following ones as they are committed.
+// DELETE /changes?cursor=N removes the changes up to the cursor N, included.
+//
+// The handler has no authentication: it must be mounted behind the access control of the application.
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/query"
+	"github.com/chaisql/chai/internal/query/statement"
+	"github.com/chaisql/chai/internal/row"
+	"github.com/chaisql/chai/internal/sql/driver"
+	"github.com/chaisql/chai/internal/sql/parser"
+	"github.com/cockroachdb/errors"
+)
+
+// MaxBodySize is the maximum size of the body of a request.
+const MaxBodySize = 1 << 20
+
+// ErrReadOnly is returned for statements that write to the database
+// when the handler is read-only.
+var ErrReadOnly = errors.New("statement not allowed: the handler is read-only")
+
+// ErrCopyNotAllowed is returned for COPY statements
+// when the handler doesn't allow them.
+var ErrCopyNotAllowed = errors.New("statement not allowed: COPY reads and writes files on the server")
+
+// ErrOpenTransaction is returned for queries that begin a transaction
+// without committing or rolling it back.
+var ErrOpenTransaction = errors.New("query leaves a transaction open: it must end with COMMIT or ROLLBACK")
+
+// Options configure a Handler.
+type Options struct {
+	// ReadOnly rejects the statements that may write to the database.
+	// Queries are run in read-only transactions.
+	ReadOnly bool
+
+	// AllowCopy allows COPY statements, which read and write
+	// files on the server with its permissions.
+	// It is ignored by read-only handlers.
+	AllowCopy bool
+}
+
+// A Request is the body of a query request.
+type Request struct {
+	Query string `json:"query"`
+	// Params is either an array of positional parameters
+	// or an object of named parameters.
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Handler is an http.Handler running the queries it receives on a database.
+type Handler struct {
+	db   *sql.DB
+	opts Options
+	mux  *http.ServeMux
+}
+
+// NewHandler returns a handler running queries on db, which must be a chai database.
+// Each request runs on its own connection. Queries are canceled when
+// the context of their request is done.
+func NewHandler(db *sql.DB, opts *Options) *Handler {
+	h := Handler{
+		db:  db,
+		mux: http.NewServeMux(),
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+
+	h.mux.HandleFunc("POST /query", h.handleQuery)
+	h.mux.HandleFunc("GET /changes", h.handleChanges)
+	h.mux.HandleFunc("DELETE /changes", h.handlePurgeChanges)
+	return &h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.Wrap(err, "invalid request"))
+		return
+	}
+
+	params, err := decodeParams(req.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	stmts, err := parser.ParseQuery(req.Query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if len(stmts) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("empty query"))
+		return
+	}
+
+	if leavesTransactionOpen(stmts) {
+		writeError(w, http.StatusBadRequest, ErrOpenTransaction)
+		return
+	}
+
+	for _, stmt := range stmts {
+		if h.opts.ReadOnly && !isReadOnly(stmt) {
+			writeError(w, http.StatusForbidden, ErrReadOnly)
+			return
+		}
+		if _, ok := stmt.(*statement.CopyStmt); ok && !h.opts.AllowCopy {
+			writeError(w, http.StatusForbidden, ErrCopyNotAllowed)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	conn, err := h.db.Conn(ctx)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer conn.Close()
+
+	out := responseWriter{w: w}
+	err = withDriverConn(conn, func(c *driver.Conn) error {
+		return h.run(ctx, &out, c, stmts, params)
+	})
+	if err != nil {
+		out.error(err)
+		return
+	}
+
+	// statements that don't return rows have an empty response
+	out.start()
+}
+
+func (h *Handler) handleChanges(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	tableName := q.Get("table")
+	cursor, err := parseCursor(q.Get("cursor"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var follow bool
+	if s := q.Get("follow"); s != "" {
+		follow, err = strconv.ParseBool(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.Errorf("invalid follow parameter %q", s))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	conn, err := h.db.Conn(ctx)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer conn.Close()
+
+	out := responseWriter{w: w}
+	err = withDriverConn(conn, func(c *driver.Conn) error {
+		db := c.DB()
+		if tableName != "" {
+			if _, err := db.Catalog().GetTableInfo(tableName); err != nil {
+				return err
+			}
+		}
+
+		writeChange := func(e *database.ChangeEvent) error {
+			data, err := e.MarshalJSON()
+			if err != nil {
+				return err
+			}
+
+			return out.writeLine(data)
+		}
+
+		if !follow {
+			return db.ReadChanges(tableName, cursor, writeChange)
+		}
+
+		// let the client know the request succeeded
+		// before waiting for the first change
+		out.start()
+		if err := out.flush(); err != nil {
+			return err
+		}
+
+		feed := db.Subscribe(tableName, cursor)
+		for {
+			e, err := feed.Next(ctx)
+			if err != nil {
+				return err
+			}
+
+			err = writeChange(e)
+			if err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		// the client is gone
+		if ctx.Err() != nil {
+			return
+		}
+
+		out.error(err)
+		return
+	}
+
+	out.start()
+}
+
+func (h *Handler) handlePurgeChanges(w http.ResponseWriter, r *http.Request) {
+	if h.opts.ReadOnly {
+		writeError(w, http.StatusForbidden, ErrReadOnly)
+		return
+	}
+
+	s := r.URL.Query().Get("cursor")
+	if s == "" {
+		writeError(w, http.StatusBadRequest, errors.New("missing cursor parameter"))
+		return
+	}
+	cursor, err := parseCursor(s)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	conn, err := h.db.Conn(r.Context())
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	defer conn.Close()
+
+	out := responseWriter{w: w}
+	err = withDriverConn(conn, func(c *driver.Conn) error {
+		return c.DB().PurgeChanges(cursor)
+	})
+	if err != nil {
+		out.error(err)
+		return
+	}
+
+	out.start()
+}
+
+// withDriverConn calls fn with the chai connection underlying conn.
+func withDriverConn(conn *sql.Conn, fn func(c *driver.Conn) error) error {
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*driver.Conn)
+		if !ok {
+			return errors.New("not a chai database")
+		}
+
+		return fn(c)
+	})
+}
+
+func parseCursor(s string) (uint64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	cursor, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("invalid cursor %q", s)
+	}
+
+	return cursor, nil
+}
+
+// run runs the statements and writes the rows returned by the last one.
+func (h *Handler) run(ctx context.Context, w *responseWriter, c *driver.Conn, stmts []statement.Statement, params []environment.Param) (err error) {
+	if h.opts.ReadOnly {
+		tx, err := c.Conn().BeginTx(&database.TxOptions{ReadOnly: true})
+		if err != nil {
+			return err
+		}
+		defer func() { _ = tx.Rollback() }()
+	}
+
+	res, err := query.New(stmts...).Run(&query.Context{
+		Ctx:    ctx,
+		DB:     c.DB(),
+		Conn:   c.Conn(),
+		Params: params,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if er := res.Close(); err == nil {
+			err = er
+		}
+	}()
+
+	return res.Iterate(func(r database.Row) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		data, err := row.MarshalJSON(r)
+		if err != nil {
+			return err
+		}
+
+		return w.writeLine(data)
+	})
+}
+
+// isReadOnly returns whether stmt can be run by a read-only handler.
+func isReadOnly(stmt statement.Statement) bool {
+	// committing would end the read-only transaction of the request
+	if _, ok := stmt.(query.CommitStmt); ok {
+		return false
+	}
+
+	ro, ok := stmt.(statement.ReadOnly)
+	return ok && ro.IsReadOnly()
+}
+
+// leavesTransactionOpen returns whether the last transaction
+// started by the statements is not committed or rolled back.
+// The transaction would be rolled back when the connection is closed,
+// silently discarding its changes.
+func leavesTransactionOpen(stmts []statement.Statement) bool {
+	var open bool
+	for _, stmt := range stmts {
+		switch stmt.(type) {
+		case query.BeginStmt:
+			open = true
+		case query.CommitStmt, query.RollbackStmt:
+			open = false
+		}
+	}
+
+	return open
+}
+
+// decodeParams decodes the parameters of a request.
+// Numbers without a fractional part or an exponent are passed as BIGINT,
+// other numbers as DOUBLE PRECISION.
+func decodeParams(data json.RawMessage) ([]environment.Param, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var params []environment.Param
+	if data[0] == '{' {
+		var named map[string]any
+		if err := dec.Decode(&named); err != nil {
+			return nil, errors.Wrap(err, "invalid params")
+		}
+		for name, v := range named {
+			params = append(params, environment.Param{Name: name, Value: v})
+		}
+	} else {
+		var list []any
+		if err := dec.Decode(&list); err != nil {
+			return nil, errors.Wrap(err, "invalid params")
+		}
+		for _, v := range list {
+			params = append(params, environment.Param{Value: v})
+		}
+	}
+
+	for i := range params {
+		v, err := paramValue(params[i].Value)
+		if err != nil {
+			return nil, err
+		}
+		params[i].Value = v
+	}
+
+	return params, nil
+}
+
+func paramValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil, string, bool:
+		return v, nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i, nil
+		}
+		return v.Float64()
+	}
+
+	return nil, errors.Errorf("invalid param %v: only strings, numbers, booleans and null are supported", v)
+}
+
+// responseWriter delays writing the status of the response until the first row,
+// so that errors occurring before can be reported with an error status.
+type responseWriter struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+func (w *responseWriter) start() {
+	if !w.started {
+		w.started = true
+		w.w.Header().Set("Content-Type", "application/x-ndjson")
+		w.w.WriteHeader(http.StatusOK)
+	}
+}
+
+// writeLine writes a line and flushes it, so that clients
+// receive the rows as they are read.
+func (w *responseWriter) writeLine(data []byte) error {
+	w.start()
+	_, err := w.w.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return w.flush()
+}
+
+// flush sends the buffered data to the client,
+// if the underlying writer supports it.
+func (w *responseWriter) flush() error {
+	err := http.NewResponseController(w.w).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+	return err
+}
+
+// error reports err as the last line of the response
+// if rows were already sent.
+func (w *responseWriter) error(err error) {
+	if !w.started {
+		writeError(w.w, http.StatusBadRequest, err)
+		return
+	}
+
+	data, _ := json.Marshal(errorResponse{Error: err.Error()})
+	_ = w.writeLine(data)
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(errorResponse{Error: err.Error()})
+}
@@ -0,0 +1,264 @@
+package httpapi_test
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/chaisql/chai"
+	"github.com/chaisql/chai/httpapi"
+	"github.com/stretchr/testify/require"
+)
+
+func post(t *testing.T, h http.Handler, body string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func TestHandler(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE test(a INT PRIMARY KEY, b TEXT, c DOUBLE PRECISION)")
+	require.NoError(t, err)
+
+	h := httpapi.NewHandler(db, nil)
+
+	t.Run("write", func(t *testing.T) {
+		code, body := post(t, h, `{"query": "INSERT INTO test (a, b, c) VALUES ($1, $2, $3), (2, 'bar', 2.5)", "params": [1, "foo", 1.5]}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Empty(t, body)
+	})
+
+	t.Run("rows", func(t *testing.T) {
+		code, body := post(t, h, `{"query": "SELECT * FROM test ORDER BY a"}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "{\"a\": 1, \"b\": \"foo\", \"c\": 1.5}\n{\"a\": 2, \"b\": \"bar\", \"c\": 2.5}\n", body)
+
+		code, body = post(t, h, `{"query": "SELECT b FROM test WHERE a = $1", "params": [2]}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "{\"b\": \"bar\"}\n", body)
+
+		code, body = post(t, h, `{"query": "SELECT b FROM test WHERE a = :a", "params": {"a": 1}}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "{\"b\": \"foo\"}\n", body)
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		code, body := post(t, h, `{"query": "BEGIN; INSERT INTO test (a) VALUES (10)"}`)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, body, httpapi.ErrOpenTransaction.Error())
+
+		code, body = post(t, h, `{"query": "BEGIN; INSERT INTO test (a) VALUES (10); ROLLBACK; BEGIN; SELECT 1"}`)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.Contains(t, body, httpapi.ErrOpenTransaction.Error())
+
+		code, body = post(t, h, `{"query": "BEGIN; INSERT INTO test (a) VALUES (10); ROLLBACK"}`)
+		require.Equal(t, http.StatusOK, code, body)
+
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM test WHERE a = 10").Scan(&n))
+		require.Equal(t, 0, n)
+	})
+
+	t.Run("flush", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(`{"query": "SELECT a FROM test"}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.True(t, w.Flushed)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			body string
+			code int
+		}{
+			{`{"query": `, http.StatusBadRequest},
+			{`{"query": ""}`, http.StatusBadRequest},
+			{`{"query": "SELEC 1"}`, http.StatusBadRequest},
+			{`{"query": "SELECT $1", "params": [[1]]}`, http.StatusBadRequest},
+			{`{"query": "SELECT * FROM unknown"}`, http.StatusBadRequest},
+			{`{"query": "INSERT INTO test (a) VALUES (1)"}`, http.StatusBadRequest},
+		}
+
+		for _, test := range tests {
+			code, body := post(t, h, test.body)
+			require.Equal(t, test.code, code, test.body)
+
+			var res struct{ Error string }
+			require.NoError(t, json.Unmarshal([]byte(body), &res))
+			require.NotEmpty(t, res.Error)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/query", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		ro := httpapi.NewHandler(db, &httpapi.Options{ReadOnly: true})
+
+		code, body := post(t, ro, `{"query": "SELECT a FROM test WHERE a = 1"}`)
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "{\"a\": 1}\n", body)
+
+		for _, q := range []string{
+			"INSERT INTO test (a) VALUES (3)",
+			"SELECT 1; DELETE FROM test",
+			"COMMIT; DELETE FROM test",
+			"DROP TABLE test",
+			"COPY test TO '/tmp/test.csv'",
+		} {
+			code, body = post(t, ro, `{"query": "`+q+`"}`)
+			require.Equal(t, http.StatusForbidden, code, q)
+			require.Contains(t, body, httpapi.ErrReadOnly.Error())
+		}
+
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM test").Scan(&n))
+		require.Equal(t, 2, n)
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.csv")
+		q := `{"query": "COPY test TO '` + path + `'"}`
+
+		code, body := post(t, h, q)
+		require.Equal(t, http.StatusForbidden, code)
+		require.Contains(t, body, httpapi.ErrCopyNotAllowed.Error())
+		_, err := os.Stat(path)
+		require.ErrorIs(t, err, os.ErrNotExist)
+
+		code, body = post(t, h, `{"query": "COPY test FROM '`+path+`'"}`)
+		require.Equal(t, http.StatusForbidden, code)
+		require.Contains(t, body, httpapi.ErrCopyNotAllowed.Error())
+
+		allowed := httpapi.NewHandler(db, &httpapi.Options{AllowCopy: true})
+		code, body = post(t, allowed, q)
+		require.Equal(t, http.StatusOK, code, body)
+		_, err = os.Stat(path)
+		require.NoError(t, err)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/query", strings.NewReader(`{"query": "SELECT * FROM test"}`))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.NotEqual(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("server", func(t *testing.T) {
+		srv := httptest.NewServer(http.StripPrefix("/db", h))
+		defer srv.Close()
+
+		resp, err := http.Post(srv.URL+"/db/query", "application/json", strings.NewReader(`{"query": "SELECT a FROM test ORDER BY a"}`))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "{\"a\": 1}\n{\"a\": 2}\n", string(data))
+	})
+}
+
+func TestChanges(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:?changelog=on")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE test(a INT PRIMARY KEY, b TEXT);
+		CREATE TABLE other(a INT PRIMARY KEY);
+		INSERT INTO test (a, b) VALUES (1, 'foo');
+		INSERT INTO other (a) VALUES (1);
+		INSERT INTO test (a, b) VALUES (2, 'bar');
+	`)
+	require.NoError(t, err)
+
+	h := httpapi.NewHandler(db, nil)
+	get := func(url string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	t.Run("read", func(t *testing.T) {
+		code, body := get("/changes?table=test&cursor=1")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, `{"cursor": 3, "table": "test", "op": "insert", "key": [2], "before": null, "after": {"a": 2, "b": "bar"}}`+"\n", body)
+
+		code, body = get("/changes")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, 3, strings.Count(body, "\n"))
+
+		for _, url := range []string{"/changes?table=unknown", "/changes?cursor=-1", "/changes?follow=maybe"} {
+			code, _ = get(url)
+			require.Equal(t, http.StatusBadRequest, code, url)
+		}
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		srv := httptest.NewServer(h)
+		defer srv.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/changes?table=test&cursor=3&follow=true", nil)
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, err = db.Exec("INSERT INTO test (a, b) VALUES (3, 'baz')")
+		require.NoError(t, err)
+
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, `{"cursor": 4, "table": "test", "op": "insert", "key": [3], "before": null, "after": {"a": 3, "b": "baz"}}`+"\n", line)
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		ro := httpapi.NewHandler(db, &httpapi.Options{ReadOnly: true})
+		req := httptest.NewRequest(http.MethodDelete, "/changes?cursor=2", nil)
+		w := httptest.NewRecorder()
+		ro.ServeHTTP(w, req)
+		require.Equal(t, http.StatusForbidden, w.Code)
+
+		req = httptest.NewRequest(http.MethodDelete, "/changes", nil)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+
+		req = httptest.NewRequest(http.MethodDelete, "/changes?cursor=2", nil)
+		w = httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		code, body := get("/changes")
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, strings.HasPrefix(body, `{"cursor": 3,`), body)
+	})
+}
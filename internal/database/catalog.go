@@ -38,6 +38,8 @@ const (
 	CatalogTableNamespace    tree.Namespace = 1
 	SequenceTableNamespace   tree.Namespace = 2
 	RollbackSegmentNamespace tree.Namespace = 3
+	ChangelogNamespace       tree.Namespace = 4
+	LargeValueNamespace      tree.Namespace = 5
 	MinTransientNamespace    tree.Namespace = math.MaxInt64 - 1<<24
 	MaxTransientNamespace    tree.Namespace = math.MaxInt64
 )
@@ -112,6 +114,11 @@ func (c *Catalog) GetIndexInfo(indexName string) (*IndexInfo, error) {
 	return r.(*IndexInfoRelation).Info, nil
 }
 
+// ListTables returns all table names sorted lexicographically.
+func (c *Catalog) ListTables() []string {
+	return c.Cache.ListObjects(RelationTableType)
+}
+
 // ListIndexes returns all indexes for a given table name. If tableName is empty
 // if returns a list of all indexes.
 // The returned list of indexes is sorted lexicographically.
@@ -298,7 +305,12 @@ func (c *CatalogWriter) DropTable(tx *Transaction, tableName string) error {
 		return err
 	}
 
-	return tree.New(tx.Session, ti.StoreNamespace, ti.PrimaryKeySortOrder()).Truncate()
+	t := Table{
+		Tx:   tx,
+		Tree: tree.New(tx.Session, ti.StoreNamespace, ti.PrimaryKeySortOrder()),
+		Info: ti,
+	}
+	return t.Truncate()
 }
 
 // CreateIndex creates an index with the given name.
@@ -399,6 +411,54 @@ func (c *CatalogWriter) AddColumnConstraint(tx *Transaction, tableName string, c
 	return c.CatalogTable.Replace(tx, tableName, cloneRel)
 }
 
+// SetTTLColumn sets the column used to expire the rows of a table.
+// An empty column disables expiration.
+func (c *CatalogWriter) SetTTLColumn(tx *Transaction, tableName string, column string) error {
+	r, err := c.Cache.Get(RelationTableType, tableName)
+	if err != nil {
+		return err
+	}
+	ti := r.(*TableInfoRelation).Info
+
+	clone := ti.Clone()
+	err = clone.SetTTLColumn(column)
+	if err != nil {
+		return err
+	}
+
+	cloneRel := &TableInfoRelation{Info: clone}
+	err = c.Cache.Replace(tx, cloneRel)
+	if err != nil {
+		return err
+	}
+
+	return c.CatalogTable.Replace(tx, tableName, cloneRel)
+}
+
+// SetCompressionDictionary sets the zstd dictionary used to compress the values of a table.
+// A nil dictionary removes it.
+func (c *CatalogWriter) SetCompressionDictionary(tx *Transaction, tableName string, dict []byte) error {
+	r, err := c.Cache.Get(RelationTableType, tableName)
+	if err != nil {
+		return err
+	}
+	ti := r.(*TableInfoRelation).Info
+
+	clone := ti.Clone()
+	err = clone.SetCompressionDictionary(dict)
+	if err != nil {
+		return err
+	}
+
+	cloneRel := &TableInfoRelation{Info: clone}
+	err = c.Cache.Replace(tx, cloneRel)
+	if err != nil {
+		return err
+	}
+
+	return c.CatalogTable.Replace(tx, tableName, cloneRel)
+}
+
 // RenameTable renames a table.
 // If it doesn't exist, it returns errs.ErrTableNotFound.
 func (c *CatalogWriter) RenameTable(tx *Transaction, oldName, newName string) error {
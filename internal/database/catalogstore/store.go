@@ -12,11 +12,15 @@ import (
 )
 
 func LoadCatalog(tx *database.Transaction) error {
-	cw := tx.CatalogWriter()
+	// read-only transactions are used to load the catalog of databases
+	// opened in read-only mode, which must not be modified.
+	if tx.Writable {
+		cw := tx.CatalogWriter()
 
-	err := cw.Init(tx)
-	if err != nil {
-		return err
+		err := cw.Init(tx)
+		if err != nil {
+			return err
+		}
 	}
 
 	tables, indexes, sequences, err := loadCatalogStore(tx, tx.Catalog.CatalogTable)
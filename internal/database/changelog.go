@@ -0,0 +1,444 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/chaisql/chai/internal/encoding"
+	"github.com/chaisql/chai/internal/row"
+	"github.com/chaisql/chai/internal/tree"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+// ChangeOp is the kind of change made to a row.
+type ChangeOp uint8
+
+// List of changes recorded in the changelog.
+const (
+	ChangeInsert ChangeOp = iota + 1
+	ChangeUpdate
+	ChangeDelete
+)
+
+func (o ChangeOp) String() string {
+	switch o {
+	case ChangeInsert:
+		return "insert"
+	case ChangeUpdate:
+		return "update"
+	case ChangeDelete:
+		return "delete"
+	}
+
+	return "unknown"
+}
+
+// A ChangeEvent describes a change made to a row by a committed transaction.
+type ChangeEvent struct {
+	// Cursor identifies the event in the changelog.
+	// Cursors increase in commit order and can be used
+	// to resume reading the changelog after this event.
+	Cursor    uint64
+	TableName string
+	Op        ChangeOp
+	Key       *tree.Key
+	// Before is the row prior to the change. It is nil for inserts.
+	Before row.Row
+	// After is the row after the change. It is nil for deletes.
+	After row.Row
+}
+
+// MarshalJSON encodes the event to json.
+func (e *ChangeEvent) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteString(`{"cursor": `)
+	buf.WriteString(strconv.FormatUint(e.Cursor, 10))
+	buf.WriteString(`, "table": `)
+	buf.WriteString(strconv.Quote(e.TableName))
+	buf.WriteString(`, "op": `)
+	buf.WriteString(strconv.Quote(e.Op.String()))
+
+	buf.WriteString(`, "key": [`)
+	values, err := e.Key.Decode()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte(']')
+
+	for _, r := range []struct {
+		name string
+		row  row.Row
+	}{{"before", e.Before}, {"after", e.After}} {
+		buf.WriteString(`, "` + r.name + `": `)
+		if r.row == nil {
+			buf.WriteString("null")
+			continue
+		}
+
+		data, err := row.MarshalJSON(r.row)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// capturesChanges returns whether changes made to the given table
+// must be recorded in the changelog.
+func (tx *Transaction) capturesChanges(tableName string) bool {
+	return tx.db != nil && tx.db.changelog && !strings.HasPrefix(tableName, InternalPrefix)
+}
+
+// logChange writes the change to the changelog, as part of the transaction.
+// The changes become visible to subscribers once the transaction is committed.
+func (tx *Transaction) logChange(tableName string, op ChangeOp, key *tree.Key, before, after row.Row) error {
+	if !tx.capturesChanges(tableName) {
+		return nil
+	}
+
+	if tx.changelogCursor == 0 {
+		tx.changelogCursor = tx.db.changelogCursor.Load()
+
+		tx.OnCommitHooks = append(tx.OnCommitHooks, func() {
+			tx.db.changelogCursor.Store(tx.changelogCursor)
+			tx.db.notifyChanges()
+		})
+		// if the changes are rolled back, the cursors can be reused
+		tx.OnRollbackHooks = append(tx.OnRollbackHooks, func() {
+			tx.changelogCursor = 0
+		})
+	}
+
+	values, err := key.Decode()
+	if err != nil {
+		return err
+	}
+
+	enc := encoding.EncodeText(nil, tableName)
+	enc = encoding.EncodeInt(enc, int64(op))
+	enc, err = encodeChangeValues(enc, values)
+	if err != nil {
+		return err
+	}
+	enc, err = encodeChangeRow(enc, before)
+	if err != nil {
+		return err
+	}
+	enc, err = encodeChangeRow(enc, after)
+	if err != nil {
+		return err
+	}
+
+	tx.changelogCursor++
+	tr := tree.New(tx.Session, ChangelogNamespace, 0)
+	return tr.Put(changelogKey(tx.changelogCursor), enc)
+}
+
+func changelogKey(cursor uint64) *tree.Key {
+	return tree.NewKey(types.NewBigintValue(int64(cursor)))
+}
+
+// encodeChangeValues encodes the number of values, followed by each value
+// prefixed by its type, so that it can be decoded regardless of the table schema.
+func encodeChangeValues(dst []byte, values []types.Value) ([]byte, error) {
+	var err error
+
+	dst = encoding.EncodeInt(dst, int64(len(values)))
+	for _, v := range values {
+		dst = append(dst, byte(v.Type()))
+		dst, err = v.EncodeAsKey(dst)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dst, nil
+}
+
+// encodeChangeRow encodes a row as a list of column names and values.
+// A nil row is encoded as -1.
+func encodeChangeRow(dst []byte, r row.Row) ([]byte, error) {
+	if r == nil {
+		return encoding.EncodeInt(dst, -1), nil
+	}
+
+	return encodeChangeValues(dst, row.Flatten(r))
+}
+
+func decodeChangeValues(b []byte) ([]types.Value, int, error) {
+	l, n := encoding.DecodeInt(b)
+	if l < 0 {
+		return nil, n, nil
+	}
+
+	values := make([]types.Value, 0, l)
+	for i := int64(0); i < l; i++ {
+		if n >= len(b) {
+			return nil, 0, errors.New("invalid changelog entry")
+		}
+
+		def := types.Type(b[n]).Def()
+		if def == nil {
+			return nil, 0, errors.Errorf("invalid type %d in changelog entry", b[n])
+		}
+		n++
+
+		v, m := def.Decode(b[n:])
+		values = append(values, v)
+		n += m
+	}
+
+	return values, n, nil
+}
+
+func decodeChangeEvent(cursor uint64, b []byte) (*ChangeEvent, error) {
+	e := ChangeEvent{
+		Cursor: cursor,
+	}
+
+	var n int
+	e.TableName, n = encoding.DecodeText(b)
+	b = b[n:]
+
+	op, n := encoding.DecodeInt(b)
+	e.Op = ChangeOp(op)
+	b = b[n:]
+
+	key, n, err := decodeChangeValues(b)
+	if err != nil {
+		return nil, err
+	}
+	e.Key = tree.NewKey(key...)
+	b = b[n:]
+
+	before, n, err := decodeChangeValues(b)
+	if err != nil {
+		return nil, err
+	}
+	if before != nil {
+		e.Before = row.Unflatten(before)
+	}
+	b = b[n:]
+
+	after, _, err := decodeChangeValues(b)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil {
+		e.After = row.Unflatten(after)
+	}
+
+	return &e, nil
+}
+
+// loadChangelogCursor loads the cursor of the last event of the changelog.
+func (db *Database) loadChangelogCursor() error {
+	tx, err := db.begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	it, err := tree.New(tx.Session, ChangelogNamespace, 0).Iterator(nil)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	if !it.Last() {
+		return it.Error()
+	}
+
+	values, err := it.Key().Decode()
+	if err != nil {
+		return err
+	}
+
+	db.changelogCursor.Store(uint64(types.AsInt64(values[0])))
+	return nil
+}
+
+// changes returns a channel that is closed the next time
+// a transaction writing to the changelog is committed.
+func (db *Database) changes() <-chan struct{} {
+	db.changesMu.Lock()
+	defer db.changesMu.Unlock()
+
+	if db.changesCh == nil {
+		db.changesCh = make(chan struct{})
+	}
+
+	return db.changesCh
+}
+
+func (db *Database) notifyChanges() {
+	db.changesMu.Lock()
+	defer db.changesMu.Unlock()
+
+	if db.changesCh != nil {
+		close(db.changesCh)
+		db.changesCh = nil
+	}
+}
+
+// ReadChanges calls fn for every change committed to the given table after
+// the given cursor, in commit order. If tableName is empty, the changes of
+// all the tables are returned.
+// Changes are only recorded if the database was opened with Options.Changelog.
+func (db *Database) ReadChanges(tableName string, cursor uint64, fn func(e *ChangeEvent) error) error {
+	_, err := db.readChanges(tableName, cursor, 0, fn)
+	return err
+}
+
+// readChanges reads at most limit changes, or all of them if limit is 0.
+// It returns the cursor of the last event read, which can be greater
+// than the cursor of the last event returned if the following events
+// belong to other tables.
+func (db *Database) readChanges(tableName string, cursor uint64, limit int, fn func(e *ChangeEvent) error) (uint64, error) {
+	tx, err := db.begin(false)
+	if err != nil {
+		return cursor, err
+	}
+	defer tx.Rollback()
+
+	it, err := tree.New(tx.Session, ChangelogNamespace, 0).Iterator(&tree.Range{
+		Min:       changelogKey(cursor),
+		Exclusive: true,
+	})
+	if err != nil {
+		return cursor, err
+	}
+	defer it.Close()
+
+	var count int
+	for it.First(); it.Valid() && (limit == 0 || count < limit); it.Next() {
+		values, err := it.Key().Decode()
+		if err != nil {
+			return cursor, err
+		}
+		cursor = uint64(types.AsInt64(values[0]))
+
+		v, err := it.Value()
+		if err != nil {
+			return cursor, err
+		}
+
+		// the value is only valid until the iterator moves
+		e, err := decodeChangeEvent(cursor, bytes.Clone(v))
+		if err != nil {
+			return cursor, err
+		}
+
+		if tableName != "" && e.TableName != tableName {
+			continue
+		}
+
+		err = fn(e)
+		if err != nil {
+			return cursor, err
+		}
+		count++
+	}
+
+	return cursor, it.Error()
+}
+
+// PurgeChanges removes the events of the changelog up to the given cursor, included.
+func (db *Database) PurgeChanges(cursor uint64) error {
+	tx, err := db.begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	start, err := changelogKey(0).Encode(ChangelogNamespace, 0)
+	if err != nil {
+		return err
+	}
+	end, err := changelogKey(cursor+1).Encode(ChangelogNamespace, 0)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Session.DeleteRange(start, end)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// A ChangeFeed delivers the changes committed to a table, in commit order.
+type ChangeFeed struct {
+	db        *Database
+	tableName string
+	cursor    uint64
+	events    []*ChangeEvent
+}
+
+// changeFeedBatchSize is the number of events read at once by a change feed.
+const changeFeedBatchSize = 128
+
+// Subscribe returns a feed of the changes committed to the given table
+// after the given cursor. A cursor of 0 starts from the beginning of the changelog.
+// If tableName is empty, the changes of all the tables are delivered.
+// Changes are only recorded if the database was opened with Options.Changelog.
+func (db *Database) Subscribe(tableName string, cursor uint64) *ChangeFeed {
+	return &ChangeFeed{
+		db:        db,
+		tableName: tableName,
+		cursor:    cursor,
+	}
+}
+
+// Next returns the next change, waiting for a transaction to commit one if needed.
+// It returns an error if the context is canceled or the database is closed.
+func (f *ChangeFeed) Next(ctx context.Context) (*ChangeEvent, error) {
+	for len(f.events) == 0 {
+		// get the channel before reading, to avoid missing a commit
+		// happening in between.
+		ch := f.db.changes()
+
+		var err error
+		f.cursor, err = f.db.readChanges(f.tableName, f.cursor, changeFeedBatchSize, func(e *ChangeEvent) error {
+			f.events = append(f.events, e)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(f.events) > 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-f.db.closeContext.Done():
+			return nil, errors.New("database is closed")
+		case <-ch:
+		}
+	}
+
+	e := f.events[0]
+	f.events = f.events[1:]
+	return e, nil
+}
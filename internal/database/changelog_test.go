@@ -0,0 +1,172 @@
+package database_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/database/catalogstore"
+	"github.com/chaisql/chai/internal/query"
+	"github.com/chaisql/chai/internal/sql/parser"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangelog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db")
+
+	open := func(t *testing.T) *database.Database {
+		db, err := database.Open(path, &database.Options{
+			CatalogLoader: catalogstore.LoadCatalog,
+			Changelog:     true,
+		})
+		require.NoError(t, err)
+		return db
+	}
+
+	exec := func(t *testing.T, db *database.Database, q string) error {
+		conn, err := db.Connect()
+		require.NoError(t, err)
+		defer conn.Close()
+
+		pq, err := parser.ParseQuery(q)
+		require.NoError(t, err)
+
+		res, err := query.New(pq...).Run(&query.Context{Ctx: context.Background(), DB: db, Conn: conn})
+		if err != nil {
+			return err
+		}
+		defer res.Close()
+
+		return res.Skip(context.Background())
+	}
+
+	readAll := func(t *testing.T, db *database.Database, table string, cursor uint64) []*database.ChangeEvent {
+		var events []*database.ChangeEvent
+		err := db.ReadChanges(table, cursor, func(e *database.ChangeEvent) error {
+			events = append(events, e)
+			return nil
+		})
+		require.NoError(t, err)
+		return events
+	}
+
+	get := func(t *testing.T, e *database.ChangeEvent, before bool, column string) types.Value {
+		r := e.After
+		if before {
+			r = e.Before
+		}
+		v, err := r.Get(column)
+		require.NoError(t, err)
+		return v
+	}
+
+	db := open(t)
+
+	err := exec(t, db, `
+		CREATE SEQUENCE seq;
+		CREATE TABLE test(a INT PRIMARY KEY DEFAULT nextval('seq'), b TEXT, c TIMESTAMP);
+		CREATE TABLE other(a INT PRIMARY KEY);
+		INSERT INTO test (b, c) VALUES ('foo', '2024-01-01'), ('bar', NULL);
+		INSERT INTO other (a) VALUES (1);
+		UPDATE test SET b = 'baz' WHERE a = 1;
+		DELETE FROM test WHERE a = 2;
+	`)
+	require.NoError(t, err)
+
+	// rolled back changes are not recorded
+	err = exec(t, db, `
+		BEGIN;
+		INSERT INTO test (b) VALUES ('rollback');
+		ROLLBACK;
+		BEGIN;
+		SAVEPOINT sp;
+		INSERT INTO test (b) VALUES ('savepoint');
+		ROLLBACK TO SAVEPOINT sp;
+		INSERT INTO test (a, b) VALUES (10, 'committed');
+		COMMIT;
+	`)
+	require.NoError(t, err)
+
+	events := readAll(t, db, "test", 0)
+	require.Len(t, events, 5)
+
+	require.Equal(t, database.ChangeInsert, events[0].Op)
+	require.Nil(t, events[0].Before)
+	require.Equal(t, types.NewTextValue("foo"), get(t, events[0], false, "b"))
+	require.Equal(t, types.TypeTimestamp, get(t, events[0], false, "c").Type())
+	require.Equal(t, database.ChangeInsert, events[1].Op)
+
+	require.Equal(t, database.ChangeUpdate, events[2].Op)
+	require.Equal(t, types.NewTextValue("foo"), get(t, events[2], true, "b"))
+	require.Equal(t, types.NewTextValue("baz"), get(t, events[2], false, "b"))
+	key, err := events[2].Key.Decode()
+	require.NoError(t, err)
+	require.Equal(t, []types.Value{types.NewIntegerValue(1)}, key)
+
+	require.Equal(t, database.ChangeDelete, events[3].Op)
+	require.Equal(t, types.NewTextValue("bar"), get(t, events[3], true, "b"))
+	require.Nil(t, events[3].After)
+
+	require.Equal(t, types.NewTextValue("committed"), get(t, events[4], false, "b"))
+
+	for i := 1; i < len(events); i++ {
+		require.Greater(t, events[i].Cursor, events[i-1].Cursor)
+	}
+
+	// all the tables
+	require.Len(t, readAll(t, db, "", 0), 6)
+	require.Len(t, readAll(t, db, "other", 0), 1)
+
+	// resume after a cursor
+	require.Equal(t, events[3:], readAll(t, db, "test", events[2].Cursor))
+
+	data, err := events[2].MarshalJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"cursor": 4, "table": "test", "op": "update", "key": [1],
+		"before": {"a": 1, "b": "foo", "c": "2024-01-01T00:00:00Z"},
+		"after": {"a": 1, "b": "baz", "c": "2024-01-01T00:00:00Z"}}`, string(data))
+
+	t.Run("Subscribe", func(t *testing.T) {
+		feed := db.Subscribe("test", events[4].Cursor)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			_ = exec(t, db, "INSERT INTO other (a) VALUES (2); INSERT INTO test (a, b) VALUES (11, 'new')")
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		e, err := feed.Next(ctx)
+		require.NoError(t, err)
+		require.Equal(t, database.ChangeInsert, e.Op)
+		require.Equal(t, types.NewTextValue("new"), get(t, e, false, "b"))
+
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = feed.Next(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Reopen", func(t *testing.T) {
+		last := readAll(t, db, "", 0)
+		require.NoError(t, db.Close())
+
+		db = open(t)
+		defer db.Close()
+
+		err := exec(t, db, "INSERT INTO test (a, b) VALUES (12, 'reopened')")
+		require.NoError(t, err)
+
+		events := readAll(t, db, "test", last[len(last)-1].Cursor)
+		require.Len(t, events, 1)
+		require.Equal(t, types.NewTextValue("reopened"), get(t, events[0], false, "b"))
+
+		err = db.PurgeChanges(last[len(last)-1].Cursor)
+		require.NoError(t, err)
+		require.Equal(t, events, readAll(t, db, "", 0))
+	})
+}
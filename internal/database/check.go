@@ -0,0 +1,535 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/chaisql/chai/internal/encoding"
+	errs "github.com/chaisql/chai/internal/errors"
+	"github.com/chaisql/chai/internal/tree"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+// CheckOptions configures the verification of a database.
+type CheckOptions struct {
+	// Tables to check, with their indexes and sequences.
+	// If empty, the whole database is checked.
+	Tables []string
+	// Repair rebuilds the indexes with missing or invalid entries,
+	// the way REINDEX does.
+	Repair bool
+}
+
+// A CheckError describes an inconsistency found in the database.
+type CheckError struct {
+	// Object is the name of the inconsistent table, index or sequence.
+	Object string
+	// Key of the row the inconsistency was found in, if any.
+	Key     *tree.Key
+	Message string
+}
+
+func (e *CheckError) Error() string {
+	if e.Key == nil {
+		return fmt.Sprintf("%s: %s", e.Object, e.Message)
+	}
+
+	return fmt.Sprintf("%s: row %s: %s", e.Object, e.Key, e.Message)
+}
+
+// A CheckReport lists the inconsistencies found in the database.
+type CheckReport struct {
+	Errors []*CheckError
+	// Repaired lists the indexes that were rebuilt.
+	Repaired []string
+}
+
+// Check verifies that the database is internally consistent. It verifies that
+// every row decodes against the schema of its table and respects its NOT NULL
+// constraints, that the indexes contain exactly one valid entry per row and
+// respect their UNIQUE constraints, and that the leases of the sequences are
+// within their bounds.
+// With opts.Repair, the indexes with missing or invalid entries are rebuilt.
+func (db *Database) Check(ctx context.Context, opts *CheckOptions) (*CheckReport, error) {
+	if opts == nil {
+		opts = new(CheckOptions)
+	}
+
+	conn, err := db.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	tx, err := conn.BeginTx(&TxOptions{ReadOnly: !opts.Repair})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tables := opts.Tables
+	if len(tables) == 0 {
+		tables = tx.Catalog.ListTables()
+	}
+
+	c := checker{ctx: ctx, tx: tx, report: new(CheckReport)}
+
+	var broken []string
+	for _, name := range tables {
+		t, err := tx.Catalog.GetTable(tx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes, err := c.checkTable(t)
+		if err != nil {
+			return nil, err
+		}
+		broken = append(broken, indexes...)
+	}
+
+	err = c.checkSequences(opts.Tables)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.Repair || len(broken) == 0 {
+		return c.report, nil
+	}
+
+	for _, name := range broken {
+		if err := rebuildIndex(tx, name); err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	c.report.Repaired = broken
+	return c.report, nil
+}
+
+type checker struct {
+	ctx    context.Context
+	tx     *Transaction
+	report *CheckReport
+}
+
+func (c *checker) addError(object string, key *tree.Key, format string, args ...any) {
+	if key != nil {
+		// keys are only valid until their iterator moves
+		key = tree.NewEncodedKey(bytes.Clone(key.Encoded))
+	}
+
+	c.report.Errors = append(c.report.Errors, &CheckError{
+		Object:  object,
+		Key:     key,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// checkTable verifies the rows of a table and its indexes.
+// It returns the indexes with missing or invalid entries.
+func (c *checker) checkTable(t *Table) ([]string, error) {
+	var indexes []*IndexInfo
+	var handles []*Index
+	for _, name := range c.tx.Catalog.ListIndexes(t.Info.TableName) {
+		info, err := c.tx.Catalog.GetIndexInfo(name)
+		if err != nil {
+			return nil, err
+		}
+		idx, err := c.tx.Catalog.GetIndex(c.tx, name)
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, info)
+		handles = append(handles, idx)
+	}
+
+	// index entries of the rows that are missing from their index
+	missing := make(map[string]bool)
+
+	it, err := t.Iterator(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	for it.First(); it.Valid(); it.Next() {
+		if err := c.ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		r, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+
+		values, ok := c.checkRow(t, r)
+		if !ok {
+			continue
+		}
+
+		for i, info := range indexes {
+			entry := indexEntry(info, values, r.Key().Encoded)
+			ok, err := handles[i].Tree.Exists(tree.NewKey(entry...))
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				c.addError(info.IndexName, r.Key(), "missing index entry %s", formatValues(entry[:len(entry)-1]))
+				missing[info.IndexName] = true
+			}
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	var broken []string
+	for i, info := range indexes {
+		invalid, err := c.checkIndex(t, info, handles[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if invalid || missing[info.IndexName] {
+			broken = append(broken, info.IndexName)
+		}
+	}
+
+	return broken, nil
+}
+
+// checkRow decodes a row and verifies its primary key and NOT NULL constraints.
+// It returns the values of the row, or false if it can't be decoded.
+func (c *checker) checkRow(t *Table, r Row) (map[string]types.Value, bool) {
+	name := t.Info.TableName
+
+	values, err := decodeRow(r.(*BasicRow).Row.(*EncodedRow))
+	if err != nil {
+		c.addError(name, r.Key(), "cannot decode row: %v", err)
+		return nil, false
+	}
+
+	for _, cc := range t.Info.ColumnConstraints.Ordered {
+		if cc.IsNotNull && values[cc.Column].Type() == types.TypeNull {
+			c.addError(name, r.Key(), "NULL value in NOT NULL column %s", cc.Column)
+		}
+	}
+
+	pk, err := decodeKey(r.Key())
+	if err != nil {
+		c.addError(name, r.Key(), "cannot decode primary key: %v", err)
+		return values, true
+	}
+	if len(pk) != len(t.Info.PrimaryKey.Columns) {
+		c.addError(name, r.Key(), "primary key has %d values, expected %d", len(pk), len(t.Info.PrimaryKey.Columns))
+		return values, true
+	}
+
+	for i, column := range t.Info.PrimaryKey.Columns {
+		if !valuesEqual(pk[i], values[column]) {
+			c.addError(name, r.Key(), "primary key doesn't match column %s: %s", column, values[column])
+		}
+	}
+
+	return values, true
+}
+
+// checkIndex verifies that every entry of an index points at an existing row
+// with matching values, and that unique indexes don't contain duplicates.
+// It returns true if the index has invalid entries.
+func (c *checker) checkIndex(t *Table, info *IndexInfo, idx *Index) (bool, error) {
+	it, err := idx.Tree.Iterator(nil)
+	if err != nil {
+		return false, err
+	}
+	defer it.Close()
+
+	var invalid bool
+	var prev []types.Value
+	for it.First(); it.Valid(); it.Next() {
+		if err := c.ctx.Err(); err != nil {
+			return false, err
+		}
+
+		entry, err := decodeKey(it.Key())
+		if err != nil || len(entry) != len(info.Columns)+1 || entry[len(entry)-1].Type() != types.TypeBytea {
+			c.addError(info.IndexName, nil, "invalid index entry %x", it.Key().Encoded)
+			invalid = true
+			continue
+		}
+
+		values := entry[:len(entry)-1]
+		key := tree.NewEncodedKey(bytes.Clone(types.AsByteSlice(entry[len(entry)-1])))
+
+		r, err := t.GetRow(key)
+		if errs.IsNotFoundError(err) {
+			c.addError(info.IndexName, key, "index entry %s points at a missing row", formatValues(values))
+			invalid = true
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+
+		rowValues, err := decodeRow(r.(*BasicRow).Row.(*EncodedRow))
+		if err != nil {
+			// already reported when checking the table
+			continue
+		}
+
+		// entries are compared encoded, as some types, like timestamps,
+		// are decoded from the index with another type
+		expected := indexEntry(info, rowValues, key.Encoded)
+		enc, err := tree.NewKey(expected...).Encode(idx.Tree.Namespace, idx.Tree.Order)
+		if err != nil {
+			return false, err
+		}
+		if !bytes.Equal(enc, it.Key().Encoded) {
+			c.addError(info.IndexName, key, "index entry %s doesn't match the row values %s", formatValues(values), formatValues(expected[:len(expected)-1]))
+			invalid = true
+			continue
+		}
+
+		// rows with NULL values are not subject to the UNIQUE constraint
+		values = expected[:len(expected)-1]
+		if info.Unique && !slices.ContainsFunc(values, isNull) {
+			if prev != nil && slices.EqualFunc(values, prev, valuesEqual) {
+				c.addError(info.IndexName, key, "duplicate value %s violates UNIQUE constraint", formatValues(values))
+			}
+			prev = values
+		}
+	}
+
+	return invalid, it.Error()
+}
+
+// checkSequences verifies that every sequence has a lease within its bounds
+// in the sequence table. If tables is not empty, only the sequences owned
+// by these tables are checked.
+func (c *checker) checkSequences(tables []string) error {
+	leases := make(map[string]types.Value)
+
+	t, err := c.tx.Catalog.GetTable(c.tx, SequenceTableName)
+	if err != nil && !errs.IsNotFoundError(err) {
+		return err
+	}
+	if err == nil {
+		err = iterateRows(t, func(r Row) error {
+			name, err := r.Get("name")
+			if err != nil {
+				return err
+			}
+			seq, err := r.Get("seq")
+			if err != nil {
+				return err
+			}
+
+			leases[types.AsString(name)] = seq
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range c.tx.Catalog.ListSequences() {
+		seq, err := c.tx.Catalog.GetSequence(name)
+		if err != nil {
+			return err
+		}
+
+		lease, ok := leases[name]
+		delete(leases, name)
+		if len(tables) > 0 && !slices.Contains(tables, seq.Info.Owner.TableName) {
+			continue
+		}
+
+		// leases of sequences that were created but never used are NULL
+		if !ok {
+			c.addError(name, nil, "missing sequence lease")
+			continue
+		}
+		if lease.Type() == types.TypeNull {
+			continue
+		}
+
+		v := types.AsInt64(lease)
+		if v < seq.Info.Min || v > seq.Info.Max {
+			c.addError(name, nil, "lease %d is out of range [%d, %d]", v, seq.Info.Min, seq.Info.Max)
+		}
+	}
+
+	if len(tables) > 0 {
+		return nil
+	}
+
+	for name := range leases {
+		c.addError(SequenceTableName, nil, "lease of unknown sequence %s", name)
+	}
+
+	return nil
+}
+
+// rebuildIndex deletes the entries of an index and indexes every row of its table.
+func rebuildIndex(tx *Transaction, indexName string) error {
+	info, err := tx.Catalog.GetIndexInfo(indexName)
+	if err != nil {
+		return err
+	}
+
+	idx, err := tx.Catalog.GetIndex(tx, indexName)
+	if err != nil {
+		return err
+	}
+
+	t, err := tx.Catalog.GetTable(tx, info.Owner.TableName)
+	if err != nil {
+		return err
+	}
+
+	err = idx.Truncate()
+	if err != nil {
+		return err
+	}
+
+	// expired rows must be indexed until they are deleted,
+	// rows that can't be decoded are reported by the check
+	return iterateRows(t, func(r Row) error {
+		values, err := decodeRow(r.(*BasicRow).Row.(*EncodedRow))
+		if err != nil {
+			return nil
+		}
+
+		entry := indexEntry(info, values, r.Key().Encoded)
+		return idx.Set(entry[:len(entry)-1], bytes.Clone(r.Key().Encoded))
+	})
+}
+
+// iterateRows calls fn for every row of the table, including expired rows.
+func iterateRows(t *Table, fn func(r Row) error) error {
+	it, err := t.Iterator(nil)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.First(); it.Valid(); it.Next() {
+		r, err := it.Value()
+		if err != nil {
+			return err
+		}
+
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+
+	return it.Error()
+}
+
+// decodeRow decodes every column of an encoded row.
+// Rows written by a bug or a crash may cause the decoders to panic.
+func decodeRow(e *EncodedRow) (values map[string]types.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("%v", r)
+		}
+	}()
+
+	values = make(map[string]types.Value, len(e.columnConstraints.Ordered))
+	b := e.encoded
+	for _, cc := range e.columnConstraints.Ordered {
+		if len(b) == 0 {
+			return nil, errors.Errorf("missing column %s", cc.Column)
+		}
+
+		v, n, err := e.decodeValue(cc, b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "column %s", cc.Column)
+		}
+		if n <= 0 || n > len(b) {
+			return nil, errors.Errorf("invalid value of column %s", cc.Column)
+		}
+		b = b[n:]
+
+		if v.Type() != types.TypeNull && v.Type() != cc.Type {
+			return nil, errors.Errorf("column %s: value of type %s, expected %s", cc.Column, v.Type(), cc.Type)
+		}
+
+		values[cc.Column] = v
+	}
+
+	if len(b) > 0 {
+		return nil, errors.Errorf("%d unexpected bytes after the last column", len(b))
+	}
+
+	return values, nil
+}
+
+// decodeKey decodes the values of a key.
+func decodeKey(key *tree.Key) (values []types.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("%v", r)
+		}
+	}()
+
+	if n := encoding.Skip(key.Encoded); n == 0 || n == len(key.Encoded) {
+		return nil, errors.New("empty key")
+	}
+
+	// decoded values may point into the key, which is reused by iterators
+	return tree.NewEncodedKey(bytes.Clone(key.Encoded)).Decode()
+}
+
+// indexEntry returns the values indexed for a row, followed by its encoded key.
+func indexEntry(info *IndexInfo, values map[string]types.Value, key []byte) []types.Value {
+	entry := make([]types.Value, 0, len(info.Columns)+1)
+	for _, column := range info.Columns {
+		v, ok := values[column]
+		if !ok {
+			v = types.NewNullValue()
+		}
+		entry = append(entry, v)
+	}
+
+	return append(entry, types.NewByteaValue(key))
+}
+
+func valuesEqual(a, b types.Value) bool {
+	if isNull(a) || isNull(b) {
+		return isNull(a) && isNull(b)
+	}
+
+	eq, err := a.EQ(b)
+	return err == nil && eq
+}
+
+func isNull(v types.Value) bool {
+	return v == nil || v.Type() == types.TypeNull
+}
+
+func formatValues(values []types.Value) string {
+	var sb strings.Builder
+
+	sb.WriteByte('(')
+	for i, v := range values {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(v.String())
+	}
+	sb.WriteByte(')')
+
+	return sb.String()
+}
@@ -0,0 +1,114 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/testutil"
+	"github.com/chaisql/chai/internal/tree"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	db := testutil.NewTestDB(t)
+
+	conn := testutil.NewTestConn(t, db)
+	tx, err := conn.BeginTx(&database.TxOptions{})
+	require.NoError(t, err)
+	testutil.MustExec(t, db, tx, `
+		CREATE TABLE test(a INT PRIMARY KEY, b TEXT NOT NULL, c INT UNIQUE);
+		CREATE INDEX test_b_idx ON test(b DESC);
+		CREATE TABLE other(a INT PRIMARY KEY);
+		CREATE TABLE nullable(a INT PRIMARY KEY, b TEXT, c INT);
+		CREATE SEQUENCE seq MAXVALUE 10;
+		INSERT INTO test (a, b, c) VALUES (1, 'a', 10), (2, 'b', 20), (3, 'c', NULL);
+		INSERT INTO other (a) VALUES (1);
+		INSERT INTO nullable (a, b, c) VALUES (4, NULL, 10);
+		SELECT nextval('seq');
+	`)
+	require.NoError(t, tx.Commit())
+
+	check := func(opts *database.CheckOptions) []string {
+		t.Helper()
+
+		report, err := db.Check(t.Context(), opts)
+		require.NoError(t, err)
+
+		var list []string
+		for _, e := range report.Errors {
+			list = append(list, e.Error())
+		}
+		return list
+	}
+
+	require.Empty(t, check(nil))
+
+	// corrupt the database, bypassing the constraints
+	tx, err = conn.BeginTx(&database.TxOptions{})
+	require.NoError(t, err)
+
+	tb, err := tx.Catalog.GetTable(tx, "test")
+	require.NoError(t, err)
+	bIdx, err := tx.Catalog.GetIndex(tx, "test_b_idx")
+	require.NoError(t, err)
+	cIdx, err := tx.Catalog.GetIndex(tx, "test_c_idx")
+	require.NoError(t, err)
+
+	key := func(a int32) *tree.Key {
+		k := tree.NewKey(types.NewIntegerValue(a))
+		_, err := tb.Info.EncodeKey(k)
+		require.NoError(t, err)
+		return k
+	}
+
+	// row 1 has no entry in test_b_idx
+	require.NoError(t, bIdx.Delete([]types.Value{types.NewTextValue("a")}, key(1).Encoded))
+	// entry of a row that doesn't exist
+	require.NoError(t, bIdx.Set([]types.Value{types.NewTextValue("z")}, key(9).Encoded))
+	// entry with values that don't match the row
+	require.NoError(t, bIdx.Set([]types.Value{types.NewTextValue("x")}, key(2).Encoded))
+	// NULL in a NOT NULL column and duplicate unique value
+	nullable, err := tx.Catalog.GetTable(tx, "nullable")
+	require.NoError(t, err)
+	enc, err := nullable.Tree.Get(tree.NewKey(types.NewIntegerValue(4)))
+	require.NoError(t, err)
+	require.NoError(t, tb.Tree.Put(key(4), enc))
+	require.NoError(t, bIdx.Set([]types.Value{types.NewNullValue()}, key(4).Encoded))
+	require.NoError(t, cIdx.Set([]types.Value{types.NewIntegerValue(10)}, key(4).Encoded))
+	// row that can't be decoded
+	require.NoError(t, tb.Tree.Put(key(5), []byte{0xff}))
+	// lease out of the bounds of the sequence
+	seq, err := tx.Catalog.GetSequence("seq")
+	require.NoError(t, err)
+	require.NoError(t, seq.SetLease(tx, "seq", 100))
+
+	require.NoError(t, tx.Commit())
+
+	expected := []string{
+		"test: row (4): NULL value in NOT NULL column b",
+		"test: row (5): cannot decode row: invalid type 255",
+		"test_b_idx: row (1): missing index entry ('a')",
+		"test_b_idx: row (9): index entry ('z') points at a missing row",
+		"test_b_idx: row (2): index entry ('x') doesn't match the row values ('b')",
+		"test_c_idx: row (4): duplicate value (10) violates UNIQUE constraint",
+		"seq: lease 100 is out of range [1, 10]",
+	}
+	require.ElementsMatch(t, expected, check(nil))
+
+	// only the sequences owned by the checked tables are checked
+	require.Empty(t, check(&database.CheckOptions{Tables: []string{"other"}}))
+
+	report, err := db.Check(t.Context(), &database.CheckOptions{Tables: []string{"test"}, Repair: true})
+	require.NoError(t, err)
+	require.Len(t, report.Errors, len(expected)-1)
+	require.Equal(t, []string{"test_b_idx"}, report.Repaired)
+
+	// the other inconsistencies can't be repaired
+	require.ElementsMatch(t, []string{
+		"test: row (4): NULL value in NOT NULL column b",
+		"test: row (5): cannot decode row: invalid type 255",
+		"test_c_idx: row (4): duplicate value (10) violates UNIQUE constraint",
+		"seq: lease 100 is out of range [1, 10]",
+	}, check(nil))
+}
@@ -0,0 +1,254 @@
+package database
+
+import (
+	"strings"
+
+	"github.com/chaisql/chai/internal/encoding"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression algorithms of text and binary values.
+// Compression is enabled for a whole table, in which case it applies to
+// all its text and binary columns, or for a single column.
+const (
+	CompressionNone   = "none"
+	CompressionSnappy = "snappy"
+	CompressionZstd   = "zstd"
+)
+
+// Every compressed value is prefixed by the codec used to compress it,
+// so that changing the compression of a table doesn't require rewriting it.
+const (
+	codecSnappy byte = 1
+	codecZstd   byte = 2
+	// zstd with the dictionary of the table
+	codecZstdDict byte = 3
+)
+
+const (
+	// maximum size of a trained dictionary
+	compressionDictionarySize = 16 << 10
+	// maximum amount of data sampled to train a dictionary
+	compressionSampleSize = 1 << 20
+)
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil)
+	zstdDecoder, _ = zstd.NewReader(nil)
+)
+
+// ParseCompression validates the name of a compression algorithm
+// and returns it in lower case.
+func ParseCompression(name string) (string, error) {
+	switch c := strings.ToLower(name); c {
+	case CompressionNone, CompressionSnappy, CompressionZstd:
+		return c, nil
+	}
+
+	return "", errors.Errorf("unknown compression %q, expected one of none, snappy or zstd", name)
+}
+
+// compressionDictionary is a zstd dictionary trained on the values of a table.
+// Dictionaries improve the compression ratio of small values, which don't
+// contain enough data to be compressed on their own.
+type compressionDictionary struct {
+	raw []byte
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+}
+
+func newCompressionDictionary(raw []byte) (*compressionDictionary, error) {
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderDict(raw))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid compression dictionary")
+	}
+
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(raw))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid compression dictionary")
+	}
+
+	return &compressionDictionary{raw: raw, enc: enc, dec: dec}, nil
+}
+
+// SetCompression sets the compression algorithm used for the text and binary
+// columns of the table that don't define their own.
+func (ti *TableInfo) SetCompression(compression string) error {
+	c, err := ParseCompression(compression)
+	if err != nil {
+		return err
+	}
+	if c == CompressionNone {
+		c = ""
+	}
+
+	ti.Compression = c
+	return nil
+}
+
+// SetCompressionDictionary sets the zstd dictionary used to compress
+// the values of the table. A nil dictionary removes it.
+func (ti *TableInfo) SetCompressionDictionary(raw []byte) error {
+	if len(raw) == 0 {
+		ti.CompressionDictionary = nil
+		ti.ColumnConstraints.dictionary = nil
+		return nil
+	}
+
+	d, err := newCompressionDictionary(raw)
+	if err != nil {
+		return err
+	}
+
+	ti.CompressionDictionary = raw
+	ti.ColumnConstraints.dictionary = d
+	return nil
+}
+
+// columnCompression returns the compression algorithm of the values of the column,
+// or an empty string if they are not compressed.
+func (ti *TableInfo) columnCompression(cc *ColumnConstraint) string {
+	switch {
+	case cc.Compression == CompressionNone:
+		return ""
+	case cc.Compression != "":
+		return cc.Compression
+	case cc.Type == types.TypeText || cc.Type == types.TypeBytea:
+		return ti.Compression
+	}
+
+	return ""
+}
+
+// usesZstd returns whether any column of the table is compressed with zstd.
+func (ti *TableInfo) usesZstd() bool {
+	for _, cc := range ti.ColumnConstraints.Ordered {
+		if ti.columnCompression(cc) == CompressionZstd {
+			return true
+		}
+	}
+
+	return false
+}
+
+// compressValue compresses the encoded text or binary value v.
+// It returns nil if compression doesn't reduce its size.
+func (f *ColumnConstraints) compressValue(compression string, v []byte) []byte {
+	var codec byte
+	var data []byte
+
+	switch compression {
+	case CompressionSnappy:
+		codec, data = codecSnappy, snappy.Encode(nil, v)
+	case CompressionZstd:
+		if f.dictionary != nil {
+			codec, data = codecZstdDict, f.dictionary.enc.EncodeAll(v, nil)
+		} else {
+			codec, data = codecZstd, zstdEncoder.EncodeAll(v, nil)
+		}
+	default:
+		return nil
+	}
+
+	// the header takes at most 1 byte for the type, 1 for the codec and 3 for the length,
+	// as values larger than LargeValueThreshold are never compressed.
+	if len(data)+5 >= len(v) {
+		return nil
+	}
+
+	return encoding.EncodeCompressedValue(nil, codec, data)
+}
+
+// decompressValue decompresses the compressed value at the beginning of b.
+// It returns the encoded value and the number of bytes read from b.
+func (f *ColumnConstraints) decompressValue(b []byte) ([]byte, int, error) {
+	codec, data, n := encoding.DecodeCompressedValue(b)
+
+	var v []byte
+	var err error
+	switch codec {
+	case codecSnappy:
+		v, err = snappy.Decode(nil, data)
+	case codecZstd:
+		v, err = zstdDecoder.DecodeAll(data, nil)
+	case codecZstdDict:
+		if f.dictionary == nil {
+			return nil, 0, errors.New("cannot decompress value: missing compression dictionary")
+		}
+		v, err = f.dictionary.dec.DecodeAll(data, nil)
+	default:
+		return nil, 0, errors.Errorf("cannot decompress value: unknown codec %d", codec)
+	}
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "cannot decompress value")
+	}
+
+	return v, n, nil
+}
+
+// TrainCompressionDictionary trains a zstd dictionary on a sample of the values
+// of the columns of the table compressed with zstd.
+func (t *Table) TrainCompressionDictionary() ([]byte, error) {
+	if !t.Info.usesZstd() {
+		return nil, errors.Errorf("table %q is not compressed with zstd", t.Info.TableName)
+	}
+
+	it, err := t.Iterator(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var samples [][]byte
+	var total int
+	for it.First(); it.Valid() && total < compressionSampleSize; it.Next() {
+		r, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cc := range t.Info.ColumnConstraints.Ordered {
+			if t.Info.columnCompression(cc) != CompressionZstd {
+				continue
+			}
+
+			v, err := r.Get(cc.Column)
+			if err != nil {
+				return nil, err
+			}
+
+			// large values are stored uncompressed
+			var data []byte
+			switch v.(type) {
+			case *LargeValue:
+				continue
+			case types.TextValue:
+				data = []byte(types.AsString(v))
+			case types.ByteaValue:
+				data = types.AsByteSlice(v)
+			default:
+				continue
+			}
+
+			samples = append(samples, data)
+			total += len(data)
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	raw, err := dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: compressionDictionarySize,
+		HashBytes:   6,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "not enough data to train a compression dictionary")
+	}
+
+	return raw, nil
+}
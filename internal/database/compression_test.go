@@ -0,0 +1,88 @@
+package database_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/encoding"
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/query/statement"
+	"github.com/chaisql/chai/internal/sql/parser"
+	"github.com/chaisql/chai/internal/testutil"
+	"github.com/chaisql/chai/internal/tree"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompression(t *testing.T) {
+	db, tx, cleanup := testutil.NewTestTx(t)
+	defer cleanup()
+
+	testutil.MustExec(t, db, tx, `CREATE TABLE test(a INT PRIMARY KEY, b TEXT, c TEXT COMPRESSION none) WITH (compression = 'zstd')`)
+
+	line := func(i int) string {
+		return strings.Repeat("GET /api/v1/users/"+strconv.Itoa(i)+" HTTP/1.1 200 ", 3)
+	}
+	for i := range 100 {
+		testutil.MustExec(t, db, tx, `INSERT INTO test VALUES ($1, $2, $2)`,
+			environment.Param{Value: i}, environment.Param{Value: line(i)})
+	}
+
+	// returns the encoded row and the encoded values of b and c
+	get := func(t *testing.T, i int) (enc, b, c []byte) {
+		t.Helper()
+
+		tb, err := tx.Catalog.GetTable(tx, "test")
+		require.NoError(t, err)
+
+		key, err := tb.Info.EncodeKey(tree.NewKey(types.NewIntegerValue(int32(i))))
+		require.NoError(t, err)
+
+		enc, err = tx.Session.Get(key)
+		require.NoError(t, err)
+
+		b = enc[encoding.Skip(enc):]
+		c = b[encoding.Skip(b):]
+		return enc, b[:encoding.Skip(b)], c
+	}
+
+	// returns the total size of the values of b
+	size := func(t *testing.T) int {
+		var n int
+		for i := range 100 {
+			_, b, _ := get(t, i)
+			n += len(b)
+		}
+		return n
+	}
+
+	_, b, c := get(t, 1)
+	require.Equal(t, encoding.CompressedValue, b[0])
+	require.Less(t, len(b), len(line(1)))
+	require.Equal(t, encoding.TextValue, c[0])
+
+	before := size(t)
+	testutil.MustExec(t, db, tx, `ALTER TABLE test TRAIN COMPRESSION DICTIONARY`)
+	require.Less(t, size(t), before)
+
+	info, err := tx.Catalog.GetTableInfo("test")
+	require.NoError(t, err)
+	require.NotEmpty(t, info.CompressionDictionary)
+
+	// the dictionary is stored in the catalog
+	stmts, err := parser.ParseQuery(info.String())
+	require.NoError(t, err)
+	parsed := stmts[0].(*statement.CreateTableStmt).Info
+	require.Equal(t, info.CompressionDictionary, parsed.CompressionDictionary)
+
+	enc, _, _ := get(t, 1)
+	v, err := database.NewEncodedRow(&parsed.ColumnConstraints, enc).Get("b")
+	require.NoError(t, err)
+	require.Equal(t, line(1), types.AsString(v))
+
+	// rows are rewritten without the dictionary
+	testutil.MustExec(t, db, tx, `ALTER TABLE test DROP COMPRESSION DICTIONARY`)
+	require.Equal(t, before, size(t))
+}
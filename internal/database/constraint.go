@@ -19,10 +19,13 @@ type ColumnConstraint struct {
 	TypeDef      types.TypeDefinition
 	IsNotNull    bool
 	DefaultValue TableExpression
+	// Compression algorithm of the values of the column.
+	// If empty, the compression of the table is used.
+	Compression string
 }
 
 func (f *ColumnConstraint) IsEmpty() bool {
-	return f.Column == "" && f.Type.IsAny() && !f.IsNotNull && f.DefaultValue == nil
+	return f.Column == "" && f.Type.IsAny() && !f.IsNotNull && f.DefaultValue == nil && f.Compression == ""
 }
 
 func (f *ColumnConstraint) String() string {
@@ -41,6 +44,11 @@ func (f *ColumnConstraint) String() string {
 		s.WriteString(f.DefaultValue.String())
 	}
 
+	if f.Compression != "" {
+		s.WriteString(" COMPRESSION ")
+		s.WriteString(f.Compression)
+	}
+
 	return s.String()
 }
 
@@ -48,6 +56,9 @@ func (f *ColumnConstraint) String() string {
 type ColumnConstraints struct {
 	Ordered  []*ColumnConstraint
 	ByColumn map[string]*ColumnConstraint
+
+	// zstd dictionary of the table, if any.
+	dictionary *compressionDictionary
 }
 
 func NewColumnConstraints(constraints ...*ColumnConstraint) (ColumnConstraints, error) {
@@ -102,6 +113,10 @@ func (f *ColumnConstraints) Add(newCc *ColumnConstraint) error {
 		}
 	}
 
+	if newCc.Compression != "" && newCc.Type != types.TypeText && newCc.Type != types.TypeBytea {
+		return fmt.Errorf("cannot compress column %q of type %s, only text and bytea columns can be compressed", newCc.Column, newCc.Type)
+	}
+
 	newCc.Position = len(f.Ordered)
 	f.Ordered = append(f.Ordered, newCc)
 	f.ByColumn[newCc.Column] = newCc
@@ -3,13 +3,17 @@ package database
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/chaisql/chai/internal/engine"
 	"github.com/chaisql/chai/internal/kv"
+	"github.com/chaisql/chai/internal/memkv"
 	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/v2"
 )
 
 const (
@@ -44,12 +48,62 @@ type Database struct {
 
 	// Underlying kv store.
 	Engine engine.Engine
+
+	// prevents write transactions from being created.
+	readOnly bool
+
+	// records the changes made to the tables in the changelog.
+	changelog bool
+	// cursor of the last event committed to the changelog.
+	changelogCursor atomic.Uint64
+	// closed when new events are committed to the changelog.
+	changesMu sync.Mutex
+	changesCh chan struct{}
+
+	// id of the last large value stored in the database.
+	largeValueID atomic.Uint64
+
+	// waitgroup to wait for the expiration worker to stop.
+	ttlWg sync.WaitGroup
 }
 
+// ErrReadOnly is returned when a write transaction is started
+// on a database opened in read-only mode.
+var ErrReadOnly = errors.New("cannot begin a write transaction: database is opened in read-only mode")
+
 // Options are passed to Open to control
 // how the database is loaded.
 type Options struct {
 	CatalogLoader func(tx *Transaction) error
+
+	// MaxBatchSize is the size in bytes after which a write transaction
+	// writes its changes to the engine. Defaults to 10MB.
+	MaxBatchSize int
+
+	// NoSync disables syncing the changes to disk when a transaction is committed.
+	// A crash may lose the most recent transactions but cannot corrupt the database.
+	NoSync bool
+
+	// PebbleOptions are used to configure the underlying Pebble engine.
+	PebbleOptions *pebble.Options
+
+	// ReadOnly opens the database in read-only mode.
+	// Nothing is written to disk and write transactions are rejected with ErrReadOnly.
+	// Opening fails if the database needs to be recovered from a crash.
+	ReadOnly bool
+
+	// Changelog records the inserts, updates and deletes committed
+	// to the tables, so that they can be read with ReadChanges or Subscribe.
+	Changelog bool
+
+	// TTLInterval is the interval at which the expired rows
+	// of the tables with a TTL column are deleted. Defaults to 1 minute.
+	TTLInterval time.Duration
+
+	// KeyProvider, if set, provides the key used to encrypt the database files.
+	// An encrypted database cannot be opened without its key,
+	// and a key cannot be used to open a database that is not encrypted.
+	KeyProvider kv.KeyProvider
 }
 
 // CatalogLoader loads the catalog from the disk.
@@ -65,23 +119,43 @@ type TxOptions struct {
 	ReadOnly bool
 }
 
+// MemoryPath is the path of a database stored in memory by a lightweight B-tree
+// engine, rather than by Pebble. Data is lost when the database is closed.
+const MemoryPath = "memory://"
+
+// Open opens the database located at path.
+// If path is MemoryPath, the database is stored in memory without using Pebble.
 func Open(path string, opts *Options) (*Database, error) {
-	store, err := kv.NewEngine(path, kv.Options{
-		RollbackSegmentNamespace: int64(RollbackSegmentNamespace),
-		MinTransientNamespace:    uint64(MinTransientNamespace),
-		MaxTransientNamespace:    uint64(MaxTransientNamespace),
-	})
+	store, err := openEngine(path, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	db := Database{
-		Engine: store,
+		Engine:    store,
+		readOnly:  opts.ReadOnly,
+		changelog: opts.Changelog,
 	}
 
 	// create a context that will be cancelled when the database is closed.
 	db.closeContext, db.closeCancel = context.WithCancel(context.Background())
 
+	if db.readOnly {
+		err = db.loadReadOnly(opts)
+		if err != nil {
+			_ = store.Close()
+			return nil, err
+		}
+
+		err = db.loadChangelogCursor()
+		if err != nil {
+			_ = store.Close()
+			return nil, err
+		}
+
+		return &db, nil
+	}
+
 	// ensure the rollback segment doesn't contain any data that needs to be rolled back
 	// due to a previous crash.
 	err = db.Engine.Recover()
@@ -121,9 +195,74 @@ func Open(path string, opts *Options) (*Database, error) {
 		return nil, err
 	}
 
+	err = db.loadChangelogCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.loadLargeValueID()
+	if err != nil {
+		return nil, err
+	}
+
+	db.startTTLWorker(opts.TTLInterval)
+
 	return &db, nil
 }
 
+func openEngine(path string, opts *Options) (engine.Engine, error) {
+	if path == MemoryPath {
+		if opts.ReadOnly {
+			return nil, errors.New("cannot open an in-memory database in read-only mode")
+		}
+
+		return memkv.NewEngine(), nil
+	}
+
+	return kv.NewEngine(path, kv.Options{
+		RollbackSegmentNamespace: int64(RollbackSegmentNamespace),
+		MinTransientNamespace:    uint64(MinTransientNamespace),
+		MaxTransientNamespace:    uint64(MaxTransientNamespace),
+		MaxBatchSize:             opts.MaxBatchSize,
+		NoSync:                   opts.NoSync,
+		ReadOnly:                 opts.ReadOnly,
+		KeyProvider:              opts.KeyProvider,
+	}, opts.PebbleOptions)
+}
+
+// loadReadOnly loads the catalog without writing anything to disk.
+// It fails if the rollback segment contains data left by a previous crash,
+// since the changes it undoes cannot be rolled back.
+func (db *Database) loadReadOnly(opts *Options) error {
+	err := db.Engine.Recover()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	db.catalog = NewCatalog()
+	tx.Catalog = db.catalog
+
+	if opts.CatalogLoader != nil {
+		err = opts.CatalogLoader(tx)
+		if err != nil {
+			return errors.Wrap(err, "failed to load catalog")
+		}
+	}
+
+	return nil
+}
+
+// ReadOnly returns whether the database was opened in read-only mode.
+func (db *Database) ReadOnly() bool {
+	return db.readOnly
+}
+
 // Close the database.
 func (db *Database) Close() error {
 	var err error
@@ -132,6 +271,7 @@ func (db *Database) Close() error {
 		db.closeCancel()
 
 		db.connectionWg.Wait()
+		db.ttlWg.Wait()
 		err = db.closeDatabase()
 	})
 
@@ -139,6 +279,10 @@ func (db *Database) Close() error {
 }
 
 func (db *Database) closeDatabase() error {
+	if db.readOnly {
+		return db.Engine.Close()
+	}
+
 	// release all sequences
 	tx, err := db.beginTxUnlocked(nil)
 	if err != nil {
@@ -206,6 +350,10 @@ func (db *Database) beginTx(opts *TxOptions) (*Transaction, error) {
 	}
 
 	if !opts.ReadOnly {
+		if db.readOnly {
+			return nil, errors.WithStack(ErrReadOnly)
+		}
+
 		db.writetxmu.Lock()
 	}
 
@@ -257,3 +405,72 @@ func (db *Database) SetCatalog(c *Catalog) {
 	db.catalog = c
 	db.catalogMu.Unlock()
 }
+
+// Backup writes a consistent copy of the database to dir, without blocking
+// the other transactions. If dir contains a previous backup, only the data
+// written since is copied.
+// The backup can be restored with Restore or opened directly.
+func (db *Database) Backup(ctx context.Context, dir string) error {
+	if db.closeContext.Err() != nil {
+		return errors.New("database is closed")
+	}
+
+	b, ok := db.Engine.(engine.Backuper)
+	if !ok {
+		return errors.New("backups are not supported by this engine")
+	}
+
+	return b.Backup(ctx, dir)
+}
+
+// Restore replaces the database located at path with the backup stored in dir.
+// The backup is copied next to the database and opened with opts to ensure
+// its catalog can be loaded, before the database is swapped in.
+// The database must not be in use: its lock is held until it is replaced.
+func Restore(ctx context.Context, dir, path string, opts *Options) error {
+	path = filepath.Clean(path)
+	staging := path + ".restore"
+
+	lock, err := kv.LockDatabase(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	err = os.RemoveAll(staging)
+	if err != nil {
+		return err
+	}
+
+	err = kv.CopyBackup(ctx, dir, staging)
+	if err != nil {
+		return err
+	}
+
+	db, err := Open(staging, opts)
+	if err == nil {
+		err = db.Close()
+	}
+	if err != nil {
+		_ = os.RemoveAll(staging)
+		return errors.Wrap(err, "invalid backup")
+	}
+
+	old := path + ".old"
+	err = os.RemoveAll(old)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(path, old)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Rename(staging, path)
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(old)
+}
@@ -14,10 +14,12 @@ func (t *TableInfo) EncodeRow(tx *Transaction, dst []byte, r row.Row) ([]byte, e
 		return ed.encoded, nil
 	}
 
-	return encodeRow(tx, dst, &t.ColumnConstraints, r)
+	return encodeRow(tx, dst, t, r)
 }
 
-func encodeRow(tx *Transaction, dst []byte, ccs *ColumnConstraints, r row.Row) ([]byte, error) {
+func encodeRow(tx *Transaction, dst []byte, ti *TableInfo, r row.Row) ([]byte, error) {
+	ccs := &ti.ColumnConstraints
+
 	// loop over all the defined column contraints in order.
 	for _, cc := range ccs.Ordered {
 		// get the column from the row
@@ -52,10 +54,24 @@ func encodeRow(tx *Transaction, dst []byte, ccs *ColumnConstraints, r row.Row) (
 			return nil, err
 		}
 
+		start := len(dst)
 		dst, err = v.Encode(dst)
 		if err != nil {
 			return nil, err
 		}
+
+		// compress text and binary values stored in the row.
+		// large values are stored uncompressed outside of the row.
+		compression := ti.columnCompression(cc)
+		if compression == "" || len(dst)-start >= LargeValueThreshold {
+			continue
+		}
+		if dst[start] != encoding.TextValue && dst[start] != encoding.ByteaValue {
+			continue
+		}
+		if c := ccs.compressValue(compression, dst[start:]); c != nil {
+			dst = append(dst[:start], c...)
+		}
 	}
 
 	return dst, nil
@@ -64,6 +80,9 @@ func encodeRow(tx *Transaction, dst []byte, ccs *ColumnConstraints, r row.Row) (
 type EncodedRow struct {
 	encoded           []byte
 	columnConstraints *ColumnConstraints
+	// transaction used to read the large values
+	// stored outside of the row.
+	tx *Transaction
 }
 
 func NewEncodedRow(ccs *ColumnConstraints, data []byte) *EncodedRow {
@@ -75,14 +94,34 @@ func NewEncodedRow(ccs *ColumnConstraints, data []byte) *EncodedRow {
 	return &e
 }
 
-func (e *EncodedRow) ResetWith(ccs *ColumnConstraints, data []byte) {
+func (e *EncodedRow) ResetWith(tx *Transaction, ccs *ColumnConstraints, data []byte) {
+	e.tx = tx
 	e.columnConstraints = ccs
 	e.encoded = data
 }
 
 func (e *EncodedRow) decodeValue(fc *ColumnConstraint, b []byte) (types.Value, int, error) {
-	if b[0] == encoding.NullValue {
+	switch b[0] {
+	case encoding.NullValue:
 		return types.NewNullValue(), 1, nil
+	case encoding.LargeValue:
+		if e.tx == nil {
+			return nil, 0, errors.New("cannot read a large value outside of a transaction")
+		}
+
+		v, n := newLargeValue(e.tx, b)
+		if _, err := v.load(); err != nil {
+			return nil, 0, errors.Wrap(err, "failed to read large value")
+		}
+		return v, n, nil
+	case encoding.CompressedValue:
+		data, n, err := e.columnConstraints.decompressValue(b)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		v, _ := fc.TypeDef.Decode(data)
+		return v, n, nil
 	}
 
 	v, n := fc.TypeDef.Decode(b)
@@ -23,7 +23,7 @@ func getIndex(t testing.TB, arity int) *database.Index {
 		MaxBatchSize:             1 << 7,
 		MinTransientNamespace:    10_000,
 		MaxTransientNamespace:    11_000,
-	})
+	}, nil)
 	require.NoError(t, err)
 
 	session := st.NewBatchSession()
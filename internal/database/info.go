@@ -6,7 +6,9 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/chaisql/chai/internal/row"
 	"github.com/chaisql/chai/internal/stringutil"
 	"github.com/chaisql/chai/internal/tree"
 	"github.com/chaisql/chai/internal/types"
@@ -25,6 +27,55 @@ type TableInfo struct {
 	TableConstraints  TableConstraints
 
 	PrimaryKey *PrimaryKey
+
+	// TTLColumn is the TIMESTAMP column after which rows expire.
+	// Expired rows are hidden from reads and deleted in the background.
+	TTLColumn string
+
+	// Compression is the compression algorithm of the text and binary columns.
+	// Columns can override it.
+	Compression string
+	// CompressionDictionary is the zstd dictionary used to compress the values
+	// of the table, trained on its content.
+	CompressionDictionary []byte
+}
+
+// SetTTLColumn sets the column used to expire the rows of the table.
+// The column must be a TIMESTAMP column. An empty column disables expiration.
+func (ti *TableInfo) SetTTLColumn(column string) error {
+	if column != "" {
+		cc := ti.GetColumnConstraint(column)
+		if cc == nil {
+			return fmt.Errorf("column %q does not exist for table %q", column, ti.TableName)
+		}
+		if cc.Type != types.TypeTimestamp {
+			return fmt.Errorf("ttl column %q must be of type timestamp, got %s", column, cc.Type)
+		}
+	}
+
+	ti.TTLColumn = column
+	return nil
+}
+
+// IsExpired returns whether the row has expired at the given time.
+// Rows whose TTL column is NULL never expire.
+func (ti *TableInfo) IsExpired(r row.Row, now time.Time) (bool, error) {
+	if ti.TTLColumn == "" {
+		return false, nil
+	}
+
+	v, err := r.Get(ti.TTLColumn)
+	if err != nil {
+		if errors.Is(err, types.ErrColumnNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if v.Type() != types.TypeTimestamp {
+		return false, nil
+	}
+
+	return !types.AsTime(v).After(now), nil
 }
 
 func (ti *TableInfo) AddColumnConstraint(newCc *ColumnConstraint) error {
@@ -210,6 +261,20 @@ func (ti *TableInfo) String() string {
 
 	s.WriteString(")")
 
+	var options []string
+	if ti.TTLColumn != "" {
+		options = append(options, "ttl_column = "+stringutil.NormalizeIdentifier(ti.TTLColumn, '`'))
+	}
+	if ti.Compression != "" {
+		options = append(options, "compression = "+types.NewTextValue(ti.Compression).String())
+	}
+	if len(ti.CompressionDictionary) > 0 {
+		options = append(options, "compression_dictionary = "+types.NewByteaValue(ti.CompressionDictionary).String())
+	}
+	if len(options) > 0 {
+		fmt.Fprintf(&s, " WITH (%s)", strings.Join(options, ", "))
+	}
+
 	return s.String()
 }
 
@@ -81,11 +81,12 @@ type TableIterator struct {
 	row BasicRow
 }
 
-func newIterator(ti *tree.Iterator, tableName string, columnConstraints *ColumnConstraints) *TableIterator {
+func newIterator(tx *Transaction, ti *tree.Iterator, tableName string, columnConstraints *ColumnConstraints) *TableIterator {
 	it := TableIterator{
 		Iterator: ti,
 	}
 
+	it.e.tx = tx
 	it.e.columnConstraints = columnConstraints
 	it.row.tableName = tableName
 	it.row.Row = &it.e
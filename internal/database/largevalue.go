@@ -0,0 +1,602 @@
+package database
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/chaisql/chai/internal/encoding"
+	"github.com/chaisql/chai/internal/engine"
+	errs "github.com/chaisql/chai/internal/errors"
+	"github.com/chaisql/chai/internal/tree"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+// LargeValueThreshold is the size in bytes from which text and binary
+// values are stored outside of their row, split in chunks.
+// Rows only hold a reference to these values, which are loaded
+// when their column is read.
+const LargeValueThreshold = 64 << 10
+
+const largeValueChunkSize = 64 << 10
+
+// Every large value is identified by a unique id and stored in the
+// LargeValueNamespace, one chunk per key:
+//
+//	[LargeValueNamespace][id][chunk number] -> chunk
+//
+// A large value is owned by the row that references it. Values that are
+// no longer referenced by any row, because their row was deleted or because
+// they were written from a reader but never stored, are tracked by
+// the transaction and deleted when it is committed. Until then, they can be
+// claimed by a row written later in the same transaction.
+
+func largeValueKey(id uint64, chunk int64) ([]byte, error) {
+	return tree.NewKey(types.NewBigintValue(int64(id)), types.NewBigintValue(chunk)).Encode(LargeValueNamespace, 0)
+}
+
+// loadLargeValueID loads the id of the last large value stored in the database.
+func (db *Database) loadLargeValueID() error {
+	tx, err := db.begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	it, err := tree.New(tx.Session, LargeValueNamespace, 0).Iterator(nil)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	if !it.Last() {
+		return it.Error()
+	}
+
+	values, err := it.Key().Decode()
+	if err != nil {
+		return err
+	}
+
+	db.largeValueID.Store(uint64(types.AsInt64(values[0])))
+	return nil
+}
+
+// writeLargeValue reads r until EOF and stores its content in chunks.
+// It returns the id of the new value and its size.
+func (tx *Transaction) writeLargeValue(r io.Reader) (uint64, int64, error) {
+	id := tx.db.largeValueID.Add(1)
+	buf := make([]byte, largeValueChunkSize)
+
+	var size int64
+	for chunk := int64(0); ; chunk++ {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			k, err := largeValueKey(id, chunk)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			err = tx.Session.Put(k, buf[:n])
+			if err != nil {
+				return 0, 0, err
+			}
+			size += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return id, size, nil
+}
+
+// copyLargeValue copies the value with the given id, chunk by chunk,
+// and returns the id of the copy.
+func (tx *Transaction) copyLargeValue(id uint64, size int64) (uint64, error) {
+	newID, _, err := tx.writeLargeValue(newLargeValueReader(tx.Session, id, size))
+	return newID, err
+}
+
+// deleteLargeValue deletes the chunks of the value with the given id.
+func (tx *Transaction) deleteLargeValue(id uint64) error {
+	start, err := tree.NewKey(types.NewBigintValue(int64(id))).Encode(LargeValueNamespace, 0)
+	if err != nil {
+		return err
+	}
+	end, err := tree.NewKey(types.NewBigintValue(int64(id+1))).Encode(LargeValueNamespace, 0)
+	if err != nil {
+		return err
+	}
+
+	return tx.Session.DeleteRange(start, end)
+}
+
+// releaseLargeValue marks the value as no longer referenced by any row.
+// Unless it is claimed by another row, it is deleted when the transaction
+// is committed.
+func (tx *Transaction) releaseLargeValue(id uint64) {
+	if tx.unownedLargeValues == nil {
+		tx.unownedLargeValues = make(map[uint64]struct{})
+	}
+
+	tx.unownedLargeValues[id] = struct{}{}
+	tx.OnRollbackHooks = append(tx.OnRollbackHooks, func() {
+		delete(tx.unownedLargeValues, id)
+	})
+}
+
+// claimLargeValue makes the caller the owner of a value that is not
+// referenced by any row. It returns false if the value is already owned.
+func (tx *Transaction) claimLargeValue(id uint64) bool {
+	if _, ok := tx.unownedLargeValues[id]; !ok {
+		return false
+	}
+
+	delete(tx.unownedLargeValues, id)
+	tx.OnRollbackHooks = append(tx.OnRollbackHooks, func() {
+		tx.unownedLargeValues[id] = struct{}{}
+	})
+	return true
+}
+
+// deleteUnownedLargeValues deletes the values that are not referenced
+// by any row at the end of the transaction.
+func (tx *Transaction) deleteUnownedLargeValues() error {
+	for id := range tx.unownedLargeValues {
+		err := tx.deleteLargeValue(id)
+		if err != nil {
+			return err
+		}
+	}
+
+	tx.unownedLargeValues = nil
+	return nil
+}
+
+// NewValueFromReader reads r until EOF and returns its content as a BYTEA value.
+// Contents larger than LargeValueThreshold are written to the transaction in chunks
+// as they are read, and rows storing the value only reference these chunks.
+// The returned value also holds the content, since it may be read by the query.
+// If the value is not stored in a row by the end of the transaction, it is discarded.
+func (tx *Transaction) NewValueFromReader(r io.Reader) (types.Value, error) {
+	data, err := io.ReadAll(io.LimitReader(r, LargeValueThreshold))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < LargeValueThreshold {
+		return types.NewByteaValue(data), nil
+	}
+
+	if !tx.Writable {
+		return nil, errors.New("cannot write a large value in a read-only transaction")
+	}
+
+	var buf bytes.Buffer
+	id, size, err := tx.writeLargeValue(io.TeeReader(io.MultiReader(bytes.NewReader(data), r), &buf))
+	if err != nil {
+		return nil, err
+	}
+	tx.releaseLargeValue(id)
+
+	return &LargeValue{
+		tx:   tx,
+		typ:  types.TypeBytea,
+		id:   id,
+		size: size,
+		v:    types.NewByteaValue(buf.Bytes()),
+	}, nil
+}
+
+// largeValueRefs calls fn with the id of every large value referenced by the encoded row.
+func largeValueRefs(enc []byte, fn func(id uint64)) {
+	for len(enc) > 0 {
+		if enc[0] == encoding.LargeValue {
+			_, _, id, _ := encoding.DecodeLargeValue(enc)
+			fn(id)
+		}
+
+		enc = enc[encoding.Skip(enc):]
+	}
+}
+
+// storeLargeValues moves the text and binary values of the encoded row larger
+// than LargeValueThreshold to chunks, and ensures the row owns every large
+// value it references, copying the ones owned by other rows.
+// It returns the new encoded row, or nil if enc is unchanged.
+func (tx *Transaction) storeLargeValues(enc []byte) ([]byte, error) {
+	var out []byte
+
+	b := enc
+	for len(b) > 0 {
+		n := encoding.Skip(b)
+
+		var ref []byte
+		switch b[0] {
+		case encoding.TextValue, encoding.ByteaValue:
+			if n < LargeValueThreshold {
+				break
+			}
+
+			// text and binary values are encoded the same way
+			data, _ := encoding.DecodeBytea(b)
+			id, size, err := tx.writeLargeValue(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			ref = encoding.EncodeLargeValue(nil, b[0], uint64(size), id)
+		case encoding.LargeValue:
+			typ, size, id, _ := encoding.DecodeLargeValue(b)
+			if tx.claimLargeValue(id) {
+				break
+			}
+
+			newID, err := tx.copyLargeValue(id, int64(size))
+			if err != nil {
+				return nil, err
+			}
+			ref = encoding.EncodeLargeValue(nil, typ, size, newID)
+		}
+
+		if ref != nil && out == nil {
+			out = append(make([]byte, 0, len(enc)), enc[:len(enc)-len(b)]...)
+		}
+		if ref != nil {
+			out = append(out, ref...)
+		} else if out != nil {
+			out = append(out, b[:n]...)
+		}
+
+		b = b[n:]
+	}
+
+	return out, nil
+}
+
+// LargeValue is a text or binary value stored outside of its row.
+// Its content is read from the transaction when its column is decoded,
+// since methods like V or String can't report read errors.
+// When stored again, only the reference to the chunks is written.
+type LargeValue struct {
+	tx   *Transaction
+	typ  types.Type
+	id   uint64
+	size int64
+	// content of the value, once loaded.
+	v types.Value
+}
+
+var _ types.Value = (*LargeValue)(nil)
+
+func newLargeValue(tx *Transaction, b []byte) (*LargeValue, int) {
+	typ, size, id, n := encoding.DecodeLargeValue(b)
+
+	v := LargeValue{
+		tx:   tx,
+		typ:  types.TypeBytea,
+		id:   id,
+		size: int64(size),
+	}
+	if typ == encoding.TextValue {
+		v.typ = types.TypeText
+	}
+
+	return &v, n
+}
+
+// Size returns the size of the value in bytes.
+func (v *LargeValue) Size() int64 {
+	return v.size
+}
+
+// NewReader returns a reader on the content of the value,
+// which reads the chunks one by one.
+func (v *LargeValue) NewReader() io.ReadSeeker {
+	return newLargeValueReader(v.tx.Session, v.id, v.size)
+}
+
+func (v *LargeValue) load() (types.Value, error) {
+	if v.v != nil {
+		return v.v, nil
+	}
+
+	data := make([]byte, v.size)
+	_, err := io.ReadFull(v.NewReader(), data)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.typ == types.TypeText {
+		v.v = types.NewTextValue(string(data))
+	} else {
+		v.v = types.NewByteaValue(data)
+	}
+
+	return v.v, nil
+}
+
+func (v *LargeValue) Type() types.Type {
+	return v.typ
+}
+
+func (v *LargeValue) TypeDef() types.TypeDefinition {
+	return v.typ.Def()
+}
+
+// V returns the content of the value as a string or a byte slice.
+func (v *LargeValue) V() any {
+	return v.v.V()
+}
+
+func (v *LargeValue) IsZero() (bool, error) {
+	return v.size == 0, nil
+}
+
+func (v *LargeValue) String() string {
+	return v.v.String()
+}
+
+func (v *LargeValue) MarshalText() ([]byte, error) {
+	x, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return x.MarshalText()
+}
+
+func (v *LargeValue) MarshalJSON() ([]byte, error) {
+	x, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return x.MarshalJSON()
+}
+
+// Encode writes a reference to the value.
+func (v *LargeValue) Encode(dst []byte) ([]byte, error) {
+	typ := encoding.ByteaValue
+	if v.typ == types.TypeText {
+		typ = encoding.TextValue
+	}
+
+	return encoding.EncodeLargeValue(dst, typ, uint64(v.size), v.id), nil
+}
+
+// EncodeAsKey encodes the content of the value.
+func (v *LargeValue) EncodeAsKey(dst []byte) ([]byte, error) {
+	x, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return x.EncodeAsKey(dst)
+}
+
+func (v *LargeValue) CastAs(target types.Type) (types.Value, error) {
+	if target == v.typ {
+		return v, nil
+	}
+
+	x, err := v.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return x.CastAs(target)
+}
+
+func (v *LargeValue) EQ(other types.Value) (bool, error) {
+	x, err := v.load()
+	if err != nil {
+		return false, err
+	}
+
+	return x.EQ(other)
+}
+
+func (v *LargeValue) GT(other types.Value) (bool, error) {
+	x, err := v.load()
+	if err != nil {
+		return false, err
+	}
+
+	return x.GT(other)
+}
+
+func (v *LargeValue) GTE(other types.Value) (bool, error) {
+	x, err := v.load()
+	if err != nil {
+		return false, err
+	}
+
+	return x.GTE(other)
+}
+
+func (v *LargeValue) LT(other types.Value) (bool, error) {
+	x, err := v.load()
+	if err != nil {
+		return false, err
+	}
+
+	return x.LT(other)
+}
+
+func (v *LargeValue) LTE(other types.Value) (bool, error) {
+	x, err := v.load()
+	if err != nil {
+		return false, err
+	}
+
+	return x.LTE(other)
+}
+
+func (v *LargeValue) Between(a, b types.Value) (bool, error) {
+	x, err := v.load()
+	if err != nil {
+		return false, err
+	}
+
+	return x.Between(a, b)
+}
+
+// largeValueReader reads a large value chunk by chunk.
+type largeValueReader struct {
+	session engine.Session
+	id      uint64
+	size    int64
+	off     int64
+	// last chunk read.
+	chunk    []byte
+	chunkNum int64
+}
+
+func newLargeValueReader(session engine.Session, id uint64, size int64) *largeValueReader {
+	return &largeValueReader{
+		session:  session,
+		id:       id,
+		size:     size,
+		chunkNum: -1,
+	}
+}
+
+func (r *largeValueReader) Read(p []byte) (int, error) {
+	if r.off >= r.size {
+		return 0, io.EOF
+	}
+
+	num := r.off / largeValueChunkSize
+	if num != r.chunkNum {
+		k, err := largeValueKey(r.id, num)
+		if err != nil {
+			return 0, err
+		}
+
+		r.chunk, err = r.session.Get(k)
+		if err != nil {
+			if errors.Is(err, engine.ErrKeyNotFound) {
+				return 0, errors.Errorf("chunk %d of large value %d not found", num, r.id)
+			}
+			return 0, err
+		}
+		r.chunkNum = num
+	}
+
+	n := copy(p, r.chunk[r.off%largeValueChunkSize:])
+	r.off += int64(n)
+	return n, nil
+}
+
+func (r *largeValueReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.off = offset
+	return offset, nil
+}
+
+// OpenReader returns a reader on the value of a text or binary column
+// of the row stored under the given key. Large values are read chunk by chunk
+// rather than loaded in memory.
+func (t *Table) OpenReader(key *tree.Key, column string) (io.ReadSeeker, error) {
+	cc := t.Info.GetColumnConstraint(column)
+	if cc == nil {
+		return nil, errors.Wrapf(types.ErrColumnNotFound, "%s not found", column)
+	}
+	if cc.Type != types.TypeText && cc.Type != types.TypeBytea {
+		return nil, errors.Errorf("cannot read column %q of type %s", column, cc.Type)
+	}
+
+	enc, err := t.Tree.Get(key)
+	if err != nil {
+		if errors.Is(err, engine.ErrKeyNotFound) {
+			return nil, errs.NewNotFoundError(key.String())
+		}
+		return nil, err
+	}
+
+	b := enc
+	for i := 0; i < cc.Position; i++ {
+		b = b[encoding.Skip(b):]
+	}
+
+	switch b[0] {
+	case encoding.NullValue:
+		return nil, errors.Errorf("column %q is NULL", column)
+	case encoding.LargeValue:
+		v, _ := newLargeValue(t.Tx, b)
+		return v.NewReader(), nil
+	case encoding.CompressedValue:
+		b, _, err = t.Info.ColumnConstraints.decompressValue(b)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, _ := encoding.DecodeBytea(b)
+	return bytes.NewReader(data), nil
+}
+
+// OpenReader returns a reader on the value of a text or binary column of a row,
+// identified by the values of its primary key. The reader reads from a snapshot
+// of the database taken when it is opened, which is released when it is closed.
+func (db *Database) OpenReader(tableName, column string, pk []types.Value) (io.ReadSeekCloser, error) {
+	tx, err := db.begin(false)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := openReader(tx, tableName, column, pk)
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+
+	return &snapshotReader{ReadSeeker: r, tx: tx}, nil
+}
+
+func openReader(tx *Transaction, tableName, column string, pk []types.Value) (io.ReadSeeker, error) {
+	t, err := tx.Catalog.GetTable(tx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pk) != len(t.Info.PrimaryKey.Columns) {
+		return nil, errors.Errorf("expected %d primary key values, got %d", len(t.Info.PrimaryKey.Columns), len(pk))
+	}
+
+	values := make([]types.Value, len(pk))
+	for i, v := range pk {
+		values[i], err = v.CastAs(t.Info.PrimaryKey.Types[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return t.OpenReader(tree.NewKey(values...), column)
+}
+
+// snapshotReader reads a value from a read-only transaction.
+type snapshotReader struct {
+	io.ReadSeeker
+	tx *Transaction
+}
+
+func (r *snapshotReader) Close() error {
+	return r.tx.Rollback()
+}
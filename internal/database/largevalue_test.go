@@ -0,0 +1,215 @@
+package database_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/query"
+	"github.com/chaisql/chai/internal/sql/parser"
+	"github.com/chaisql/chai/internal/testutil"
+	"github.com/chaisql/chai/internal/tree"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLargeValues(t *testing.T) {
+	db := testutil.NewTestDB(t)
+	conn := testutil.NewTestConn(t, db)
+	rconn := testutil.NewTestConn(t, db)
+
+	exec := func(t *testing.T, q string, params ...any) {
+		t.Helper()
+
+		pq, err := parser.ParseQuery(q)
+		require.NoError(t, err)
+
+		var ps []environment.Param
+		for _, p := range params {
+			ps = append(ps, environment.Param{Value: p})
+		}
+
+		res, err := query.New(pq...).Run(&query.Context{Ctx: context.Background(), DB: db, Conn: conn, Params: ps})
+		require.NoError(t, err)
+		defer res.Close()
+
+		require.NoError(t, res.Skip(context.Background()))
+	}
+
+	// returns the number of chunks stored in the database
+	chunks := func(t *testing.T) int {
+		t.Helper()
+
+		tx, err := rconn.BeginTx(&database.TxOptions{ReadOnly: true})
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		it, err := tree.New(tx.Session, database.LargeValueNamespace, 0).Iterator(nil)
+		require.NoError(t, err)
+		defer it.Close()
+
+		var n int
+		for it.First(); it.Valid(); it.Next() {
+			n++
+		}
+		require.NoError(t, it.Error())
+		return n
+	}
+
+	read := func(t *testing.T, table, column string, pk int64) []byte {
+		t.Helper()
+
+		r, err := db.OpenReader(table, column, []types.Value{types.NewBigintValue(pk)})
+		require.NoError(t, err)
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return data
+	}
+
+	// returns the first column of the first row
+	get := func(t *testing.T, q string) string {
+		t.Helper()
+
+		pq, err := parser.ParseQuery(q)
+		require.NoError(t, err)
+
+		res, err := query.New(pq...).Run(&query.Context{Ctx: context.Background(), DB: db, Conn: conn})
+		require.NoError(t, err)
+		defer res.Close()
+
+		var s string
+		err = res.Iterate(func(r database.Row) error {
+			return r.Iterate(func(_ string, v types.Value) error {
+				s = types.AsString(v)
+				return nil
+			})
+		})
+		require.NoError(t, err)
+		return s
+	}
+
+	blob := bytes.Repeat([]byte("0123456789"), 20_000)
+	text := strings.Repeat("abcdefghij", 10_000)
+
+	exec(t, `CREATE TABLE test(a INT PRIMARY KEY, b BYTEA, c TEXT, d INT)`)
+	exec(t, `INSERT INTO test (a, b, c, d) VALUES (1, $1, $2, 1), (2, '\x00', 'small', 2)`, blob, text)
+
+	// 200KB and 100KB values are stored in 4 and 2 chunks
+	require.Equal(t, 6, chunks(t))
+	require.Equal(t, blob, read(t, "test", "b", 1))
+	require.Equal(t, []byte(text), read(t, "test", "c", 1))
+	require.Equal(t, []byte("small"), read(t, "test", "c", 2))
+	require.Equal(t, text, get(t, `SELECT c FROM test WHERE a = 1`))
+
+	t.Run("reader", func(t *testing.T) {
+		r, err := db.OpenReader("test", "b", []types.Value{types.NewBigintValue(1)})
+		require.NoError(t, err)
+		defer r.Close()
+
+		_, err = r.Seek(-15, io.SeekEnd)
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		require.Equal(t, blob[len(blob)-15:], data)
+
+		_, err = r.Seek(70_000, io.SeekStart)
+		require.NoError(t, err)
+		data = make([]byte, 5)
+		_, err = io.ReadFull(r, data)
+		require.NoError(t, err)
+		require.Equal(t, blob[70_000:70_005], data)
+
+		_, err = db.OpenReader("test", "d", []types.Value{types.NewBigintValue(1)})
+		require.Error(t, err)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		// unchanged values are not copied
+		exec(t, `UPDATE test SET d = d + 1`)
+		require.Equal(t, 6, chunks(t))
+
+		// changing the primary key keeps the values
+		exec(t, `UPDATE test SET a = a + 10`)
+		require.Equal(t, 6, chunks(t))
+		require.Equal(t, blob, read(t, "test", "b", 11))
+
+		// replaced values are deleted
+		exec(t, `UPDATE test SET b = '\x01' WHERE a = 11`)
+		require.Equal(t, 2, chunks(t))
+
+		exec(t, `UPDATE test SET b = $1 WHERE a = 11`, blob)
+		require.Equal(t, 6, chunks(t))
+	})
+
+	t.Run("rollback", func(t *testing.T) {
+		exec(t, `BEGIN`)
+		exec(t, `DELETE FROM test`)
+		exec(t, `ROLLBACK`)
+		require.Equal(t, 6, chunks(t))
+
+		exec(t, `BEGIN`)
+		exec(t, `SAVEPOINT sp`)
+		exec(t, `DELETE FROM test WHERE a = 11`)
+		exec(t, `ROLLBACK TO SAVEPOINT sp`)
+		exec(t, `COMMIT`)
+		require.Equal(t, 6, chunks(t))
+		require.Equal(t, []byte(text), read(t, "test", "c", 11))
+	})
+
+	t.Run("copy", func(t *testing.T) {
+		exec(t, `CREATE TABLE other(a INT PRIMARY KEY, b BYTEA, c TEXT, d INT)`)
+		exec(t, `INSERT INTO other SELECT * FROM test`)
+		require.Equal(t, 12, chunks(t))
+		require.Equal(t, blob, read(t, "other", "b", 11))
+
+		exec(t, `DROP TABLE other`)
+		require.Equal(t, 6, chunks(t))
+		require.Equal(t, blob, read(t, "test", "b", 11))
+	})
+
+	t.Run("missing chunk", func(t *testing.T) {
+		exec(t, `BEGIN`)
+		defer exec(t, `ROLLBACK`)
+
+		tr := tree.New(conn.GetTx().Session, database.LargeValueNamespace, 0)
+		it, err := tr.Iterator(nil)
+		require.NoError(t, err)
+		require.True(t, it.First())
+		k := tree.NewEncodedKey(bytes.Clone(it.Key().Encoded))
+		require.NoError(t, it.Close())
+		require.NoError(t, tr.Delete(k))
+
+		pq, err := parser.ParseQuery(`SELECT * FROM test`)
+		require.NoError(t, err)
+		res, err := query.New(pq...).Run(&query.Context{Ctx: context.Background(), DB: db, Conn: conn})
+		require.NoError(t, err)
+		defer res.Close()
+
+		// the error is returned when the row is read
+		err = res.Iterate(func(r database.Row) error {
+			return r.Iterate(func(string, types.Value) error { return nil })
+		})
+		require.ErrorContains(t, err, "failed to read large value")
+	})
+
+	t.Run("io.Reader", func(t *testing.T) {
+		exec(t, `INSERT INTO test (a, b) VALUES (20, $1)`, bytes.NewReader(blob))
+		require.Equal(t, 10, chunks(t))
+		require.Equal(t, blob, read(t, "test", "b", 20))
+
+		// small values are stored in the row
+		exec(t, `INSERT INTO test (a, b) VALUES (21, $1)`, strings.NewReader("hello"))
+		require.Equal(t, 10, chunks(t))
+		require.Equal(t, []byte("hello"), read(t, "test", "b", 21))
+
+		// values that are not stored are discarded
+		exec(t, `UPDATE test SET b = $1 WHERE a = 100`, bytes.NewReader(blob))
+		require.Equal(t, 10, chunks(t))
+	})
+}
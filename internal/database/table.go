@@ -2,6 +2,7 @@ package database
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/chaisql/chai/internal/engine"
 	errs "github.com/chaisql/chai/internal/errors"
@@ -23,9 +24,52 @@ type Table struct {
 
 // Truncate deletes all the objects from the table.
 func (t *Table) Truncate() error {
+	if t.storesLargeValues() {
+		err := t.releaseLargeValues()
+		if err != nil {
+			return err
+		}
+	}
+
 	return t.Tree.Truncate()
 }
 
+// storesLargeValues returns whether the rows of the table may reference
+// values stored outside of the row.
+func (t *Table) storesLargeValues() bool {
+	if t.Tx.db == nil || strings.HasPrefix(t.Info.TableName, InternalPrefix) {
+		return false
+	}
+
+	for _, cc := range t.Info.ColumnConstraints.Ordered {
+		if cc.Type == types.TypeText || cc.Type == types.TypeBytea {
+			return true
+		}
+	}
+
+	return false
+}
+
+// releaseLargeValues releases the large values referenced by all the rows of the table.
+func (t *Table) releaseLargeValues() error {
+	it, err := t.Tree.Iterator(nil)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.First(); it.Valid(); it.Next() {
+		enc, err := it.Value()
+		if err != nil {
+			return err
+		}
+
+		largeValueRefs(enc, t.Tx.releaseLargeValue)
+	}
+
+	return it.Error()
+}
+
 // Insert the object into the table.
 // If a primary key has been specified during the table creation, the field is expected to be present
 // in the given row.
@@ -48,7 +92,20 @@ func (t *Table) Insert(r row.Row) (*tree.Key, Row, error) {
 
 	// insert into the table
 	err = t.Tree.Insert(key, enc)
+	if errors.Is(err, engine.ErrKeyAlreadyExists) {
+		var deleted bool
+		deleted, err = t.DeleteIfExpired(key)
+		if err == nil {
+			err = engine.ErrKeyAlreadyExists
+			if deleted {
+				err = t.Tree.Insert(key, enc)
+			}
+		}
+	}
 	if err != nil {
+		// the large values are not referenced by the row
+		largeValueRefs(enc, t.Tx.releaseLargeValue)
+
 		if errors.Is(err, engine.ErrKeyAlreadyExists) {
 			return nil, nil, &ConstraintViolationError{
 				Constraint: "PRIMARY KEY",
@@ -60,6 +117,11 @@ func (t *Table) Insert(r row.Row) (*tree.Key, Row, error) {
 		return nil, nil, errors.Wrapf(err, "failed to insert row %q", key)
 	}
 
+	err = t.Tx.logChange(t.Info.TableName, ChangeInsert, key, nil, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	return key, &BasicRow{
 		tableName: t.Info.TableName,
 		Row:       r,
@@ -68,18 +130,42 @@ func (t *Table) Insert(r row.Row) (*tree.Key, Row, error) {
 }
 
 func (t *Table) encodeRow(r row.Row) (row.Row, []byte, error) {
+	var enc []byte
+
 	ed, ok := r.(*EncodedRow)
 	// pointer comparison is enough here
 	if ok && ed.columnConstraints == &t.Info.ColumnConstraints {
-		return r, ed.encoded, nil
+		enc = ed.encoded
+	} else {
+		var err error
+		enc, err = t.Info.EncodeRow(t.Tx, nil, r)
+		if err != nil {
+			return nil, nil, err
+		}
+		ed = nil
 	}
 
-	dst, err := t.Info.EncodeRow(t.Tx, nil, r)
-	if err != nil {
-		return nil, nil, err
+	if t.storesLargeValues() {
+		dst, err := t.Tx.storeLargeValues(enc)
+		if err != nil {
+			return nil, nil, err
+		}
+		if dst != nil {
+			ed, enc = nil, dst
+		}
+	}
+
+	if ed != nil {
+		return r, enc, nil
 	}
 
-	return NewEncodedRow(&t.Info.ColumnConstraints, dst), dst, nil
+	return t.newEncodedRow(enc), enc, nil
+}
+
+func (t *Table) newEncodedRow(enc []byte) *EncodedRow {
+	r := NewEncodedRow(&t.Info.ColumnConstraints, enc)
+	r.tx = t.Tx
+	return r
 }
 
 // Delete a object by key.
@@ -88,6 +174,26 @@ func (t *Table) Delete(key *tree.Key) error {
 		return errors.New("cannot write to read-only table")
 	}
 
+	if t.Tx.capturesChanges(t.Info.TableName) {
+		before, err := t.GetRow(key)
+		if err != nil {
+			return err
+		}
+
+		// the change is only committed along with the deletion
+		err = t.Tx.logChange(t.Info.TableName, ChangeDelete, key, before, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.storesLargeValues() {
+		_, err := t.releaseRowLargeValues(key)
+		if err != nil {
+			return err
+		}
+	}
+
 	err := t.Tree.Delete(key)
 	if errors.Is(err, engine.ErrKeyNotFound) {
 		return errs.NewNotFoundError(key.String())
@@ -96,6 +202,22 @@ func (t *Table) Delete(key *tree.Key) error {
 	return err
 }
 
+// releaseRowLargeValues releases the large values referenced by the row
+// stored under the given key, if any, before it is deleted or replaced.
+// It returns the encoded row.
+func (t *Table) releaseRowLargeValues(key *tree.Key) ([]byte, error) {
+	enc, err := t.Tree.Get(key)
+	if err != nil {
+		if errors.Is(err, engine.ErrKeyNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	largeValueRefs(enc, t.Tx.releaseLargeValue)
+	return enc, nil
+}
+
 // Replace a row by key.
 // An error is returned if the key doesn't exist.
 func (t *Table) Replace(key *tree.Key, r row.Row) (Row, error) {
@@ -127,11 +249,31 @@ func (t *Table) Put(key *tree.Key, r row.Row) (Row, error) {
 		return nil, errors.New("cannot write to read-only table")
 	}
 
+	// the large values of the previous version of the row
+	// can be claimed by the new one
+	var old []byte
+	if t.storesLargeValues() {
+		var err error
+		old, err = t.releaseRowLargeValues(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	r, enc, err := t.encodeRow(r)
 	if err != nil {
+		// the previous version of the row is kept
+		largeValueRefs(old, func(id uint64) { t.Tx.claimLargeValue(id) })
 		return nil, err
 	}
 
+	if t.Tx.capturesChanges(t.Info.TableName) {
+		err = t.logPut(key, r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// replace old row with new row
 	err = t.Tree.Put(key, enc)
 	if err != nil {
@@ -145,6 +287,20 @@ func (t *Table) Put(key *tree.Key, r row.Row) (Row, error) {
 	}, nil
 }
 
+// logPut records the change made by Put, which is an update
+// if the key already exists and an insert otherwise.
+func (t *Table) logPut(key *tree.Key, r row.Row) error {
+	before, err := t.GetRow(key)
+	if err != nil && !errs.IsNotFoundError(err) {
+		return err
+	}
+	if before == nil {
+		return t.Tx.logChange(t.Info.TableName, ChangeInsert, key, nil, r)
+	}
+
+	return t.Tx.logChange(t.Info.TableName, ChangeUpdate, key, before, r)
+}
+
 func (t *Table) Iterator(rng *Range) (*TableIterator, error) {
 	var columns []string
 
@@ -168,7 +324,7 @@ func (t *Table) Iterator(rng *Range) (*TableIterator, error) {
 		return nil, err
 	}
 
-	return newIterator(it, t.Info.TableName, &t.Info.ColumnConstraints), nil
+	return newIterator(t.Tx, it, t.Info.TableName, &t.Info.ColumnConstraints), nil
 }
 
 // GetRow returns one row by key.
@@ -183,7 +339,7 @@ func (t *Table) GetRow(key *tree.Key) (Row, error) {
 
 	return &BasicRow{
 		tableName: t.Info.TableName,
-		Row:       NewEncodedRow(&t.Info.ColumnConstraints, enc),
+		Row:       t.newEncodedRow(enc),
 		key:       key,
 	}, nil
 }
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"strings"
 	"sync"
 	"time"
 
@@ -32,6 +33,25 @@ type Transaction struct {
 
 	Catalog       *Catalog
 	catalogWriter *CatalogWriter
+
+	savepoints []savepoint
+
+	// cursor of the last event written to the changelog by this transaction,
+	// or 0 if no event was written.
+	changelogCursor uint64
+
+	// large values that are not referenced by any row.
+	// They are deleted when the transaction is committed.
+	unownedLargeValues map[uint64]struct{}
+}
+
+// savepoint marks a point of the transaction that can be rolled back to.
+type savepoint struct {
+	name       string
+	checkpoint int
+	// number of hooks registered when the savepoint was created.
+	rollbackHooks int
+	commitHooks   int
 }
 
 func (tx *Transaction) Connection() *Connection {
@@ -75,7 +95,12 @@ func (tx *Transaction) Commit() error {
 	tx.db.txmu.Lock()
 	defer tx.db.txmu.Unlock()
 
-	err := tx.Session.Commit()
+	err := tx.deleteUnownedLargeValues()
+	if err != nil {
+		return err
+	}
+
+	err = tx.Session.Commit()
 	if err != nil {
 		return err
 	}
@@ -111,3 +136,91 @@ func (tx *Transaction) CatalogWriter() *CatalogWriter {
 
 	return tx.catalogWriter
 }
+
+// Savepoint creates a savepoint with the given name.
+// If a savepoint with the same name already exists, it is kept
+// but the new one takes precedence until it is released.
+func (tx *Transaction) Savepoint(name string) error {
+	sp := savepoint{
+		name:          name,
+		checkpoint:    -1,
+		rollbackHooks: len(tx.OnRollbackHooks),
+		commitHooks:   len(tx.OnCommitHooks),
+	}
+
+	// read-only transactions have nothing to roll back
+	if tx.Writable {
+		cs, ok := tx.Session.(engine.CheckpointSession)
+		if !ok {
+			return errors.New("savepoints are not supported by this session")
+		}
+
+		id, err := cs.Checkpoint()
+		if err != nil {
+			return err
+		}
+		sp.checkpoint = id
+	}
+
+	tx.savepoints = append(tx.savepoints, sp)
+	return nil
+}
+
+// RollbackToSavepoint discards all the changes made after the given savepoint.
+// The savepoint remains valid and can be rolled back to again, while the savepoints
+// created after it are destroyed.
+func (tx *Transaction) RollbackToSavepoint(name string) error {
+	i, err := tx.getSavepoint(name)
+	if err != nil {
+		return err
+	}
+	sp := tx.savepoints[i]
+
+	if sp.checkpoint >= 0 {
+		err = tx.Session.(engine.CheckpointSession).RollbackTo(sp.checkpoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	// revert the in-memory changes made after the savepoint
+	for j := len(tx.OnRollbackHooks) - 1; j >= sp.rollbackHooks; j-- {
+		tx.OnRollbackHooks[j]()
+	}
+	tx.OnRollbackHooks = tx.OnRollbackHooks[:sp.rollbackHooks]
+	tx.OnCommitHooks = tx.OnCommitHooks[:sp.commitHooks]
+
+	tx.savepoints = tx.savepoints[:i+1]
+	return nil
+}
+
+// ReleaseSavepoint destroys the given savepoint and the ones created after it.
+// The changes made after the savepoint are kept.
+func (tx *Transaction) ReleaseSavepoint(name string) error {
+	i, err := tx.getSavepoint(name)
+	if err != nil {
+		return err
+	}
+	sp := tx.savepoints[i]
+
+	if sp.checkpoint >= 0 {
+		err = tx.Session.(engine.CheckpointSession).Release(sp.checkpoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	tx.savepoints = tx.savepoints[:i]
+	return nil
+}
+
+// getSavepoint returns the position of the most recent savepoint with the given name.
+func (tx *Transaction) getSavepoint(name string) (int, error) {
+	for i := len(tx.savepoints) - 1; i >= 0; i-- {
+		if strings.EqualFold(tx.savepoints[i].name, name) {
+			return i, nil
+		}
+	}
+
+	return 0, errors.Errorf("savepoint %q does not exist", name)
+}
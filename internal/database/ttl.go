@@ -0,0 +1,262 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	errs "github.com/chaisql/chai/internal/errors"
+	"github.com/chaisql/chai/internal/tree"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	// defaultTTLInterval is the default interval between two
+	// runs of the expiration worker.
+	defaultTTLInterval = time.Minute
+
+	// ttlBatchSize is the maximum number of expired rows
+	// deleted by a single transaction.
+	ttlBatchSize = 100
+)
+
+// IsExpired returns whether the row has expired when the transaction started.
+func (t *Table) IsExpired(r Row) (bool, error) {
+	return t.Info.IsExpired(r, t.Tx.TxStart)
+}
+
+// DeleteIfExpired deletes the row with the given key and its index entries
+// if it has expired. Expired rows are hidden from queries but only deleted
+// periodically, so they are deleted before their key or unique values
+// are reused, instead of reporting a conflict.
+// It returns whether the row was deleted.
+func (t *Table) DeleteIfExpired(key *tree.Key) (bool, error) {
+	if t.Info.TTLColumn == "" {
+		return false, nil
+	}
+
+	r, err := t.GetRow(key)
+	if err != nil {
+		return false, err
+	}
+
+	expired, err := t.IsExpired(r)
+	if err != nil || !expired {
+		return false, err
+	}
+
+	indexes := t.Tx.Catalog.Cache.GetTableIndexes(t.Info.TableName)
+	return true, t.deleteRow(indexes, key)
+}
+
+// startTTLWorker starts a goroutine that periodically deletes the expired rows,
+// until the database is closed.
+func (db *Database) startTTLWorker(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultTTLInterval
+	}
+
+	db.ttlWg.Add(1)
+	go func() {
+		defer db.ttlWg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-db.closeContext.Done():
+				return
+			case <-ticker.C:
+			}
+
+			// errors are ignored, the remaining rows
+			// will be deleted by the next run.
+			_, _ = db.DeleteExpiredRows(db.closeContext)
+		}
+	}()
+}
+
+// DeleteExpiredRows deletes the expired rows of every table with a TTL column,
+// using small write transactions so that other writers are not blocked for long.
+// It returns the number of deleted rows.
+func (db *Database) DeleteExpiredRows(ctx context.Context) (int, error) {
+	var total int
+
+	for _, tableName := range db.ttlTables() {
+		for {
+			if err := ctx.Err(); err != nil {
+				return total, err
+			}
+
+			n, err := db.deleteExpiredBatch(tableName)
+			total += n
+			if err != nil {
+				return total, err
+			}
+			if n < ttlBatchSize {
+				break
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// ttlTables returns the list of tables with a TTL column.
+func (db *Database) ttlTables() []string {
+	c := db.Catalog()
+
+	var tables []string
+	for _, name := range c.Cache.ListObjects(RelationTableType) {
+		info, err := c.GetTableInfo(name)
+		if err == nil && info.TTLColumn != "" {
+			tables = append(tables, name)
+		}
+	}
+
+	return tables
+}
+
+// deleteExpiredBatch deletes at most ttlBatchSize expired rows
+// of the given table in a single transaction.
+func (db *Database) deleteExpiredBatch(tableName string) (int, error) {
+	tx, err := db.begin(true)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	table, err := tx.Catalog.GetTable(tx, tableName)
+	if err != nil {
+		// the table was dropped in the meantime
+		if errs.IsNotFoundError(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	keys, err := table.expiredKeys(ttlBatchSize)
+	if err != nil || len(keys) == 0 {
+		return 0, err
+	}
+
+	indexes := tx.Catalog.Cache.GetTableIndexes(tableName)
+	for _, key := range keys {
+		err = table.deleteRow(indexes, key)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), tx.Commit()
+}
+
+// expiredKeys returns the keys of at most n expired rows.
+// If an index starts with the TTL column, it is used to find them,
+// otherwise the table is scanned.
+func (t *Table) expiredKeys(n int) ([]*tree.Key, error) {
+	if t.Info.TTLColumn == "" {
+		return nil, nil
+	}
+
+	for _, info := range t.Tx.Catalog.Cache.GetTableIndexes(t.Info.TableName) {
+		if info.Columns[0] != t.Info.TTLColumn || info.KeySortOrder.IsDesc(0) {
+			continue
+		}
+
+		idx, err := t.Tx.Catalog.GetIndex(t.Tx, info.IndexName)
+		if err != nil {
+			return nil, err
+		}
+
+		return expiredKeysFromIndex(idx, t.Tx.TxStart, n)
+	}
+
+	it, err := t.Iterator(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var keys []*tree.Key
+	for it.First(); it.Valid() && len(keys) < n; it.Next() {
+		r, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+
+		expired, err := t.IsExpired(r)
+		if err != nil {
+			return nil, err
+		}
+		if expired {
+			// the key is only valid until the iterator moves
+			keys = append(keys, tree.NewEncodedKey(bytes.Clone(r.Key().Encoded)))
+		}
+	}
+
+	return keys, it.Error()
+}
+
+// expiredKeysFromIndex returns the keys of at most n rows whose
+// indexed timestamp is not after now. NULL values are ignored.
+func expiredKeysFromIndex(idx *Index, now time.Time, n int) ([]*tree.Key, error) {
+	it, err := idx.Iterator(&tree.Range{
+		Max: tree.NewKey(types.NewTimestampValue(now)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	var keys []*tree.Key
+	for it.First(); it.Valid() && len(keys) < n; it.Next() {
+		key, err := it.Value()
+		if err != nil {
+			return nil, err
+		}
+
+		// the key is only valid until the iterator moves
+		keys = append(keys, tree.NewEncodedKey(bytes.Clone(key.Encoded)))
+	}
+
+	return keys, it.Error()
+}
+
+// deleteRow deletes a row and its entries from the given indexes.
+func (t *Table) deleteRow(indexes []*IndexInfo, key *tree.Key) error {
+	r, err := t.GetRow(key)
+	if err != nil {
+		return err
+	}
+
+	encKey, err := t.Info.EncodeKey(key)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range indexes {
+		idx, err := t.Tx.Catalog.GetIndex(t.Tx, info.IndexName)
+		if err != nil {
+			return err
+		}
+
+		vs := make([]types.Value, 0, len(info.Columns))
+		for _, column := range info.Columns {
+			v, err := r.Get(column)
+			if err != nil {
+				v = types.NewNullValue()
+			}
+			vs = append(vs, v)
+		}
+
+		err = idx.Delete(vs, encKey)
+		if err != nil {
+			return errors.Wrap(err, "error while deleting index value")
+		}
+	}
+
+	return t.Delete(key)
+}
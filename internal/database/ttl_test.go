@@ -0,0 +1,124 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/database/catalogstore"
+	"github.com/chaisql/chai/internal/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteExpiredRows(t *testing.T) {
+	insertRows := func(t *testing.T, db *database.Database) {
+		var values []string
+		for i := 0; i < 250; i++ {
+			values = append(values, fmt.Sprintf("(%d, '2000-01-01')", i))
+		}
+		values = append(values, "(1000, '2100-01-01')", "(1001, NULL)")
+
+		conn := testutil.NewTestConn(t, db)
+		tx, err := conn.BeginTx(&database.TxOptions{})
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		testutil.MustExec(t, db, tx, "INSERT INTO test (a, b) VALUES "+strings.Join(values, ", "))
+		require.NoError(t, tx.Commit())
+	}
+
+	count := func(t *testing.T, db *database.Database, q string) int {
+		conn := testutil.NewTestConn(t, db)
+		tx, err := conn.BeginTx(&database.TxOptions{ReadOnly: true})
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		var n int
+		err = testutil.MustQuery(t, db, tx, q).Iterate(func(r database.Row) error {
+			n++
+			return nil
+		})
+		require.NoError(t, err)
+		return n
+	}
+
+	for _, withIndex := range []bool{false, true} {
+		t.Run(fmt.Sprintf("index=%v", withIndex), func(t *testing.T) {
+			db := testutil.NewTestDB(t)
+
+			conn := testutil.NewTestConn(t, db)
+			tx, err := conn.BeginTx(&database.TxOptions{})
+			require.NoError(t, err)
+			testutil.MustExec(t, db, tx, "CREATE TABLE test(a INT PRIMARY KEY, b TIMESTAMP) WITH (ttl_column = b)")
+			if withIndex {
+				testutil.MustExec(t, db, tx, "CREATE INDEX test_b_idx ON test(b)")
+			}
+			require.NoError(t, tx.Commit())
+
+			insertRows(t, db)
+
+			n, err := db.DeleteExpiredRows(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 250, n)
+
+			// nothing left to delete
+			n, err = db.DeleteExpiredRows(context.Background())
+			require.NoError(t, err)
+			require.Equal(t, 0, n)
+
+			// expired rows are no longer stored, nor indexed
+			tx, err = conn.BeginTx(&database.TxOptions{})
+			require.NoError(t, err)
+			testutil.MustExec(t, db, tx, "ALTER TABLE test DROP TTL")
+			require.NoError(t, tx.Commit())
+
+			require.Equal(t, 2, count(t, db, "SELECT * FROM test"))
+			require.Equal(t, 1, count(t, db, "SELECT * FROM test WHERE b < '2200-01-01'"))
+		})
+	}
+
+	t.Run("worker", func(t *testing.T) {
+		db, err := database.Open(":memory:", &database.Options{
+			CatalogLoader: catalogstore.LoadCatalog,
+			TTLInterval:   10 * time.Millisecond,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+
+		conn := testutil.NewTestConn(t, db)
+		tx, err := conn.BeginTx(&database.TxOptions{})
+		require.NoError(t, err)
+		testutil.MustExec(t, db, tx, "CREATE TABLE test(a INT PRIMARY KEY, b TIMESTAMP) WITH (ttl_column = b)")
+		require.NoError(t, tx.Commit())
+
+		insertRows(t, db)
+
+		// count the stored rows, including the expired ones
+		stored := func() int {
+			tx, err := conn.BeginTx(&database.TxOptions{ReadOnly: true})
+			require.NoError(t, err)
+			defer tx.Rollback()
+
+			table, err := tx.Catalog.GetTable(tx, "test")
+			require.NoError(t, err)
+
+			it, err := table.Iterator(nil)
+			require.NoError(t, err)
+			defer it.Close()
+
+			var n int
+			for it.First(); it.Valid(); it.Next() {
+				n++
+			}
+			require.NoError(t, it.Error())
+			return n
+		}
+
+		require.Eventually(t, func() bool {
+			return stored() == 2
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
@@ -40,3 +40,54 @@ func DecodeText(b []byte) (string, int) {
 	b = b[n : n+int(l)]
 	return string(b), 1 + n + int(l)
 }
+
+// UUIDLen is the size in bytes of an encoded UUID, without the type.
+const UUIDLen = 16
+
+// EncodeUUID encodes a 16 bytes UUID. The bytes are written as is,
+// which preserves the byte-wise ordering of UUIDs.
+func EncodeUUID(dst []byte, x [UUIDLen]byte) []byte {
+	dst = append(dst, UUIDValue)
+	return append(dst, x[:]...)
+}
+
+func DecodeUUID(b []byte) ([UUIDLen]byte, int) {
+	var x [UUIDLen]byte
+	copy(x[:], b[1:1+UUIDLen])
+	return x, 1 + UUIDLen
+}
+
+// EncodeLargeValue encodes a reference to a text or binary value
+// stored outside of the row. typ is the type of the value, either
+// TextValue or ByteaValue, size its length in bytes and id the identifier
+// of the stored value.
+func EncodeLargeValue(dst []byte, typ byte, size, id uint64) []byte {
+	dst = append(dst, LargeValue, typ)
+	dst = binary.AppendUvarint(dst, size)
+	return binary.AppendUvarint(dst, id)
+}
+
+// EncodeCompressedValue encodes a compressed value. codec identifies the
+// compression algorithm and data is the compressed form of the encoded value.
+func EncodeCompressedValue(dst []byte, codec byte, data []byte) []byte {
+	dst = append(dst, CompressedValue, codec)
+	dst = binary.AppendUvarint(dst, uint64(len(data)))
+	return append(dst, data...)
+}
+
+func DecodeCompressedValue(b []byte) (codec byte, data []byte, n int) {
+	codec = b[1]
+	n = 2
+	l, m := binary.Uvarint(b[n:])
+	n += m
+	return codec, b[n : n+int(l)], n + int(l)
+}
+
+func DecodeLargeValue(b []byte) (typ byte, size, id uint64, n int) {
+	typ = b[1]
+	n = 2
+	size, m := binary.Uvarint(b[n:])
+	n += m
+	id, m = binary.Uvarint(b[n:])
+	return typ, size, id, n + m
+}
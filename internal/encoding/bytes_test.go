@@ -57,3 +57,49 @@ func TestEncodeDecodeBytea(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeDecodeUUID(t *testing.T) {
+	x := [16]byte{0x55, 0x0e, 0x84, 0x00, 0xe2, 0x9b, 0x41, 0xd4, 0xa7, 0x16, 0x44, 0x66, 0x55, 0x44, 0x00, 0x00}
+
+	got := encoding.EncodeUUID(nil, x)
+	require.Equal(t, append([]byte{encoding.UUIDValue}, x[:]...), got)
+	require.Equal(t, 17, encoding.Skip(got))
+
+	v, n := encoding.DecodeUUID(got)
+	require.Equal(t, x, v)
+	require.Equal(t, 17, n)
+
+	// ordering follows the bytes of the uuid
+	y := x
+	y[15] = 1
+	require.Less(t, encoding.Compare(got, encoding.EncodeUUID(nil, y)), 0)
+	require.Equal(t, 0, encoding.Compare(got, encoding.EncodeUUID(nil, x)))
+}
+
+func TestEncodeDecodeLargeValue(t *testing.T) {
+	got := encoding.EncodeLargeValue(nil, encoding.ByteaValue, 1<<20, 300)
+	// the reference can be skipped like any other value
+	got = encoding.EncodeInt(got, 10)
+
+	typ, size, id, n := encoding.DecodeLargeValue(got)
+	require.Equal(t, encoding.ByteaValue, typ)
+	require.EqualValues(t, 1<<20, size)
+	require.EqualValues(t, 300, id)
+	require.Equal(t, n, encoding.Skip(got))
+
+	x, _ := encoding.DecodeInt(got[n:])
+	require.EqualValues(t, 10, x)
+}
+
+func TestEncodeDecodeCompressedValue(t *testing.T) {
+	got := encoding.EncodeCompressedValue(nil, 2, []byte("compressed"))
+	got = encoding.EncodeInt(got, 10)
+
+	codec, data, n := encoding.DecodeCompressedValue(got)
+	require.EqualValues(t, 2, codec)
+	require.Equal(t, []byte("compressed"), data)
+	require.Equal(t, n, encoding.Skip(got))
+
+	x, _ := encoding.DecodeInt(got[n:])
+	require.EqualValues(t, 10, x)
+}
@@ -60,6 +60,14 @@ func Skip(b []byte) int {
 	case TextValue, ByteaValue, DESC_TextValue, DESC_ByteaValue:
 		l, n := binary.Uvarint(b[1:])
 		return n + int(l) + 1
+	case UUIDValue, DESC_UUIDValue:
+		return 1 + UUIDLen
+	case LargeValue:
+		_, _, _, n := DecodeLargeValue(b)
+		return n
+	case CompressedValue:
+		_, _, n := DecodeCompressedValue(b)
+		return n
 	case ArrayValue, DESC_ArrayValue:
 		return 1 + SkipArray(b[1:])
 	case ObjectValue, DESC_ObjectValue:
@@ -201,6 +209,8 @@ func compareNonEmptyValues(t byte, a, b []byte) (cmp int, n int) {
 		n++
 		endb := n + int(l)
 		return bytes.Compare(a[n:enda], b[n:endb]), enda
+	case UUIDValue:
+		return bytes.Compare(a[1:1+UUIDLen], b[1:1+UUIDLen]), 1 + UUIDLen
 	case ArrayValue:
 		la, na := binary.Uvarint(a[1:])
 		lb, nb := binary.Uvarint(b[1:])
@@ -354,6 +364,15 @@ func abbreviatedValue(key []byte) uint64 {
 			abbv |= uint64(key[i]) << (32 - uint64(i)*8)
 		}
 		return abbv
+	case UUIDValue:
+		if len(key) < 6 {
+			return 0
+		}
+		var abbv uint64
+		for i := 0; i < 5; i++ {
+			abbv |= uint64(key[1+i]) << (32 - uint64(i)*8)
+		}
+		return abbv
 	case ArrayValue, ObjectValue:
 		key = key[1:]
 		l, n := binary.Uvarint(key)
@@ -56,7 +56,18 @@ const (
 	// Binary
 	ByteaValue byte = 103
 
-	// 104 to 109: 6 types are free
+	// Reference to a text or binary value stored
+	// outside of the row. Never used in keys.
+	LargeValue byte = 104
+
+	// Text or binary value compressed in its row.
+	// Never used in keys.
+	CompressedValue byte = 105
+
+	// UUID
+	UUIDValue byte = 106
+
+	// 107 to 109: 3 types are free
 
 	// Arrays
 	ArrayValue byte = 110
@@ -74,6 +85,7 @@ const (
 	// DESC_ prefix means that the value is encoded in reverse order.
 	DESC_ObjectValue   byte = 255 - ObjectValue
 	DESC_ArrayValue    byte = 255 - ArrayValue
+	DESC_UUIDValue     byte = 255 - UUIDValue
 	DESC_ByteaValue    byte = 255 - ByteaValue
 	DESC_TextValue     byte = 255 - TextValue
 	DESC_Float64Value  byte = 255 - Float64Value
@@ -1,6 +1,10 @@
 package engine
 
-import "github.com/cockroachdb/errors"
+import (
+	"context"
+
+	"github.com/cockroachdb/errors"
+)
 
 // Common errors returned by the engine.
 var (
@@ -21,6 +25,12 @@ type Engine interface {
 	NewTransientSession() Session
 }
 
+// A Backuper is an engine that can copy its content
+// to a directory while it is being used.
+type Backuper interface {
+	Backup(ctx context.Context, dir string) error
+}
+
 type Session interface {
 	Commit() error
 	Close() error
@@ -38,6 +48,20 @@ type Session interface {
 	Iterator(opts *IterOptions) (Iterator, error)
 }
 
+// A CheckpointSession is a session that can discard the changes made
+// after a given point of the session, without discarding the whole session.
+type CheckpointSession interface {
+	Session
+	// Checkpoint marks the current state of the session and returns the checkpoint id.
+	Checkpoint() (int, error)
+	// RollbackTo discards all the changes made after the given checkpoint.
+	// The checkpoint remains valid, while the checkpoints created after it are removed.
+	RollbackTo(id int) error
+	// Release removes the given checkpoint and the ones created after it,
+	// keeping the changes.
+	Release(id int) error
+}
+
 type Iterator interface {
 	Close() error
 	First() bool
@@ -59,6 +59,16 @@ func (e *Environment) GetParamByIndex(pos int) (types.Value, error) {
 	return row.NewValue(e.params[idx].Value)
 }
 
+func (e *Environment) GetParamByName(name string) (types.Value, error) {
+	for _, p := range e.params {
+		if p.Name == name {
+			return row.NewValue(p.Value)
+		}
+	}
+
+	return nil, fmt.Errorf("cannot find param named %q", name)
+}
+
 func (e *Environment) GetTx() *database.Transaction {
 	return e.tx
 }
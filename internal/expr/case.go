@@ -0,0 +1,182 @@
+package expr
+
+import (
+	"strings"
+
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/types"
+)
+
+// A WhenClause is a WHEN ... THEN ... branch of a CASE expression.
+type WhenClause struct {
+	Cond Expr
+	Then Expr
+}
+
+// CaseExpr represents a CASE expression.
+// If Operand is nil, it evaluates the condition of each branch in order
+// and returns the result of the first one that is truthy:
+//
+//	CASE WHEN a > 10 THEN 'big' WHEN a > 5 THEN 'medium' ELSE 'small' END
+//
+// Otherwise, the operand is compared with the value of each branch
+// and the result of the first one that is equal is returned:
+//
+//	CASE a WHEN 1 THEN 'one' WHEN 2 THEN 'two' END
+//
+// If no branch matches, it returns the result of the ELSE clause, or NULL.
+type CaseExpr struct {
+	Operand Expr
+	Whens   []WhenClause
+	Else    Expr
+	// Type is the common type of the results, set by the planner.
+	// Results are converted to it, unless it is TypeAny.
+	Type types.Type
+}
+
+// Eval evaluates the branches in order and returns the result
+// of the first matching branch.
+func (c *CaseExpr) Eval(env *environment.Environment) (types.Value, error) {
+	var operand types.Value
+	if c.Operand != nil {
+		var err error
+		operand, err = c.Operand.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, w := range c.Whens {
+		v, err := w.Cond.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+
+		ok, err := c.matches(operand, v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return c.result(env, w.Then)
+		}
+	}
+
+	if c.Else != nil {
+		return c.result(env, c.Else)
+	}
+
+	return NullLiteral, nil
+}
+
+// result evaluates e and converts its value to the type of the CASE expression.
+func (c *CaseExpr) result(env *environment.Environment, e Expr) (types.Value, error) {
+	v, err := e.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if c.Type.IsAny() || v.Type() == types.TypeNull || v.Type() == c.Type {
+		return v, nil
+	}
+
+	return v.CastAs(c.Type)
+}
+
+// matches returns whether v satisfies the branch condition.
+// Like with the = operator, NULL never equals anything.
+func (c *CaseExpr) matches(operand, v types.Value) (bool, error) {
+	if c.Operand == nil {
+		return types.IsTruthy(v)
+	}
+
+	if operand.Type() == types.TypeNull || v.Type() == types.TypeNull {
+		return false, nil
+	}
+
+	return operand.EQ(v)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (c *CaseExpr) IsEqual(other Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*CaseExpr)
+	if !ok {
+		return false
+	}
+
+	if len(c.Whens) != len(o.Whens) {
+		return false
+	}
+
+	if (c.Operand == nil) != (o.Operand == nil) || (c.Operand != nil && !Equal(c.Operand, o.Operand)) {
+		return false
+	}
+
+	if (c.Else == nil) != (o.Else == nil) || (c.Else != nil && !Equal(c.Else, o.Else)) {
+		return false
+	}
+
+	for i := range c.Whens {
+		if !Equal(c.Whens[i].Cond, o.Whens[i].Cond) || !Equal(c.Whens[i].Then, o.Whens[i].Then) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Params returns the operand, the conditions and results of each branch
+// and the ELSE clause, in that order.
+func (c *CaseExpr) Params() []Expr {
+	var params []Expr
+	if c.Operand != nil {
+		params = append(params, c.Operand)
+	}
+	for _, w := range c.Whens {
+		params = append(params, w.Cond, w.Then)
+	}
+	if c.Else != nil {
+		params = append(params, c.Else)
+	}
+
+	return params
+}
+
+// Results returns the expressions that can be returned by the CASE expression.
+func (c *CaseExpr) Results() []Expr {
+	results := make([]Expr, 0, len(c.Whens)+1)
+	for _, w := range c.Whens {
+		results = append(results, w.Then)
+	}
+	if c.Else != nil {
+		results = append(results, c.Else)
+	}
+
+	return results
+}
+
+func (c *CaseExpr) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("CASE")
+	if c.Operand != nil {
+		sb.WriteString(" ")
+		sb.WriteString(c.Operand.String())
+	}
+	for _, w := range c.Whens {
+		sb.WriteString(" WHEN ")
+		sb.WriteString(w.Cond.String())
+		sb.WriteString(" THEN ")
+		sb.WriteString(w.Then.String())
+	}
+	if c.Else != nil {
+		sb.WriteString(" ELSE ")
+		sb.WriteString(c.Else.String())
+	}
+	sb.WriteString(" END")
+
+	return sb.String()
+}
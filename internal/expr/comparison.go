@@ -134,7 +134,7 @@ func (op *BetweenOperator) String() string {
 // =, !=, >, >=, <, <=, IS, IS NOT, IN, or NOT IN operators.
 func IsComparisonOperator(op Operator) bool {
 	switch op.(type) {
-	case *cmpOp, *IsOperator, *IsNotOperator, *InOperator, *NotInOperator, *LikeOperator, *NotLikeOperator, *BetweenOperator:
+	case *cmpOp, *IsOperator, *IsNotOperator, *InOperator, *NotInOperator, *LikeOperator, *NotLikeOperator, *RegexOperator, *NotRegexOperator, *BetweenOperator:
 		return true
 	}
 
@@ -0,0 +1,864 @@
+package functions
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/expr"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+// aggregateResult returns the result of an aggregate function
+// computed by the GroupAggregate operator, which stores it in the
+// current row under the name of the function.
+func aggregateResult(env *environment.Environment, fn expr.Expr) (types.Value, error) {
+	r, ok := env.GetRow()
+	if !ok {
+		name, _, _ := strings.Cut(fn.String(), "(")
+		return nil, errors.Errorf("misuse of aggregation function %s()", name)
+	}
+
+	return r.Get(fn.String())
+}
+
+// evalAggregateArg evaluates the argument of an aggregate function,
+// treating missing columns as NULL.
+func evalAggregateArg(env *environment.Environment, e expr.Expr) (types.Value, error) {
+	v, err := e.Eval(env)
+	if err != nil && !errors.Is(err, types.ErrColumnNotFound) {
+		return nil, err
+	}
+	if v == nil {
+		return types.NewNullValue(), nil
+	}
+
+	return v, nil
+}
+
+// cloneValue copies values that may reference the buffer of the current row,
+// so that they can be kept until the end of the aggregation.
+func cloneValue(v types.Value) types.Value {
+	if v.Type() == types.TypeBytea {
+		return types.NewByteaValue(bytes.Clone(types.AsByteSlice(v)))
+	}
+
+	return v
+}
+
+// compareValues returns -1, 0 or 1 depending on whether a is lower, equal
+// or greater than b. NULL values are considered lower than any other value.
+func compareValues(a, b types.Value) (int, error) {
+	an, bn := a.Type() == types.TypeNull, b.Type() == types.TypeNull
+	switch {
+	case an && bn:
+		return 0, nil
+	case an:
+		return -1, nil
+	case bn:
+		return 1, nil
+	}
+
+	lt, err := a.LT(b)
+	if err != nil || lt {
+		return -1, err
+	}
+	gt, err := a.GT(b)
+	if err != nil || gt {
+		return 1, err
+	}
+
+	return 0, nil
+}
+
+// A SortKey is an expression used to sort the input rows
+// of an aggregate function.
+type SortKey struct {
+	Expr expr.Expr
+	Desc bool
+}
+
+func (k SortKey) String() string {
+	if k.Desc {
+		return fmt.Sprintf("%v DESC", k.Expr)
+	}
+
+	return k.Expr.String()
+}
+
+func sortKeysEqual(a, b []SortKey) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Desc != b[i].Desc || !expr.Equal(a[i].Expr, b[i].Expr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sortKeysString(keys []SortKey) string {
+	s := make([]string, len(keys))
+	for i := range keys {
+		s[i] = keys[i].String()
+	}
+
+	return "ORDER BY " + strings.Join(s, ", ")
+}
+
+// An OrderedAggregate is an aggregate function whose result depends on the order
+// of its input. The order is either specified within the arguments of the function,
+// like string_agg(a, ',' ORDER BY b), or using a WITHIN GROUP clause, like
+// percentile_cont(0.5) WITHIN GROUP (ORDER BY a).
+type OrderedAggregate interface {
+	expr.AggregatorBuilder
+
+	SetOrderBy(keys []SortKey, withinGroup bool) error
+}
+
+// sortedRows accumulates values with their sort keys and sorts them on demand.
+type sortedRows[T any] struct {
+	keys   []SortKey
+	values []T
+	sortBy [][]types.Value
+}
+
+func (s *sortedRows[T]) add(env *environment.Environment, v T) error {
+	sortBy := make([]types.Value, len(s.keys))
+	for i, k := range s.keys {
+		kv, err := evalAggregateArg(env, k.Expr)
+		if err != nil {
+			return err
+		}
+		sortBy[i] = cloneValue(kv)
+	}
+
+	s.values = append(s.values, v)
+	s.sortBy = append(s.sortBy, sortBy)
+	return nil
+}
+
+func (s *sortedRows[T]) sort() error {
+	if len(s.keys) == 0 {
+		return nil
+	}
+
+	idx := make([]int, len(s.values))
+	for i := range idx {
+		idx[i] = i
+	}
+
+	var err error
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := s.sortBy[idx[i]], s.sortBy[idx[j]]
+		for k := range s.keys {
+			cmp, cerr := compareValues(a[k], b[k])
+			if cerr != nil {
+				err = cerr
+				return false
+			}
+			if s.keys[k].Desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+
+	values := make([]T, len(idx))
+	for i, n := range idx {
+		values[i] = s.values[n]
+	}
+	s.values = values
+	return nil
+}
+
+// Aggregate wraps an aggregate function call to apply the DISTINCT and FILTER (WHERE ...)
+// clauses. Only the rows that satisfy the filter, and whose arguments haven't been seen before
+// if DISTINCT is set, are passed to the underlying aggregator.
+type Aggregate struct {
+	Fn       expr.AggregatorBuilder
+	Distinct bool
+	Filter   expr.Expr
+}
+
+// NewAggregate returns an Aggregate if e is an aggregate function, or an error otherwise.
+func NewAggregate(e expr.Expr, distinct bool, filter expr.Expr) (*Aggregate, error) {
+	fn, ok := e.(expr.AggregatorBuilder)
+	if !ok {
+		return nil, errors.Errorf("DISTINCT or FILTER specified but %s is not an aggregate function", e)
+	}
+
+	if distinct {
+		f, ok := e.(expr.Function)
+		if !ok || len(f.Params()) == 0 {
+			return nil, errors.Errorf("DISTINCT specified but %s has no arguments", e)
+		}
+		for _, p := range f.Params() {
+			if _, ok := p.(expr.Wildcard); ok {
+				return nil, errors.Errorf("DISTINCT cannot be used with *")
+			}
+		}
+	}
+
+	return &Aggregate{Fn: fn, Distinct: distinct, Filter: filter}, nil
+}
+
+func (a *Aggregate) Eval(env *environment.Environment) (types.Value, error) {
+	return aggregateResult(env, a)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (a *Aggregate) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*Aggregate)
+	if !ok {
+		return false
+	}
+
+	return a.Distinct == o.Distinct && expr.Equal(a.Fn, o.Fn) && expr.Equal(a.Filter, o.Filter)
+}
+
+func (a *Aggregate) Params() []expr.Expr {
+	var params []expr.Expr
+	if f, ok := a.Fn.(expr.Function); ok {
+		params = append(params, f.Params()...)
+	}
+	if a.Filter != nil {
+		params = append(params, a.Filter)
+	}
+
+	return params
+}
+
+func (a *Aggregate) String() string {
+	s := a.Fn.String()
+	if a.Distinct {
+		name, args, _ := strings.Cut(s, "(")
+		s = name + "(DISTINCT " + args
+	}
+	if a.Filter != nil {
+		s += fmt.Sprintf(" FILTER (WHERE %v)", a.Filter)
+	}
+
+	return s
+}
+
+// Aggregator returns an aggregator wrapping the aggregator of the underlying function.
+func (a *Aggregate) Aggregator() expr.Aggregator {
+	agg := aggregateAggregator{
+		fn:    a,
+		inner: a.Fn.Aggregator(),
+	}
+	if a.Distinct {
+		agg.seen = make(map[string]struct{})
+	}
+
+	return &agg
+}
+
+type aggregateAggregator struct {
+	fn    *Aggregate
+	inner expr.Aggregator
+	seen  map[string]struct{}
+	buf   []byte
+}
+
+func (a *aggregateAggregator) Aggregate(env *environment.Environment) error {
+	if a.fn.Filter != nil {
+		v, err := evalAggregateArg(env, a.fn.Filter)
+		if err != nil {
+			return err
+		}
+		ok, err := types.IsTruthy(v)
+		if err != nil || !ok {
+			return err
+		}
+	}
+
+	if a.seen != nil {
+		a.buf = a.buf[:0]
+		for _, p := range a.fn.Fn.(expr.Function).Params() {
+			v, err := evalAggregateArg(env, p)
+			if err != nil {
+				return err
+			}
+			a.buf, err = v.EncodeAsKey(a.buf)
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, ok := a.seen[string(a.buf)]; ok {
+			return nil
+		}
+		a.seen[string(a.buf)] = struct{}{}
+	}
+
+	return a.inner.Aggregate(env)
+}
+
+func (a *aggregateAggregator) Eval(env *environment.Environment) (types.Value, error) {
+	return a.inner.Eval(env)
+}
+
+func (a *aggregateAggregator) String() string {
+	return a.fn.String()
+}
+
+// BoolAgg is the BOOL_AND and BOOL_OR aggregator functions.
+// BOOL_AND returns true if all the non-null input values are true,
+// BOOL_OR returns true if at least one of them is true.
+type BoolAgg struct {
+	Expr expr.Expr
+	Or   bool
+}
+
+func (b *BoolAgg) Eval(env *environment.Environment) (types.Value, error) {
+	return aggregateResult(env, b)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (b *BoolAgg) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*BoolAgg)
+	if !ok {
+		return false
+	}
+
+	return b.Or == o.Or && expr.Equal(b.Expr, o.Expr)
+}
+
+func (b *BoolAgg) Params() []expr.Expr { return []expr.Expr{b.Expr} }
+
+func (b *BoolAgg) String() string {
+	if b.Or {
+		return fmt.Sprintf("BOOL_OR(%v)", b.Expr)
+	}
+
+	return fmt.Sprintf("BOOL_AND(%v)", b.Expr)
+}
+
+// Aggregator returns a BoolAggregator. It implements the AggregatorBuilder interface.
+func (b *BoolAgg) Aggregator() expr.Aggregator {
+	return &BoolAggregator{Fn: b}
+}
+
+// BoolAggregator computes the result of BOOL_AND or BOOL_OR.
+type BoolAggregator struct {
+	Fn     *BoolAgg
+	Result *bool
+}
+
+func (b *BoolAggregator) Aggregate(env *environment.Environment) error {
+	v, err := evalAggregateArg(env, b.Fn.Expr)
+	if err != nil {
+		return err
+	}
+	if v.Type() == types.TypeNull {
+		return nil
+	}
+
+	v, err = v.CastAs(types.TypeBoolean)
+	if err != nil {
+		return err
+	}
+
+	x := types.AsBool(v)
+	if b.Result == nil {
+		b.Result = &x
+		return nil
+	}
+
+	if b.Fn.Or {
+		*b.Result = *b.Result || x
+	} else {
+		*b.Result = *b.Result && x
+	}
+
+	return nil
+}
+
+// Eval returns the result of the aggregation, or NULL if there were no non-null values.
+func (b *BoolAggregator) Eval(_ *environment.Environment) (types.Value, error) {
+	if b.Result == nil {
+		return types.NewNullValue(), nil
+	}
+
+	return types.NewBooleanValue(*b.Result), nil
+}
+
+func (b *BoolAggregator) String() string {
+	return b.Fn.String()
+}
+
+// Statistical aggregate functions.
+const (
+	varPop = iota
+	varSamp
+	stddevPop
+	stddevSamp
+)
+
+var statAggNames = map[int]string{
+	varPop:     "VAR_POP",
+	varSamp:    "VAR_SAMP",
+	stddevPop:  "STDDEV_POP",
+	stddevSamp: "STDDEV_SAMP",
+}
+
+// StatAgg is the VAR_POP, VAR_SAMP, STDDEV_POP and STDDEV_SAMP aggregator functions.
+type StatAgg struct {
+	Expr expr.Expr
+	Kind int
+}
+
+func (s *StatAgg) Eval(env *environment.Environment) (types.Value, error) {
+	return aggregateResult(env, s)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (s *StatAgg) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*StatAgg)
+	if !ok {
+		return false
+	}
+
+	return s.Kind == o.Kind && expr.Equal(s.Expr, o.Expr)
+}
+
+func (s *StatAgg) Params() []expr.Expr { return []expr.Expr{s.Expr} }
+
+func (s *StatAgg) String() string {
+	return fmt.Sprintf("%s(%v)", statAggNames[s.Kind], s.Expr)
+}
+
+// Aggregator returns a StatAggregator. It implements the AggregatorBuilder interface.
+func (s *StatAgg) Aggregator() expr.Aggregator {
+	return &StatAggregator{Fn: s}
+}
+
+// StatAggregator computes the variance of numeric values
+// using Welford's online algorithm.
+type StatAggregator struct {
+	Fn    *StatAgg
+	Count int64
+	Mean  float64
+	M2    float64
+}
+
+func (s *StatAggregator) Aggregate(env *environment.Environment) error {
+	v, err := evalAggregateArg(env, s.Fn.Expr)
+	if err != nil {
+		return err
+	}
+
+	var x float64
+	switch v.Type() {
+	case types.TypeInteger, types.TypeBigint:
+		x = float64(types.AsInt64(v))
+	case types.TypeDoublePrecision:
+		x = types.AsFloat64(v)
+	default:
+		return nil
+	}
+
+	s.Count++
+	delta := x - s.Mean
+	s.Mean += delta / float64(s.Count)
+	s.M2 += delta * (x - s.Mean)
+	return nil
+}
+
+// Eval returns the result as a double, or NULL if there aren't enough values.
+func (s *StatAggregator) Eval(_ *environment.Environment) (types.Value, error) {
+	var variance float64
+	switch s.Fn.Kind {
+	case varPop, stddevPop:
+		if s.Count == 0 {
+			return types.NewNullValue(), nil
+		}
+		variance = s.M2 / float64(s.Count)
+	default:
+		if s.Count < 2 {
+			return types.NewNullValue(), nil
+		}
+		variance = s.M2 / float64(s.Count-1)
+	}
+
+	if s.Fn.Kind == stddevPop || s.Fn.Kind == stddevSamp {
+		return types.NewDoublePrecisionValue(math.Sqrt(variance)), nil
+	}
+
+	return types.NewDoublePrecisionValue(variance), nil
+}
+
+func (s *StatAggregator) String() string {
+	return s.Fn.String()
+}
+
+var _ OrderedAggregate = (*StringAgg)(nil)
+
+// StringAgg is the STRING_AGG aggregator function.
+// It concatenates the non-null input values, separated by the separator.
+type StringAgg struct {
+	Expr      expr.Expr
+	Separator expr.Expr
+	OrderBy   []SortKey
+}
+
+func (s *StringAgg) Eval(env *environment.Environment) (types.Value, error) {
+	return aggregateResult(env, s)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (s *StringAgg) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*StringAgg)
+	if !ok {
+		return false
+	}
+
+	return expr.Equal(s.Expr, o.Expr) && expr.Equal(s.Separator, o.Separator) && sortKeysEqual(s.OrderBy, o.OrderBy)
+}
+
+func (s *StringAgg) Params() []expr.Expr { return []expr.Expr{s.Expr, s.Separator} }
+
+func (s *StringAgg) SetOrderBy(keys []SortKey, withinGroup bool) error {
+	if withinGroup {
+		return errors.New("WITHIN GROUP is not supported by STRING_AGG, use ORDER BY within the arguments instead")
+	}
+
+	s.OrderBy = keys
+	return nil
+}
+
+func (s *StringAgg) String() string {
+	if len(s.OrderBy) > 0 {
+		return fmt.Sprintf("STRING_AGG(%v, %v %s)", s.Expr, s.Separator, sortKeysString(s.OrderBy))
+	}
+
+	return fmt.Sprintf("STRING_AGG(%v, %v)", s.Expr, s.Separator)
+}
+
+// Aggregator returns a StringAggregator. It implements the AggregatorBuilder interface.
+func (s *StringAgg) Aggregator() expr.Aggregator {
+	return &StringAggregator{
+		Fn:   s,
+		rows: sortedRows[stringAggValue]{keys: s.OrderBy},
+	}
+}
+
+// StringAggregator accumulates the values and their separators.
+type StringAggregator struct {
+	Fn   *StringAgg
+	rows sortedRows[stringAggValue]
+}
+
+func (s *StringAggregator) Aggregate(env *environment.Environment) error {
+	v, err := evalAggregateArg(env, s.Fn.Expr)
+	if err != nil {
+		return err
+	}
+	if v.Type() == types.TypeNull {
+		return nil
+	}
+	str, err := textArg(v)
+	if err != nil {
+		return err
+	}
+
+	sep, err := evalAggregateArg(env, s.Fn.Separator)
+	if err != nil {
+		return err
+	}
+	var sepStr string
+	if sep.Type() != types.TypeNull {
+		sepStr, err = textArg(sep)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.rows.add(env, stringAggValue{sep: sepStr, str: str})
+}
+
+// stringAggValue is a value of STRING_AGG along with the separator that precedes it.
+type stringAggValue struct {
+	sep string
+	str string
+}
+
+// Eval returns the concatenated string, or NULL if there were no non-null values.
+func (s *StringAggregator) Eval(_ *environment.Environment) (types.Value, error) {
+	if len(s.rows.values) == 0 {
+		return types.NewNullValue(), nil
+	}
+
+	err := s.rows.sort()
+	if err != nil {
+		return nil, err
+	}
+
+	var sb strings.Builder
+	for i, v := range s.rows.values {
+		if i > 0 {
+			sb.WriteString(v.sep)
+		}
+		sb.WriteString(v.str)
+	}
+
+	return types.NewTextValue(sb.String()), nil
+}
+
+func (s *StringAggregator) String() string {
+	return s.Fn.String()
+}
+
+// Ordered-set aggregate functions.
+const (
+	percentileCont = iota
+	percentileDisc
+	mode
+)
+
+var orderedSetAggNames = map[int]string{
+	percentileCont: "PERCENTILE_CONT",
+	percentileDisc: "PERCENTILE_DISC",
+	mode:           "MODE",
+}
+
+var _ OrderedAggregate = (*OrderedSetAgg)(nil)
+
+// OrderedSetAgg is the PERCENTILE_CONT, PERCENTILE_DISC and MODE aggregator functions.
+// They require a WITHIN GROUP (ORDER BY ...) clause which determines the aggregated values.
+//
+//	PERCENTILE_CONT(fraction) returns the value at the given fraction, interpolating between
+//	adjacent values if needed.
+//	PERCENTILE_DISC(fraction) returns the first value whose position is greater than or equal
+//	to the given fraction.
+//	MODE() returns the most frequent value.
+type OrderedSetAgg struct {
+	Kind     int
+	Fraction expr.Expr
+	OrderBy  []SortKey
+}
+
+// isConstantFraction returns whether the fraction of a percentile is the same for
+// every row, since only its first value is used.
+func isConstantFraction(e expr.Expr) bool {
+	return expr.Walk(e, func(e expr.Expr) bool {
+		switch e.(type) {
+		case *expr.Column, expr.AggregatorBuilder, *NextVal:
+			return false
+		}
+		return true
+	})
+}
+
+func (o *OrderedSetAgg) Eval(env *environment.Environment) (types.Value, error) {
+	return aggregateResult(env, o)
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (o *OrderedSetAgg) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	oo, ok := other.(*OrderedSetAgg)
+	if !ok {
+		return false
+	}
+
+	return o.Kind == oo.Kind && expr.Equal(o.Fraction, oo.Fraction) && sortKeysEqual(o.OrderBy, oo.OrderBy)
+}
+
+func (o *OrderedSetAgg) Params() []expr.Expr {
+	var params []expr.Expr
+	if o.Fraction != nil {
+		params = append(params, o.Fraction)
+	}
+	for _, k := range o.OrderBy {
+		params = append(params, k.Expr)
+	}
+
+	return params
+}
+
+func (o *OrderedSetAgg) SetOrderBy(keys []SortKey, withinGroup bool) error {
+	name := orderedSetAggNames[o.Kind]
+	if !withinGroup {
+		return errors.Errorf("%s requires a WITHIN GROUP clause", name)
+	}
+	if len(keys) != 1 {
+		return errors.Errorf("%s requires exactly one ORDER BY expression", name)
+	}
+
+	o.OrderBy = keys
+	return nil
+}
+
+func (o *OrderedSetAgg) String() string {
+	var fraction string
+	if o.Fraction != nil {
+		fraction = o.Fraction.String()
+	}
+
+	return fmt.Sprintf("%s(%s) WITHIN GROUP (%s)", orderedSetAggNames[o.Kind], fraction, sortKeysString(o.OrderBy))
+}
+
+// Aggregator returns an OrderedSetAggregator. It implements the AggregatorBuilder interface.
+func (o *OrderedSetAgg) Aggregator() expr.Aggregator {
+	return &OrderedSetAggregator{
+		Fn: o,
+	}
+}
+
+// OrderedSetAggregator accumulates all the non-null values and computes
+// the result once they are sorted.
+type OrderedSetAggregator struct {
+	Fn       *OrderedSetAgg
+	Fraction *float64
+	Values   []types.Value
+}
+
+func (o *OrderedSetAggregator) Aggregate(env *environment.Environment) error {
+	if len(o.Fn.OrderBy) == 0 {
+		return errors.Errorf("%s requires a WITHIN GROUP clause", orderedSetAggNames[o.Fn.Kind])
+	}
+
+	if o.Fn.Fraction != nil && o.Fraction == nil {
+		v, err := o.Fn.Fraction.Eval(env)
+		if err != nil {
+			return err
+		}
+		// a NULL fraction returns NULL
+		if v.Type() == types.TypeNull {
+			return nil
+		}
+		v, err = v.CastAs(types.TypeDoublePrecision)
+		if err != nil {
+			return err
+		}
+		f := types.AsFloat64(v)
+		if f < 0 || f > 1 {
+			return errors.Errorf("percentile value %v is not between 0 and 1", f)
+		}
+		o.Fraction = &f
+	}
+
+	v, err := evalAggregateArg(env, o.Fn.OrderBy[0].Expr)
+	if err != nil {
+		return err
+	}
+	if v.Type() == types.TypeNull {
+		return nil
+	}
+	if o.Fn.Kind == percentileCont && !v.Type().IsNumber() {
+		return errors.Errorf("PERCENTILE_CONT expects numeric values, got %s", v.Type())
+	}
+
+	o.Values = append(o.Values, cloneValue(v))
+	return nil
+}
+
+// Eval returns the result of the aggregation, or NULL if there were no non-null values.
+func (o *OrderedSetAggregator) Eval(_ *environment.Environment) (types.Value, error) {
+	if len(o.Values) == 0 || o.Fn.Fraction != nil && o.Fraction == nil {
+		return types.NewNullValue(), nil
+	}
+
+	var err error
+	desc := o.Fn.OrderBy[0].Desc
+	sort.SliceStable(o.Values, func(i, j int) bool {
+		cmp, cerr := compareValues(o.Values[i], o.Values[j])
+		if cerr != nil {
+			err = cerr
+		}
+		if desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(o.Values)
+	switch o.Fn.Kind {
+	case percentileCont:
+		pos := *o.Fraction * float64(n-1)
+		lo, hi := int(math.Floor(pos)), int(math.Ceil(pos))
+		a, err := o.Values[lo].CastAs(types.TypeDoublePrecision)
+		if err != nil {
+			return nil, err
+		}
+		b, err := o.Values[hi].CastAs(types.TypeDoublePrecision)
+		if err != nil {
+			return nil, err
+		}
+		x, y := types.AsFloat64(a), types.AsFloat64(b)
+		return types.NewDoublePrecisionValue(x + (y-x)*(pos-float64(lo))), nil
+	case percentileDisc:
+		idx := int(math.Ceil(*o.Fraction*float64(n))) - 1
+		return o.Values[max(idx, 0)], nil
+	}
+
+	// mode: values are sorted, find the longest run of equal values.
+	// In case of a tie, the first one in sort order wins.
+	best, bestCount := 0, 0
+	for i := 0; i < n; {
+		j := i + 1
+		for j < n {
+			cmp, err := compareValues(o.Values[i], o.Values[j])
+			if err != nil {
+				return nil, err
+			}
+			if cmp != 0 {
+				break
+			}
+			j++
+		}
+		if j-i > bestCount {
+			best, bestCount = i, j-i
+		}
+		i = j
+	}
+
+	return o.Values[best], nil
+}
+
+func (o *OrderedSetAggregator) String() string {
+	return o.Fn.String()
+}
@@ -0,0 +1,25 @@
+package functions_test
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/chaisql/chai"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringAggNUL(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE test(a INT PRIMARY KEY, b TEXT)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO test (a, b) VALUES (1, $1), (2, $2)", "x\x00y", "z")
+	require.NoError(t, err)
+
+	var s string
+	err = db.QueryRow("SELECT string_agg(b, $1 ORDER BY a) FROM test", "\x00-\x00").Scan(&s)
+	require.NoError(t, err)
+	require.Equal(t, "x\x00y\x00-\x00z", s)
+}
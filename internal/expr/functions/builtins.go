@@ -53,6 +53,96 @@ var builtinFunctions = Definitions{
 			return &Avg{Expr: args[0]}, nil
 		},
 	},
+	"bool_and": &definition{
+		name:  "bool_and",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &BoolAgg{Expr: args[0]}, nil
+		},
+	},
+	"bool_or": &definition{
+		name:  "bool_or",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &BoolAgg{Expr: args[0], Or: true}, nil
+		},
+	},
+	"var_pop": &definition{
+		name:  "var_pop",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &StatAgg{Expr: args[0], Kind: varPop}, nil
+		},
+	},
+	"var_samp": &definition{
+		name:  "var_samp",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &StatAgg{Expr: args[0], Kind: varSamp}, nil
+		},
+	},
+	"variance": &definition{
+		name:  "variance",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &StatAgg{Expr: args[0], Kind: varSamp}, nil
+		},
+	},
+	"stddev_pop": &definition{
+		name:  "stddev_pop",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &StatAgg{Expr: args[0], Kind: stddevPop}, nil
+		},
+	},
+	"stddev_samp": &definition{
+		name:  "stddev_samp",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &StatAgg{Expr: args[0], Kind: stddevSamp}, nil
+		},
+	},
+	"stddev": &definition{
+		name:  "stddev",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &StatAgg{Expr: args[0], Kind: stddevSamp}, nil
+		},
+	},
+	"string_agg": &definition{
+		name:  "string_agg",
+		arity: 2,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &StringAgg{Expr: args[0], Separator: args[1]}, nil
+		},
+	},
+	"percentile_cont": &definition{
+		name:  "percentile_cont",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			if !isConstantFraction(args[0]) {
+				return nil, errors.New("the fraction of percentile_cont must be a constant")
+			}
+			return &OrderedSetAgg{Kind: percentileCont, Fraction: args[0]}, nil
+		},
+	},
+	"percentile_disc": &definition{
+		name:  "percentile_disc",
+		arity: 1,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			if !isConstantFraction(args[0]) {
+				return nil, errors.New("the fraction of percentile_disc must be a constant")
+			}
+			return &OrderedSetAgg{Kind: percentileDisc, Fraction: args[0]}, nil
+		},
+	},
+	"mode": &definition{
+		name:  "mode",
+		arity: 0,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &OrderedSetAgg{Kind: mode}, nil
+		},
+	},
 	"len": &definition{
 		name:  "len",
 		arity: 1,
@@ -67,6 +157,33 @@ var builtinFunctions = Definitions{
 			return &Coalesce{Exprs: args}, nil
 		},
 	},
+	"nullif": &definition{
+		name:  "nullif",
+		arity: 2,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &NullIf{A: args[0], B: args[1]}, nil
+		},
+	},
+	"greatest": &definition{
+		name:  "greatest",
+		arity: variadicArity,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			if len(args) == 0 {
+				return nil, errors.New("greatest() requires at least one argument")
+			}
+			return &Greatest{Exprs: args}, nil
+		},
+	},
+	"least": &definition{
+		name:  "least",
+		arity: variadicArity,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			if len(args) == 0 {
+				return nil, errors.New("least() requires at least one argument")
+			}
+			return &Greatest{Exprs: args, Least: true}, nil
+		},
+	},
 	"now": &definition{
 		name:  "now",
 		arity: 0,
@@ -110,6 +227,20 @@ var builtinFunctions = Definitions{
 			return &Trim{Expr: args, TrimFunc: strings.TrimRight, Name: "RTRIM"}, nil
 		},
 	},
+	"gen_random_uuid": &definition{
+		name:  "gen_random_uuid",
+		arity: 0,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &GenRandomUUID{}, nil
+		},
+	},
+	"uuidv7": &definition{
+		name:  "uuidv7",
+		arity: 0,
+		constructorFn: func(args ...expr.Expr) (expr.Function, error) {
+			return &UUIDv7{}, nil
+		},
+	},
 	"nextval": &definition{
 		name:  "nextval",
 		arity: 1,
@@ -128,6 +259,27 @@ var builtinFunctions = Definitions{
 	"atan2":  atan2,
 	"random": random,
 	"sqrt":   sqrt,
+
+	"substring":      substring,
+	"substr":         substring,
+	"replace":        replace,
+	"position":       positionDefinition{position},
+	"strpos":         strposFn,
+	"left":           left,
+	"right":          right,
+	"lpad":           lpad,
+	"rpad":           rpad,
+	"split_part":     splitPart,
+	"concat":         concat,
+	"concat_ws":      concatWS,
+	"repeat":         repeat,
+	"reverse":        reverse,
+	"initcap":        initcap,
+	"format":         format,
+	"md5":            md5Fn,
+	"sha256":         sha256Fn,
+	"regexp_replace": regexpReplace,
+	"regexp_match":   regexpMatch,
 }
 
 type TypeOf struct {
@@ -722,6 +874,149 @@ func (c *Coalesce) Params() []expr.Expr {
 	return c.Exprs
 }
 
+// NullIf returns NULL if both of its arguments are equal, otherwise
+// it returns the first one.
+type NullIf struct {
+	A, B expr.Expr
+}
+
+func (n *NullIf) Eval(env *environment.Environment) (types.Value, error) {
+	a, err := n.A.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := n.B.Eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.Type() == types.TypeNull || b.Type() == types.TypeNull {
+		return a, nil
+	}
+
+	eq, err := a.EQ(b)
+	if err != nil {
+		return nil, err
+	}
+	if eq {
+		return types.NewNullValue(), nil
+	}
+
+	return a, nil
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (n *NullIf) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*NullIf)
+	if !ok {
+		return false
+	}
+
+	return expr.Equal(n.A, o.A) && expr.Equal(n.B, o.B)
+}
+
+func (n *NullIf) Params() []expr.Expr { return []expr.Expr{n.A, n.B} }
+
+func (n *NullIf) String() string {
+	return fmt.Sprintf("NULLIF(%v, %v)", n.A, n.B)
+}
+
+// Greatest is the GREATEST and LEAST functions.
+// They return the largest or smallest of their arguments, ignoring NULL values.
+// They return NULL only if all the arguments are NULL.
+type Greatest struct {
+	Exprs []expr.Expr
+	Least bool
+}
+
+func (g *Greatest) Eval(env *environment.Environment) (types.Value, error) {
+	var res types.Value
+
+	for _, e := range g.Exprs {
+		v, err := e.Eval(env)
+		if err != nil {
+			return nil, err
+		}
+		if v.Type() == types.TypeNull {
+			continue
+		}
+
+		if res == nil {
+			res = v
+			continue
+		}
+
+		if !v.Type().Def().IsComparableWith(res.Type()) {
+			return nil, errors.Errorf("%s types %s and %s cannot be matched", g.name(), res.Type(), v.Type())
+		}
+
+		var ok bool
+		if g.Least {
+			ok, err = v.LT(res)
+		} else {
+			ok, err = v.GT(res)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res = v
+		}
+	}
+
+	if res == nil {
+		return types.NewNullValue(), nil
+	}
+
+	return res, nil
+}
+
+func (g *Greatest) name() string {
+	if g.Least {
+		return "LEAST"
+	}
+
+	return "GREATEST"
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (g *Greatest) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*Greatest)
+	if !ok || g.Least != o.Least || len(g.Exprs) != len(o.Exprs) {
+		return false
+	}
+
+	for i := range g.Exprs {
+		if !expr.Equal(g.Exprs[i], o.Exprs[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (g *Greatest) Params() []expr.Expr { return g.Exprs }
+
+func (g *Greatest) String() string {
+	args := make([]string, len(g.Exprs))
+	for i, e := range g.Exprs {
+		args[i] = e.String()
+	}
+
+	return fmt.Sprintf("%s(%s)", g.name(), strings.Join(args, ", "))
+}
+
 type Now struct{}
 
 func (n *Now) Eval(env *environment.Environment) (types.Value, error) {
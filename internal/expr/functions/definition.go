@@ -2,6 +2,7 @@ package functions
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/chaisql/chai/internal/expr"
@@ -29,6 +30,17 @@ func GetFunc(fname string) (Definition, error) {
 	return def, nil
 }
 
+// Names returns the names of the builtin functions, sorted lexicographically.
+func Names() []string {
+	names := make([]string, 0, len(builtinFunctions))
+	for name := range builtinFunctions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
 // A definition is the most basic version of a function definition.
 type definition struct {
 	name          string
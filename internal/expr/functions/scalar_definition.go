@@ -15,9 +15,13 @@ import (
 // This difference allows to simply define them with a CallFn function that takes multiple row.Value and
 // return another types.Value, rather than having to manually evaluate expressions (see Definition).
 type ScalarDefinition struct {
-	name   string
-	arity  int
-	callFn func(...types.Value) (types.Value, error)
+	name  string
+	arity int
+	// maxArity is the maximum number of arguments accepted by the function.
+	// If zero, the function takes exactly arity arguments.
+	// If set to variadicArity, there is no upper bound.
+	maxArity int
+	callFn   func(...types.Value) (types.Value, error)
 }
 
 func NewScalarDefinition(name string, arity int, callFn func(...types.Value) (types.Value, error)) *ScalarDefinition {
@@ -35,13 +39,27 @@ func (fd *ScalarDefinition) String() string {
 	for i := 0; i < fd.arity; i++ {
 		args = append(args, fmt.Sprintf("arg%d", i+1))
 	}
-	return fmt.Sprintf("%s(%s)", fd.name, strings.Join(args, ", "))
+	s := strings.Join(args, ", ")
+	switch {
+	case fd.maxArity == variadicArity:
+		s += ", ..."
+	case fd.maxArity > fd.arity:
+		for i := fd.arity; i < fd.maxArity; i++ {
+			s += fmt.Sprintf("[, arg%d]", i+1)
+		}
+	}
+	return fmt.Sprintf("%s(%s)", fd.name, s)
 }
 
 // Function returns a Function expr node.
 func (fd *ScalarDefinition) Function(args ...expr.Expr) (expr.Function, error) {
-	if len(args) != fd.arity {
+	switch {
+	case fd.maxArity == 0 && len(args) != fd.arity:
 		return nil, fmt.Errorf("%s takes %d argument(s), not %d", fd.String(), fd.arity, len(args))
+	case fd.maxArity == variadicArity && len(args) < fd.arity:
+		return nil, fmt.Errorf("%s takes at least %d argument(s), not %d", fd.String(), fd.arity, len(args))
+	case fd.maxArity > 0 && (len(args) < fd.arity || len(args) > fd.maxArity):
+		return nil, fmt.Errorf("%s takes %d to %d argument(s), not %d", fd.String(), fd.arity, fd.maxArity, len(args))
 	}
 	return &ScalarFunction{
 		params: args,
@@ -81,7 +99,32 @@ func (sf *ScalarFunction) evalParams(env *environment.Environment) ([]types.Valu
 
 // String returns a string represention of the function expression and its arguments.
 func (sf *ScalarFunction) String() string {
-	return fmt.Sprintf("%s(%v)", sf.def.name, sf.params)
+	args := make([]string, len(sf.params))
+	for i := range sf.params {
+		args[i] = sf.params[i].String()
+	}
+	return fmt.Sprintf("%s(%s)", sf.def.name, strings.Join(args, ", "))
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (sf *ScalarFunction) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	o, ok := other.(*ScalarFunction)
+	if !ok || o.def != sf.def || len(o.params) != len(sf.params) {
+		return false
+	}
+
+	for i := range sf.params {
+		if !expr.Equal(sf.params[i], o.params[i]) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // Params return the function arguments.
@@ -1,11 +1,21 @@
 package functions
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/chaisql/chai/internal/environment"
 	"github.com/chaisql/chai/internal/expr"
+	"github.com/chaisql/chai/internal/stringutil"
 	"github.com/chaisql/chai/internal/types"
 )
 
@@ -164,3 +174,731 @@ func (s *Trim) String() string {
 	}
 	return fmt.Sprintf("%v(%v, %v)", s.Name, s.Expr[0], s.Expr[1])
 }
+
+// textArg converts a function argument to a string.
+// Non-text values are cast to text.
+func textArg(v types.Value) (string, error) {
+	if v.Type() == types.TypeText {
+		return types.AsString(v), nil
+	}
+
+	tv, err := v.CastAs(types.TypeText)
+	if err != nil {
+		return "", err
+	}
+
+	return types.AsString(tv), nil
+}
+
+// intArg converts a function argument to an integer.
+func intArg(v types.Value) (int, error) {
+	if v.Type() == types.TypeInteger || v.Type() == types.TypeBigint {
+		return int(types.AsInt64(v)), nil
+	}
+
+	iv, err := v.CastAs(types.TypeBigint)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(types.AsInt64(iv)), nil
+}
+
+func hasNull(args []types.Value) bool {
+	for _, a := range args {
+		if a.Type() == types.TypeNull {
+			return true
+		}
+	}
+
+	return false
+}
+
+// strictTextFn returns a function that returns NULL if any of its arguments is NULL,
+// and calls fn with all the arguments converted to text otherwise.
+func strictTextFn(fn func(args ...string) (types.Value, error)) func(args ...types.Value) (types.Value, error) {
+	return func(args ...types.Value) (types.Value, error) {
+		if hasNull(args) {
+			return types.NewNullValue(), nil
+		}
+
+		strs := make([]string, len(args))
+		for i, a := range args {
+			s, err := textArg(a)
+			if err != nil {
+				return nil, err
+			}
+			strs[i] = s
+		}
+
+		return fn(strs...)
+	}
+}
+
+var substring = &ScalarDefinition{
+	name:     "substring",
+	arity:    2,
+	maxArity: 3,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		if hasNull(args) {
+			return types.NewNullValue(), nil
+		}
+
+		s, err := textArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		start, err := intArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		runes := []rune(s)
+		// positions are 1-based
+		from := start - 1
+		to := len(runes)
+		if len(args) == 3 {
+			count, err := intArg(args[2])
+			if err != nil {
+				return nil, err
+			}
+			if count < 0 {
+				return nil, fmt.Errorf("negative substring length not allowed")
+			}
+			to = from + count
+		}
+
+		from = max(from, 0)
+		to = min(to, len(runes))
+		if from >= to {
+			return types.NewTextValue(""), nil
+		}
+
+		return types.NewTextValue(string(runes[from:to])), nil
+	},
+}
+
+var replace = &ScalarDefinition{
+	name:  "replace",
+	arity: 3,
+	callFn: strictTextFn(func(args ...string) (types.Value, error) {
+		if args[1] == "" {
+			return types.NewTextValue(args[0]), nil
+		}
+
+		return types.NewTextValue(strings.ReplaceAll(args[0], args[1], args[2])), nil
+	}),
+}
+
+// strpos returns the 1-based position of the substring in the string,
+// in characters, or 0 if it is not present.
+func strpos(s, substr string) types.Value {
+	idx := strings.Index(s, substr)
+	if idx < 0 {
+		return types.NewIntegerValue(0)
+	}
+
+	return types.NewIntegerValue(int32(utf8.RuneCountInString(s[:idx]) + 1))
+}
+
+var strposFn = &ScalarDefinition{
+	name:  "strpos",
+	arity: 2,
+	callFn: strictTextFn(func(args ...string) (types.Value, error) {
+		return strpos(args[0], args[1]), nil
+	}),
+}
+
+// position follows the SQL standard, the substring comes first.
+// Both position(substring, string) and position(substring IN string) are supported.
+var position = &ScalarDefinition{
+	name:  "position",
+	arity: 2,
+	callFn: strictTextFn(func(args ...string) (types.Value, error) {
+		return strpos(args[1], args[0]), nil
+	}),
+}
+
+type positionDefinition struct {
+	*ScalarDefinition
+}
+
+func (p positionDefinition) Function(args ...expr.Expr) (expr.Function, error) {
+	if len(args) == 1 {
+		if in, ok := args[0].(*expr.InOperator); ok {
+			args = []expr.Expr{in.LeftHand(), in.RightHand()}
+		}
+	}
+
+	return p.ScalarDefinition.Function(args...)
+}
+
+var left = &ScalarDefinition{
+	name:  "left",
+	arity: 2,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		if hasNull(args) {
+			return types.NewNullValue(), nil
+		}
+
+		s, err := textArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		n, err := intArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		runes := []rune(s)
+		// a negative n returns all but the last |n| characters
+		if n < 0 {
+			n = max(len(runes)+n, 0)
+		}
+		n = min(n, len(runes))
+
+		return types.NewTextValue(string(runes[:n])), nil
+	},
+}
+
+var right = &ScalarDefinition{
+	name:  "right",
+	arity: 2,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		if hasNull(args) {
+			return types.NewNullValue(), nil
+		}
+
+		s, err := textArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		n, err := intArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		runes := []rune(s)
+		// a negative n returns all but the first |n| characters
+		if n < 0 {
+			n = max(len(runes)+n, 0)
+		}
+		n = min(n, len(runes))
+
+		return types.NewTextValue(string(runes[len(runes)-n:])), nil
+	},
+}
+
+// maxTextSize is the maximum size in bytes of the strings built by functions
+// like repeat or lpad, to fail instead of allocating huge amounts of memory.
+const maxTextSize = 64 << 20
+
+var errTextTooLarge = fmt.Errorf("requested length too large")
+
+// pad fills the string up to length n with the fill characters.
+// If the string is already longer than n, it is truncated.
+func pad(args []types.Value, leftPad bool) (types.Value, error) {
+	if hasNull(args) {
+		return types.NewNullValue(), nil
+	}
+
+	s, err := textArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	n, err := intArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	fill := " "
+	if len(args) == 3 {
+		fill, err = textArg(args[2])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// characters take at least one byte
+	if n > maxTextSize {
+		return nil, errTextTooLarge
+	}
+
+	n = max(n, 0)
+	count := utf8.RuneCountInString(s)
+	if count >= n {
+		return types.NewTextValue(runePrefix(s, n)), nil
+	}
+	if fill == "" {
+		return types.NewTextValue(s), nil
+	}
+
+	// the padding repeats the fill string and ends with a part of it
+	padCount := n - count
+	fillCount := utf8.RuneCountInString(fill)
+	rest := runePrefix(fill, padCount%fillCount)
+	size := len(s) + padCount/fillCount*len(fill) + len(rest)
+	if size > maxTextSize {
+		return nil, errTextTooLarge
+	}
+
+	var b strings.Builder
+	b.Grow(size)
+	if !leftPad {
+		b.WriteString(s)
+	}
+	for range padCount / fillCount {
+		b.WriteString(fill)
+	}
+	b.WriteString(rest)
+	if leftPad {
+		b.WriteString(s)
+	}
+
+	return types.NewTextValue(b.String()), nil
+}
+
+// runePrefix returns the first n characters of s.
+func runePrefix(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+
+	return s
+}
+
+var lpad = &ScalarDefinition{
+	name:     "lpad",
+	arity:    2,
+	maxArity: 3,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		return pad(args, true)
+	},
+}
+
+var rpad = &ScalarDefinition{
+	name:     "rpad",
+	arity:    2,
+	maxArity: 3,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		return pad(args, false)
+	},
+}
+
+var splitPart = &ScalarDefinition{
+	name:  "split_part",
+	arity: 3,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		if hasNull(args) {
+			return types.NewNullValue(), nil
+		}
+
+		s, err := textArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		delim, err := textArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		n, err := intArg(args[2])
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("field position must not be zero")
+		}
+
+		var parts []string
+		if delim == "" {
+			parts = []string{s}
+		} else {
+			parts = strings.Split(s, delim)
+		}
+
+		// negative positions count from the end
+		if n < 0 {
+			n = len(parts) + n + 1
+		}
+		if n <= 0 || n > len(parts) {
+			return types.NewTextValue(""), nil
+		}
+
+		return types.NewTextValue(parts[n-1]), nil
+	},
+}
+
+// concat concatenates all its arguments, ignoring NULL values.
+var concat = &ScalarDefinition{
+	name:     "concat",
+	arity:    1,
+	maxArity: variadicArity,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		var sb strings.Builder
+		for _, a := range args {
+			if a.Type() == types.TypeNull {
+				continue
+			}
+			s, err := textArg(a)
+			if err != nil {
+				return nil, err
+			}
+			sb.WriteString(s)
+		}
+
+		return types.NewTextValue(sb.String()), nil
+	},
+}
+
+// concatWS concatenates all its arguments but the first, using the first one as
+// a separator. NULL values are ignored, unless the separator is NULL.
+var concatWS = &ScalarDefinition{
+	name:     "concat_ws",
+	arity:    2,
+	maxArity: variadicArity,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		if args[0].Type() == types.TypeNull {
+			return types.NewNullValue(), nil
+		}
+		sep, err := textArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		strs := make([]string, 0, len(args)-1)
+		for _, a := range args[1:] {
+			if a.Type() == types.TypeNull {
+				continue
+			}
+			s, err := textArg(a)
+			if err != nil {
+				return nil, err
+			}
+			strs = append(strs, s)
+		}
+
+		return types.NewTextValue(strings.Join(strs, sep)), nil
+	},
+}
+
+var repeat = &ScalarDefinition{
+	name:  "repeat",
+	arity: 2,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		if hasNull(args) {
+			return types.NewNullValue(), nil
+		}
+
+		s, err := textArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		n, err := intArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+
+		if n > 0 && len(s) > maxTextSize/n {
+			return nil, errTextTooLarge
+		}
+
+		return types.NewTextValue(strings.Repeat(s, max(n, 0))), nil
+	},
+}
+
+var reverse = &ScalarDefinition{
+	name:  "reverse",
+	arity: 1,
+	callFn: strictTextFn(func(args ...string) (types.Value, error) {
+		runes := []rune(args[0])
+		slices.Reverse(runes)
+		return types.NewTextValue(string(runes)), nil
+	}),
+}
+
+// initcap converts the first letter of each word to upper case and the rest to lower case.
+// Words are sequences of alphanumeric characters.
+var initcap = &ScalarDefinition{
+	name:  "initcap",
+	arity: 1,
+	callFn: strictTextFn(func(args ...string) (types.Value, error) {
+		var sb strings.Builder
+		inWord := false
+		for _, r := range args[0] {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				if inWord {
+					sb.WriteRune(unicode.ToLower(r))
+				} else {
+					sb.WriteRune(unicode.ToUpper(r))
+				}
+				inWord = true
+				continue
+			}
+
+			inWord = false
+			sb.WriteRune(r)
+		}
+
+		return types.NewTextValue(sb.String()), nil
+	}),
+}
+
+// format formats its arguments according to a format string.
+// Supported format specifiers are:
+//   - %s: the argument as text, NULL is formatted as an empty string
+//   - %I: the argument as an SQL identifier, double quoted if necessary
+//   - %L: the argument as an SQL literal, NULL is formatted as NULL
+//   - %%: a literal %
+//
+// An explicit argument position can be given with %n$s.
+var format = &ScalarDefinition{
+	name:     "format",
+	arity:    1,
+	maxArity: variadicArity,
+	callFn: func(args ...types.Value) (types.Value, error) {
+		if args[0].Type() == types.TypeNull {
+			return types.NewNullValue(), nil
+		}
+		f, err := textArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		params := args[1:]
+
+		var sb strings.Builder
+		next := 0
+		for i := 0; i < len(f); i++ {
+			if f[i] != '%' {
+				sb.WriteByte(f[i])
+				continue
+			}
+
+			i++
+			if i >= len(f) {
+				return nil, fmt.Errorf("unterminated format() type specifier")
+			}
+			if f[i] == '%' {
+				sb.WriteByte('%')
+				continue
+			}
+
+			// optional argument position
+			argIdx := next
+			if f[i] >= '0' && f[i] <= '9' {
+				j := i
+				for j < len(f) && f[j] >= '0' && f[j] <= '9' {
+					j++
+				}
+				if j >= len(f) || f[j] != '$' {
+					return nil, fmt.Errorf("invalid format() argument position")
+				}
+				pos, _ := strconv.Atoi(f[i:j])
+				if pos == 0 {
+					return nil, fmt.Errorf("format() argument positions start at 1")
+				}
+				argIdx = pos - 1
+				i = j + 1
+				if i >= len(f) {
+					return nil, fmt.Errorf("unterminated format() type specifier")
+				}
+			}
+
+			if argIdx >= len(params) {
+				return nil, fmt.Errorf("too few arguments for format()")
+			}
+			next = argIdx + 1
+			v := params[argIdx]
+
+			switch f[i] {
+			case 's':
+				if v.Type() == types.TypeNull {
+					continue
+				}
+				s, err := textArg(v)
+				if err != nil {
+					return nil, err
+				}
+				sb.WriteString(s)
+			case 'I':
+				if v.Type() == types.TypeNull {
+					return nil, fmt.Errorf("null values cannot be formatted as an SQL identifier")
+				}
+				s, err := textArg(v)
+				if err != nil {
+					return nil, err
+				}
+				sb.WriteString(stringutil.NormalizeIdentifier(s, '"'))
+			case 'L':
+				if v.Type() == types.TypeNull {
+					sb.WriteString("NULL")
+					continue
+				}
+				s, err := textArg(v)
+				if err != nil {
+					return nil, err
+				}
+				sb.WriteString(types.NewTextValue(s).String())
+			default:
+				return nil, fmt.Errorf("unrecognized format() type specifier %q", string(f[i]))
+			}
+		}
+
+		return types.NewTextValue(sb.String()), nil
+	},
+}
+
+// hashFn returns the hexadecimal digest of its text or bytea argument.
+func hashFn(newHash func() hash.Hash) func(args ...types.Value) (types.Value, error) {
+	return func(args ...types.Value) (types.Value, error) {
+		var data []byte
+		switch args[0].Type() {
+		case types.TypeNull:
+			return types.NewNullValue(), nil
+		case types.TypeBytea:
+			data = types.AsByteSlice(args[0])
+		default:
+			s, err := textArg(args[0])
+			if err != nil {
+				return nil, err
+			}
+			data = []byte(s)
+		}
+
+		h := newHash()
+		h.Write(data)
+		return types.NewTextValue(hex.EncodeToString(h.Sum(nil))), nil
+	}
+}
+
+var md5Fn = &ScalarDefinition{
+	name:   "md5",
+	arity:  1,
+	callFn: hashFn(md5.New),
+}
+
+var sha256Fn = &ScalarDefinition{
+	name:   "sha256",
+	arity:  1,
+	callFn: hashFn(sha256.New),
+}
+
+// compileRegexWithFlags compiles the pattern using the shared regex cache.
+// Supported flags are 'i' (case insensitive) and, if allowGlobal is true, 'g'
+// (replace all matches).
+func compileRegexWithFlags(pattern, flags string, allowGlobal bool) (re *regexp.Regexp, global bool, err error) {
+	var caseInsensitive bool
+	for _, f := range flags {
+		switch {
+		case f == 'i':
+			caseInsensitive = true
+		case f == 'g' && allowGlobal:
+			global = true
+		default:
+			return nil, false, fmt.Errorf("invalid regular expression option: %q", string(f))
+		}
+	}
+
+	if caseInsensitive {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err = expr.CompileRegex(pattern)
+	return re, global, err
+}
+
+// convertRegexReplacement converts a replacement string using \1 style
+// back references to the $1 style used by the regexp package.
+// \& refers to the whole match.
+func convertRegexReplacement(repl string) string {
+	var sb strings.Builder
+	for i := 0; i < len(repl); i++ {
+		c := repl[i]
+		switch {
+		case c == '$':
+			sb.WriteString("$$")
+		case c == '\\' && i+1 < len(repl) && repl[i+1] >= '0' && repl[i+1] <= '9':
+			sb.WriteString("${")
+			sb.WriteByte(repl[i+1])
+			sb.WriteByte('}')
+			i++
+		case c == '\\' && i+1 < len(repl) && repl[i+1] == '&':
+			sb.WriteString("${0}")
+			i++
+		case c == '\\' && i+1 < len(repl) && repl[i+1] == '\\':
+			sb.WriteByte('\\')
+			i++
+		default:
+			sb.WriteByte(c)
+		}
+	}
+
+	return sb.String()
+}
+
+// regexpReplace replaces the first match of the pattern, or all of them
+// if the 'g' flag is set.
+var regexpReplace = &ScalarDefinition{
+	name:     "regexp_replace",
+	arity:    3,
+	maxArity: 4,
+	callFn: strictTextFn(func(args ...string) (types.Value, error) {
+		var flags string
+		if len(args) == 4 {
+			flags = args[3]
+		}
+		re, global, err := compileRegexWithFlags(args[1], flags, true)
+		if err != nil {
+			return nil, err
+		}
+
+		repl := convertRegexReplacement(args[2])
+		if global {
+			return types.NewTextValue(re.ReplaceAllString(args[0], repl)), nil
+		}
+
+		loc := re.FindStringSubmatchIndex(args[0])
+		if loc == nil {
+			return types.NewTextValue(args[0]), nil
+		}
+		var dst []byte
+		dst = append(dst, args[0][:loc[0]]...)
+		dst = re.ExpandString(dst, repl, args[0], loc)
+		dst = append(dst, args[0][loc[1]:]...)
+		return types.NewTextValue(string(dst)), nil
+	}),
+}
+
+// regexpMatch returns the first match of the pattern in the string,
+// or NULL if there is no match.
+// If the pattern contains capturing groups, the first group is returned
+// instead of the whole match.
+var regexpMatch = &ScalarDefinition{
+	name:     "regexp_match",
+	arity:    2,
+	maxArity: 3,
+	callFn: strictTextFn(func(args ...string) (types.Value, error) {
+		var flags string
+		if len(args) == 3 {
+			flags = args[2]
+		}
+		re, _, err := compileRegexWithFlags(args[1], flags, false)
+		if err != nil {
+			return nil, err
+		}
+
+		m := re.FindStringSubmatchIndex(args[0])
+		if m == nil {
+			return types.NewNullValue(), nil
+		}
+		if len(m) > 2 {
+			if m[2] < 0 {
+				return types.NewNullValue(), nil
+			}
+			return types.NewTextValue(args[0][m[2]:m[3]]), nil
+		}
+
+		return types.NewTextValue(args[0][m[0]:m[1]]), nil
+	}),
+}
@@ -0,0 +1,175 @@
+-- test: substring
+> substring('hello', 2)
+'ello'
+> substring('hello', 2, 3)
+'ell'
+> substr('hello', 0, 3)
+'he'
+> substring('héllo', 2, 1)
+'é'
+> substring('hello', 10)
+''
+> substring(NULL, 1)
+NULL
+> substring('hello', NULL)
+NULL
+! substring('hello', 1, -1)
+'negative substring length not allowed'
+! substring('hello')
+'substring(arg1, arg2[, arg3]) takes 2 to 3 argument(s), not 1'
+
+-- test: replace
+> replace('hello world', 'o', '0')
+'hell0 w0rld'
+> replace('hello', '', 'x')
+'hello'
+> replace('hello', 'l', NULL)
+NULL
+
+-- test: position
+> position('lo', 'hello')
+4
+> position('lo' IN 'hello')
+4
+> strpos('hello', 'lo')
+4
+> strpos('héllo', 'l')
+3
+> strpos('hello', 'x')
+0
+> strpos(NULL, 'x')
+NULL
+
+-- test: left / right
+> left('hello', 2)
+'he'
+> left('hello', -2)
+'hel'
+> left('hello', 10)
+'hello'
+> right('hello', 2)
+'lo'
+> right('hello', -2)
+'llo'
+> right(NULL, 2)
+NULL
+
+-- test: lpad / rpad
+> lpad('hi', 5)
+'   hi'
+> lpad('hi', 5, 'xy')
+'xyxhi'
+> lpad('hello', 2)
+'he'
+> rpad('hi', 5, 'xy')
+'hixyx'
+> lpad('héllo', 3, 'x')
+'hél'
+> lpad('é', 4, 'àb')
+'àbàé'
+> rpad('hi', 4, '')
+'hi'
+! lpad('a', 1000000000, 'b')
+'requested length too large'
+! rpad('a', 40000000, 'éèàù')
+'requested length too large'
+! lpad('a', 9223372036854775807)
+'requested length too large'
+! rpad('a', 9223372036854775807, 'b')
+'requested length too large'
+> rpad('hi', NULL)
+NULL
+
+-- test: split_part
+> split_part('a,b,c', ',', 2)
+'b'
+> split_part('a,b,c', ',', -1)
+'c'
+> split_part('a,b,c', ',', 4)
+''
+! split_part('a,b,c', ',', 0)
+'field position must not be zero'
+
+-- test: concat
+> concat('a', NULL, 'b', 1)
+'ab1'
+> concat_ws(', ', 'a', NULL, 'b')
+'a, b'
+> concat_ws(NULL, 'a', 'b')
+NULL
+! concat()
+'concat(arg1, ...) takes at least 1 argument(s), not 0'
+
+-- test: repeat / reverse
+> repeat('ab', 3)
+'ababab'
+> repeat('ab', -1)
+''
+! repeat('ab', 9223372036854775807)
+'requested length too large'
+! repeat('ab', 40000000)
+'requested length too large'
+> reverse('héllo')
+'olléh'
+> reverse(NULL)
+NULL
+
+-- test: initcap
+> initcap('hELLO wORLD-foo bar1baz')
+'Hello World-Foo Bar1baz'
+
+-- test: format
+> format('Hello %s, %s', 'world', NULL)
+'Hello world, '
+> format('%2$s %1$s', 'a', 'b')
+'b a'
+> format('%I = %L', 'my col', 'foo')
+'"my col" = \'foo\''
+> format('%L', NULL)
+'NULL'
+> format('100%%')
+'100%'
+! format('%s')
+'too few arguments for format()'
+! format('%x', 1)
+'unrecognized format() type specifier "x"'
+
+-- test: hashes
+> md5('hello')
+'5d41402abc4b2a76b9719d911017c592'
+> sha256('hello')
+'2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824'
+> md5(NULL)
+NULL
+
+-- test: regexp_replace
+> regexp_replace('foobarbaz', 'b(..)', 'X\\1Y')
+'fooXarYbaz'
+> regexp_replace('foobarbaz', 'b(..)', 'X\\1Y', 'g')
+'fooXarYXazY'
+> regexp_replace('FooBar', 'o+', '$', 'i')
+'F$Bar'
+! regexp_replace('foo', '(', 'x')
+'invalid regular expression "(": error parsing regexp: missing closing ): `(`'
+! regexp_replace('foo', 'o', 'x', 'z')
+'invalid regular expression option: "z"'
+
+-- test: regexp_match
+> regexp_match('foobarbequebaz', 'ba.')
+'bar'
+> regexp_match('foobarbequebaz', '(bar)(beque)')
+'bar'
+> regexp_match('foo', 'x')
+NULL
+> regexp_match('FOO', 'o+', 'i')
+'OO'
+
+-- test: regex operator
+> 'hello' =~ '^h.*o$'
+true
+> 'hello' =~ '^x'
+false
+> 'hello' !~ '^x'
+true
+> NULL =~ 'x'
+NULL
@@ -0,0 +1,55 @@
+package functions
+
+import (
+	"time"
+
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/expr"
+	"github.com/chaisql/chai/internal/types"
+)
+
+// GenRandomUUID is the GEN_RANDOM_UUID function.
+// It returns a random (version 4) UUID.
+type GenRandomUUID struct{}
+
+func (g *GenRandomUUID) Eval(env *environment.Environment) (types.Value, error) {
+	return types.NewRandomUUIDValue(), nil
+}
+
+func (g *GenRandomUUID) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	_, ok := other.(*GenRandomUUID)
+	return ok
+}
+
+func (g *GenRandomUUID) Params() []expr.Expr { return nil }
+
+func (g *GenRandomUUID) String() string {
+	return "GEN_RANDOM_UUID()"
+}
+
+// UUIDv7 is the UUIDV7 function.
+// It returns a time-ordered (version 7) UUID.
+type UUIDv7 struct{}
+
+func (u *UUIDv7) Eval(env *environment.Environment) (types.Value, error) {
+	return types.NewTimeOrderedUUIDValue(time.Now()), nil
+}
+
+func (u *UUIDv7) IsEqual(other expr.Expr) bool {
+	if other == nil {
+		return false
+	}
+
+	_, ok := other.(*UUIDv7)
+	return ok
+}
+
+func (u *UUIDv7) Params() []expr.Expr { return nil }
+
+func (u *UUIDv7) String() string {
+	return "UUIDV7()"
+}
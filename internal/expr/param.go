@@ -27,3 +27,35 @@ func (p PositionalParam) IsEqual(other Expr) bool {
 func (p PositionalParam) String() string {
 	return "$" + strconv.Itoa(int(p))
 }
+
+// NamedParam is an expression which represents the name of a parameter,
+// written as :name or @name in a query.
+type NamedParam string
+
+// Eval looks up for the parameters in the env for the one that has the same name as p
+// and returns the value.
+func (p NamedParam) Eval(env *environment.Environment) (types.Value, error) {
+	return env.GetParamByName(string(p))
+}
+
+// IsEqual compares this expression with the other expression and returns
+// true if they are equal.
+func (p NamedParam) IsEqual(other Expr) bool {
+	o, ok := other.(NamedParam)
+	return ok && p == o
+}
+
+// String implements the fmt.Stringer interface.
+func (p NamedParam) String() string {
+	return ":" + string(p)
+}
+
+// IsParam returns true if e is a positional or a named parameter.
+func IsParam(e Expr) bool {
+	switch e.(type) {
+	case PositionalParam, NamedParam:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,100 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/sql/scanner"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+// maxCachedRegexps is the maximum number of compiled regular expressions
+// kept in memory.
+const maxCachedRegexps = 256
+
+var regexCache = struct {
+	sync.Mutex
+	m map[string]*regexp.Regexp
+}{
+	m: make(map[string]*regexp.Regexp),
+}
+
+// CompileRegex compiles the given pattern and caches the result.
+// It is used by the =~ operator and the regexp_* functions to avoid
+// recompiling the same pattern for every row.
+func CompileRegex(pattern string) (*regexp.Regexp, error) {
+	regexCache.Lock()
+	defer regexCache.Unlock()
+
+	if re, ok := regexCache.m[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.Errorf("invalid regular expression %q: %v", pattern, err)
+	}
+
+	if len(regexCache.m) >= maxCachedRegexps {
+		// evict a random entry
+		for k := range regexCache.m {
+			delete(regexCache.m, k)
+			break
+		}
+	}
+	regexCache.m[pattern] = re
+
+	return re, nil
+}
+
+type RegexOperator struct {
+	*simpleOperator
+}
+
+// Regex creates an expression that evaluates to the result of a =~ b.
+func Regex(a, b Expr) Expr {
+	return &RegexOperator{&simpleOperator{a, b, scanner.EQREGEX}}
+}
+
+func (op *RegexOperator) Eval(env *environment.Environment) (types.Value, error) {
+	return op.simpleOperator.eval(env, func(a, b types.Value) (types.Value, error) {
+		if a.Type() != types.TypeText || b.Type() != types.TypeText {
+			return NullLiteral, nil
+		}
+
+		re, err := CompileRegex(types.AsString(b))
+		if err != nil {
+			return nil, err
+		}
+
+		if re.MatchString(types.AsString(a)) {
+			return TrueLiteral, nil
+		}
+
+		return FalseLiteral, nil
+	})
+}
+
+func (op *RegexOperator) String() string {
+	return fmt.Sprintf("%v =~ %v", op.a, op.b)
+}
+
+type NotRegexOperator struct {
+	*RegexOperator
+}
+
+// NotRegex creates an expression that evaluates to the result of a !~ b.
+func NotRegex(a, b Expr) Expr {
+	return &NotRegexOperator{&RegexOperator{&simpleOperator{a, b, scanner.NEQREGEX}}}
+}
+
+func (op *NotRegexOperator) Eval(env *environment.Environment) (types.Value, error) {
+	return invertBoolResult(op.RegexOperator.Eval)(env)
+}
+
+func (op *NotRegexOperator) String() string {
+	return fmt.Sprintf("%v !~ %v", op.a, op.b)
+}
@@ -0,0 +1,323 @@
+package kv
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/v2"
+	"github.com/cockroachdb/pebble/v2/vfs"
+)
+
+const (
+	// BackupDir is the directory of a backup containing the Pebble files.
+	// It uses the same name as the directory of a database, so that
+	// a backup can be opened as is.
+	BackupDir = "pebble"
+
+	// IDFile is the name of the file stored next to the Pebble files,
+	// holding a random identifier of the database.
+	IDFile = "DATABASE-ID"
+
+	// BackupSourceFile is the name of the file stored next to the Pebble files
+	// of a backup, holding the identifier of the database it was created from.
+	// Table files of different databases can have the same name and size,
+	// so only the ones of a previous backup of the same database are reused.
+	BackupSourceFile = "BACKUP-SOURCE"
+)
+
+// Backup copies a consistent snapshot of the engine to dir, without blocking writers.
+// Changes made by ongoing write transactions that have been written to the engine
+// are copied along with the rollback segment and are rolled back when the backup
+// is opened.
+// If dir already contains a backup of the same database, only the table files created
+// since then are copied, the others are hard linked from the previous backup,
+// which is then replaced.
+func (s *PebbleEngine) Backup(ctx context.Context, dir string) error {
+	if s.opts.ReadOnly {
+		// checkpoints are written to the database directory
+		return errors.New("cannot backup a database opened in read-only mode")
+	}
+
+	// create a checkpoint on the same filesystem as the database,
+	// so that table files are hard linked instead of copied.
+	cpDir := s.fs.PathJoin(s.fs.PathDir(s.path), fmt.Sprintf("backup-%d", time.Now().UnixNano()))
+	err := s.db.Checkpoint(cpDir, pebble.WithFlushedWAL())
+	if err != nil {
+		return errors.Wrap(err, "failed to create checkpoint")
+	}
+	defer func() { _ = s.fs.RemoveAll(cpDir) }()
+
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	// copy the checkpoint next to the previous backup, if any,
+	// then swap them.
+	prev := filepath.Join(dir, BackupDir)
+	staging := prev + ".tmp"
+	err = os.RemoveAll(staging)
+	if err != nil {
+		return err
+	}
+
+	// the previous backup may be of another database
+	linkFrom := prev
+	source, err := readFile(vfs.Default, filepath.Join(prev, BackupSourceFile))
+	if err != nil && !oserror.IsNotExist(err) {
+		return err
+	}
+	if string(source) != s.id {
+		linkFrom = ""
+	}
+
+	err = copyDir(ctx, s.fs, cpDir, staging, linkFrom)
+	if err == nil {
+		err = copyKeyCheck(s.fs, s.path, staging)
+	}
+	if err == nil {
+		err = writeFile(vfs.Default, filepath.Join(staging, BackupSourceFile), []byte(s.id))
+	}
+	if err != nil {
+		_ = os.RemoveAll(staging)
+		return err
+	}
+
+	return swapDir(staging, prev)
+}
+
+// CopyBackup copies the backup stored in dir to the database directory path,
+// which must not exist.
+// Table files are hard linked when possible, as they are never modified.
+func CopyBackup(ctx context.Context, dir, path string) error {
+	src := filepath.Join(dir, BackupDir)
+	fi, err := os.Stat(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf("%q is not a backup directory", dir)
+		}
+		return err
+	}
+	if !fi.IsDir() {
+		return errors.Errorf("%q is not a backup directory", dir)
+	}
+
+	_, err = os.Stat(path)
+	if err == nil {
+		return errors.Errorf("%q already exists", path)
+	}
+
+	return copyDir(ctx, vfs.Default, src, filepath.Join(path, BackupDir), src)
+}
+
+// copyDir copies the files of the src directory to dst.
+// Table files that already exist in the linkFrom directory, if not empty,
+// are hard linked rather than copied.
+func copyDir(ctx context.Context, fs vfs.FS, src, dst, linkFrom string) error {
+	err := os.MkdirAll(dst, 0700)
+	if err != nil {
+		return err
+	}
+
+	names, err := fs.List(src)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// copies of a backup are not backups
+		if name == BackupSourceFile {
+			continue
+		}
+
+		srcPath := fs.PathJoin(src, name)
+		dstPath := filepath.Join(dst, name)
+
+		if linkFrom != "" && isTableFile(name) {
+			ok, err := linkFile(fs, srcPath, filepath.Join(linkFrom, name), dstPath)
+			if err != nil {
+				return err
+			}
+			if ok {
+				continue
+			}
+		}
+
+		err = copyFile(fs, srcPath, dstPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return syncDir(dst)
+}
+
+// linkFile creates dst as a hard link to existing, if it is a copy of src.
+func linkFile(fs vfs.FS, src, existing, dst string) (bool, error) {
+	efi, err := os.Stat(existing)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	sfi, err := fs.Stat(src)
+	if err != nil {
+		return false, err
+	}
+
+	if sfi.Size() != efi.Size() {
+		return false, nil
+	}
+
+	// fall back to copying if links are not supported
+	return os.Link(existing, dst) == nil, nil
+}
+
+func copyFile(fs vfs.FS, src, dst string) error {
+	in, err := fs.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if err == nil {
+		err = out.Sync()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// loadID returns the identifier of the database stored in dir,
+// generating it if the database doesn't have one yet.
+// In read-only mode, a generated identifier is not stored.
+func loadID(fs vfs.FS, dir string, readOnly bool) (string, error) {
+	path := fs.PathJoin(dir, IDFile)
+	id, err := readFile(fs, path)
+	if err == nil {
+		return string(id), nil
+	}
+	if !oserror.IsNotExist(err) {
+		return "", err
+	}
+
+	id = make([]byte, 16)
+	_, err = rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	s := hex.EncodeToString(id)
+
+	if readOnly {
+		return s, nil
+	}
+
+	return s, writeFile(fs, path, []byte(s))
+}
+
+// writeFile creates the file at path with the given content.
+func writeFile(fs vfs.FS, path string, data []byte) error {
+	f, err := fs.Create(path, vfs.WriteCategoryUnspecified)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// copyKeyCheck copies the key check file of an encrypted database,
+// which is not part of checkpoints, so that the backup can be opened
+// with the same key.
+func copyKeyCheck(fs vfs.FS, src, dst string) error {
+	srcPath := fs.PathJoin(src, KeyCheckFile)
+	_, err := fs.Stat(srcPath)
+	if err != nil {
+		if oserror.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	err = copyFile(fs, srcPath, filepath.Join(dst, KeyCheckFile))
+	if err != nil {
+		return err
+	}
+
+	return syncDir(dst)
+}
+
+// swapDir replaces the dst directory with src.
+func swapDir(src, dst string) error {
+	old := dst + ".old"
+	err := os.RemoveAll(old)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(dst, old)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Rename(src, dst)
+	if err != nil {
+		return err
+	}
+
+	err = syncDir(filepath.Dir(dst))
+	if err != nil {
+		return err
+	}
+
+	return os.RemoveAll(old)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// isTableFile returns whether the file is an immutable Pebble table or blob file.
+func isTableFile(name string) bool {
+	return strings.HasSuffix(name, ".sst") || strings.HasSuffix(name, ".blob")
+}
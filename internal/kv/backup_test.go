@@ -0,0 +1,89 @@
+package kv_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/encoding"
+	"github.com/chaisql/chai/internal/kv"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupOtherDatabase(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "backup")
+
+	open := func(path string) *kv.PebbleEngine {
+		ng, err := kv.NewEngine(path, kv.Options{
+			RollbackSegmentNamespace: int64(database.RollbackSegmentNamespace),
+			MinTransientNamespace:    10_000,
+			MaxTransientNamespace:    11_000,
+		}, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { ng.Close() })
+		return ng
+	}
+
+	// both databases create table files with the same names and sizes
+	write := func(ng *kv.PebbleEngine, v string) {
+		for i := range 10 {
+			k := encoding.EncodeInt(encoding.EncodeInt(nil, 10), int64(i))
+			require.NoError(t, ng.DB().Set(k, []byte(v), nil))
+		}
+		require.NoError(t, ng.DB().Flush())
+	}
+
+	// returns the table files of the backup
+	tableFiles := func() map[string]os.FileInfo {
+		entries, err := os.ReadDir(filepath.Join(backupDir, kv.BackupDir))
+		require.NoError(t, err)
+
+		files := make(map[string]os.FileInfo)
+		for _, e := range entries {
+			if filepath.Ext(e.Name()) == ".sst" {
+				fi, err := e.Info()
+				require.NoError(t, err)
+				files[e.Name()] = fi
+			}
+		}
+		return files
+	}
+
+	a := open(filepath.Join(dir, "a"))
+	write(a, "a")
+	require.NoError(t, a.Backup(ctx, backupDir))
+	before := tableFiles()
+	require.NotEmpty(t, before)
+
+	// incremental backups of the same database link the table files
+	require.NoError(t, a.Backup(ctx, backupDir))
+	for name, fi := range tableFiles() {
+		require.True(t, os.SameFile(before[name], fi), name)
+	}
+
+	// but not the ones of another database
+	b := open(filepath.Join(dir, "b"))
+	write(b, "b")
+	require.NoError(t, b.Backup(ctx, backupDir))
+	var shared int
+	for name, fi := range tableFiles() {
+		if prev, ok := before[name]; ok {
+			shared++
+			require.False(t, os.SameFile(prev, fi), name)
+		}
+	}
+	require.NotZero(t, shared)
+
+	restored := filepath.Join(dir, "restored")
+	require.NoError(t, kv.CopyBackup(ctx, backupDir, restored))
+	ng := open(restored)
+	s := ng.NewSnapshotSession()
+	defer s.Close()
+	v, err := s.Get(encoding.EncodeInt(encoding.EncodeInt(nil, 10), 1))
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), v)
+}
@@ -6,10 +6,18 @@ import (
 	"github.com/cockroachdb/pebble/v2"
 )
 
-var _ engine.Session = (*BatchSession)(nil)
-
+var _ engine.CheckpointSession = (*BatchSession)(nil)
+
+// Values stored in the rollback segment are prefixed by a byte telling
+// whether the key existed, since any value, including tombStone,
+// may be stored by the trees.
+// Segments using this format start with a header holding segmentFormat,
+// segments without a header were written by older versions and store
+// the values as is.
 var (
-	tombStone = []byte{0}
+	tombStone     = []byte{0}
+	valuePrefix   = byte(1)
+	segmentFormat = []byte{1}
 )
 
 type BatchSession struct {
@@ -19,6 +27,15 @@ type BatchSession struct {
 	closed          bool
 	rollbackSegment *RollbackSegment
 	maxBatchSize    int
+	checkpoints     []checkpoint
+}
+
+// checkpoint holds the values the keys had when the checkpoint was created,
+// for every key written to the database after it.
+// Keys modified after the checkpoint that are still in the batch
+// are not recorded, they are discarded along with the batch.
+type checkpoint struct {
+	undo map[string][]byte
 }
 
 func (s *PebbleEngine) NewBatchSession() engine.Session {
@@ -49,7 +66,12 @@ func (s *BatchSession) Commit() error {
 		return err
 	}
 
-	err = s.Batch.Commit(nil)
+	wo := pebble.Sync
+	if s.Store.opts.NoSync {
+		wo = pebble.NoSync
+	}
+
+	err = s.Batch.Commit(wo)
 	if err != nil {
 		return err
 	}
@@ -62,6 +84,7 @@ func (s *BatchSession) Close() error {
 		return errors.New("already closed")
 	}
 	s.closed = true
+	s.checkpoints = nil
 
 	s.Store.UnlockSharedSnapshot()
 
@@ -83,7 +106,12 @@ func (s *BatchSession) applyBatch() error {
 		return nil
 	}
 
-	err := s.rollbackSegment.Apply(s.Batch)
+	var undo map[string][]byte
+	if len(s.checkpoints) > 0 {
+		undo = s.checkpoints[len(s.checkpoints)-1].undo
+	}
+
+	err := s.rollbackSegment.Apply(s.Batch, undo)
 	if err != nil {
 		return err
 	}
@@ -101,6 +129,101 @@ func (s *BatchSession) applyBatch() error {
 	return nil
 }
 
+// Checkpoint marks the current state of the session and returns its id.
+// The content of the batch is written to the database first, so that
+// any change made after the checkpoint can be discarded by resetting the batch
+// and restoring the values saved by the rollback segment.
+func (s *BatchSession) Checkpoint() (int, error) {
+	if s.closed {
+		return 0, errors.New("already closed")
+	}
+
+	err := s.applyBatch()
+	if err != nil {
+		return 0, err
+	}
+
+	s.checkpoints = append(s.checkpoints, checkpoint{
+		undo: make(map[string][]byte),
+	})
+
+	return len(s.checkpoints) - 1, nil
+}
+
+// RollbackTo discards all the changes made after the given checkpoint.
+func (s *BatchSession) RollbackTo(id int) error {
+	if s.closed {
+		return errors.New("already closed")
+	}
+	if id < 0 || id >= len(s.checkpoints) {
+		return errors.Errorf("unknown checkpoint %d", id)
+	}
+
+	// changes that are still in the batch were all made after the last checkpoint.
+	s.Batch.Reset()
+
+	// restore the keys that were written to the database, starting with
+	// the most recent checkpoint so that older values take precedence.
+	b := s.DB.NewBatch()
+	defer b.Close()
+
+	for i := len(s.checkpoints) - 1; i >= id; i-- {
+		for k, v := range s.checkpoints[i].undo {
+			var err error
+			if v == nil {
+				err = b.Delete([]byte(k), nil)
+			} else {
+				err = b.Set([]byte(k), v, nil)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !b.Empty() {
+		// like intermediary commits, this doesn't need to be durable:
+		// the rollback segment still holds the original values.
+		err := b.Commit(pebble.NoSync)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.checkpoints = s.checkpoints[:id+1]
+	s.checkpoints[id].undo = make(map[string][]byte)
+
+	return nil
+}
+
+// Release removes the given checkpoint and the ones created after it.
+// The changes are kept and become part of the previous checkpoint, if any.
+func (s *BatchSession) Release(id int) error {
+	if s.closed {
+		return errors.New("already closed")
+	}
+	if id < 0 || id >= len(s.checkpoints) {
+		return errors.Errorf("unknown checkpoint %d", id)
+	}
+
+	if id > 0 {
+		// the previous checkpoint must now be able to undo the changes
+		// recorded by the released ones. Older values take precedence.
+		parent := s.checkpoints[id-1].undo
+		for _, cp := range s.checkpoints[id:] {
+			for k, v := range cp.undo {
+				if _, ok := parent[k]; !ok {
+					parent[k] = v
+				}
+			}
+		}
+	}
+
+	s.checkpoints = s.checkpoints[:id]
+
+	return nil
+}
+
 func (s *BatchSession) ensureBatchSize() error {
 	if s.Batch.Len() < s.maxBatchSize {
 		return nil
@@ -0,0 +1,477 @@
+package kv
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/errors/oserror"
+	"github.com/cockroachdb/pebble/v2/vfs"
+)
+
+// ErrInvalidEncryptionKey is returned when opening an encrypted database
+// with the wrong key, or without a key.
+var ErrInvalidEncryptionKey = errors.New("invalid encryption key")
+
+// A KeyProvider provides the key used to encrypt the database files.
+// Keys must be 16, 24 or 32 bytes long, to select AES-128, AES-192 or AES-256.
+type KeyProvider interface {
+	EncryptionKey() ([]byte, error)
+}
+
+// StaticKey is a KeyProvider that always returns the same key.
+type StaticKey []byte
+
+// EncryptionKey implements the KeyProvider interface.
+func (k StaticKey) EncryptionKey() ([]byte, error) {
+	return k, nil
+}
+
+const (
+	// KeyCheckFile is the name of the file stored next to the Pebble files
+	// of an encrypted database, used to verify the key when it is opened.
+	KeyCheckFile = "ENCRYPTION"
+
+	// magic number at the beginning of every encrypted file.
+	encryptionMagic = "chaienc1"
+
+	// every encrypted file starts with the magic number
+	// followed by the random IV used to encrypt its content.
+	encryptionHeaderSize = len(encryptionMagic) + aes.BlockSize
+)
+
+// newCipher returns the block cipher used to encrypt the files,
+// using the key returned by the provider.
+func newCipher(kp KeyProvider) (cipher.Block, []byte, error) {
+	key, err := kp.EncryptionKey()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get encryption key")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid encryption key")
+	}
+
+	return block, key, nil
+}
+
+// setupEncryption checks that the database stored in dir is encrypted
+// with the key returned by kp and returns a filesystem that encrypts
+// and decrypts the files transparently.
+// If kp is nil, it ensures the database is not encrypted and returns fs as is.
+// If the directory is empty, it is initialized for encryption.
+func setupEncryption(fs vfs.FS, dir string, kp KeyProvider, readOnly bool) (vfs.FS, error) {
+	checkPath := fs.PathJoin(dir, KeyCheckFile)
+
+	check, err := readFile(fs, checkPath)
+	if err != nil && !oserror.IsNotExist(err) {
+		return nil, err
+	}
+	encrypted := err == nil
+
+	if kp == nil {
+		if encrypted {
+			return nil, errors.Wrap(ErrInvalidEncryptionKey, "database is encrypted but no key was provided")
+		}
+
+		return fs, nil
+	}
+
+	block, key, err := newCipher(kp)
+	if err != nil {
+		return nil, err
+	}
+
+	if encrypted {
+		if !verifyKeyCheck(check, key) {
+			return nil, errors.WithStack(ErrInvalidEncryptionKey)
+		}
+
+		return &encryptedFS{FS: fs, block: block}, nil
+	}
+
+	// the database cannot be encrypted if it already exists
+	names, err := fs.List(dir)
+	if err != nil && !oserror.IsNotExist(err) {
+		return nil, err
+	}
+	if len(names) > 0 || readOnly {
+		return nil, errors.Wrap(ErrInvalidEncryptionKey, "database is not encrypted")
+	}
+
+	err = fs.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeKeyCheck(fs, checkPath, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encryptedFS{FS: fs, block: block}, nil
+}
+
+// keyCheck returns a random salt followed by a MAC of the salt using the key.
+// It allows verifying the key without storing it.
+func keyCheck(key []byte) ([]byte, error) {
+	salt := make([]byte, 32)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(salt)
+
+	return mac.Sum(append([]byte(encryptionMagic), salt...)), nil
+}
+
+func verifyKeyCheck(check, key []byte) bool {
+	if len(check) != len(encryptionMagic)+32+sha256.Size || !bytes.HasPrefix(check, []byte(encryptionMagic)) {
+		return false
+	}
+	check = check[len(encryptionMagic):]
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(check[:32])
+
+	return hmac.Equal(mac.Sum(nil), check[32:])
+}
+
+func writeKeyCheck(fs vfs.FS, path string, key []byte) error {
+	check, err := keyCheck(key)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(fs, path, check)
+}
+
+func readFile(fs vfs.FS, path string) ([]byte, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return io.ReadAll(f)
+}
+
+// encryptedFS is a filesystem that encrypts the content of the files
+// using AES in counter mode. Each file starts with a header containing
+// a random IV, which allows reading and writing at any offset.
+// Directories and locks are not encrypted.
+type encryptedFS struct {
+	vfs.FS
+
+	block cipher.Block
+}
+
+func (fs *encryptedFS) Create(name string, category vfs.DiskWriteCategory) (vfs.File, error) {
+	f, err := fs.FS.Create(name, category)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.initFile(f)
+}
+
+func (fs *encryptedFS) ReuseForWrite(oldname, newname string, category vfs.DiskWriteCategory) (vfs.File, error) {
+	f, err := fs.FS.ReuseForWrite(oldname, newname, category)
+	if err != nil {
+		return nil, err
+	}
+
+	// the file is rewritten from the beginning with a new IV,
+	// the same keystream must never be used twice.
+	return fs.initFile(f)
+}
+
+func (fs *encryptedFS) Open(name string, opts ...vfs.OpenOption) (vfs.File, error) {
+	f, err := fs.FS.Open(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs.openFile(f, name)
+}
+
+func (fs *encryptedFS) OpenReadWrite(name string, category vfs.DiskWriteCategory, opts ...vfs.OpenOption) (vfs.File, error) {
+	f, err := fs.FS.OpenReadWrite(name, category, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	if fi.Size() == 0 {
+		return fs.initFile(f)
+	}
+
+	return fs.openFile(f, name)
+}
+
+func (fs *encryptedFS) Stat(name string) (vfs.FileInfo, error) {
+	fi, err := fs.FS.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedFileInfo{fi}, nil
+}
+
+func (fs *encryptedFS) Unwrap() vfs.FS {
+	return fs.FS
+}
+
+// initFile writes the header of a new file.
+func (fs *encryptedFS) initFile(f vfs.File) (vfs.File, error) {
+	header := make([]byte, encryptionHeaderSize)
+	copy(header, encryptionMagic)
+	_, err := rand.Read(header[len(encryptionMagic):])
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	_, err = f.Write(header)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &encryptedFile{
+		File:  f,
+		block: fs.block,
+		iv:    header[len(encryptionMagic):],
+	}, nil
+}
+
+// openFile reads the header of an existing file.
+func (fs *encryptedFS) openFile(f vfs.File, name string) (vfs.File, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	// directories are not encrypted
+	if fi.IsDir() {
+		return f, nil
+	}
+
+	header := make([]byte, encryptionHeaderSize)
+	_, err = io.ReadFull(f, header)
+	if err != nil || !bytes.HasPrefix(header, []byte(encryptionMagic)) {
+		_ = f.Close()
+		return nil, errors.Errorf("%q is not an encrypted file", name)
+	}
+
+	return &encryptedFile{
+		File:  f,
+		block: fs.block,
+		iv:    header[len(encryptionMagic):],
+	}, nil
+}
+
+// encryptedFile encrypts and decrypts the content of a file.
+// Offsets are relative to the end of the header.
+type encryptedFile struct {
+	vfs.File
+
+	block cipher.Block
+	iv    []byte
+	// offsets of the next Read and Write calls.
+	roff, woff int64
+	// buffer used to encrypt the data before writing it.
+	buf []byte
+}
+
+// xor encrypts or decrypts src into dst, as if src was located at
+// the given offset of the file.
+func (f *encryptedFile) xor(dst, src []byte, off int64) {
+	var iv [aes.BlockSize]byte
+	copy(iv[:], f.iv)
+
+	// add the index of the block to the IV, as a big-endian integer
+	ctr := uint64(off / aes.BlockSize)
+	for i := aes.BlockSize - 1; i >= 0 && ctr > 0; i-- {
+		sum := uint64(iv[i]) + ctr&0xff
+		iv[i] = byte(sum)
+		ctr = ctr>>8 + sum>>8
+	}
+
+	stream := cipher.NewCTR(f.block, iv[:])
+	if skip := off % aes.BlockSize; skip > 0 {
+		var discard [aes.BlockSize]byte
+		stream.XORKeyStream(discard[:skip], discard[:skip])
+	}
+
+	stream.XORKeyStream(dst, src)
+}
+
+func (f *encryptedFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.xor(p[:n], p[:n], f.roff)
+	f.roff += int64(n)
+	return n, err
+}
+
+func (f *encryptedFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.File.ReadAt(p, off+int64(encryptionHeaderSize))
+	f.xor(p[:n], p[:n], off)
+	return n, err
+}
+
+func (f *encryptedFile) Write(p []byte) (int, error) {
+	f.buf = append(f.buf[:0], p...)
+	f.xor(f.buf, f.buf, f.woff)
+
+	n, err := f.File.Write(f.buf)
+	f.woff += int64(n)
+	return n, err
+}
+
+func (f *encryptedFile) WriteAt(p []byte, off int64) (int, error) {
+	buf := make([]byte, len(p))
+	f.xor(buf, p, off)
+
+	return f.File.WriteAt(buf, off+int64(encryptionHeaderSize))
+}
+
+func (f *encryptedFile) Preallocate(offset, length int64) error {
+	return f.File.Preallocate(offset+int64(encryptionHeaderSize), length)
+}
+
+func (f *encryptedFile) SyncTo(length int64) (bool, error) {
+	return f.File.SyncTo(length + int64(encryptionHeaderSize))
+}
+
+func (f *encryptedFile) Prefetch(offset, length int64) error {
+	return f.File.Prefetch(offset+int64(encryptionHeaderSize), length)
+}
+
+func (f *encryptedFile) Stat() (vfs.FileInfo, error) {
+	fi, err := f.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptedFileInfo{fi}, nil
+}
+
+// encryptedFileInfo reports the size of the content of the file,
+// without its header.
+type encryptedFileInfo struct {
+	vfs.FileInfo
+}
+
+func (fi encryptedFileInfo) Size() int64 {
+	size := fi.FileInfo.Size()
+	if fi.IsDir() || size < int64(encryptionHeaderSize) {
+		return size
+	}
+
+	return size - int64(encryptionHeaderSize)
+}
+
+// Rekey re-encrypts the database located at path with the key returned by newKey.
+// If oldKey is nil, the database must not be encrypted; if newKey is nil,
+// the database is decrypted.
+// The database must not be in use. The files are rewritten to a new directory
+// which replaces the current one once complete, so that an interrupted rekey
+// leaves the database untouched.
+func Rekey(path string, oldKey, newKey KeyProvider) error {
+	fs := vfs.Default
+	dir := fs.PathJoin(path, BackupDir)
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf("database %q does not exist", path)
+		}
+		return err
+	}
+	if !fi.IsDir() {
+		return errors.Errorf("%q is not a database", path)
+	}
+
+	lock, err := LockDatabase(path)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	src, err := setupEncryption(fs, dir, oldKey, true)
+	if err != nil {
+		return err
+	}
+
+	staging := dir + ".rekey"
+	err = os.RemoveAll(staging)
+	if err != nil {
+		return err
+	}
+
+	dst, err := setupEncryption(fs, staging, newKey, false)
+	if err != nil {
+		return err
+	}
+
+	err = rekeyFiles(src, dst, dir, staging)
+	if err != nil {
+		_ = os.RemoveAll(staging)
+		return err
+	}
+
+	return swapDir(staging, dir)
+}
+
+func rekeyFiles(src, dst vfs.FS, dir, staging string) error {
+	err := dst.MkdirAll(staging, 0755)
+	if err != nil {
+		return err
+	}
+
+	names, err := src.List(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if name == KeyCheckFile || name == "LOCK" {
+			continue
+		}
+
+		// the rekeyed files differ from the ones of previous backups:
+		// the database gets a new identifier when it is opened.
+		if name == IDFile || name == BackupSourceFile {
+			continue
+		}
+
+		fi, err := os.Stat(src.PathJoin(dir, name))
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return errors.Errorf("unexpected directory %q", name)
+		}
+
+		err = vfs.CopyAcrossFS(src, src.PathJoin(dir, name), dst, dst.PathJoin(staging, name))
+		if err != nil {
+			return errors.Wrapf(err, "failed to copy %q", name)
+		}
+	}
+
+	return syncDir(staging)
+}
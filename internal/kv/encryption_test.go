@@ -0,0 +1,147 @@
+package kv_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/encoding"
+	"github.com/chaisql/chai/internal/kv"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryption(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "db")
+
+	key := kv.StaticKey(bytes.Repeat([]byte{1}, 32))
+	otherKey := kv.StaticKey(bytes.Repeat([]byte{2}, 32))
+	value := bytes.Repeat([]byte("secret"), 200)
+
+	open := func(path string, kp kv.KeyProvider) (*kv.PebbleEngine, error) {
+		return kv.NewEngine(path, kv.Options{
+			RollbackSegmentNamespace: int64(database.RollbackSegmentNamespace),
+			MinTransientNamespace:    10_000,
+			MaxTransientNamespace:    11_000,
+			KeyProvider:              kp,
+		}, &pebble.Options{
+			// flush often to create table files
+			MemTableSize: 256 << 10,
+		})
+	}
+
+	keyOf := func(i int) []byte {
+		return encoding.EncodeInt(encoding.EncodeInt(nil, 10), int64(i))
+	}
+
+	// checks that the data can be read and that no file contains it in clear
+	check := func(t *testing.T, path string, kp kv.KeyProvider) {
+		t.Helper()
+
+		ng, err := open(path, kp)
+		require.NoError(t, err)
+		defer ng.Close()
+
+		s := ng.NewSnapshotSession()
+		defer s.Close()
+		for i := 0; i < 3000; i += 100 {
+			v, err := s.Get(keyOf(i))
+			require.NoError(t, err)
+			require.Equal(t, value, v)
+		}
+
+		if kp == nil {
+			return
+		}
+
+		files, err := filepath.Glob(filepath.Join(path, "pebble", "*"))
+		require.NoError(t, err)
+		var tables int
+		for _, f := range files {
+			if strings.HasSuffix(f, ".sst") {
+				tables++
+			}
+			data, err := os.ReadFile(f)
+			// obsolete files are deleted in the background
+			if os.IsNotExist(err) {
+				continue
+			}
+			require.NoError(t, err)
+			require.False(t, bytes.Contains(data, []byte("secret")), f)
+		}
+		require.NotZero(t, tables)
+	}
+
+	ng, err := open(path, key)
+	require.NoError(t, err)
+
+	for i := 0; i < 3000; i += 100 {
+		s := ng.NewBatchSession()
+		for j := i; j < i+100; j++ {
+			require.NoError(t, s.Put(keyOf(j), value))
+		}
+		require.NoError(t, s.Commit())
+	}
+
+	// transient sessions use the same files
+	ts := ng.NewTransientSession()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, ts.Put(encoding.EncodeInt(encoding.EncodeInt(nil, 10_000), int64(i)), value))
+	}
+	require.NoError(t, ts.Close())
+
+	require.NoError(t, ng.Backup(context.Background(), filepath.Join(dir, "backup")))
+	require.NoError(t, ng.Close())
+
+	check(t, path, key)
+
+	t.Run("wrong key", func(t *testing.T) {
+		_, err := open(path, otherKey)
+		require.True(t, errors.Is(err, kv.ErrInvalidEncryptionKey))
+
+		_, err = open(path, nil)
+		require.True(t, errors.Is(err, kv.ErrInvalidEncryptionKey))
+
+		_, err = open(path, kv.StaticKey("short"))
+		require.Error(t, err)
+	})
+
+	t.Run("unencrypted database", func(t *testing.T) {
+		path := filepath.Join(dir, "plain")
+		ng, err := open(path, nil)
+		require.NoError(t, err)
+		require.NoError(t, ng.Close())
+
+		_, err = open(path, key)
+		require.True(t, errors.Is(err, kv.ErrInvalidEncryptionKey))
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		path := filepath.Join(dir, "restored")
+		require.NoError(t, kv.CopyBackup(context.Background(), filepath.Join(dir, "backup"), path))
+
+		check(t, path, key)
+	})
+
+	t.Run("rekey", func(t *testing.T) {
+		err := kv.Rekey(path, otherKey, key)
+		require.True(t, errors.Is(err, kv.ErrInvalidEncryptionKey))
+
+		require.NoError(t, kv.Rekey(path, key, otherKey))
+		check(t, path, otherKey)
+		_, err = open(path, key)
+		require.True(t, errors.Is(err, kv.ErrInvalidEncryptionKey))
+
+		// decrypt then encrypt again
+		require.NoError(t, kv.Rekey(path, otherKey, nil))
+		check(t, path, nil)
+		require.NoError(t, kv.Rekey(path, nil, key))
+		check(t, path, key)
+	})
+}
@@ -26,6 +26,12 @@ type PebbleEngine struct {
 	opts            Options
 	rollbackSegment *RollbackSegment
 
+	// location of the Pebble files, used to create backups.
+	path string
+	fs   vfs.FS
+	// identifier of the database, recorded by backups.
+	id string
+
 	// holds the shared snapshot read by all the read sessions
 	// when a write session is open.
 	// when no write session is open, the snapshot is nil
@@ -46,6 +52,16 @@ type Options struct {
 	MaxTransientBatchSize    int
 	MinTransientNamespace    uint64
 	MaxTransientNamespace    uint64
+	// NoSync disables syncing the write-ahead log to disk
+	// when a batch session is committed.
+	NoSync bool
+	// ReadOnly opens the engine in read-only mode.
+	// Batch sessions cannot be committed and transient sessions
+	// are kept in memory.
+	ReadOnly bool
+	// KeyProvider, if set, provides the key used to encrypt
+	// the files of the engine. It is ignored for in-memory engines.
+	KeyProvider KeyProvider
 }
 
 func NewEngineWith(path string, opts Options, popts *pebble.Options) (*PebbleEngine, error) {
@@ -61,19 +77,45 @@ func NewEngineWith(path string, opts Options, popts *pebble.Options) (*PebbleEng
 
 	popts.EnsureDefaults()
 
+	// backups copy the files as is, encrypted or not
+	fs := popts.FS
+	if efs, ok := fs.(*encryptedFS); ok {
+		fs = efs.FS
+	}
+
 	db, err := pebble.Open(path, popts)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewStore(db, opts), nil
+	ng := NewStore(db, opts)
+	ng.path = path
+	ng.fs = fs
+	ng.id, err = loadID(fs, path, opts.ReadOnly)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return ng, nil
 }
 
-func NewEngine(path string, opts Options) (*PebbleEngine, error) {
-	var popts pebble.Options
+// NewEngine opens a Pebble engine in the given directory, creating it if needed.
+// If path is ":memory:", the engine is stored in memory.
+// If popts is not nil, it is used to configure Pebble.
+func NewEngine(path string, opts Options, popts *pebble.Options) (*PebbleEngine, error) {
 	var pbpath string
 
+	if popts == nil {
+		popts = &pebble.Options{}
+	}
+
+	popts.ReadOnly = opts.ReadOnly
+
 	if path == ":memory:" {
+		if opts.ReadOnly {
+			return nil, errors.New("cannot open an in-memory database in read-only mode")
+		}
+
 		popts.FS = vfs.NewMem()
 	} else {
 		path = strings.TrimSpace(path)
@@ -88,6 +130,10 @@ func NewEngine(path string, opts Options) (*PebbleEngine, error) {
 				return nil, err
 			}
 
+			if opts.ReadOnly {
+				return nil, errors.Errorf("database %q does not exist", path)
+			}
+
 			err = os.MkdirAll(path, 0700)
 			if err != nil {
 				return nil, err
@@ -99,9 +145,18 @@ func NewEngine(path string, opts Options) (*PebbleEngine, error) {
 		}
 
 		pbpath = filepath.Join(path, "pebble")
+
+		if popts.FS == nil {
+			popts.FS = lockFS{FS: vfs.Default, shared: opts.ReadOnly}
+		}
+
+		popts.FS, err = setupEncryption(popts.FS, pbpath, opts.KeyProvider, opts.ReadOnly)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return NewEngineWith(pbpath, opts, &popts)
+	return NewEngineWith(pbpath, opts, popts)
 }
 
 // DefaultComparer is the default implementation of the Comparer interface for chai.
@@ -136,6 +191,10 @@ func NewStore(db *pebble.DB, opts Options) *PebbleEngine {
 	if opts.MaxTransientBatchSize <= 0 {
 		opts.MaxTransientBatchSize = defaultMaxTransientBatchSize
 	}
+	if opts.ReadOnly {
+		// transient sessions cannot write to the engine
+		opts.MaxTransientBatchSize = math.MaxInt
+	}
 	if opts.MinTransientNamespace == 0 {
 		panic("min transient namespace cannot be 0")
 	}
@@ -158,7 +217,21 @@ func (s *PebbleEngine) Rollback() error {
 	return s.rollbackSegment.Rollback()
 }
 
+// Recover rolls back the changes left in the rollback segment by a previous crash.
+// In read-only mode, nothing can be rolled back: it returns an error
+// if the rollback segment is not empty.
 func (s *PebbleEngine) Recover() error {
+	if s.opts.ReadOnly {
+		empty, err := s.rollbackSegment.IsEmpty()
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return errors.New("the database was not closed properly and must be opened in read-write mode to be recovered")
+		}
+		return nil
+	}
+
 	return s.rollbackSegment.Reset()
 }
 
@@ -0,0 +1,111 @@
+package kv
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/v2/vfs"
+)
+
+// ErrDatabaseLocked is returned when opening a database
+// that is already used by another engine.
+var ErrDatabaseLocked = errors.New("database is locked by another process")
+
+// lockFS overrides how Pebble locks the database directory.
+// Read-write engines acquire an exclusive lock, while read-only engines
+// acquire a shared lock, allowing multiple readers to open the same database
+// as long as no engine writes to it.
+type lockFS struct {
+	vfs.FS
+
+	shared bool
+}
+
+// file locks are held by processes, they must also be tracked
+// within the process to detect conflicts.
+var lockedFiles struct {
+	sync.Mutex
+
+	m map[string]*fileLock
+}
+
+type fileLock struct {
+	name   string
+	f      io.Closer
+	shared bool
+	refs   int
+}
+
+func (l *fileLock) Close() error {
+	lockedFiles.Lock()
+	defer lockedFiles.Unlock()
+
+	l.refs--
+	if l.refs > 0 {
+		return nil
+	}
+
+	delete(lockedFiles.m, l.name)
+	return l.f.Close()
+}
+
+func (fs lockFS) Lock(name string) (io.Closer, error) {
+	name, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+
+	lockedFiles.Lock()
+	defer lockedFiles.Unlock()
+
+	if lockedFiles.m == nil {
+		lockedFiles.m = make(map[string]*fileLock)
+	}
+
+	if l, ok := lockedFiles.m[name]; ok {
+		if !fs.shared || !l.shared {
+			return nil, errors.WithStack(ErrDatabaseLocked)
+		}
+
+		l.refs++
+		return l, nil
+	}
+
+	f, err := lockFile(name, fs.shared)
+	if err != nil {
+		return nil, err
+	}
+
+	l := fileLock{
+		name:   name,
+		f:      f,
+		shared: fs.shared,
+		refs:   1,
+	}
+	lockedFiles.m[name] = &l
+
+	return &l, nil
+}
+
+// LockDatabase acquires the exclusive lock of the database located at path,
+// preventing other engines from opening it until the returned closer is closed.
+// If the database doesn't exist, there is nothing to lock.
+func LockDatabase(path string) (io.Closer, error) {
+	dir := filepath.Join(path, BackupDir)
+	_, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return nopCloser{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return lockFS{FS: vfs.Default}.Lock(filepath.Join(dir, "LOCK"))
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
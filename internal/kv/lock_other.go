@@ -0,0 +1,15 @@
+//go:build !unix
+
+package kv
+
+import (
+	"io"
+
+	"github.com/cockroachdb/pebble/v2/vfs"
+)
+
+// lockFile acquires a lock on the given file for the current process.
+// Shared locks are not supported on this platform, all locks are exclusive.
+func lockFile(name string, shared bool) (io.Closer, error) {
+	return vfs.Default.Lock(name)
+}
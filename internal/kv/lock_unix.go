@@ -0,0 +1,50 @@
+//go:build unix
+
+package kv
+
+import (
+	"io"
+	"os"
+
+	"github.com/cockroachdb/errors"
+	"golang.org/x/sys/unix"
+)
+
+// lockFile acquires a lock on the given file for the current process.
+func lockFile(name string, shared bool) (io.Closer, error) {
+	var f *os.File
+	var err error
+	spec := unix.Flock_t{
+		Whence: io.SeekStart,
+		Start:  0,
+		Len:    0, // 0 means to lock the entire file.
+	}
+
+	if shared {
+		f, err = os.Open(name)
+		if os.IsNotExist(err) {
+			// the file is created by the first read-write engine,
+			// its absence means no one is writing to the database.
+			return nopCloser{}, nil
+		}
+		spec.Type = unix.F_RDLCK
+	} else {
+		f, err = os.Create(name)
+		spec.Type = unix.F_WRLCK
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = unix.FcntlFlock(f.Fd(), unix.F_SETLK, &spec)
+	if err != nil {
+		_ = f.Close()
+
+		if errors.Is(err, unix.EAGAIN) || errors.Is(err, unix.EACCES) {
+			return nil, errors.WithStack(ErrDatabaseLocked)
+		}
+		return nil, err
+	}
+
+	return f, nil
+}
@@ -3,7 +3,7 @@ package kv
 import (
 	"bytes"
 	"errors"
-	"io"
+	"fmt"
 
 	"github.com/chaisql/chai/internal/encoding"
 	"github.com/cockroachdb/pebble/v2"
@@ -29,13 +29,25 @@ func NewRollbackSegment(db *pebble.DB, namespace int64) *RollbackSegment {
 	}
 }
 
-func (s *RollbackSegment) Apply(b *pebble.Batch) error {
+// Apply stores the current value of every key modified by the batch
+// in the rollback segment, before the batch gets committed.
+// Range deletions are expanded into the keys they delete.
+// If undo is not nil, the current value of the keys that are not already
+// in the map are also added to it, nil meaning the key doesn't exist.
+func (s *RollbackSegment) Apply(b *pebble.Batch, undo map[string][]byte) error {
 	r := b.Reader()
 
-	for {
-		s.buf = s.buf[:len(s.nsStart)]
+	if !s.segmentCommitted {
+		// the header is stored under the namespace prefix alone,
+		// which sorts before the keys of the segment.
+		err := b.Set(s.nsStart, segmentFormat, nil)
+		if err != nil {
+			return err
+		}
+	}
 
-		kind, key, _, ok, err := r.Next()
+	for {
+		kind, key, value, ok, err := r.Next()
 		if err != nil {
 			return err
 		}
@@ -43,52 +55,98 @@ func (s *RollbackSegment) Apply(b *pebble.Batch) error {
 			break
 		}
 
-		if _, ok := s.seen[string(key)]; ok {
-			continue
+		switch kind {
+		case pebble.InternalKeyKindSet, pebble.InternalKeyKindDelete:
+			err = s.save(b, undo, key)
+		case pebble.InternalKeyKindRangeDelete:
+			// the value of a range deletion is its end key
+			err = s.saveRange(b, undo, key, value)
 		}
-		s.seen[string(key)] = struct{}{}
+		if err != nil {
+			return err
+		}
+	}
 
-		var v []byte
-		var closer io.Closer
+	s.segmentCommitted = true
+	s.buf = s.buf[:len(s.nsStart)]
 
-		switch kind {
-		case pebble.InternalKeyKindDelete, pebble.InternalKeyKindSet:
-			v, closer, err = s.db.Get(key)
-			if err != nil {
-				if !errors.Is(err, pebble.ErrNotFound) {
-					return err
-				}
-			}
-		default:
-			continue
-		}
+	return nil
+}
 
-		if v == nil {
-			// key not found, add a tombstone to the rollback segment
-			v = tombStone
+// save stores the current value of key in the rollback segment
+// and in undo, if it wasn't already.
+func (s *RollbackSegment) save(b *pebble.Batch, undo map[string][]byte, key []byte) error {
+	_, seen := s.seen[string(key)]
+	_, saved := undo[string(key)]
+	if seen && (undo == nil || saved) {
+		return nil
+	}
+
+	v, closer, err := s.db.Get(key)
+	if err != nil {
+		if !errors.Is(err, pebble.ErrNotFound) {
+			return err
 		}
+		return s.saveValue(b, undo, key, nil)
+	}
+	defer closer.Close()
+
+	if v == nil {
+		v = []byte{}
+	}
+	return s.saveValue(b, undo, key, v)
+}
 
-		// append the key to the buffer
-		s.buf = encoding.EncodeBytea(s.buf, key)
+// saveRange stores the current value of the keys between start and end.
+func (s *RollbackSegment) saveRange(b *pebble.Batch, undo map[string][]byte, start, end []byte) error {
+	it, err := s.db.NewIter(&pebble.IterOptions{
+		LowerBound: start,
+		UpperBound: end,
+	})
+	if err != nil {
+		return err
+	}
+	defer it.Close()
 
-		err = b.Set(s.buf, v, nil)
+	for it.First(); it.Valid(); it.Next() {
+		v, err := it.ValueAndErr()
 		if err != nil {
-			_ = closer.Close()
 			return err
 		}
 
-		if closer != nil {
-			err = closer.Close()
-			if err != nil {
-				return err
-			}
+		if v == nil {
+			v = []byte{}
+		}
+		err = s.saveValue(b, undo, it.Key(), v)
+		if err != nil {
+			return err
 		}
 	}
 
-	s.segmentCommitted = true
-	s.buf = s.buf[:len(s.nsStart)]
+	return it.Error()
+}
 
-	return nil
+// saveValue stores v, the current value of key, nil if it doesn't exist.
+func (s *RollbackSegment) saveValue(b *pebble.Batch, undo map[string][]byte, key, v []byte) error {
+	if _, saved := undo[string(key)]; undo != nil && !saved {
+		undo[string(key)] = bytes.Clone(v)
+	}
+
+	if _, seen := s.seen[string(key)]; seen {
+		return nil
+	}
+	s.seen[string(key)] = struct{}{}
+
+	// keys that were not found are stored as a tombstone
+	val := tombStone
+	if v != nil {
+		val = append([]byte{valuePrefix}, v...)
+	}
+
+	// append the key to the buffer
+	s.buf = encoding.EncodeBytea(s.buf[:len(s.nsStart)], key)
+
+	return b.Set(s.buf, val, nil)
 }
 
 func (s *RollbackSegment) Rollback() error {
@@ -107,8 +165,21 @@ func (s *RollbackSegment) Rollback() error {
 	}
 	defer it.Close()
 
+	var prefixed bool
 	for it.First(); it.Valid(); it.Next() {
 		k := it.Key()
+		v, err := it.ValueAndErr()
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(k, s.nsStart) {
+			if !bytes.Equal(v, segmentFormat) {
+				return fmt.Errorf("unsupported rollback segment format %v", v)
+			}
+			prefixed = true
+			continue
+		}
 
 		// skip the namespace prefix
 		n := encoding.Skip(k)
@@ -116,15 +187,16 @@ func (s *RollbackSegment) Rollback() error {
 
 		// get the key
 		uk, _ := encoding.DecodeBytea(k)
-		v, err := it.ValueAndErr()
-		if err != nil {
-			return err
-		}
 
-		if bytes.Equal(v, tombStone) {
+		switch {
+		case bytes.Equal(v, tombStone):
 			err = b.Delete(uk, nil)
-		} else {
+		case !prefixed:
 			err = b.Set(uk, v, nil)
+		case v[0] == valuePrefix:
+			err = b.Set(uk, v[1:], nil)
+		default:
+			err = fmt.Errorf("invalid value in rollback segment for key %v", uk)
 		}
 		if err != nil {
 			return err
@@ -148,6 +220,24 @@ func (s *RollbackSegment) Rollback() error {
 	return nil
 }
 
+// IsEmpty returns whether the rollback segment contains no changes.
+func (s *RollbackSegment) IsEmpty() (bool, error) {
+	it, err := s.db.NewIter(&pebble.IterOptions{
+		LowerBound: s.nsStart,
+		UpperBound: s.nsEnd,
+	})
+	if err != nil {
+		return false, err
+	}
+	defer it.Close()
+
+	if it.First() {
+		return false, nil
+	}
+
+	return true, it.Error()
+}
+
 func (s *RollbackSegment) Reset() error {
 	s.segmentCommitted = true
 	return s.Rollback()
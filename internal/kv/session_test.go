@@ -7,8 +7,10 @@ import (
 	"testing"
 
 	_ "github.com/chaisql/chai"
+	"github.com/chaisql/chai/internal/database"
 	"github.com/chaisql/chai/internal/encoding"
 	"github.com/chaisql/chai/internal/engine"
+	"github.com/chaisql/chai/internal/kv"
 	"github.com/chaisql/chai/internal/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -125,6 +127,142 @@ func TestRollback(t *testing.T) {
 	}
 }
 
+func TestCheckpoint(t *testing.T) {
+	key := func(i int64) []byte {
+		return encoding.EncodeInt(encoding.EncodeInt(nil, 10), i)
+	}
+
+	// put writes enough keys to make the batch be written
+	// to the database multiple times.
+	put := func(t *testing.T, s engine.Session, from, to, v int64) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			err := s.Put(key(i), encoding.EncodeInt(nil, v))
+			require.NoError(t, err)
+		}
+	}
+
+	requireValues := func(t *testing.T, s engine.Session, from, to, v int64) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			require.Equal(t, encoding.EncodeInt(nil, v), getValue(t, s, key(i)))
+		}
+	}
+
+	requireNotFound := func(t *testing.T, s engine.Session, from, to int64) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			_, err := s.Get(key(i))
+			require.ErrorIs(t, err, engine.ErrKeyNotFound)
+		}
+	}
+
+	t.Run("RollbackTo", func(t *testing.T) {
+		ng := testutil.NewEngine(t)
+		s := ng.NewBatchSession().(engine.CheckpointSession)
+		defer s.Close()
+
+		put(t, s, 0, 20, 1)
+		cp, err := s.Checkpoint()
+		require.NoError(t, err)
+
+		put(t, s, 10, 30, 2)
+		require.NoError(t, s.Delete(key(0)))
+
+		err = s.RollbackTo(cp)
+		require.NoError(t, err)
+
+		requireValues(t, s, 0, 20, 1)
+		requireNotFound(t, s, 20, 30)
+
+		// the checkpoint can be reused
+		put(t, s, 0, 30, 3)
+		err = s.RollbackTo(cp)
+		require.NoError(t, err)
+		requireValues(t, s, 0, 20, 1)
+		requireNotFound(t, s, 20, 30)
+
+		// the whole session can still be rolled back
+		require.NoError(t, s.Close())
+		require.NoError(t, ng.Rollback())
+
+		snapshot := ng.NewSnapshotSession()
+		defer snapshot.Close()
+		requireNotFound(t, snapshot, 0, 30)
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		ng := testutil.NewEngine(t)
+		s := ng.NewBatchSession().(engine.CheckpointSession)
+		defer s.Close()
+
+		cp1, err := s.Checkpoint()
+		require.NoError(t, err)
+		put(t, s, 0, 20, 1)
+
+		cp2, err := s.Checkpoint()
+		require.NoError(t, err)
+		put(t, s, 0, 30, 2)
+
+		err = s.RollbackTo(cp2)
+		require.NoError(t, err)
+		requireValues(t, s, 0, 20, 1)
+		requireNotFound(t, s, 20, 30)
+
+		put(t, s, 0, 30, 3)
+		err = s.RollbackTo(cp1)
+		require.NoError(t, err)
+		requireNotFound(t, s, 0, 30)
+
+		// cp2 was removed
+		err = s.RollbackTo(cp2)
+		require.Error(t, err)
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		ng := testutil.NewEngine(t)
+		s := ng.NewBatchSession().(engine.CheckpointSession)
+		defer s.Close()
+
+		cp1, err := s.Checkpoint()
+		require.NoError(t, err)
+		put(t, s, 0, 20, 1)
+
+		cp2, err := s.Checkpoint()
+		require.NoError(t, err)
+		put(t, s, 10, 30, 2)
+
+		err = s.Release(cp2)
+		require.NoError(t, err)
+		requireValues(t, s, 0, 10, 1)
+		requireValues(t, s, 10, 30, 2)
+
+		// changes made after cp2 are now part of cp1
+		err = s.RollbackTo(cp1)
+		require.NoError(t, err)
+		requireNotFound(t, s, 0, 30)
+	})
+
+	t.Run("Commit", func(t *testing.T) {
+		ng := testutil.NewEngine(t)
+		s := ng.NewBatchSession().(engine.CheckpointSession)
+
+		put(t, s, 0, 20, 1)
+		cp, err := s.Checkpoint()
+		require.NoError(t, err)
+		put(t, s, 0, 30, 2)
+		err = s.RollbackTo(cp)
+		require.NoError(t, err)
+
+		require.NoError(t, s.Commit())
+
+		snapshot := ng.NewSnapshotSession()
+		defer snapshot.Close()
+		requireValues(t, snapshot, 0, 20, 1)
+		requireNotFound(t, snapshot, 20, 30)
+	})
+}
+
 func TestStorePut(t *testing.T) {
 	key := encoding.EncodeInt64(nil, 1)
 
@@ -429,3 +567,112 @@ func TestQueriesSameTransaction(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func TestRecoverReadOnly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "db")
+	opts := kv.Options{
+		RollbackSegmentNamespace: int64(database.RollbackSegmentNamespace),
+		MinTransientNamespace:    10_000,
+		MaxTransientNamespace:    11_000,
+	}
+
+	// simulate a crash by leaving a key in the rollback segment
+	ng, err := kv.NewEngine(dir, opts, nil)
+	require.NoError(t, err)
+	key := encoding.EncodeInt(encoding.EncodeInt(nil, 10), 1)
+	rkey := encoding.EncodeBytea(encoding.EncodeInt(nil, opts.RollbackSegmentNamespace), key)
+	require.NoError(t, ng.DB().Set(key, []byte("new"), nil))
+	require.NoError(t, ng.DB().Set(rkey, []byte{0}, nil))
+	require.NoError(t, ng.Close())
+
+	// the changes cannot be rolled back in read-only mode
+	opts.ReadOnly = true
+	ng, err = kv.NewEngine(dir, opts, nil)
+	require.NoError(t, err)
+	require.ErrorContains(t, ng.Recover(), "must be opened in read-write mode")
+	require.NoError(t, ng.Close())
+
+	opts.ReadOnly = false
+	ng, err = kv.NewEngine(dir, opts, nil)
+	require.NoError(t, err)
+	require.NoError(t, ng.Recover())
+	require.NoError(t, ng.Close())
+
+	opts.ReadOnly = true
+	ng, err = kv.NewEngine(dir, opts, nil)
+	require.NoError(t, err)
+	defer ng.Close()
+	require.NoError(t, ng.Recover())
+
+	s := ng.NewSnapshotSession()
+	defer s.Close()
+	_, err = s.Get(key)
+	require.ErrorIs(t, err, engine.ErrKeyNotFound)
+}
+
+func TestRecoverSegmentFormat(t *testing.T) {
+	opts := kv.Options{
+		RollbackSegmentNamespace: int64(database.RollbackSegmentNamespace),
+		MinTransientNamespace:    10_000,
+		MaxTransientNamespace:    11_000,
+	}
+	ns := encoding.EncodeInt(nil, opts.RollbackSegmentNamespace)
+	k1 := encoding.EncodeInt(encoding.EncodeInt(nil, 10), 1)
+	k2 := encoding.EncodeInt(encoding.EncodeInt(nil, 10), 2)
+
+	t.Run("with header", func(t *testing.T) {
+		ng, err := kv.NewEngine(":memory:", opts, nil)
+		require.NoError(t, err)
+		defer ng.Close()
+
+		require.NoError(t, ng.DB().Set(k1, []byte{1, 2, 3}, nil))
+
+		s := ng.NewBatchSession().(engine.CheckpointSession)
+		require.NoError(t, s.Put(k1, []byte("new")))
+		require.NoError(t, s.Put(k2, []byte("new")))
+		// write the batch and the rollback segment to the database
+		_, err = s.Checkpoint()
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+
+		require.NoError(t, ng.Rollback())
+
+		ss := ng.NewSnapshotSession()
+		defer ss.Close()
+		require.Equal(t, []byte{1, 2, 3}, getValue(t, ss, k1))
+		_, err = ss.Get(k2)
+		require.ErrorIs(t, err, engine.ErrKeyNotFound)
+	})
+
+	t.Run("without header", func(t *testing.T) {
+		ng, err := kv.NewEngine(":memory:", opts, nil)
+		require.NoError(t, err)
+		defer ng.Close()
+
+		// segments written by older versions store the values as is
+		require.NoError(t, ng.DB().Set(k1, []byte("new"), nil))
+		require.NoError(t, ng.DB().Set(k2, []byte("new"), nil))
+		require.NoError(t, ng.DB().Set(encoding.EncodeBytea(ns, k1), []byte{1, 2, 3}, nil))
+		require.NoError(t, ng.DB().Set(encoding.EncodeBytea(ns, k2), []byte{0}, nil))
+
+		require.NoError(t, ng.Recover())
+
+		s := ng.NewSnapshotSession()
+		defer s.Close()
+		require.Equal(t, []byte{1, 2, 3}, getValue(t, s, k1))
+		_, err = s.Get(k2)
+		require.ErrorIs(t, err, engine.ErrKeyNotFound)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		ng, err := kv.NewEngine(":memory:", opts, nil)
+		require.NoError(t, err)
+		defer ng.Close()
+
+		require.NoError(t, ng.DB().Set(k1, []byte("new"), nil))
+		require.NoError(t, ng.DB().Set(ns, []byte{99}, nil))
+		require.NoError(t, ng.DB().Set(encoding.EncodeBytea(ns, k1), []byte{1, 2, 3}, nil))
+
+		require.ErrorContains(t, ng.Recover(), "unsupported rollback segment format")
+	})
+}
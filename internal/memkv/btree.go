@@ -0,0 +1,394 @@
+package memkv
+
+import "github.com/chaisql/chai/internal/kv"
+
+// degree of the B-tree: nodes hold between degree-1
+// and 2*degree-1 items, except for the root.
+const (
+	degree   = 32
+	maxItems = 2*degree - 1
+	minItems = degree - 1
+)
+
+// compare uses the same ordering as the Pebble engine.
+var compare = kv.DefaultComparer.Compare
+
+type item struct {
+	key   []byte
+	value []byte
+}
+
+// owner identifies the tree allowed to modify a node in place.
+// Nodes owned by another tree are copied before being modified,
+// which makes cloning a tree a constant time operation.
+type owner struct {
+	// make the struct non-zero sized, so that
+	// every allocation returns a distinct pointer.
+	_ byte
+}
+
+type node struct {
+	items    []item
+	children []*node
+	owner    *owner
+}
+
+// btree is an ordered map of keys to values with copy-on-write snapshots.
+// It is not safe for concurrent use, but its snapshots can be read
+// concurrently with the tree.
+type btree struct {
+	root  *node
+	owner *owner
+}
+
+// newBtree returns a tree starting from the given snapshot.
+func newBtree(root *node) *btree {
+	return &btree{root: root, owner: new(owner)}
+}
+
+// snapshot returns the root of an immutable copy of the tree.
+// The nodes are shared until the tree is modified.
+func (t *btree) snapshot() *node {
+	// the tree can no longer modify its nodes in place
+	t.owner = new(owner)
+	return t.root
+}
+
+// find returns the index of the first item whose key is greater
+// or equal to k, and whether the key of that item equals k.
+func (n *node) find(k []byte) (int, bool) {
+	lo, hi := 0, len(n.items)
+	for lo < hi {
+		m := int(uint(lo+hi) >> 1)
+		if compare(n.items[m].key, k) < 0 {
+			lo = m + 1
+		} else {
+			hi = m
+		}
+	}
+
+	return lo, lo < len(n.items) && compare(n.items[lo].key, k) == 0
+}
+
+func (n *node) leaf() bool {
+	return len(n.children) == 0
+}
+
+// mutableFor returns n if it is owned by o, or a copy of n owned by o.
+func (n *node) mutableFor(o *owner) *node {
+	if n.owner == o {
+		return n
+	}
+
+	c := node{
+		items: make([]item, len(n.items), cap(n.items)),
+		owner: o,
+	}
+	copy(c.items, n.items)
+	if !n.leaf() {
+		c.children = make([]*node, len(n.children), cap(n.children))
+		copy(c.children, n.children)
+	}
+
+	return &c
+}
+
+func (n *node) mutableChild(i int) *node {
+	c := n.children[i].mutableFor(n.owner)
+	n.children[i] = c
+	return c
+}
+
+func (t *btree) get(k []byte) ([]byte, bool) {
+	n := t.root
+	for n != nil {
+		i, found := n.find(k)
+		if found {
+			return n.items[i].value, true
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.children[i]
+	}
+
+	return nil, false
+}
+
+// set stores the key value pair, replacing the existing value if any.
+// The tree takes ownership of k and v.
+func (t *btree) set(k, v []byte) {
+	it := item{key: k, value: v}
+
+	if t.root == nil {
+		t.root = &node{owner: t.owner}
+		t.root.items = append(t.root.items, it)
+		return
+	}
+
+	t.root = t.root.mutableFor(t.owner)
+	if len(t.root.items) >= maxItems {
+		middle, right := t.root.split(maxItems / 2)
+		t.root = &node{
+			items:    []item{middle},
+			children: []*node{t.root, right},
+			owner:    t.owner,
+		}
+	}
+
+	t.root.insert(it)
+}
+
+// split splits the node at the given index. The item at that index
+// is returned along with the new node holding the items after it.
+func (n *node) split(i int) (item, *node) {
+	middle := n.items[i]
+
+	right := node{owner: n.owner}
+	right.items = append(right.items, n.items[i+1:]...)
+	clear(n.items[i:])
+	n.items = n.items[:i]
+	if !n.leaf() {
+		right.children = append(right.children, n.children[i+1:]...)
+		clear(n.children[i+1:])
+		n.children = n.children[:i+1]
+	}
+
+	return middle, &right
+}
+
+// insert inserts the item in the subtree, which must not be full.
+func (n *node) insert(it item) {
+	i, found := n.find(it.key)
+	if found {
+		n.items[i] = it
+		return
+	}
+
+	if n.leaf() {
+		n.items = append(n.items, item{})
+		copy(n.items[i+1:], n.items[i:])
+		n.items[i] = it
+		return
+	}
+
+	// split full children before descending, so that the
+	// split item can always be inserted in this node.
+	if len(n.children[i].items) >= maxItems {
+		middle, right := n.mutableChild(i).split(maxItems / 2)
+
+		n.items = append(n.items, item{})
+		copy(n.items[i+1:], n.items[i:])
+		n.items[i] = middle
+
+		n.children = append(n.children, nil)
+		copy(n.children[i+2:], n.children[i+1:])
+		n.children[i+1] = right
+
+		switch c := compare(it.key, middle.key); {
+		case c == 0:
+			n.items[i] = it
+			return
+		case c > 0:
+			i++
+		}
+	}
+
+	n.mutableChild(i).insert(it)
+}
+
+// delete removes the key from the tree and returns whether it existed.
+func (t *btree) delete(k []byte) bool {
+	if t.root == nil {
+		return false
+	}
+
+	t.root = t.root.mutableFor(t.owner)
+	_, ok := t.root.remove(k, false)
+
+	// the root was merged with its children
+	if len(t.root.items) == 0 {
+		if t.root.leaf() {
+			t.root = nil
+		} else {
+			t.root = t.root.children[0]
+		}
+	}
+
+	return ok
+}
+
+// remove removes the item with the given key from the subtree,
+// or its greatest item if max is true.
+func (n *node) remove(k []byte, max bool) (item, bool) {
+	var i int
+	var found bool
+	if max {
+		i = len(n.items)
+		if n.leaf() {
+			i--
+			found = true
+		}
+	} else {
+		i, found = n.find(k)
+	}
+
+	if n.leaf() {
+		if !found {
+			return item{}, false
+		}
+
+		it := n.items[i]
+		copy(n.items[i:], n.items[i+1:])
+		n.items[len(n.items)-1] = item{}
+		n.items = n.items[:len(n.items)-1]
+		return it, true
+	}
+
+	// ensure the child has enough items to remove one
+	if len(n.children[i].items) <= minItems {
+		n.growChild(i)
+		return n.remove(k, max)
+	}
+
+	child := n.mutableChild(i)
+	if found {
+		// replace the item with its predecessor
+		it := n.items[i]
+		n.items[i], _ = child.remove(nil, true)
+		return it, true
+	}
+
+	return child.remove(k, max)
+}
+
+// growChild adds an item to the child at index i, either by taking one
+// from a sibling or by merging it with a sibling.
+func (n *node) growChild(i int) {
+	switch {
+	case i > 0 && len(n.children[i-1].items) > minItems:
+		// steal from the left sibling
+		child := n.mutableChild(i)
+		left := n.mutableChild(i - 1)
+
+		stolen := left.items[len(left.items)-1]
+		left.items[len(left.items)-1] = item{}
+		left.items = left.items[:len(left.items)-1]
+
+		child.items = append(child.items, item{})
+		copy(child.items[1:], child.items)
+		child.items[0] = n.items[i-1]
+		n.items[i-1] = stolen
+
+		if !left.leaf() {
+			c := left.children[len(left.children)-1]
+			left.children[len(left.children)-1] = nil
+			left.children = left.children[:len(left.children)-1]
+
+			child.children = append(child.children, nil)
+			copy(child.children[1:], child.children)
+			child.children[0] = c
+		}
+	case i < len(n.items) && len(n.children[i+1].items) > minItems:
+		// steal from the right sibling
+		child := n.mutableChild(i)
+		right := n.mutableChild(i + 1)
+
+		stolen := right.items[0]
+		copy(right.items, right.items[1:])
+		right.items[len(right.items)-1] = item{}
+		right.items = right.items[:len(right.items)-1]
+
+		child.items = append(child.items, n.items[i])
+		n.items[i] = stolen
+
+		if !right.leaf() {
+			c := right.children[0]
+			copy(right.children, right.children[1:])
+			right.children[len(right.children)-1] = nil
+			right.children = right.children[:len(right.children)-1]
+
+			child.children = append(child.children, c)
+		}
+	default:
+		// merge with the right sibling
+		if i >= len(n.items) {
+			i--
+		}
+		child := n.mutableChild(i)
+		right := n.children[i+1]
+
+		child.items = append(child.items, n.items[i])
+		child.items = append(child.items, right.items...)
+		child.children = append(child.children, right.children...)
+
+		copy(n.items[i:], n.items[i+1:])
+		n.items[len(n.items)-1] = item{}
+		n.items = n.items[:len(n.items)-1]
+
+		copy(n.children[i+1:], n.children[i+2:])
+		n.children[len(n.children)-1] = nil
+		n.children = n.children[:len(n.children)-1]
+	}
+}
+
+// seekGE returns the first item whose key is greater or equal to k,
+// or strictly greater if strict is true. If k is nil, it returns the first item.
+func (n *node) seekGE(k []byte, strict bool) (item, bool) {
+	if n == nil {
+		return item{}, false
+	}
+
+	i := 0
+	if k != nil {
+		var found bool
+		i, found = n.find(k)
+		if found {
+			if !strict {
+				return n.items[i], true
+			}
+			i++
+		}
+	}
+
+	if !n.leaf() {
+		if it, ok := n.children[i].seekGE(k, strict); ok {
+			return it, true
+		}
+	}
+
+	if i < len(n.items) {
+		return n.items[i], true
+	}
+
+	return item{}, false
+}
+
+// seekLT returns the last item whose key is strictly lower than k,
+// or lower or equal if inclusive is true. If k is nil, it returns the last item.
+func (n *node) seekLT(k []byte, inclusive bool) (item, bool) {
+	if n == nil {
+		return item{}, false
+	}
+
+	i := len(n.items)
+	if k != nil {
+		var found bool
+		i, found = n.find(k)
+		if found && inclusive {
+			return n.items[i], true
+		}
+	}
+
+	if !n.leaf() {
+		if it, ok := n.children[i].seekLT(k, inclusive); ok {
+			return it, true
+		}
+	}
+
+	if i > 0 {
+		return n.items[i-1], true
+	}
+
+	return item{}, false
+}
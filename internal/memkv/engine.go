@@ -0,0 +1,363 @@
+// Package memkv implements a storage engine that keeps the data in memory,
+// in a copy-on-write B-tree. Unlike the Pebble engine, it doesn't have
+// any write-ahead log or compaction overhead, which makes it well suited
+// for tests and short-lived databases.
+package memkv
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/chaisql/chai/internal/engine"
+	"github.com/cockroachdb/errors"
+)
+
+var _ engine.Engine = (*Engine)(nil)
+
+// Engine is an in-memory implementation of the engine.Engine interface.
+// Write sessions work on a private copy of the tree which replaces
+// the shared one when they are committed, while read sessions use
+// the tree as it was when they were created.
+type Engine struct {
+	mu     sync.RWMutex
+	root   *node
+	closed bool
+}
+
+// NewEngine returns an empty in-memory engine.
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+func (ng *Engine) Close() error {
+	ng.mu.Lock()
+	defer ng.mu.Unlock()
+
+	if ng.closed {
+		return errors.New("already closed")
+	}
+	ng.closed = true
+	ng.root = nil
+
+	return nil
+}
+
+// Rollback does nothing: uncommitted changes are never visible
+// outside of their session.
+func (ng *Engine) Rollback() error {
+	return nil
+}
+
+// Recover does nothing: there is nothing to recover from
+// once the process exits.
+func (ng *Engine) Recover() error {
+	return nil
+}
+
+// CleanupTransientNamespaces does nothing: transient sessions
+// store their data in their own tree.
+func (ng *Engine) CleanupTransientNamespaces() error {
+	return nil
+}
+
+func (ng *Engine) snapshot() *node {
+	ng.mu.RLock()
+	defer ng.mu.RUnlock()
+
+	return ng.root
+}
+
+func (ng *Engine) NewSnapshotSession() engine.Session {
+	return &SnapshotSession{
+		root: ng.snapshot(),
+	}
+}
+
+func (ng *Engine) NewBatchSession() engine.Session {
+	return &BatchSession{
+		ng:   ng,
+		tree: newBtree(ng.snapshot()),
+	}
+}
+
+func (ng *Engine) NewTransientSession() engine.Session {
+	return &TransientSession{
+		tree: newBtree(nil),
+	}
+}
+
+func get(root *node, k []byte) ([]byte, error) {
+	t := btree{root: root}
+	v, ok := t.get(k)
+	if !ok {
+		return nil, errors.WithStack(engine.ErrKeyNotFound)
+	}
+
+	return bytes.Clone(v), nil
+}
+
+func exists(root *node, k []byte) bool {
+	t := btree{root: root}
+	_, ok := t.get(k)
+	return ok
+}
+
+func put(t *btree, k, v []byte) error {
+	if len(k) == 0 {
+		return errors.New("cannot store empty key")
+	}
+
+	if len(v) == 0 {
+		return errors.New("cannot store empty value")
+	}
+
+	t.set(bytes.Clone(k), bytes.Clone(v))
+	return nil
+}
+
+// deleteRange deletes the keys greater or equal to start and lower than end.
+func deleteRange(t *btree, start, end []byte) {
+	var keys [][]byte
+	it, ok := t.root.seekGE(start, false)
+	for ok && compare(it.key, end) < 0 {
+		keys = append(keys, it.key)
+		it, ok = t.root.seekGE(it.key, true)
+	}
+
+	for _, k := range keys {
+		t.delete(k)
+	}
+}
+
+// SnapshotSession is a read-only session on the state
+// of the engine when it was created.
+type SnapshotSession struct {
+	root   *node
+	closed bool
+}
+
+var _ engine.Session = (*SnapshotSession)(nil)
+
+func (s *SnapshotSession) Commit() error {
+	return errors.New("cannot commit in read-only mode")
+}
+
+func (s *SnapshotSession) Close() error {
+	if s.closed {
+		return errors.New("already closed")
+	}
+	s.closed = true
+	s.root = nil
+
+	return nil
+}
+
+func (s *SnapshotSession) Insert(k, v []byte) error {
+	return errors.New("cannot insert in read-only mode")
+}
+
+func (s *SnapshotSession) Put(k, v []byte) error {
+	return errors.New("cannot put in read-only mode")
+}
+
+// Get returns a value associated with the given key. If not found, returns ErrKeyNotFound.
+func (s *SnapshotSession) Get(k []byte) ([]byte, error) {
+	return get(s.root, k)
+}
+
+// Exists returns whether a key exists and is visible by the current session.
+func (s *SnapshotSession) Exists(k []byte) (bool, error) {
+	return exists(s.root, k), nil
+}
+
+func (s *SnapshotSession) Delete(k []byte) error {
+	return errors.New("cannot delete in read-only mode")
+}
+
+func (s *SnapshotSession) DeleteRange(start []byte, end []byte) error {
+	return errors.New("cannot delete range in read-only mode")
+}
+
+func (s *SnapshotSession) Iterator(opts *engine.IterOptions) (engine.Iterator, error) {
+	return newIterator(s.root, opts), nil
+}
+
+// BatchSession is a read-write session. Its changes are
+// made visible to the other sessions when it is committed.
+type BatchSession struct {
+	ng     *Engine
+	tree   *btree
+	closed bool
+	// state of the tree at every checkpoint.
+	checkpoints []*node
+}
+
+var _ engine.CheckpointSession = (*BatchSession)(nil)
+
+func (s *BatchSession) Commit() error {
+	if s.closed {
+		return errors.New("already closed")
+	}
+
+	s.ng.mu.Lock()
+	if s.ng.closed {
+		s.ng.mu.Unlock()
+		return errors.New("engine is closed")
+	}
+	s.ng.root = s.tree.snapshot()
+	s.ng.mu.Unlock()
+
+	return s.Close()
+}
+
+func (s *BatchSession) Close() error {
+	if s.closed {
+		return errors.New("already closed")
+	}
+	s.closed = true
+	s.checkpoints = nil
+
+	return nil
+}
+
+// Get returns a value associated with the given key. If not found, returns ErrKeyNotFound.
+func (s *BatchSession) Get(k []byte) ([]byte, error) {
+	return get(s.tree.root, k)
+}
+
+// Exists returns whether a key exists and is visible by the current session.
+func (s *BatchSession) Exists(k []byte) (bool, error) {
+	return exists(s.tree.root, k), nil
+}
+
+// Insert inserts a key-value pair. If it already exists, it returns ErrKeyAlreadyExists.
+func (s *BatchSession) Insert(k, v []byte) error {
+	if exists(s.tree.root, k) {
+		return engine.ErrKeyAlreadyExists
+	}
+
+	return put(s.tree, k, v)
+}
+
+// Put stores a key value pair. If it already exists, it overrides it.
+func (s *BatchSession) Put(k, v []byte) error {
+	return put(s.tree, k, v)
+}
+
+// Delete a record by key. If the key doesn't exist, it doesn't do anything.
+func (s *BatchSession) Delete(k []byte) error {
+	s.tree.delete(k)
+	return nil
+}
+
+// DeleteRange deletes all keys in the given range.
+func (s *BatchSession) DeleteRange(start []byte, end []byte) error {
+	deleteRange(s.tree, start, end)
+	return nil
+}
+
+// Iterator returns an iterator on the state of the session
+// when it is created. Changes made afterwards are not visible.
+func (s *BatchSession) Iterator(opts *engine.IterOptions) (engine.Iterator, error) {
+	return newIterator(s.tree.snapshot(), opts), nil
+}
+
+// Checkpoint marks the current state of the session and returns its id.
+func (s *BatchSession) Checkpoint() (int, error) {
+	if s.closed {
+		return 0, errors.New("already closed")
+	}
+
+	s.checkpoints = append(s.checkpoints, s.tree.snapshot())
+	return len(s.checkpoints) - 1, nil
+}
+
+// RollbackTo discards all the changes made after the given checkpoint.
+func (s *BatchSession) RollbackTo(id int) error {
+	if s.closed {
+		return errors.New("already closed")
+	}
+	if id < 0 || id >= len(s.checkpoints) {
+		return errors.Errorf("unknown checkpoint %d", id)
+	}
+
+	// the nodes of the checkpoint are not owned by the tree,
+	// they are copied if modified again.
+	s.tree.root = s.checkpoints[id]
+	s.checkpoints = s.checkpoints[:id+1]
+
+	return nil
+}
+
+// Release removes the given checkpoint and the ones created after it.
+func (s *BatchSession) Release(id int) error {
+	if s.closed {
+		return errors.New("already closed")
+	}
+	if id < 0 || id >= len(s.checkpoints) {
+		return errors.Errorf("unknown checkpoint %d", id)
+	}
+
+	s.checkpoints = s.checkpoints[:id]
+
+	return nil
+}
+
+// TransientSession stores temporary data in its own tree,
+// which is discarded when the session is closed.
+type TransientSession struct {
+	tree   *btree
+	closed bool
+}
+
+var _ engine.Session = (*TransientSession)(nil)
+
+func (s *TransientSession) Commit() error {
+	return errors.New("cannot commit in transient mode")
+}
+
+func (s *TransientSession) Close() error {
+	if s.closed {
+		return errors.New("already closed")
+	}
+	s.closed = true
+
+	return nil
+}
+
+func (s *TransientSession) Insert(k, v []byte) error {
+	return errors.New("cannot insert in transient mode")
+}
+
+// Put stores a key value pair. If it already exists, it overrides it.
+func (s *TransientSession) Put(k, v []byte) error {
+	return put(s.tree, k, v)
+}
+
+// Get returns a value associated with the given key. If not found, returns ErrKeyNotFound.
+func (s *TransientSession) Get(k []byte) ([]byte, error) {
+	return get(s.tree.root, k)
+}
+
+// Exists returns whether a key exists and is visible by the current session.
+func (s *TransientSession) Exists(k []byte) (bool, error) {
+	return exists(s.tree.root, k), nil
+}
+
+// Delete a record by key. If not found, returns ErrKeyNotFound.
+func (s *TransientSession) Delete(k []byte) error {
+	if !s.tree.delete(k) {
+		return errors.WithStack(engine.ErrKeyNotFound)
+	}
+
+	return nil
+}
+
+func (s *TransientSession) DeleteRange(start []byte, end []byte) error {
+	deleteRange(s.tree, start, end)
+	return nil
+}
+
+func (s *TransientSession) Iterator(opts *engine.IterOptions) (engine.Iterator, error) {
+	return newIterator(s.tree.snapshot(), opts), nil
+}
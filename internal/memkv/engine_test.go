@@ -0,0 +1,133 @@
+package memkv_test
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/chaisql/chai/internal/encoding"
+	"github.com/chaisql/chai/internal/engine"
+	"github.com/chaisql/chai/internal/memkv"
+	"github.com/stretchr/testify/require"
+)
+
+func key(i int64) []byte {
+	return encoding.EncodeInt(encoding.EncodeInt(nil, 10), i)
+}
+
+// requireContent checks that the session contains exactly the given keys,
+// in order, in both directions.
+func requireContent(t *testing.T, s engine.Session, want map[int64]int64) {
+	t.Helper()
+
+	keys := make([]int64, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	it, err := s.Iterator(nil)
+	require.NoError(t, err)
+	defer it.Close()
+
+	var i int
+	for it.First(); it.Valid(); it.Next() {
+		require.Less(t, i, len(keys))
+		require.Equal(t, key(keys[i]), it.Key())
+		v, err := it.Value()
+		require.NoError(t, err)
+		require.Equal(t, encoding.EncodeInt(nil, want[keys[i]]), v)
+		i++
+	}
+	require.Equal(t, len(keys), i)
+
+	for it.Last(); it.Valid(); it.Prev() {
+		i--
+		require.Equal(t, key(keys[i]), it.Key())
+	}
+	require.Equal(t, 0, i)
+}
+
+func TestEngine(t *testing.T) {
+	ng := memkv.NewEngine()
+	defer ng.Close()
+
+	rng := rand.New(rand.NewSource(42))
+	want := make(map[int64]int64)
+
+	for round := 0; round < 5; round++ {
+		before := ng.NewSnapshotSession()
+		snapshot := make(map[int64]int64, len(want))
+		for k, v := range want {
+			snapshot[k] = v
+		}
+
+		s := ng.NewBatchSession().(engine.CheckpointSession)
+		for i := 0; i < 20000; i++ {
+			k := rng.Int63n(5000)
+			if rng.Intn(3) == 0 {
+				require.NoError(t, s.Delete(key(k)))
+				delete(want, k)
+			} else {
+				require.NoError(t, s.Put(key(k), encoding.EncodeInt(nil, int64(i))))
+				want[k] = int64(i)
+			}
+		}
+
+		// changes made after a checkpoint can be discarded
+		_, err := s.Checkpoint()
+		require.NoError(t, err)
+		require.NoError(t, s.DeleteRange(key(100), key(4900)))
+		remaining := make(map[int64]int64)
+		for k, v := range want {
+			if k < 100 || k >= 4900 {
+				remaining[k] = v
+			}
+		}
+		requireContent(t, s, remaining)
+		require.NoError(t, s.RollbackTo(0))
+
+		requireContent(t, s, want)
+		// changes are not visible until committed
+		requireContent(t, before, snapshot)
+		require.NoError(t, s.Commit())
+
+		requireContent(t, before, snapshot)
+		require.NoError(t, before.Close())
+
+		after := ng.NewSnapshotSession()
+		requireContent(t, after, want)
+		require.NoError(t, after.Close())
+	}
+}
+
+func TestIterator(t *testing.T) {
+	ng := memkv.NewEngine()
+	defer ng.Close()
+
+	s := ng.NewBatchSession()
+	defer s.Close()
+
+	for i := int64(0); i < 100; i += 2 {
+		require.NoError(t, s.Put(key(i), []byte{1}))
+	}
+
+	it, err := s.Iterator(&engine.IterOptions{
+		LowerBound: key(11),
+		UpperBound: key(20),
+	})
+	require.NoError(t, err)
+	defer it.Close()
+
+	// writes made after the iterator is created are not visible
+	require.NoError(t, s.Put(key(15), []byte{1}))
+
+	var keys [][]byte
+	for it.First(); it.Valid(); it.Next() {
+		keys = append(keys, it.Key())
+	}
+	require.Equal(t, [][]byte{key(12), key(14), key(16), key(18)}, keys)
+
+	require.True(t, it.Last())
+	require.Equal(t, key(18), it.Key())
+}
@@ -0,0 +1,109 @@
+package memkv
+
+import "github.com/chaisql/chai/internal/engine"
+
+// iterator iterates over an immutable snapshot of a tree.
+// Moving the iterator seeks the next key from the root,
+// which keeps it valid regardless of the changes made to the tree.
+type iterator struct {
+	root         *node
+	lower, upper []byte
+	cur          item
+	valid        bool
+}
+
+var _ engine.Iterator = (*iterator)(nil)
+
+func newIterator(root *node, opts *engine.IterOptions) *iterator {
+	it := iterator{
+		root: root,
+	}
+	if opts != nil {
+		it.lower = opts.LowerBound
+		it.upper = opts.UpperBound
+	}
+
+	return &it
+}
+
+func (it *iterator) setForward(cur item, ok bool) bool {
+	it.cur = cur
+	it.valid = ok && (it.upper == nil || compare(cur.key, it.upper) < 0)
+	return it.valid
+}
+
+func (it *iterator) setBackward(cur item, ok bool) bool {
+	it.cur = cur
+	it.valid = ok && (it.lower == nil || compare(cur.key, it.lower) >= 0)
+	return it.valid
+}
+
+func (it *iterator) Close() error {
+	it.valid = false
+	it.root = nil
+	return nil
+}
+
+func (it *iterator) First() bool {
+	return it.setForward(it.root.seekGE(it.lower, false))
+}
+
+func (it *iterator) Last() bool {
+	return it.setBackward(it.root.seekLT(it.upper, false))
+}
+
+func (it *iterator) Start(reverse bool) bool {
+	if !reverse {
+		return it.First()
+	}
+
+	return it.Last()
+}
+
+func (it *iterator) End(reverse bool) bool {
+	if !reverse {
+		return it.Last()
+	}
+
+	return it.First()
+}
+
+func (it *iterator) Valid() bool {
+	return it.valid
+}
+
+func (it *iterator) Next() bool {
+	if !it.valid {
+		return false
+	}
+
+	return it.setForward(it.root.seekGE(it.cur.key, true))
+}
+
+func (it *iterator) Prev() bool {
+	if !it.valid {
+		return false
+	}
+
+	return it.setBackward(it.root.seekLT(it.cur.key, false))
+}
+
+func (it *iterator) Move(reverse bool) bool {
+	if !reverse {
+		return it.Next()
+	}
+
+	return it.Prev()
+}
+
+func (it *iterator) Error() error {
+	return nil
+}
+
+func (it *iterator) Key() []byte {
+	return it.cur.key
+}
+
+func (it *iterator) Value() ([]byte, error) {
+	return it.cur.value, nil
+}
@@ -686,11 +686,10 @@ func (i *indexSelector) operatorCanUseIndex(op expr.Operator) (bool, string, exp
 
 	// column OP literal | param
 	if leftIsCol {
-		param, ok := rh.(expr.PositionalParam)
-		if ok {
-			// if the right hand side is a positional param, we cannot
+		if expr.IsParam(rh) {
+			// if the right hand side is a param, we cannot
 			// determine its type at this point, so we assume it's compatible
-			return true, lc.Name, param, nil
+			return true, lc.Name, rh, nil
 		}
 
 		ok, v, err := exprIsCompatibleLiteral(rh, cc.Type)
@@ -703,11 +702,10 @@ func (i *indexSelector) operatorCanUseIndex(op expr.Operator) (bool, string, exp
 
 	// literal OP column
 	if rightIsCol {
-		param, ok := lh.(expr.PositionalParam)
-		if ok {
-			// if the right hand side is a positional param, we cannot
+		if expr.IsParam(lh) {
+			// if the left hand side is a param, we cannot
 			// determine its type at this point, so we assume it's compatible
-			return true, rc.Name, param, nil
+			return true, rc.Name, lh, nil
 		}
 
 		ok, v, err := exprIsCompatibleLiteral(lh, cc.Type)
@@ -16,6 +16,7 @@ import (
 var optimizerRules = []func(sctx *StreamContext) error{
 	SplitANDConditionRule,
 	PrecalculateExprRule,
+	CheckExprTypeRule,
 	RemoveUnnecessaryProjection,
 	RemoveUnnecessaryFilterNodesRule,
 	RemoveUnnecessaryTempSortNodesRule,
@@ -279,6 +280,14 @@ func precalculateExpr(sctx *StreamContext, e expr.Expr) (expr.Expr, error) {
 			}
 			t[i] = newExpr
 		}
+	case *expr.NamedExpr:
+		var err error
+		t.Expr, err = precalculateExpr(sctx, t.Expr)
+		if err != nil {
+			return nil, err
+		}
+	case *expr.CaseExpr:
+		return precalculateCaseExpr(sctx, t)
 	case expr.Operator:
 		// since expr.Operator is an interface,
 		// this optimization must only be applied to
@@ -325,6 +334,11 @@ func precalculateExpr(sctx *StreamContext, e expr.Expr) (expr.Expr, error) {
 			return expr.LiteralValue{Value: v}, nil
 		}
 
+		// NULL can be compared with any type
+		if (leftIsLit && lv.Value.Type() == types.TypeNull) || (rightIsLit && rv.Value.Type() == types.TypeNull) {
+			return t, nil
+		}
+
 		// if one operand is a column and the other is a literal
 		// we can check if the types are compatible
 		lc, leftIsCol := lh.(*expr.Column)
@@ -366,6 +380,90 @@ func precalculateExpr(sctx *StreamContext, e expr.Expr) (expr.Expr, error) {
 	return e, nil
 }
 
+// precalculateCaseExpr precalculates every branch of a CASE expression
+// and removes the branches whose condition is constant and never matches.
+// If the first remaining branch always matches, or if no branch remains,
+// the CASE expression is replaced by the corresponding result.
+// Examples:
+//
+//	CASE WHEN 1 > 2 THEN a WHEN b THEN c ELSE d END --> CASE WHEN b THEN c ELSE d END
+//	CASE WHEN b THEN c WHEN true THEN d ELSE e END --> CASE WHEN b THEN c ELSE d END
+//	CASE 1 WHEN 1 THEN a ELSE b END --> a
+func precalculateCaseExpr(sctx *StreamContext, c *expr.CaseExpr) (expr.Expr, error) {
+	var err error
+
+	var operand expr.LiteralValue
+	operandIsLit := true
+	if c.Operand != nil {
+		c.Operand, err = precalculateExpr(sctx, c.Operand)
+		if err != nil {
+			return nil, err
+		}
+		operand, operandIsLit = c.Operand.(expr.LiteralValue)
+	}
+
+	whens := make([]expr.WhenClause, 0, len(c.Whens))
+	els := c.Else
+	for _, w := range c.Whens {
+		w.Cond, err = precalculateExpr(sctx, w.Cond)
+		if err != nil {
+			return nil, err
+		}
+		w.Then, err = precalculateExpr(sctx, w.Then)
+		if err != nil {
+			return nil, err
+		}
+
+		cond, condIsLit := w.Cond.(expr.LiteralValue)
+		if !operandIsLit || !condIsLit {
+			whens = append(whens, w)
+			continue
+		}
+
+		v := cond.Value
+		if c.Operand != nil {
+			v, err = expr.Eq(operand, cond).Eval(&environment.Environment{})
+			if err != nil {
+				return nil, err
+			}
+		}
+		ok, err := types.IsTruthy(v)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			// this branch can never be taken
+			continue
+		}
+
+		// this branch is always taken if reached,
+		// the following ones are unreachable
+		els = w.Then
+		if len(whens) == 0 {
+			return els, nil
+		}
+		break
+	}
+
+	if els != nil {
+		els, err = precalculateExpr(sctx, els)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(whens) == 0 {
+		if els == nil {
+			return expr.LiteralValue{Value: types.NewNullValue()}, nil
+		}
+		return els, nil
+	}
+
+	c.Whens = whens
+	c.Else = els
+	return c, nil
+}
+
 func CheckExprTypeRule(sctx *StreamContext) error {
 	n := sctx.Stream.Op
 	var err error
@@ -405,6 +503,18 @@ func CheckExprTypeRule(sctx *StreamContext) error {
 }
 
 func checkExprType(sctx *StreamContext, e expr.Expr) (err error) {
+	// ensure the branches of CASE expressions can be matched to a common type,
+	// to which their results are converted
+	expr.Walk(e, func(e expr.Expr) bool {
+		if c, ok := e.(*expr.CaseExpr); ok {
+			c.Type, err = caseExprType(sctx, c)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
 	op, ok := e.(expr.Operator)
 	if !ok {
 		return nil
@@ -423,6 +533,11 @@ func checkExprType(sctx *StreamContext, e expr.Expr) (err error) {
 		return nil
 	}
 
+	// NULL can be compared with any type
+	if (leftIsLit && lv.Value.Type() == types.TypeNull) || (rightIsLit && rv.Value.Type() == types.TypeNull) {
+		return nil
+	}
+
 	if leftIsCol && rightIsLit {
 		tp := sctx.TableInfo.ColumnConstraints.GetColumnConstraint(lc.Name).Type
 		_, err := rv.Value.CastAs(tp)
@@ -446,6 +561,63 @@ func checkExprType(sctx *StreamContext, e expr.Expr) (err error) {
 	return nil
 }
 
+// staticExprType returns the type of the given expression if it can be
+// determined before running the query, or TypeAny otherwise.
+func staticExprType(sctx *StreamContext, e expr.Expr) (types.Type, error) {
+	switch t := e.(type) {
+	case expr.LiteralValue:
+		return t.Value.Type(), nil
+	case *expr.Column:
+		if sctx.TableInfo == nil {
+			return types.TypeAny, nil
+		}
+		cc := sctx.TableInfo.ColumnConstraints.GetColumnConstraint(t.Name)
+		if cc == nil {
+			return types.TypeAny, nil
+		}
+		return cc.Type, nil
+	case *expr.Cast:
+		return t.CastAs, nil
+	case expr.Parentheses:
+		return staticExprType(sctx, t.E)
+	case *expr.CaseExpr:
+		return caseExprType(sctx, t)
+	}
+
+	return types.TypeAny, nil
+}
+
+// caseExprType infers the type of a CASE expression from the types of its results.
+// All the results must be of the same type, or all numeric, in which case
+// the widest numeric type is returned. NULL results and results whose type
+// is only known at runtime are ignored.
+func caseExprType(sctx *StreamContext, c *expr.CaseExpr) (types.Type, error) {
+	common := types.TypeAny
+
+	for _, r := range c.Results() {
+		tp, err := staticExprType(sctx, r)
+		if err != nil {
+			return 0, err
+		}
+		if tp == types.TypeAny || tp == types.TypeNull {
+			continue
+		}
+
+		switch {
+		case common == types.TypeAny || common == tp:
+			common = tp
+		case common.IsNumber() && tp.IsNumber():
+			if tp == types.TypeDoublePrecision || (tp == types.TypeBigint && common == types.TypeInteger) {
+				common = tp
+			}
+		default:
+			return 0, errors.Errorf("CASE types %s and %s cannot be matched", common, tp)
+		}
+	}
+
+	return common, nil
+}
+
 // RemoveUnnecessaryFilterNodesRule removes any filter node whose
 // condition is a constant expression that evaluates to a truthy value.
 // if it evaluates to a falsy value, it considers that the tree
@@ -122,6 +122,47 @@ func TestPrecalculateExprRule(t *testing.T) {
 				testutil.DoubleValue(-39),
 			},
 		},
+		{
+			"constant CASE: CASE WHEN 1 > 2 THEN a > 1 ELSE true END -> true",
+			&expr.CaseExpr{
+				Whens: []expr.WhenClause{{Cond: expr.Gt(testutil.IntegerValue(1), testutil.IntegerValue(2)), Then: expr.Gt(&expr.Column{Name: "a"}, testutil.IntegerValue(1))}},
+				Else:  testutil.BoolValue(true),
+			},
+			testutil.BoolValue(true),
+		},
+		{
+			"CASE with constant branches: CASE WHEN false THEN 1 WHEN a > 1 THEN true WHEN true THEN a < 10 ELSE false END -> CASE WHEN a > 1 THEN true ELSE a < 10 END",
+			&expr.CaseExpr{
+				Whens: []expr.WhenClause{
+					{Cond: testutil.BoolValue(false), Then: testutil.IntegerValue(1)},
+					{Cond: expr.Gt(&expr.Column{Name: "a"}, testutil.IntegerValue(1)), Then: testutil.BoolValue(true)},
+					{Cond: testutil.BoolValue(true), Then: expr.Lt(&expr.Column{Name: "a"}, testutil.IntegerValue(10))},
+				},
+				Else: testutil.BoolValue(false),
+			},
+			&expr.CaseExpr{
+				Whens: []expr.WhenClause{{Cond: expr.Gt(&expr.Column{Name: "a"}, testutil.IntegerValue(1)), Then: testutil.BoolValue(true)}},
+				Else:  expr.Lt(&expr.Column{Name: "a"}, testutil.IntegerValue(10)),
+			},
+		},
+		{
+			"constant simple CASE: CASE 2 WHEN 1 THEN false WHEN 1 + 1 THEN true END -> true",
+			&expr.CaseExpr{
+				Operand: testutil.IntegerValue(2),
+				Whens: []expr.WhenClause{
+					{Cond: testutil.IntegerValue(1), Then: testutil.BoolValue(false)},
+					{Cond: expr.Add(testutil.IntegerValue(1), testutil.IntegerValue(1)), Then: testutil.BoolValue(true)},
+				},
+			},
+			testutil.BoolValue(true),
+		},
+		{
+			"CASE without matching branch: CASE WHEN false THEN true END -> NULL",
+			&expr.CaseExpr{
+				Whens: []expr.WhenClause{{Cond: testutil.BoolValue(false), Then: testutil.BoolValue(true)}},
+			},
+			testutil.NullValue(),
+		},
 	}
 
 	for _, test := range tests {
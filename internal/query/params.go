@@ -0,0 +1,129 @@
+package query
+
+import (
+	"io"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/expr"
+	"github.com/chaisql/chai/internal/query/statement"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+// inferParamTypes returns the type expected for the parameters whose value
+// is directly assigned to a column, like in:
+//
+//	INSERT INTO foo (a, b) VALUES ($1, :b)
+//	UPDATE foo SET a = $1
+//
+// It must be called on a bound statement.
+func inferParamTypes(ctx *statement.Context, stmt statement.Statement) (map[expr.Expr]types.Type, error) {
+	paramTypes := make(map[expr.Expr]types.Type)
+
+	switch t := stmt.(type) {
+	case *statement.InsertStmt:
+		if len(t.Values) == 0 {
+			break
+		}
+
+		info, err := ctx.Conn.GetTx().Catalog.GetTableInfo(t.TableName)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, v := range t.Values {
+			l, ok := v.(expr.LiteralExprList)
+			if !ok {
+				continue
+			}
+
+			for i, e := range l {
+				if !expr.IsParam(e) {
+					continue
+				}
+
+				var cc *database.ColumnConstraint
+				if len(t.Columns) > 0 {
+					if i < len(t.Columns) {
+						cc = info.ColumnConstraints.GetColumnConstraint(t.Columns[i])
+					}
+				} else if i < len(info.ColumnConstraints.Ordered) {
+					cc = info.ColumnConstraints.Ordered[i]
+				}
+				if cc != nil {
+					paramTypes[e] = cc.Type
+				}
+			}
+		}
+	case *statement.UpdateStmt:
+		for _, p := range t.SetPairs {
+			if expr.IsParam(p.E) && p.Column.Type != 0 {
+				paramTypes[p.E] = p.Column.Type
+			}
+		}
+	}
+
+	return paramTypes, nil
+}
+
+// checkParamTypes ensures the values of the parameters can be assigned
+// to the columns they are inserted into, before running the statement.
+func checkParamTypes(paramTypes map[expr.Expr]types.Type, params []environment.Param) error {
+	if len(paramTypes) == 0 {
+		return nil
+	}
+
+	env := environment.New(nil, nil, params, nil)
+	for p, tp := range paramTypes {
+		v, err := p.Eval(env)
+		if err != nil {
+			return err
+		}
+
+		if !isAssignable(v, tp) {
+			return errors.Errorf("invalid value for parameter %s: cannot use %s as %s", p, v.Type(), tp)
+		}
+	}
+
+	return nil
+}
+
+// readParams replaces the value of the parameters that are io.Readers
+// by the content of the reader. Large contents are written to the transaction
+// in chunks as they are read.
+func readParams(tx *database.Transaction, params []environment.Param) error {
+	for i := range params {
+		r, ok := params[i].Value.(io.Reader)
+		if !ok {
+			continue
+		}
+		if tx == nil {
+			return errors.Errorf("parameter %d cannot be read outside of a transaction", i+1)
+		}
+
+		v, err := tx.NewValueFromReader(r)
+		if err != nil {
+			return err
+		}
+		params[i].Value = v
+	}
+
+	return nil
+}
+
+// isAssignable returns whether the value of a parameter can be assigned to a column
+// of the given type. Numeric columns only accept numbers and booleans, other types
+// are accepted if they can be converted to the column type.
+func isAssignable(v types.Value, tp types.Type) bool {
+	vt := v.Type()
+	switch {
+	case vt == types.TypeNull || vt == tp:
+		return true
+	case tp.IsNumber():
+		return vt.IsNumber() || vt == types.TypeBoolean
+	}
+
+	_, err := v.CastAs(tp)
+	return err == nil
+}
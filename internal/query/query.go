@@ -5,7 +5,9 @@ import (
 
 	"github.com/chaisql/chai/internal/database"
 	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/expr"
 	"github.com/chaisql/chai/internal/query/statement"
+	"github.com/chaisql/chai/internal/types"
 )
 
 // A Query can execute statements against the database. It can read or write data
@@ -87,6 +89,28 @@ func (q *Query) Run(c *Context) (*statement.Result, error) {
 			return nil, err
 		}
 
+		// read the parameters passed as readers
+		err = readParams(c.Conn.GetTx(), c.Params)
+		if err != nil {
+			if tx != nil {
+				_ = tx.Rollback()
+			}
+
+			return nil, err
+		}
+
+		// ensure the parameters have the expected types
+		if ps, ok := stmt.(PreparedStatement); ok {
+			err = checkParamTypes(ps.ParamTypes, c.Params)
+			if err != nil {
+				if tx != nil {
+					_ = tx.Rollback()
+				}
+
+				return nil, err
+			}
+		}
+
 		// run
 		res, err = stmt.Run(&sctx)
 		if err != nil {
@@ -174,6 +198,11 @@ func Prepare(ctx *statement.Context, stmt statement.Statement) (statement.Statem
 		}
 	}
 
+	paramTypes, err := inferParamTypes(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+
 	// prepare
 	if prep, ok := stmt.(statement.Preparer); ok {
 		stmt, err = prep.Prepare(ctx)
@@ -182,9 +211,13 @@ func Prepare(ctx *statement.Context, stmt statement.Statement) (statement.Statem
 		}
 	}
 
-	return PreparedStatement{stmt}, nil
+	return PreparedStatement{Statement: stmt, ParamTypes: paramTypes}, nil
 }
 
 type PreparedStatement struct {
 	statement.Statement
+
+	// ParamTypes holds the type expected for the parameters
+	// that could be inferred during preparation.
+	ParamTypes map[expr.Expr]types.Type
 }
@@ -11,6 +11,8 @@ import (
 
 var _ Statement = (*AlterTableRenameStmt)(nil)
 var _ Statement = (*AlterTableAddColumnStmt)(nil)
+var _ Statement = (*AlterTableSetTTLStmt)(nil)
+var _ Statement = (*AlterTableSetCompressionDictionaryStmt)(nil)
 
 // AlterTableRenameStmt is a DSL that allows creating a full ALTER TABLE query.
 type AlterTableRenameStmt struct {
@@ -57,6 +59,8 @@ func (stmt *AlterTableAddColumnStmt) Run(ctx *Context) (*Result, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get table")
 	}
+	// expired rows must be rewritten as well
+	scan.IncludeExpired = true
 
 	// get the current list of indexes
 	indexNames := ctx.Conn.GetTx().Catalog.ListIndexes(stmt.TableName)
@@ -163,3 +167,76 @@ func (stmt *AlterTableAddColumnStmt) Run(ctx *Context) (*Result, error) {
 		},
 	}, nil
 }
+
+// AlterTableSetTTLStmt sets or removes the column used to expire the rows of a table.
+type AlterTableSetTTLStmt struct {
+	TableName string
+	// TTLColumn is empty when the TTL is dropped.
+	TTLColumn string
+}
+
+// Run runs the ALTER TABLE SET TTL statement in the given transaction.
+// It implements the Statement interface.
+func (stmt *AlterTableSetTTLStmt) Run(ctx *Context) (*Result, error) {
+	if stmt.TableName == "" {
+		return nil, errors.New("missing table name")
+	}
+
+	err := ctx.Conn.GetTx().CatalogWriter().SetTTLColumn(ctx.Conn.GetTx(), stmt.TableName, stmt.TTLColumn)
+	return nil, err
+}
+
+// AlterTableSetCompressionDictionaryStmt trains or removes the zstd dictionary
+// used to compress the values of a table.
+type AlterTableSetCompressionDictionaryStmt struct {
+	TableName string
+	// Train is false when the dictionary is dropped.
+	Train bool
+}
+
+// Run runs the ALTER TABLE TRAIN COMPRESSION DICTIONARY statement in the given transaction.
+// It implements the Statement interface.
+// The statement rewrites every row of the table with the new dictionary.
+func (stmt *AlterTableSetCompressionDictionaryStmt) Run(ctx *Context) (*Result, error) {
+	if stmt.TableName == "" {
+		return nil, errors.New("missing table name")
+	}
+
+	tx := ctx.Conn.GetTx()
+
+	// get the table before changing the dictionary
+	// so that the rows are decoded with the current one
+	scan := table.Scan(stmt.TableName)
+	var err error
+	scan.Table, err = tx.Catalog.GetTable(tx, stmt.TableName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get table")
+	}
+	scan.IncludeExpired = true
+
+	var dict []byte
+	if stmt.Train {
+		dict, err = scan.Table.TrainCompressionDictionary()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.CatalogWriter().SetCompressionDictionary(tx, stmt.TableName, dict)
+	if err != nil {
+		return nil, err
+	}
+
+	s := stream.New(scan).
+		Pipe(table.Validate(stmt.TableName)).
+		Pipe(table.Replace(stmt.TableName)).
+		Pipe(stream.Discard())
+
+	// do NOT optimize the stream
+	return &Result{
+		Result: &StreamStmtResult{
+			Stream:  s,
+			Context: ctx,
+		},
+	}, nil
+}
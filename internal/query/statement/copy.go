@@ -0,0 +1,129 @@
+package statement
+
+import (
+	"os"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/planner"
+	"github.com/chaisql/chai/internal/stream"
+	"github.com/chaisql/chai/internal/stream/file"
+	"github.com/cockroachdb/errors"
+)
+
+var _ Statement = (*CopyStmt)(nil)
+
+// CopyStmt copies the rows of a table or of a query to a file,
+// or the rows of a file to a table.
+type CopyStmt struct {
+	// TableName is the table the rows are copied to.
+	// It is empty when copying to a file.
+	TableName string
+	// Columns copied from the file. If empty, all the columns are copied.
+	Columns []string
+	// Query whose rows are copied to the file.
+	// It is nil when copying from a file.
+	Query Preparer
+	Path  string
+
+	Options file.Options
+
+	stream *stream.Stream
+	read   *file.ReadOperator
+}
+
+func (stmt *CopyStmt) Bind(ctx *Context) error {
+	if b, ok := stmt.Query.(Bindable); ok {
+		return b.Bind(ctx)
+	}
+
+	return nil
+}
+
+// Prepare implements the Preparer interface.
+func (stmt *CopyStmt) Prepare(ctx *Context) (Statement, error) {
+	if err := stmt.Options.Validate(); err != nil {
+		return nil, err
+	}
+
+	if stmt.Query != nil {
+		st, err := stmt.Query.Prepare(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.stream = st.(*SelectStmt).Stream
+		return stmt, nil
+	}
+
+	// rows read from the file are inserted like the rows of an INSERT statement
+	stmt.read = file.Read(stmt.TableName, stmt.Path, stmt.Columns, stmt.Options)
+	s, err := insertStream(ctx, stream.New(stmt.read), stmt.TableName, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt.stream = s.Pipe(stream.Discard())
+	return stmt, nil
+}
+
+// Run copies the rows. It doesn't return any result.
+func (stmt *CopyStmt) Run(ctx *Context) (*Result, error) {
+	s, err := planner.Optimize(stmt.stream, ctx.Conn.GetTx().Catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	env := environment.New(ctx.DB, ctx.Conn.GetTx(), ctx.Params, nil)
+
+	if stmt.Query == nil {
+		err = s.Iterate(env, func(database.Row) error { return nil })
+		if err != nil && stmt.read.Line() > 0 {
+			return nil, errors.Wrapf(err, "line %d", stmt.read.Line())
+		}
+		return nil, err
+	}
+
+	return nil, copyToFile(env, s, stmt.Path, stmt.Options)
+}
+
+// copyToFile writes the rows of the stream to a file.
+// The file is removed if an error occurs.
+func copyToFile(env *environment.Environment, s *stream.Stream, path string, opts file.Options) (err error) {
+	columns, err := s.Columns(env)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(path)
+		}
+	}()
+
+	w, err := file.NewWriter(f, columns, opts)
+	if err != nil {
+		return err
+	}
+
+	err = s.Iterate(env, w.WriteRow)
+	if err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// IsReadOnly always returns false: copying to a file doesn't write into
+// the database, but it writes to the filesystem of the server,
+// which read-only callers must not be allowed to do.
+func (stmt *CopyStmt) IsReadOnly() bool {
+	return false
+}
@@ -0,0 +1,107 @@
+package statement_test
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyStmt(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`
+		CREATE TABLE test (a INT PRIMARY KEY, b TEXT, c DOUBLE PRECISION, d TIMESTAMP, e BYTEA);
+		INSERT INTO test VALUES (1, 'foo, bar', 1.5, '2024-01-02 03:04:05', '\xaabb');
+		INSERT INTO test (a, b) VALUES (2, '');
+		INSERT INTO test (a) VALUES (3);
+	`)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	read := func(t *testing.T, name string) string {
+		t.Helper()
+
+		data, err := os.ReadFile(path(name))
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	exec := func(t *testing.T, q string, args ...any) error {
+		t.Helper()
+
+		_, err := db.Exec(fmt.Sprintf(q, args...))
+		return err
+	}
+
+	t.Run("To", func(t *testing.T) {
+		require.NoError(t, exec(t, `COPY test TO '%s' WITH (FORMAT csv, HEADER)`, path("test.csv")))
+		require.Equal(t, "a,b,c,d,e\n1,\"foo, bar\",1.5,2024-01-02T03:04:05Z,qrs=\n2,,,,\n3,,,,\n", read(t, "test.csv"))
+
+		require.NoError(t, exec(t, `COPY (SELECT a, b FROM test WHERE a < 3) TO '%s' WITH (FORMAT json)`, path("test.json")))
+		require.Equal(t, "{\"a\": 1, \"b\": \"foo, bar\"}\n{\"a\": 2, \"b\": \"\"}\n", read(t, "test.json"))
+
+		require.NoError(t, exec(t, `COPY test (b, a) TO '%s' WITH (DELIMITER ';')`, path("columns.csv")))
+		require.Equal(t, "foo, bar;1\n;2\n;3\n", read(t, "columns.csv"))
+	})
+
+	t.Run("From", func(t *testing.T) {
+		require.NoError(t, exec(t, `CREATE TABLE test2 (a INT PRIMARY KEY, b TEXT, c DOUBLE PRECISION, d TIMESTAMP, e BYTEA)`))
+		require.NoError(t, exec(t, `COPY test2 FROM '%s' WITH (HEADER)`, path("test.csv")))
+		require.NoError(t, exec(t, `COPY test2 TO '%s' WITH (HEADER)`, path("test2.csv")))
+		// NULL text values are copied as empty strings
+		require.Equal(t, read(t, "test.csv"), read(t, "test2.csv"))
+
+		var b string
+		require.NoError(t, db.QueryRow(`SELECT b FROM test2 WHERE a = 3`).Scan(&b))
+		require.Equal(t, "", b)
+
+		require.NoError(t, exec(t, `CREATE TABLE test3 (a INT PRIMARY KEY, b TEXT)`))
+		require.NoError(t, exec(t, `COPY test3 FROM '%s' WITH (FORMAT json)`, path("test.json")))
+		require.NoError(t, exec(t, `COPY test3 TO '%s' WITH (FORMAT json)`, path("test3.json")))
+		require.Equal(t, read(t, "test.json"), read(t, "test3.json"))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		require.NoError(t, exec(t, `CREATE TABLE test4 (a INT PRIMARY KEY, b INT NOT NULL)`))
+
+		write := func(t *testing.T, name, content string) string {
+			require.NoError(t, os.WriteFile(path(name), []byte(content), 0o644))
+			return path(name)
+		}
+
+		err := exec(t, `COPY test4 FROM '%s' WITH (HEADER)`, write(t, "bad.csv", "a,b\n1,1\n2,x\n"))
+		require.ErrorContains(t, err, "line 3")
+
+		err = exec(t, `COPY test4 FROM '%s'`, write(t, "dup.csv", "1,1\n2,2\n1,3\n"))
+		require.ErrorContains(t, err, "line 3")
+
+		err = exec(t, `COPY test4 FROM '%s'`, write(t, "fields.csv", "1,1\n2\n"))
+		require.ErrorContains(t, err, "line 2")
+
+		err = exec(t, `COPY test4 FROM '%s' WITH (FORMAT json)`, write(t, "bad.json", "{\"a\": 1, \"b\": 1}\n\n{\"a\": 2}\n"))
+		require.ErrorContains(t, err, "line 3")
+
+		err = exec(t, `COPY test4 FROM '%s' WITH (HEADER)`, write(t, "unknown.csv", "a,c\n1,1\n"))
+		require.ErrorContains(t, err, "table has no column c")
+
+		// nothing was copied
+		var n int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM test4`).Scan(&n))
+		require.Zero(t, n)
+
+		// the file is not created if the query fails
+		err = exec(t, `COPY (SELECT a / 0 FROM test) TO '%s'`, path("none.csv"))
+		require.Error(t, err)
+		require.NoFileExists(t, path("none.csv"))
+	})
+}
@@ -36,7 +36,12 @@ func (stmt *CreateTableStmt) Run(ctx *Context) (*Result, error) {
 		cc.IsNotNull = true
 	}
 
-	err := ctx.Conn.GetTx().CatalogWriter().CreateTable(ctx.Conn.GetTx(), stmt.Info.TableName, &stmt.Info)
+	err := stmt.Info.SetTTLColumn(stmt.Info.TTLColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.Conn.GetTx().CatalogWriter().CreateTable(ctx.Conn.GetTx(), stmt.Info.TableName, &stmt.Info)
 	if stmt.IfNotExists {
 		if errs.IsAlreadyExistsError(err) {
 			return nil, nil
@@ -83,7 +88,10 @@ func (stmt *CreateIndexStmt) Run(ctx *Context) (*Result, error) {
 		return nil, err
 	}
 
-	s := stream.New(table.Scan(stmt.Info.Owner.TableName)).
+	// expired rows must be indexed until they are deleted
+	scan := table.Scan(stmt.Info.Owner.TableName)
+	scan.IncludeExpired = true
+	s := stream.New(scan).
 		Pipe(index.Insert(stmt.Info.IndexName)).
 		Pipe(stream.Discard())
 
@@ -18,7 +18,7 @@ type DeleteStmt struct {
 	TableName        string
 	WhereExpr        expr.Expr
 	OffsetExpr       expr.Expr
-	OrderBy          *expr.Column
+	OrderBy          expr.Expr
 	LimitExpr        expr.Expr
 	OrderByDirection scanner.Token
 }
@@ -131,53 +131,64 @@ func (stmt *InsertStmt) Prepare(c *Context) (Statement, error) {
 		}
 	}
 
+	s, err := insertStream(c, s, stmt.TableName, stmt.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(stmt.Returning) > 0 {
+		s = s.Pipe(rows.Project(stmt.Returning...))
+	} else {
+		s = s.Pipe(stream.Discard())
+	}
+
+	stmt.PreparedStreamStmt.Stream = s
+	return stmt, nil
+}
+
+// insertStream pipes the operators that validate and insert
+// the rows of the stream into the table and its indexes.
+func insertStream(ctx *Context, s *stream.Stream, tableName string, onConflict database.OnConflictAction) (*stream.Stream, error) {
 	// validate object
-	s = s.Pipe(table.Validate(stmt.TableName))
+	s = s.Pipe(table.Validate(tableName))
 
 	// generate primary key
-	switch stmt.OnConflict {
+	switch onConflict {
 	case database.OnConflictDoNothing:
-		s = s.Pipe(table.GenerateKeyOnConflictDoNothing(stmt.TableName))
+		s = s.Pipe(table.GenerateKeyOnConflictDoNothing(tableName))
 	case database.OnConflictDoReplace:
 		// TODO: update index
-		s = s.Pipe(table.GenerateKeyOnConflict(stmt.TableName, stream.New(table.Replace(stmt.TableName))))
+		s = s.Pipe(table.GenerateKeyOnConflict(tableName, stream.New(table.Replace(tableName))))
 	default:
-		s = s.Pipe(table.GenerateKey(stmt.TableName))
+		s = s.Pipe(table.GenerateKey(tableName))
 	}
 
 	// check unique constraints
-	indexNames := c.Conn.GetTx().Catalog.ListIndexes(stmt.TableName)
+	indexNames := ctx.Conn.GetTx().Catalog.ListIndexes(tableName)
 	for _, indexName := range indexNames {
-		info, err := c.Conn.GetTx().Catalog.GetIndexInfo(indexName)
+		info, err := ctx.Conn.GetTx().Catalog.GetIndexInfo(indexName)
 		if err != nil {
 			return nil, err
 		}
 
 		if info.Unique {
 			// validate object
-			switch stmt.OnConflict {
+			switch onConflict {
 			case database.OnConflictDoNothing:
 				s = s.Pipe(index.ValidateOnConflictDoNothing(indexName))
 			case database.OnConflictDoReplace:
-				s = s.Pipe(index.ValidateOnConflict(indexName, stream.New(table.Replace(stmt.TableName))))
+				s = s.Pipe(index.ValidateOnConflict(indexName, stream.New(table.Replace(tableName))))
 			default:
 				s = s.Pipe(index.Validate(indexName))
 			}
 		}
 	}
 
-	s = s.Pipe(table.Insert(stmt.TableName))
+	s = s.Pipe(table.Insert(tableName))
 
 	for _, indexName := range indexNames {
 		s = s.Pipe(index.Insert(indexName))
 	}
 
-	if len(stmt.Returning) > 0 {
-		s = s.Pipe(rows.Project(stmt.Returning...))
-	} else {
-		s = s.Pipe(stream.Discard())
-	}
-
-	stmt.PreparedStreamStmt.Stream = s
-	return stmt, nil
+	return s, nil
 }
@@ -48,7 +48,10 @@ func (stmt *ReIndexStmt) Prepare(ctx *Context) (Statement, error) {
 			return nil, err
 		}
 
-		s := stream.New(table.Scan(info.Owner.TableName)).Pipe(index.Insert(info.IndexName))
+		// expired rows must be indexed until they are deleted
+		scan := table.Scan(info.Owner.TableName)
+		scan.IncludeExpired = true
+		s := stream.New(scan).Pipe(index.Insert(info.IndexName))
 		streams = append(streams, s)
 	}
 
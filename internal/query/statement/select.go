@@ -181,7 +181,7 @@ type SelectStmt struct {
 
 	CompoundSelect    []*SelectCoreStmt
 	CompoundOperators []scanner.Token
-	OrderBy           *expr.Column
+	OrderBy           expr.Expr
 	OrderByDirection  scanner.Token
 	OffsetExpr        expr.Expr
 	LimitExpr         expr.Expr
@@ -61,3 +61,59 @@ func (stmt CommitStmt) Run(ctx *statement.Context) (*statement.Result, error) {
 
 	return nil, tx.Commit()
 }
+
+// SavepointStmt is a statement that creates a savepoint in the current active transaction.
+type SavepointStmt struct {
+	Name string
+}
+
+func (stmt SavepointStmt) NeedsTransaction() bool {
+	return false
+}
+
+func (stmt SavepointStmt) Run(ctx *statement.Context) (*statement.Result, error) {
+	tx := ctx.Conn.GetTx()
+	if tx == nil {
+		return nil, errors.New("cannot create a savepoint with no active transaction")
+	}
+
+	return nil, tx.Savepoint(stmt.Name)
+}
+
+// RollbackToSavepointStmt is a statement that discards the changes made
+// in the current active transaction after the given savepoint.
+type RollbackToSavepointStmt struct {
+	Name string
+}
+
+func (stmt RollbackToSavepointStmt) NeedsTransaction() bool {
+	return false
+}
+
+func (stmt RollbackToSavepointStmt) Run(ctx *statement.Context) (*statement.Result, error) {
+	tx := ctx.Conn.GetTx()
+	if tx == nil {
+		return nil, errors.New("cannot rollback to a savepoint with no active transaction")
+	}
+
+	return nil, tx.RollbackToSavepoint(stmt.Name)
+}
+
+// ReleaseSavepointStmt is a statement that destroys a savepoint of the current active transaction,
+// keeping the changes made after it.
+type ReleaseSavepointStmt struct {
+	Name string
+}
+
+func (stmt ReleaseSavepointStmt) NeedsTransaction() bool {
+	return false
+}
+
+func (stmt ReleaseSavepointStmt) Run(ctx *statement.Context) (*statement.Result, error) {
+	tx := ctx.Conn.GetTx()
+	if tx == nil {
+		return nil, errors.New("cannot release a savepoint with no active transaction")
+	}
+
+	return nil, tx.ReleaseSavepoint(stmt.Name)
+}
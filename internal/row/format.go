@@ -125,6 +125,9 @@ func marshalText(dst *bytes.Buffer, v types.Value) error {
 		_, _ = hex.NewEncoder(dst).Write(src)
 		dst.WriteByte('"')
 		return nil
+	case types.TypeUUID:
+		dst.WriteString(strconv.Quote(v.(types.UUIDValue).Canonical()))
+		return nil
 	default:
 		return fmt.Errorf("unexpected type: %d", v.Type())
 	}
@@ -40,6 +40,8 @@ func NewValue(x any) (types.Value, error) {
 		return types.NewNullValue(), nil
 	}
 	switch v := x.(type) {
+	case types.Value:
+		return v, nil
 	case *int:
 		if v == nil {
 			return types.NewNullValue(), nil
@@ -4,10 +4,10 @@ import (
 	"context"
 	"database/sql/driver"
 	"io"
+	"strings"
 	"sync"
 
 	"github.com/chaisql/chai/internal/database"
-	"github.com/chaisql/chai/internal/database/catalogstore"
 	"github.com/chaisql/chai/internal/environment"
 	"github.com/chaisql/chai/internal/query"
 	"github.com/chaisql/chai/internal/query/statement"
@@ -17,10 +17,11 @@ import (
 )
 
 var (
-	_ driver.Driver         = (*Driver)(nil)
-	_ driver.DriverContext  = (*Driver)(nil)
-	_ driver.QueryerContext = (*Conn)(nil)
-	_ driver.ExecerContext  = (*Conn)(nil)
+	_ driver.Driver            = (*Driver)(nil)
+	_ driver.DriverContext     = (*Driver)(nil)
+	_ driver.QueryerContext    = (*Conn)(nil)
+	_ driver.ExecerContext     = (*Conn)(nil)
+	_ driver.NamedValueChecker = (*Conn)(nil)
 )
 
 // Driver is a driver.Driver that can open a new connection to a Chai database.
@@ -31,18 +32,15 @@ func (d Driver) Open(name string) (driver.Conn, error) {
 	return nil, errors.New("requires go1.10 or greater")
 }
 
+// OpenConnector parses the data source name and opens the database.
+// See ParseDSN for the list of supported options.
 func (d Driver) OpenConnector(name string) (driver.Connector, error) {
-	db, err := database.Open(name, &database.Options{
-		CatalogLoader: catalogstore.LoadCatalog,
-	})
+	path, opts, err := ParseDSN(name)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Connector{
-		db:     db,
-		driver: d,
-	}, nil
+	return NewConnector(path, opts)
 }
 
 var (
@@ -68,6 +66,11 @@ func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
 	}, nil
 }
 
+// DB returns the database opened by the connector.
+func (c *Connector) DB() *database.Database {
+	return c.db
+}
+
 func (c *Connector) Driver() driver.Driver {
 	return c.driver
 }
@@ -95,6 +98,17 @@ func (c *Conn) Conn() *database.Connection {
 	return c.conn
 }
 
+// CheckNamedValue accepts io.Readers as parameters, in addition to the
+// default types. Their content is stored as BYTEA and is not loaded in memory
+// if it is large. Other values are converted by the default converter.
+func (c *Conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if _, ok := nv.Value.(io.Reader); ok {
+		return nil
+	}
+
+	return driver.ErrSkip
+}
+
 // Prepare returns a prepared statement, bound to this connection.
 func (c *Conn) Prepare(q string) (driver.Stmt, error) {
 	return c.PrepareContext(context.Background(), q)
@@ -154,7 +168,8 @@ func (c *Conn) QueryContext(ctx context.Context, q string, args []driver.NamedVa
 
 // PrepareContext returns a prepared statement, bound to this connection.
 func (c *Conn) PrepareContext(ctx context.Context, q string) (driver.Stmt, error) {
-	statements, err := parser.ParseQuery(q)
+	p := parser.NewParser(strings.NewReader(q))
+	statements, err := p.ParseQuery()
 	if err != nil {
 		return nil, err
 	}
@@ -176,8 +191,9 @@ func (c *Conn) PrepareContext(ctx context.Context, q string) (driver.Stmt, error
 	}
 
 	return &Stmt{
-		stmt: stmt,
-		conn: c,
+		stmt:      stmt,
+		conn:      c,
+		numParams: p.NumParams(),
 	}, nil
 }
 
@@ -223,12 +239,14 @@ func (c *Conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, e
 // Stmt is a prepared statement. It is bound to a Conn and not
 // used by multiple goroutines concurrently.
 type Stmt struct {
-	stmt statement.Statement
-	conn *Conn
+	stmt      statement.Statement
+	conn      *Conn
+	numParams int
 }
 
 // NumInput returns the number of placeholder parameters.
-func (s *Stmt) NumInput() int { return -1 }
+// Named parameters used multiple times are only counted once.
+func (s *Stmt) NumInput() int { return s.numParams }
 
 // Exec executes a query that doesn't return rows, such
 // as an INSERT or UPDATE.
@@ -397,6 +415,8 @@ func (rs *Rows) Next(dest []driver.Value) error {
 			cp := make([]byte, len(b))
 			copy(cp, b)
 			dest[i] = cp
+		case types.TypeUUID:
+			dest[i] = v.(types.UUIDValue).Canonical()
 		default:
 			panic("unsupported type: " + v.Type().String())
 		}
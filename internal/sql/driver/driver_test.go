@@ -288,6 +288,64 @@ func TestPreparedStatement(t *testing.T) {
 	require.Equal(t, 12, count)
 }
 
+func TestNamedParams(t *testing.T) {
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE foo(a INT PRIMARY KEY, b TEXT)`)
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO foo (a, b) VALUES (:a, @b)", sql.Named("a", 1), sql.Named("b", "foo"))
+	require.NoError(t, err)
+
+	var b string
+	err = db.QueryRow("SELECT b FROM foo WHERE a = :a AND b = :b OR a = :a", sql.Named("b", "foo"), sql.Named("a", 1)).Scan(&b)
+	require.NoError(t, err)
+	require.Equal(t, "foo", b)
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err = db.Exec("INSERT INTO foo (a, b) VALUES (:a, :b)", sql.Named("a", 2), sql.Named("c", "bar"))
+		require.ErrorContains(t, err, `cannot find param named "b"`)
+	})
+
+	t.Run("NumInput", func(t *testing.T) {
+		stmt, err := db.Prepare("SELECT b FROM foo WHERE a = :a OR a = :a OR b = @b")
+		require.NoError(t, err)
+		defer stmt.Close()
+
+		err = stmt.QueryRow(sql.Named("a", 1), sql.Named("b", "foo")).Scan(&b)
+		require.NoError(t, err)
+		require.Equal(t, "foo", b)
+
+		// database/sql checks the number of arguments using NumInput
+		err = stmt.QueryRow(sql.Named("a", 1)).Scan(&b)
+		require.ErrorContains(t, err, "expected 2 arguments, got 1")
+
+		stmt2, err := db.Prepare("SELECT b FROM foo WHERE a = $2 OR a = $1")
+		require.NoError(t, err)
+		defer stmt2.Close()
+
+		err = stmt2.QueryRow(1).Scan(&b)
+		require.ErrorContains(t, err, "expected 2 arguments, got 1")
+	})
+
+	t.Run("Types", func(t *testing.T) {
+		stmt, err := db.Prepare("INSERT INTO foo (a, b) VALUES ($1, $2)")
+		require.NoError(t, err)
+		defer stmt.Close()
+
+		_, err = stmt.Exec("10", "bar")
+		require.ErrorContains(t, err, "invalid value for parameter $1: cannot use text as integer")
+
+		_, err = db.Exec("UPDATE foo SET a = :a", sql.Named("a", "hello"))
+		require.ErrorContains(t, err, "invalid value for parameter :a: cannot use text as integer")
+
+		_, err = stmt.Exec(10, nil)
+		require.NoError(t, err)
+	})
+}
+
 func TestDriverWithTimeValues(t *testing.T) {
 	db, err := sql.Open("chai", ":memory:")
 	require.NoError(t, err)
@@ -0,0 +1,280 @@
+package driver
+
+import (
+	"context"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/database/catalogstore"
+	"github.com/chaisql/chai/internal/kv"
+	"github.com/cockroachdb/errors"
+	"github.com/cockroachdb/pebble/v2"
+	"github.com/cockroachdb/pebble/v2/sstable"
+)
+
+// Options are used to tune the database when it is opened.
+// The zero value uses the default settings.
+type Options struct {
+	// CacheSize is the size of the block cache, in bytes.
+	// Defaults to 8MB.
+	CacheSize int64
+
+	// MemTableSize is the size of the memtable, in bytes.
+	// Larger memtables reduce the number of flushes
+	// at the cost of memory usage. Defaults to 4MB.
+	MemTableSize uint64
+
+	// NoSync disables syncing the changes to disk when a transaction is committed.
+	// A crash may lose the most recent transactions but cannot corrupt the database.
+	NoSync bool
+
+	// MaxBatchSize is the size in bytes after which a write transaction
+	// writes its changes to the engine. Defaults to 10MB.
+	MaxBatchSize int
+
+	// Compression is the algorithm used to compress the data on disk.
+	// Possible values are "none", "snappy", "zstd" and "minlz".
+	// Defaults to "snappy".
+	Compression string
+
+	// ReadOnly opens the database in read-only mode.
+	// The database must exist and write transactions are rejected.
+	// Multiple processes can open the same database in read-only mode,
+	// as long as no process opened it for writing.
+	ReadOnly bool
+
+	// Changelog records the inserts, updates and deletes committed to the tables,
+	// so that they can be consumed in commit order, for example with chai watch.
+	Changelog bool
+
+	// TTLInterval is the interval at which the expired rows
+	// of the tables with a TTL column are deleted. Defaults to 1 minute.
+	TTLInterval time.Duration
+
+	// EncryptionKey, if set, is used to encrypt the database files with AES.
+	// It must be 16, 24 or 32 bytes long.
+	// An encrypted database cannot be opened without its key.
+	EncryptionKey []byte
+}
+
+// ParseDSN parses a data source name of the form path?key=value&... and
+// returns the database path and the options.
+// The path can be ":memory:" or "memory://" to store the database in memory,
+// see database.MemoryPath.
+// Supported parameters are:
+//
+//	cache_size      size of the block cache (e.g. 512MB)
+//	memtable_size   size of the memtable (e.g. 64MB)
+//	sync            whether commits are synced to disk (on, off)
+//	max_batch_size  size after which a write transaction writes its changes to the engine
+//	compression     none, snappy, zstd or minlz
+//	read_only       whether the database is opened in read-only mode (true, false)
+//	changelog       whether changes are recorded in the changelog (on, off)
+//	ttl_interval    interval at which the expired rows are deleted (e.g. 30s)
+//	encryption_key  hex encoded key used to encrypt the database files
+func ParseDSN(dsn string) (string, Options, error) {
+	var opts Options
+
+	path, query, ok := strings.Cut(dsn, "?")
+	if !ok {
+		return dsn, opts, nil
+	}
+
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", opts, errors.Wrap(err, "invalid DSN")
+	}
+
+	for k, v := range values {
+		if len(v) != 1 {
+			return "", opts, errors.Errorf("invalid DSN: parameter %q must be set once", k)
+		}
+
+		switch k {
+		case "cache_size":
+			opts.CacheSize, err = parseSize(v[0])
+		case "memtable_size":
+			var n int64
+			n, err = parseSize(v[0])
+			opts.MemTableSize = uint64(n)
+		case "sync":
+			var b bool
+			b, err = parseBool(v[0])
+			opts.NoSync = !b
+		case "max_batch_size":
+			var n int64
+			n, err = parseSize(v[0])
+			opts.MaxBatchSize = int(n)
+		case "read_only":
+			opts.ReadOnly, err = parseBool(v[0])
+		case "changelog":
+			opts.Changelog, err = parseBool(v[0])
+		case "ttl_interval":
+			opts.TTLInterval, err = parseDuration(v[0])
+		case "encryption_key":
+			opts.EncryptionKey, err = hex.DecodeString(v[0])
+		case "compression":
+			opts.Compression = v[0]
+			_, err = opts.compressionProfile()
+		default:
+			return "", opts, errors.Errorf("invalid DSN: unknown parameter %q", k)
+		}
+		if err != nil {
+			return "", opts, errors.Wrapf(err, "invalid DSN: invalid value for %q", k)
+		}
+	}
+
+	return path, opts, nil
+}
+
+// NewConnector opens the database located at path and returns
+// a connector that can be passed to sql.OpenDB.
+func NewConnector(path string, opts Options) (*Connector, error) {
+	popts, err := opts.pebbleOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := database.Open(path, &database.Options{
+		CatalogLoader: catalogstore.LoadCatalog,
+		MaxBatchSize:  opts.MaxBatchSize,
+		NoSync:        opts.NoSync,
+		PebbleOptions: popts,
+		ReadOnly:      opts.ReadOnly,
+		Changelog:     opts.Changelog,
+		TTLInterval:   opts.TTLInterval,
+		KeyProvider:   opts.keyProvider(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Connector{
+		db:     db,
+		driver: Driver{},
+	}, nil
+}
+
+func (o *Options) pebbleOptions() (*pebble.Options, error) {
+	var popts pebble.Options
+
+	popts.CacheSize = o.CacheSize
+	popts.MemTableSize = o.MemTableSize
+
+	profile, err := o.compressionProfile()
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil {
+		popts.ApplyCompressionSettings(func() pebble.DBCompressionSettings {
+			return pebble.UniformDBCompressionSettings(profile)
+		})
+	}
+
+	return &popts, nil
+}
+
+func (o *Options) keyProvider() kv.KeyProvider {
+	if len(o.EncryptionKey) == 0 {
+		return nil
+	}
+
+	return kv.StaticKey(o.EncryptionKey)
+}
+
+func (o *Options) compressionProfile() (*sstable.CompressionProfile, error) {
+	switch strings.ToLower(o.Compression) {
+	case "":
+		return nil, nil
+	case "none":
+		return sstable.NoCompression, nil
+	case "snappy":
+		return sstable.SnappyCompression, nil
+	case "zstd":
+		return sstable.ZstdCompression, nil
+	case "minlz":
+		return sstable.MinLZCompression, nil
+	}
+
+	return nil, errors.Errorf("unknown compression %q", o.Compression)
+}
+
+// parseSize parses a size in bytes, with an optional unit (B, KB, MB, GB).
+// Units are powers of 1024.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KB", 1 << 10},
+		{"MB", 1 << 20},
+		{"GB", 1 << 30},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(s, u.suffix) {
+			s = strings.TrimSpace(strings.TrimSuffix(s, u.suffix))
+			mult = u.mult
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, errors.New("size cannot be negative")
+	}
+
+	return n * mult, nil
+}
+
+// parseDuration parses a positive duration, such as 30s or 5m.
+func parseDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("duration must be positive")
+	}
+
+	return d, nil
+}
+
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on", "yes":
+		return true, nil
+	case "off", "no":
+		return false, nil
+	}
+
+	return strconv.ParseBool(s)
+}
+
+// Restore replaces the database located at path with the backup stored in dir.
+// The database must not be in use.
+func Restore(ctx context.Context, dir, path string, opts Options) error {
+	popts, err := opts.pebbleOptions()
+	if err != nil {
+		return err
+	}
+
+	return database.Restore(ctx, dir, path, &database.Options{
+		CatalogLoader: catalogstore.LoadCatalog,
+		MaxBatchSize:  opts.MaxBatchSize,
+		NoSync:        opts.NoSync,
+		PebbleOptions: popts,
+		Changelog:     opts.Changelog,
+		TTLInterval:   opts.TTLInterval,
+		KeyProvider:   opts.keyProvider(),
+	})
+}
@@ -0,0 +1,54 @@
+package driver_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chaisql/chai/internal/sql/driver"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn      string
+		path     string
+		expected driver.Options
+		fails    bool
+	}{
+		{":memory:", ":memory:", driver.Options{}, false},
+		{"/tmp/mydb", "/tmp/mydb", driver.Options{}, false},
+		{"mydb?cache_size=512MB", "mydb", driver.Options{CacheSize: 512 << 20}, false},
+		{"mydb?cache_size=1024", "mydb", driver.Options{CacheSize: 1024}, false},
+		{"mydb?memtable_size=64kb&max_batch_size=1GB", "mydb", driver.Options{MemTableSize: 64 << 10, MaxBatchSize: 1 << 30}, false},
+		{"mydb?sync=off", "mydb", driver.Options{NoSync: true}, false},
+		{"mydb?sync=on", "mydb", driver.Options{}, false},
+		{"mydb?compression=zstd", "mydb", driver.Options{Compression: "zstd"}, false},
+		{":memory:?sync=false&compression=none", ":memory:", driver.Options{NoSync: true, Compression: "none"}, false},
+		{"mydb?read_only=true", "mydb", driver.Options{ReadOnly: true}, false},
+		{"mydb?changelog=on", "mydb", driver.Options{Changelog: true}, false},
+		{"mydb?ttl_interval=30s", "mydb", driver.Options{TTLInterval: 30 * time.Second}, false},
+		{"mydb?ttl_interval=0s", "", driver.Options{}, true},
+		{"mydb?ttl_interval=often", "", driver.Options{}, true},
+		{"mydb?encryption_key=0a0b", "mydb", driver.Options{EncryptionKey: []byte{0x0a, 0x0b}}, false},
+		{"mydb?encryption_key=xyz", "", driver.Options{}, true},
+		{"mydb?cache_size=lots", "", driver.Options{}, true},
+		{"mydb?cache_size=-1MB", "", driver.Options{}, true},
+		{"mydb?sync=maybe", "", driver.Options{}, true},
+		{"mydb?compression=lz4", "", driver.Options{}, true},
+		{"mydb?foo=bar", "", driver.Options{}, true},
+		{"mydb?sync=on&sync=off", "", driver.Options{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.dsn, func(t *testing.T) {
+			path, opts, err := driver.ParseDSN(test.dsn)
+			if test.fails {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.path, path)
+			require.Equal(t, test.expected, opts)
+		})
+	}
+}
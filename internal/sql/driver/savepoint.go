@@ -0,0 +1,28 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/chaisql/chai/internal/stringutil"
+)
+
+// Savepoint creates a savepoint with the given name in the transaction.
+func Savepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "SAVEPOINT "+stringutil.NormalizeIdentifier(name, '`'))
+	return err
+}
+
+// RollbackToSavepoint discards the changes made in the transaction
+// after the given savepoint. The savepoint can be used again.
+func RollbackToSavepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+stringutil.NormalizeIdentifier(name, '`'))
+	return err
+}
+
+// ReleaseSavepoint destroys the given savepoint, keeping the changes
+// made after it.
+func ReleaseSavepoint(ctx context.Context, tx *sql.Tx, name string) error {
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+stringutil.NormalizeIdentifier(name, '`'))
+	return err
+}
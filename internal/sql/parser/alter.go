@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"strings"
+
 	"github.com/cockroachdb/errors"
 
 	"github.com/chaisql/chai/internal/query/statement"
@@ -48,6 +50,76 @@ func (p *Parser) parseAlterTableAddColumnStatement(tableName string) (*statement
 	return &stmt, nil
 }
 
+// parseTTL parses the TTL word, which is not a reserved keyword
+// so that it can still be used as a column name.
+func (p *Parser) parseTTL() error {
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	if tok != scanner.IDENT || !strings.EqualFold(lit, "ttl") {
+		return newParseError(scanner.Tokstr(tok, lit), []string{"TTL"}, pos)
+	}
+
+	return nil
+}
+
+func (p *Parser) parseAlterTableSetTTLStatement(tableName string) (_ *statement.AlterTableSetTTLStmt, err error) {
+	var stmt statement.AlterTableSetTTLStmt
+	stmt.TableName = tableName
+
+	// Parse "TTL".
+	if err := p.parseTTL(); err != nil {
+		return nil, err
+	}
+
+	// Parse the ttl column.
+	stmt.TTLColumn, err = p.parseIdent()
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt, nil
+}
+
+func (p *Parser) parseAlterTableDropStatement(tableName string) (statement.Statement, error) {
+	tok, _, lit := p.ScanIgnoreWhitespace()
+	p.Unscan()
+	if tok == scanner.IDENT && strings.EqualFold(lit, "compression") {
+		// Parse "COMPRESSION DICTIONARY".
+		if err := p.parseWords("compression", "dictionary"); err != nil {
+			return nil, err
+		}
+
+		return &statement.AlterTableSetCompressionDictionaryStmt{TableName: tableName}, nil
+	}
+
+	// Parse "TTL".
+	if err := p.parseTTL(); err != nil {
+		return nil, err
+	}
+
+	return &statement.AlterTableSetTTLStmt{TableName: tableName}, nil
+}
+
+// parseWords parses a sequence of words which are not reserved keywords.
+func (p *Parser) parseWords(words ...string) error {
+	for _, w := range words {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		if tok != scanner.IDENT || !strings.EqualFold(lit, w) {
+			return newParseError(scanner.Tokstr(tok, lit), []string{strings.ToUpper(w)}, pos)
+		}
+	}
+
+	return nil
+}
+
+func (p *Parser) parseAlterTableTrainStatement(tableName string) (*statement.AlterTableSetCompressionDictionaryStmt, error) {
+	// Parse "TRAIN COMPRESSION DICTIONARY".
+	if err := p.parseWords("train", "compression", "dictionary"); err != nil {
+		return nil, err
+	}
+
+	return &statement.AlterTableSetCompressionDictionaryStmt{TableName: tableName, Train: true}, nil
+}
+
 // parseAlterStatement parses a Alter query string and returns a Statement AST row.
 func (p *Parser) parseAlterStatement() (statement.Statement, error) {
 	var err error
@@ -71,7 +143,16 @@ func (p *Parser) parseAlterStatement() (statement.Statement, error) {
 		return p.parseAlterTableRenameStatement(tableName)
 	case scanner.ADD_KEYWORD:
 		return p.parseAlterTableAddColumnStatement(tableName)
+	case scanner.SET:
+		return p.parseAlterTableSetTTLStatement(tableName)
+	case scanner.DROP:
+		return p.parseAlterTableDropStatement(tableName)
+	case scanner.IDENT:
+		if strings.EqualFold(lit, "train") {
+			p.Unscan()
+			return p.parseAlterTableTrainStatement(tableName)
+		}
 	}
 
-	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"ADD", "RENAME"}, pos)
+	return nil, newParseError(scanner.Tokstr(tok, lit), []string{"ADD", "RENAME", "SET", "DROP", "TRAIN"}, pos)
 }
@@ -22,6 +22,13 @@ func TestParserAlterTable(t *testing.T) {
 		{"With error / missing TABLE keyword", "ALTER foo RENAME TO bar", nil, true},
 		{"With error / two identifiers for table name", "ALTER TABLE foo baz RENAME TO bar", nil, true},
 		{"With error / two identifiers for new table name", "ALTER TABLE foo RENAME TO bar baz", nil, true},
+		{"Set TTL", "ALTER TABLE foo SET TTL expires_at", &statement.AlterTableSetTTLStmt{TableName: "foo", TTLColumn: "expires_at"}, false},
+		{"Drop TTL", "ALTER TABLE foo DROP TTL", &statement.AlterTableSetTTLStmt{TableName: "foo"}, false},
+		{"With error / missing TTL column", "ALTER TABLE foo SET TTL", nil, true},
+		{"With error / SET without TTL", "ALTER TABLE foo SET expires_at", nil, true},
+		{"Train compression dictionary", "ALTER TABLE foo TRAIN COMPRESSION DICTIONARY", &statement.AlterTableSetCompressionDictionaryStmt{TableName: "foo", Train: true}, false},
+		{"Drop compression dictionary", "ALTER TABLE foo DROP COMPRESSION DICTIONARY", &statement.AlterTableSetCompressionDictionaryStmt{TableName: "foo"}, false},
+		{"With error / missing DICTIONARY", "ALTER TABLE foo TRAIN COMPRESSION", nil, true},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,153 @@
+package parser
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/chaisql/chai/internal/expr"
+	"github.com/chaisql/chai/internal/query/statement"
+	"github.com/chaisql/chai/internal/sql/scanner"
+)
+
+// parseCopyStatement parses a copy string and returns a Statement AST row.
+// This function assumes the COPY token has already been consumed.
+func (p *Parser) parseCopyStatement() (*statement.CopyStmt, error) {
+	var stmt statement.CopyStmt
+	var err error
+
+	// Parse "COPY".
+	if err := p.ParseTokens(scanner.COPY); err != nil {
+		return nil, err
+	}
+
+	// Parse "(query)" or "table [(column, ...)]"
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.LPAREN {
+		query, err := p.parseSelectStatement()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.ParseTokens(scanner.RPAREN); err != nil {
+			return nil, err
+		}
+
+		stmt.Query = query
+	} else {
+		p.Unscan()
+
+		stmt.TableName, err = p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+
+		stmt.Columns, err = p.parseSimpleColumnList()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse "TO" or "FROM".
+	tok, pos, lit := p.ScanIgnoreWhitespace()
+	switch {
+	case tok == scanner.TO && stmt.Query == nil:
+		stmt.Query = copyTableQuery(stmt.TableName, stmt.Columns)
+		stmt.TableName, stmt.Columns = "", nil
+	case tok == scanner.TO:
+	case tok == scanner.FROM && stmt.Query == nil:
+	case stmt.Query != nil:
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TO"}, pos)
+	default:
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"TO", "FROM"}, pos)
+	}
+
+	tok, pos, lit = p.ScanIgnoreWhitespace()
+	if tok != scanner.STRING {
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"file name"}, pos)
+	}
+	stmt.Path = lit
+
+	err = p.parseCopyOptions(&stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &stmt, nil
+}
+
+// copyTableQuery returns the query selecting the columns of the table.
+func copyTableQuery(tableName string, columns []string) *statement.SelectStmt {
+	core := statement.SelectCoreStmt{TableName: tableName}
+	if len(columns) == 0 {
+		core.ProjectionExprs = []expr.Expr{expr.Wildcard{}}
+	}
+	for _, c := range columns {
+		core.ProjectionExprs = append(core.ProjectionExprs, &expr.NamedExpr{
+			Expr:     &expr.Column{Name: c},
+			ExprName: c,
+		})
+	}
+
+	return &statement.SelectStmt{CompoundSelect: []*statement.SelectCoreStmt{&core}}
+}
+
+// parseCopyOptions parses "WITH (FORMAT csv|json, HEADER [bool], DELIMITER 'c')".
+func (p *Parser) parseCopyOptions(stmt *statement.CopyStmt) error {
+	if ok, err := p.parseOptional(scanner.WITH); !ok || err != nil {
+		return err
+	}
+
+	if err := p.ParseTokens(scanner.LPAREN); err != nil {
+		return err
+	}
+
+	for {
+		_, pos, _ := p.ScanIgnoreWhitespace()
+		p.Unscan()
+
+		option, err := p.parseIdent()
+		if err != nil {
+			return err
+		}
+
+		switch strings.ToLower(option) {
+		case "format":
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			if tok != scanner.IDENT && tok != scanner.STRING {
+				return newParseError(scanner.Tokstr(tok, lit), []string{"csv", "json"}, pos)
+			}
+			stmt.Options.Format = strings.ToLower(lit)
+		case "header":
+			stmt.Options.Header = true
+
+			switch tok, _, _ := p.ScanIgnoreWhitespace(); tok {
+			case scanner.TRUE:
+			case scanner.FALSE:
+				stmt.Options.Header = false
+			default:
+				p.Unscan()
+			}
+		case "delimiter":
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			if tok != scanner.STRING || utf8.RuneCountInString(lit) != 1 {
+				return newParseError(scanner.Tokstr(tok, lit), []string{"single character"}, pos)
+			}
+			stmt.Options.Delimiter, _ = utf8.DecodeRuneInString(lit)
+		default:
+			return newParseError(option, []string{"FORMAT", "HEADER", "DELIMITER"}, pos)
+		}
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			break
+		}
+	}
+
+	if err := p.ParseTokens(scanner.RPAREN); err != nil {
+		return err
+	}
+
+	if err := stmt.Options.Validate(); err != nil {
+		return &ParseError{Message: err.Error()}
+	}
+
+	return nil
+}
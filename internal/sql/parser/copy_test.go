@@ -0,0 +1,59 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/chaisql/chai/internal/expr"
+	"github.com/chaisql/chai/internal/query/statement"
+	"github.com/chaisql/chai/internal/sql/parser"
+	"github.com/chaisql/chai/internal/stream/file"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserCopy(t *testing.T) {
+	query := func(exprs ...expr.Expr) *statement.SelectStmt {
+		return &statement.SelectStmt{CompoundSelect: []*statement.SelectCoreStmt{
+			{TableName: "test", ProjectionExprs: exprs},
+		}}
+	}
+
+	tests := []struct {
+		name     string
+		s        string
+		expected statement.Statement
+		errored  bool
+	}{
+		{"To", "COPY test TO 'test.csv'",
+			&statement.CopyStmt{Query: query(expr.Wildcard{}), Path: "test.csv"}, false},
+		{"To with columns", "COPY test (a, b) TO 'test.csv' WITH (FORMAT csv, HEADER)",
+			&statement.CopyStmt{
+				Query:   query(&expr.NamedExpr{Expr: &expr.Column{Name: "a"}, ExprName: "a"}, &expr.NamedExpr{Expr: &expr.Column{Name: "b"}, ExprName: "b"}),
+				Path:    "test.csv",
+				Options: file.Options{Format: file.FormatCSV, Header: true},
+			}, false},
+		{"Query to", "COPY (SELECT * FROM test) TO 'test.json' WITH (FORMAT json)",
+			&statement.CopyStmt{Query: query(expr.Wildcard{}), Path: "test.json", Options: file.Options{Format: file.FormatJSON}}, false},
+		{"From", "COPY test FROM 'test.csv' WITH (HEADER false, DELIMITER ';')",
+			&statement.CopyStmt{TableName: "test", Path: "test.csv", Options: file.Options{Delimiter: ';'}}, false},
+		{"From with columns", "COPY test (a, b) FROM 'test.csv'",
+			&statement.CopyStmt{TableName: "test", Columns: []string{"a", "b"}, Path: "test.csv"}, false},
+		{"Query from", "COPY (SELECT * FROM test) FROM 'test.csv'", nil, true},
+		{"No file", "COPY test TO STDOUT", nil, true},
+		{"Unknown format", "COPY test TO 'test.xml' WITH (FORMAT xml)", nil, true},
+		{"Unknown option", "COPY test TO 'test.csv' WITH (ENCODING 'utf8')", nil, true},
+		{"JSON header", "COPY test TO 'test.json' WITH (FORMAT json, HEADER)", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stmts, err := parser.ParseQuery(test.s)
+			if test.errored {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, stmts, 1)
+			require.EqualValues(t, test.expected, stmts[0])
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package parser
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math"
+	"strings"
 
 	"github.com/chaisql/chai/internal/database"
 	"github.com/chaisql/chai/internal/expr"
@@ -61,9 +63,84 @@ func (p *Parser) parseCreateTableStatement() (*statement.CreateTableStmt, error)
 		return nil, err
 	}
 
+	// parse table options
+	err = p.parseTableOptions(&stmt)
+	if err != nil {
+		return nil, err
+	}
+
 	return &stmt, err
 }
 
+// parseTableOptions parses the options of a table, if any:
+//
+//	WITH (option = value [, ...])
+//
+// The supported options are ttl_column, compression and compression_dictionary.
+func (p *Parser) parseTableOptions(stmt *statement.CreateTableStmt) error {
+	if ok, err := p.parseOptional(scanner.WITH); !ok || err != nil {
+		return err
+	}
+
+	if err := p.ParseTokens(scanner.LPAREN); err != nil {
+		return err
+	}
+
+	for {
+		_, pos, _ := p.ScanIgnoreWhitespace()
+		p.Unscan()
+
+		option, err := p.parseIdent()
+		if err != nil {
+			return err
+		}
+
+		if err := p.ParseTokens(scanner.EQ); err != nil {
+			return err
+		}
+
+		switch strings.ToLower(option) {
+		case "ttl_column":
+			stmt.Info.TTLColumn, err = p.parseIdent()
+			if err != nil {
+				return err
+			}
+		case "compression":
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			if tok != scanner.STRING {
+				return newParseError(scanner.Tokstr(tok, lit), []string{"STRING"}, pos)
+			}
+
+			if err := stmt.Info.SetCompression(lit); err != nil {
+				return &ParseError{Message: err.Error(), Pos: pos}
+			}
+		case "compression_dictionary":
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			if tok != scanner.STRING || !strings.HasPrefix(lit, `\x`) {
+				return newParseError(scanner.Tokstr(tok, lit), []string{"BYTEA"}, pos)
+			}
+
+			raw, err := hex.DecodeString(lit[2:])
+			if err != nil {
+				return &ParseError{Message: err.Error(), Pos: pos}
+			}
+
+			if err := stmt.Info.SetCompressionDictionary(raw); err != nil {
+				return &ParseError{Message: err.Error(), Pos: pos}
+			}
+		default:
+			return newParseError(option, []string{"ttl_column", "compression", "compression_dictionary"}, pos)
+		}
+
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+			p.Unscan()
+			break
+		}
+	}
+
+	return p.ParseTokens(scanner.RPAREN)
+}
+
 func (p *Parser) parseConstraints(stmt *statement.CreateTableStmt) error {
 	// Parse ( token.
 	tok, pos, lit := p.ScanIgnoreWhitespace()
@@ -263,6 +340,26 @@ LOOP:
 				Check:   expr.Constraint(e),
 				Columns: cols,
 			})
+		case scanner.IDENT:
+			// COMPRESSION is not a reserved keyword
+			if !strings.EqualFold(lit, "compression") {
+				p.Unscan()
+				break LOOP
+			}
+
+			if cc.Compression != "" {
+				return nil, nil, newParseError(scanner.Tokstr(tok, lit), []string{"CONSTRAINT", ")"}, pos)
+			}
+
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			if tok != scanner.IDENT && tok != scanner.STRING {
+				return nil, nil, newParseError(scanner.Tokstr(tok, lit), []string{"none", "snappy", "zstd"}, pos)
+			}
+
+			cc.Compression, err = database.ParseCompression(lit)
+			if err != nil {
+				return nil, nil, &ParseError{Message: err.Error(), Pos: pos}
+			}
 		default:
 			p.Unscan()
 			break LOOP
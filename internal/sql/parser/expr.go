@@ -93,12 +93,6 @@ func (p *Parser) parseOperator(minPrecedence int, allowed ...scanner.Token) (fun
 		return nil, 0, nil
 	}
 
-	// Ignore currently unused operators.
-	if op == scanner.EQREGEX || op == scanner.NEQREGEX {
-		p.Unscan()
-		return nil, 0, nil
-	}
-
 	if op == scanner.NOT {
 		tok, pos, lit := p.ScanIgnoreWhitespace()
 		if tok.Precedence() >= minPrecedence {
@@ -161,6 +155,10 @@ func (p *Parser) parseOperator(minPrecedence int, allowed ...scanner.Token) (fun
 		return expr.Is, op, nil
 	case scanner.LIKE:
 		return expr.Like, op, nil
+	case scanner.EQREGEX:
+		return expr.Regex, op, nil
+	case scanner.NEQREGEX:
+		return expr.NotRegex, op, nil
 	case scanner.CONCAT:
 		return expr.Concat, op, nil
 	case scanner.BETWEEN:
@@ -210,6 +208,9 @@ func (p *Parser) parseUnaryExprRec(allowed ...scanner.Token) (expr.Expr, error)
 	case scanner.CAST:
 		p.Unscan()
 		return p.parseCastExpression()
+	case scanner.CASE:
+		p.Unscan()
+		return p.parseCaseExpression()
 	case scanner.IDENT:
 		tok1, _, _ := p.ScanIgnoreWhitespace()
 		// if the next token is a left parenthesis, this is a function
@@ -229,12 +230,24 @@ func (p *Parser) parseUnaryExprRec(allowed ...scanner.Token) (expr.Expr, error)
 		p.Unscan()
 
 		return p.parseColumn()
+	case scanner.REPLACE:
+		// REPLACE is a keyword but also the name of a function.
+		p.Unscan()
+		return p.parseFunction()
 	case scanner.POSITIONALPARAM:
 		pp, err := strconv.Atoi(lit[1:])
 		if err != nil {
 			return nil, errors.WithStack(&ParseError{Message: "invalid positional parameter syntax", Pos: pos})
 		}
+		p.maxPositionalParam = max(p.maxPositionalParam, pp)
 		return expr.PositionalParam(pp), nil
+	case scanner.NAMEDPARAM:
+		name := lit[1:]
+		if p.namedParams == nil {
+			p.namedParams = make(map[string]struct{})
+		}
+		p.namedParams[name] = struct{}{}
+		return expr.NamedParam(name), nil
 	case scanner.STRING:
 		if strings.HasPrefix(lit, `\x`) {
 			bytea, err := hex.DecodeString(lit[2:])
@@ -398,6 +411,8 @@ func (p *Parser) parseType() (types.Type, error) {
 		return types.TypeText, nil
 	case scanner.TYPETIMESTAMP:
 		return types.TypeTimestamp, nil
+	case scanner.TYPEUUID:
+		return types.TypeUUID, nil
 	case scanner.TYPEVARCHAR, scanner.TYPECHARACTER:
 		if tok, pos, lit := p.ScanIgnoreWhitespace(); tok != scanner.LPAREN {
 			return 0, newParseError(scanner.Tokstr(tok, lit), []string{"("}, pos)
@@ -478,9 +493,14 @@ func (p *Parser) parseExprList(leftToken, rightToken scanner.Token) (expr.Litera
 // an optional coma-separated list of expressions and a closing parenthesis.
 func (p *Parser) parseFunction() (expr.Expr, error) {
 	// Parse function name.
-	funcName, err := p.parseIdent()
-	if err != nil {
-		return nil, err
+	// Some function names are also keywords.
+	tok, pos, funcName := p.ScanIgnoreWhitespace()
+	switch tok {
+	case scanner.IDENT:
+	case scanner.REPLACE:
+		funcName = "replace"
+	default:
+		return nil, newParseError(scanner.Tokstr(tok, funcName), []string{"identifier"}, pos)
 	}
 
 	// Parse required ( token.
@@ -488,26 +508,138 @@ func (p *Parser) parseFunction() (expr.Expr, error) {
 		return nil, err
 	}
 
+	// Parse optional DISTINCT token, used by aggregate functions.
+	distinct, err := p.parseOptional(scanner.DISTINCT)
+	if err != nil {
+		return nil, err
+	}
+
+	var exprs []expr.Expr
+	var orderBy []functions.SortKey
+
 	// Check if the function is called without arguments.
-	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.RPAREN {
-		def, err := functions.GetFunc(funcName)
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.RPAREN {
+		p.Unscan()
+
+		// Parse expressions.
+		for {
+			e, err := p.ParseExpr()
+			if err != nil {
+				return nil, err
+			}
+
+			exprs = append(exprs, e)
+
+			if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
+				p.Unscan()
+				break
+			}
+		}
+
+		// Parse optional ORDER BY clause, used by ordered aggregate functions.
+		orderBy, err = p.parseAggregateOrderBy()
 		if err != nil {
 			return nil, err
 		}
-		return def.Function()
+
+		// Parse required ) token.
+		if err := p.ParseTokens(scanner.RPAREN); err != nil {
+			return nil, err
+		}
 	}
-	p.Unscan()
 
-	var exprs []expr.Expr
+	def, err := functions.GetFunc(funcName)
+	if err != nil {
+		return nil, err
+	}
+	fn, err := def.Function(exprs...)
+	if err != nil {
+		return nil, err
+	}
 
-	// Parse expressions.
+	// Parse optional WITHIN GROUP (ORDER BY ...) and FILTER (WHERE ...) clauses.
+	// WITHIN and FILTER are not reserved keywords, they are only
+	// recognized after a function call.
+	withinGroup := false
+	if p.parseOptionalIdent("WITHIN") {
+		if err := p.ParseTokens(scanner.GROUP, scanner.LPAREN); err != nil {
+			return nil, err
+		}
+		if len(orderBy) > 0 {
+			return nil, fmt.Errorf("cannot use both ORDER BY and WITHIN GROUP in %s", funcName)
+		}
+		orderBy, err = p.parseAggregateOrderBy()
+		if err != nil {
+			return nil, err
+		}
+		if len(orderBy) == 0 {
+			tok, pos, lit := p.ScanIgnoreWhitespace()
+			return nil, newParseError(scanner.Tokstr(tok, lit), []string{"ORDER BY"}, pos)
+		}
+		if err := p.ParseTokens(scanner.RPAREN); err != nil {
+			return nil, err
+		}
+		withinGroup = true
+	}
+
+	var filter expr.Expr
+	if p.parseOptionalIdent("FILTER") {
+		if err := p.ParseTokens(scanner.LPAREN, scanner.WHERE); err != nil {
+			return nil, err
+		}
+		filter, err = p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.ParseTokens(scanner.RPAREN); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(orderBy) > 0 {
+		oa, ok := fn.(functions.OrderedAggregate)
+		if !ok {
+			return nil, fmt.Errorf("ORDER BY specified but %s is not an ordered aggregate function", funcName)
+		}
+		if err := oa.SetOrderBy(orderBy, withinGroup); err != nil {
+			return nil, err
+		}
+	} else if oa, ok := fn.(functions.OrderedAggregate); ok {
+		// let the function decide whether an ORDER BY clause is required
+		if err := oa.SetOrderBy(nil, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if distinct || filter != nil {
+		return functions.NewAggregate(fn, distinct, filter)
+	}
+
+	return fn, nil
+}
+
+// parseAggregateOrderBy parses an optional ORDER BY clause used within
+// aggregate function calls, e.g. string_agg(a, ',' ORDER BY b DESC).
+func (p *Parser) parseAggregateOrderBy() ([]functions.SortKey, error) {
+	ok, err := p.parseOptional(scanner.ORDER, scanner.BY)
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var keys []functions.SortKey
 	for {
 		e, err := p.ParseExpr()
 		if err != nil {
 			return nil, err
 		}
 
-		exprs = append(exprs, e)
+		k := functions.SortKey{Expr: e}
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.DESC {
+			k.Desc = true
+		} else if tok != scanner.ASC {
+			p.Unscan()
+		}
+		keys = append(keys, k)
 
 		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.COMMA {
 			p.Unscan()
@@ -515,16 +647,90 @@ func (p *Parser) parseFunction() (expr.Expr, error) {
 		}
 	}
 
-	// Parse required ) token.
-	if err := p.ParseTokens(scanner.RPAREN); err != nil {
+	return keys, nil
+}
+
+// parseOptionalIdent consumes the next token if it is an identifier
+// matching name, case-insensitively.
+func (p *Parser) parseOptionalIdent(name string) bool {
+	tok, _, lit := p.ScanIgnoreWhitespace()
+	if tok == scanner.IDENT && strings.EqualFold(lit, name) {
+		return true
+	}
+	p.Unscan()
+	return false
+}
+
+// parseCaseExpression parses a CASE expression. It supports both forms:
+//
+//	CASE WHEN cond THEN expr [WHEN ...] [ELSE expr] END
+//	CASE operand WHEN value THEN expr [WHEN ...] [ELSE expr] END
+func (p *Parser) parseCaseExpression() (expr.Expr, error) {
+	// Parse required CASE token.
+	if err := p.ParseTokens(scanner.CASE); err != nil {
 		return nil, err
 	}
 
-	def, err := functions.GetFunc(funcName)
-	if err != nil {
+	var c expr.CaseExpr
+
+	// Parse optional operand.
+	if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.WHEN {
+		p.Unscan()
+
+		e, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+		c.Operand = e
+	} else {
+		p.Unscan()
+	}
+
+	// Parse WHEN ... THEN ... clauses, at least one is required.
+	for {
+		if tok, _, _ := p.ScanIgnoreWhitespace(); tok != scanner.WHEN {
+			p.Unscan()
+			break
+		}
+
+		cond, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.ParseTokens(scanner.THEN); err != nil {
+			return nil, err
+		}
+
+		then, err := p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+
+		c.Whens = append(c.Whens, expr.WhenClause{Cond: cond, Then: then})
+	}
+
+	if len(c.Whens) == 0 {
+		tok, pos, lit := p.ScanIgnoreWhitespace()
+		return nil, newParseError(scanner.Tokstr(tok, lit), []string{"WHEN"}, pos)
+	}
+
+	// Parse optional ELSE clause.
+	if ok, err := p.parseOptional(scanner.ELSE); err != nil {
+		return nil, err
+	} else if ok {
+		c.Else, err = p.ParseExpr()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse required END token.
+	if err := p.ParseTokens(scanner.END); err != nil {
 		return nil, err
 	}
-	return def.Function(exprs...)
+
+	return &c, nil
 }
 
 // parseCastExpression parses a string of the form CAST(expr AS type).
@@ -82,6 +82,8 @@ func TestParserExpr(t *testing.T) {
 		{"IS NOT", "age IS NOT NULL", expr.IsNot(&expr.Column{Name: "age"}, testutil.NullValue()), false},
 		{"LIKE", "name LIKE 'foo'", expr.Like(&expr.Column{Name: "name"}, testutil.TextValue("foo")), false},
 		{"NOT LIKE", "name NOT LIKE 'foo'", expr.NotLike(&expr.Column{Name: "name"}, testutil.TextValue("foo")), false},
+		{"=~", "name =~ '^fo+$'", expr.Regex(&expr.Column{Name: "name"}, testutil.TextValue("^fo+$")), false},
+		{"!~", "name !~ '^fo+$'", expr.NotRegex(&expr.Column{Name: "name"}, testutil.TextValue("^fo+$")), false},
 		{"NOT =", "name NOT = 'foo'", nil, true},
 		{"precedence", "4 > 1 + 2", expr.Gt(
 			testutil.IntegerValue(4),
@@ -117,11 +119,34 @@ func TestParserExpr(t *testing.T) {
 		{"NOT", "NOT NOT 10", expr.Not(expr.Not(testutil.IntegerValue(10))), false},
 		{"nextval", "nextval('hello')", &functions.NextVal{Expr: testutil.TextValue("hello")}, false},
 
+		// CASE
+		{"CASE WHEN", "CASE WHEN a > 1 THEN 'a' WHEN b THEN 'b' ELSE 'c' END", &expr.CaseExpr{
+			Whens: []expr.WhenClause{
+				{Cond: expr.Gt(&expr.Column{Name: "a"}, testutil.IntegerValue(1)), Then: testutil.TextValue("a")},
+				{Cond: &expr.Column{Name: "b"}, Then: testutil.TextValue("b")},
+			},
+			Else: testutil.TextValue("c"),
+		}, false},
+		{"CASE operand", "CASE a + 1 WHEN 1 THEN 'one' END", &expr.CaseExpr{
+			Operand: expr.Add(&expr.Column{Name: "a"}, testutil.IntegerValue(1)),
+			Whens:   []expr.WhenClause{{Cond: testutil.IntegerValue(1), Then: testutil.TextValue("one")}},
+		}, false},
+		{"CASE without WHEN", "CASE a END", nil, true},
+		{"CASE without END", "CASE WHEN a THEN 1", nil, true},
+		{"CASE without THEN", "CASE WHEN a 1 END", nil, true},
+
 		// functions
 		{"count(expr) function", "count(a)", &functions.Count{Expr: &expr.Column{Name: "a"}}, false},
 		{"count(*) function", "count(*)", functions.NewCount(expr.Wildcard{}), false},
 		{"count (*) function with spaces", "count      (*)", functions.NewCount(expr.Wildcard{}), false},
 		{"packaged function", "floor(1.2)", testutil.FunctionExpr(t, "floor", testutil.DoubleValue(1.2)), false},
+		{"count(DISTINCT expr)", "count(DISTINCT a)", &functions.Aggregate{Fn: &functions.Count{Expr: &expr.Column{Name: "a"}}, Distinct: true}, false},
+		{"count(DISTINCT *)", "count(DISTINCT *)", nil, true},
+		{"FILTER", "count(*) FILTER (WHERE a > 1)", &functions.Aggregate{Fn: functions.NewCount(expr.Wildcard{}), Filter: expr.Gt(&expr.Column{Name: "a"}, testutil.IntegerValue(1))}, false},
+		{"FILTER on scalar function", "lower(a) FILTER (WHERE a > 1)", nil, true},
+		{"string_agg ORDER BY", "string_agg(a, ',' ORDER BY b DESC)", &functions.StringAgg{Expr: &expr.Column{Name: "a"}, Separator: testutil.TextValue(","), OrderBy: []functions.SortKey{{Expr: &expr.Column{Name: "b"}, Desc: true}}}, false},
+		{"ORDER BY on non-ordered aggregate", "count(a ORDER BY a)", nil, true},
+		{"missing WITHIN GROUP", "percentile_cont(0.5)", nil, true},
 	}
 
 	for _, test := range tests {
@@ -152,6 +177,14 @@ func TestParserParams(t *testing.T) {
 				expr.Gte(&expr.Column{Name: "age"}, expr.PositionalParam(111)),
 				expr.Lte(&expr.Column{Name: "age"}, expr.PositionalParam(11)),
 			), false},
+		{"named with colon", "age = :age", expr.Eq(&expr.Column{Name: "age"}, expr.NamedParam("age")), false},
+		{"named with at sign", "age = @age", expr.Eq(&expr.Column{Name: "age"}, expr.NamedParam("age")), false},
+		{"positional and named", "age >= $1 AND name = :name",
+			expr.And(
+				expr.Gte(&expr.Column{Name: "age"}, expr.PositionalParam(1)),
+				expr.Eq(&expr.Column{Name: "name"}, expr.NamedParam("name")),
+			), false},
+		{"invalid named", "age = @1", nil, true},
 	}
 
 	for _, test := range tests {
@@ -166,3 +199,25 @@ func TestParserParams(t *testing.T) {
 		})
 	}
 }
+
+func TestParserNumParams(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected int
+	}{
+		{"SELECT * FROM test", 0},
+		{"SELECT * FROM test WHERE a = $1 AND b = $1", 1},
+		{"SELECT * FROM test WHERE a = $3", 3},
+		{"SELECT * FROM test WHERE a = :a AND b = @a AND c = :c", 2},
+		{"INSERT INTO test (a, b) VALUES ($1, :b)", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			p := parser.NewParser(strings.NewReader(test.s))
+			_, err := p.ParseQuery()
+			require.NoError(t, err)
+			require.Equal(t, test.expected, p.NumParams())
+		})
+	}
+}
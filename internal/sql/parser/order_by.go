@@ -7,26 +7,26 @@ import (
 	"github.com/chaisql/chai/internal/sql/scanner"
 )
 
-func (p *Parser) parseOrderBy() (*expr.Column, scanner.Token, error) {
+func (p *Parser) parseOrderBy() (expr.Expr, scanner.Token, error) {
 	// parse ORDER token
 	ok, err := p.parseOptional(scanner.ORDER, scanner.BY)
 	if err != nil || !ok {
 		return nil, 0, err
 	}
 
-	// parse col
-	col, err := p.parseColumn()
+	// parse expr
+	e, err := p.ParseExpr()
 	if err != nil {
 		return nil, 0, err
 	}
 
 	// parse optional ASC or DESC
 	if tok, _, _ := p.ScanIgnoreWhitespace(); tok == scanner.ASC || tok == scanner.DESC {
-		return col, tok, nil
+		return e, tok, nil
 	}
 	p.Unscan()
 
-	return col, 0, nil
+	return e, 0, nil
 }
 
 func (p *Parser) parseLimit() (expr.Expr, error) {
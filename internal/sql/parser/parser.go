@@ -15,6 +15,10 @@ import (
 // Parser represents an Chai SQL Parser.
 type Parser struct {
 	s *scanner.Scanner
+
+	// parameters found while parsing
+	maxPositionalParam int
+	namedParams        map[string]struct{}
 }
 
 // NewParser returns a new instance of Parser.
@@ -43,6 +47,13 @@ func MustParseExpr(s string) expr.Expr {
 	return e
 }
 
+// NumParams returns the number of distinct parameters found so far by the parser.
+// Positional parameters are counted up to the highest position used,
+// named parameters are counted once per name.
+func (p *Parser) NumParams() int {
+	return p.maxPositionalParam + len(p.namedParams)
+}
+
 // ParseQuery parses a Chai SQL string and returns a Query.
 func (p *Parser) ParseQuery() ([]statement.Statement, error) {
 	var statements []statement.Statement
@@ -106,6 +117,8 @@ func (p *Parser) ParseStatement() (statement.Statement, error) {
 		return p.parseBeginStatement()
 	case scanner.COMMIT:
 		return p.parseCommitStatement()
+	case scanner.COPY:
+		return p.parseCopyStatement()
 	case scanner.SELECT:
 		return p.parseSelectStatement()
 	case scanner.DELETE:
@@ -122,12 +135,16 @@ func (p *Parser) ParseStatement() (statement.Statement, error) {
 		return p.parseExplainStatement()
 	case scanner.REINDEX:
 		return p.parseReIndexStatement()
+	case scanner.RELEASE:
+		return p.parseReleaseStatement()
 	case scanner.ROLLBACK:
 		return p.parseRollbackStatement()
+	case scanner.SAVEPOINT:
+		return p.parseSavepointStatement()
 	}
 
 	return nil, newParseError(scanner.Tokstr(tok, lit), []string{
-		"ALTER", "BEGIN", "COMMIT", "SELECT", "DELETE", "UPDATE", "INSERT", "CREATE", "DROP", "EXPLAIN", "REINDEX", "ROLLBACK",
+		"ALTER", "BEGIN", "COMMIT", "COPY", "SELECT", "DELETE", "UPDATE", "INSERT", "CREATE", "DROP", "EXPLAIN", "REINDEX", "RELEASE", "ROLLBACK", "SAVEPOINT",
 	}, pos)
 }
 
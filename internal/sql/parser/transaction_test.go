@@ -25,6 +25,15 @@ func TestParserTransactions(t *testing.T) {
 		{"ROLLBACK TRANSACTION", query.RollbackStmt{}, false},
 		{"COMMIT", query.CommitStmt{}, false},
 		{"COMMIT TRANSACTION", query.CommitStmt{}, false},
+		{"SAVEPOINT foo", query.SavepointStmt{Name: "foo"}, false},
+		{"SAVEPOINT", nil, true},
+		{"ROLLBACK TO foo", query.RollbackToSavepointStmt{Name: "foo"}, false},
+		{"ROLLBACK TO SAVEPOINT foo", query.RollbackToSavepointStmt{Name: "foo"}, false},
+		{"ROLLBACK TRANSACTION TO SAVEPOINT foo", query.RollbackToSavepointStmt{Name: "foo"}, false},
+		{"ROLLBACK TO SAVEPOINT", nil, true},
+		{"RELEASE foo", query.ReleaseSavepointStmt{Name: "foo"}, false},
+		{"RELEASE SAVEPOINT foo", query.ReleaseSavepointStmt{Name: "foo"}, false},
+		{"RELEASE", nil, true},
 	}
 
 	for _, test := range tests {
@@ -72,6 +72,12 @@ func (s *scanner) Scan() (tok Token, pos Pos, lit string) {
 	case '$':
 		lit := s.scanDigits()
 		return POSITIONALPARAM, pos, "$" + lit
+	case '@':
+		if ch1, _ := s.r.read(); isLetter(ch1) || ch1 == '_' {
+			s.r.unread()
+			return NAMEDPARAM, pos, "@" + s.scanParamName()
+		}
+		s.r.unread()
 	case '+':
 		return ADD, pos, ""
 	case '-':
@@ -155,9 +161,14 @@ func (s *scanner) Scan() (tok Token, pos Pos, lit string) {
 	case ';':
 		return SEMICOLON, pos, ""
 	case ':':
-		if ch1, _ := s.r.read(); ch1 == ':' {
+		ch1, _ := s.r.read()
+		if ch1 == ':' {
 			return DOUBLECOLON, pos, ""
 		}
+		if isLetter(ch1) || ch1 == '_' {
+			s.r.unread()
+			return NAMEDPARAM, pos, ":" + s.scanParamName()
+		}
 		s.r.unread()
 		return COLON, pos, ""
 	}
@@ -372,6 +383,21 @@ func (s *scanner) scanDigits() string {
 	return buf.String()
 }
 
+// scanParamName consumes all contiguous identifier characters
+// and returns them as the name of a parameter.
+func (s *scanner) scanParamName() string {
+	var buf bytes.Buffer
+	for {
+		ch, _ := s.r.read()
+		if !isIdentChar(ch) {
+			s.r.unread()
+			break
+		}
+		_, _ = buf.WriteRune(ch)
+	}
+	return buf.String()
+}
+
 // isWhitespace returns true if the rune is a space, tab, or newline.
 func isWhitespace(ch rune) bool { return ch == ' ' || ch == '\t' || ch == '\n' }
 
@@ -83,6 +83,11 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `"test`, tok: BADSTRING, lit: "test"},
 		{s: "?", tok: ILLEGAL, lit: "?"},
 		{s: "$10", tok: POSITIONALPARAM, lit: "$10"},
+		{s: ":foo", tok: NAMEDPARAM, lit: ":foo"},
+		{s: ":foo_1", tok: NAMEDPARAM, lit: ":foo_1"},
+		{s: "@foo", tok: NAMEDPARAM, lit: "@foo"},
+		{s: "@1", tok: ILLEGAL, lit: "@"},
+		{s: ":1", tok: COLON},
 		{s: `"testing 123!"`, tok: IDENT, lit: `testing 123!`},
 
 		// Booleans
@@ -123,11 +128,13 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `BEGIN`, tok: BEGIN},
 		{s: `BETWEEN`, tok: BETWEEN},
 		{s: `CACHE`, tok: CACHE},
+		{s: `CASE`, tok: CASE},
 		{s: `CAST`, tok: CAST},
 		{s: `CHECK`, tok: CHECK},
 		{s: `COMMIT`, tok: COMMIT},
 		{s: `CONFLICT`, tok: CONFLICT},
 		{s: `CONSTRAINT`, tok: CONSTRAINT},
+		{s: `COPY`, tok: COPY},
 		{s: `CREATE`, tok: CREATE},
 		{s: `CYCLE`, tok: CYCLE},
 		{s: `DEFAULT`, tok: DEFAULT},
@@ -136,6 +143,8 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `DO`, tok: DO},
 		{s: `DISTINCT`, tok: DISTINCT},
 		{s: `DROP`, tok: DROP},
+		{s: `ELSE`, tok: ELSE},
+		{s: `END`, tok: END},
 		{s: `EXPLAIN`, tok: EXPLAIN},
 		{s: `GROUP`, tok: GROUP},
 		{s: `COLUMN`, tok: COLUMN},
@@ -158,20 +167,24 @@ func TestScanner_Scan(t *testing.T) {
 		{s: `PRIMARY`, tok: PRIMARY},
 		{s: `READ`, tok: READ},
 		{s: `REINDEX`, tok: REINDEX},
+		{s: `RELEASE`, tok: RELEASE},
 		{s: `RENAME`, tok: RENAME},
 		{s: `REPLACE`, tok: REPLACE},
 		{s: `RETURNING`, tok: RETURNING},
 		{s: `ROLLBACK`, tok: ROLLBACK},
+		{s: `SAVEPOINT`, tok: SAVEPOINT},
 		{s: `SELECT`, tok: SELECT},
 		{s: `SEQUENCE`, tok: SEQUENCE},
 		{s: `SET`, tok: SET},
 		{s: `START`, tok: START},
 		{s: `TABLE`, tok: TABLE},
+		{s: `THEN`, tok: THEN},
 		{s: `TO`, tok: TO},
 		{s: `TRANSACTION`, tok: TRANSACTION},
 		{s: `UPDATE`, tok: UPDATE},
 		{s: `UNION`, tok: UNION},
 		{s: `VALUES`, tok: VALUES},
+		{s: `WHEN`, tok: WHEN},
 		{s: `WITH`, tok: WITH},
 		{s: `WHERE`, tok: WHERE},
 		{s: `WRITE`, tok: WRITE},
@@ -185,6 +198,7 @@ func TestScanner_Scan(t *testing.T) {
 		{s: "INTEGER", tok: TYPEINTEGER},
 		{s: "TEXT", tok: TYPETEXT},
 		{s: "TIMESTAMP", tok: TYPETIMESTAMP},
+		{s: "UUID", tok: TYPEUUID},
 	}
 
 	for i, tt := range tests {
@@ -18,6 +18,7 @@ const (
 	// IDENT and the following are Chai SQL literal tokens.
 	IDENT           // main
 	POSITIONALPARAM // $1
+	NAMEDPARAM      // :name or @name
 	NUMBER          // 12345.67
 	INTEGER         // 12345
 	STRING          // "abc"
@@ -84,12 +85,14 @@ const (
 	BEGIN
 	BY
 	CACHE
+	CASE
 	CAST
 	CHECK
 	COLUMN
 	COMMIT
 	CONFLICT
 	CONSTRAINT
+	COPY
 	CREATE
 	CYCLE
 	DEFAULT
@@ -98,6 +101,8 @@ const (
 	DISTINCT
 	DO
 	DROP
+	ELSE
+	END
 	EXISTS
 	EXPLAIN
 	FOR
@@ -124,21 +129,25 @@ const (
 	PRIMARY
 	READ
 	REINDEX
+	RELEASE
 	RENAME
 	REPLACE
 	RETURNING
 	ROLLBACK
+	SAVEPOINT
 	SELECT
 	SEQUENCE
 	SET
 	START
 	TABLE
+	THEN
 	TO
 	TRANSACTION
 	UNION
 	UNIQUE
 	UPDATE
 	VALUES
+	WHEN
 	WITH
 	WHERE
 	WRITE
@@ -161,6 +170,7 @@ const (
 	TYPETEXT
 	TYPETIMESTAMP
 	TYPETINYINT
+	TYPEUUID
 	TYPEVARCHAR
 
 	keywordEnd
@@ -173,6 +183,7 @@ var tokens = [...]string{
 
 	IDENT:           "IDENT",
 	POSITIONALPARAM: "?",
+	NAMEDPARAM:      "NAMEDPARAM",
 	NUMBER:          "NUMBER",
 	STRING:          "STRING",
 	BADSTRING:       "BADSTRING",
@@ -227,12 +238,14 @@ var tokens = [...]string{
 	BEGIN:       "BEGIN",
 	BY:          "BY",
 	CACHE:       "CACHE",
+	CASE:        "CASE",
 	CAST:        "CAST",
 	CHECK:       "CHECK",
 	COLUMN:      "COLUMN",
 	COMMIT:      "COMMIT",
 	CONFLICT:    "CONFLICT",
 	CONSTRAINT:  "CONSTRAINT",
+	COPY:        "COPY",
 	CREATE:      "CREATE",
 	CYCLE:       "CYCLE",
 	DO:          "DO",
@@ -241,6 +254,8 @@ var tokens = [...]string{
 	DESC:        "DESC",
 	DISTINCT:    "DISTINCT",
 	DROP:        "DROP",
+	ELSE:        "ELSE",
+	END:         "END",
 	EXISTS:      "EXISTS",
 	EXPLAIN:     "EXPLAIN",
 	GROUP:       "GROUP",
@@ -267,21 +282,25 @@ var tokens = [...]string{
 	PRIMARY:     "PRIMARY",
 	READ:        "READ",
 	REINDEX:     "REINDEX",
+	RELEASE:     "RELEASE",
 	RENAME:      "RENAME",
 	RETURNING:   "RETURNING",
 	REPLACE:     "REPLACE",
 	ROLLBACK:    "ROLLBACK",
 	START:       "START",
+	SAVEPOINT:   "SAVEPOINT",
 	SELECT:      "SELECT",
 	SET:         "SET",
 	SEQUENCE:    "SEQUENCE",
 	TABLE:       "TABLE",
+	THEN:        "THEN",
 	TO:          "TO",
 	TRANSACTION: "TRANSACTION",
 	UNION:       "UNION",
 	UNIQUE:      "UNIQUE",
 	UPDATE:      "UPDATE",
 	VALUES:      "VALUES",
+	WHEN:        "WHEN",
 	WITH:        "WITH",
 	WHERE:       "WHERE",
 	WRITE:       "WRITE",
@@ -303,6 +322,7 @@ var tokens = [...]string{
 	TYPETEXT:      "TEXT",
 	TYPETIMESTAMP: "TIMESTAMP",
 	TYPETINYINT:   "TINYINT",
+	TYPEUUID:      "UUID",
 	TYPEVARCHAR:   "VARCHAR",
 }
 
@@ -0,0 +1,377 @@
+// Package file provides the operators used to copy rows from and to files.
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/chaisql/chai/internal/database"
+	"github.com/chaisql/chai/internal/environment"
+	"github.com/chaisql/chai/internal/row"
+	"github.com/chaisql/chai/internal/stream"
+	"github.com/chaisql/chai/internal/types"
+	"github.com/cockroachdb/errors"
+)
+
+// Formats of the files.
+const (
+	// FormatCSV files contain one record per line.
+	// Empty fields are NULL, unless the column is of type TEXT.
+	FormatCSV = "csv"
+	// FormatJSON files contain one JSON object per line.
+	FormatJSON = "json"
+)
+
+// Options describe the format of a file.
+type Options struct {
+	// Format of the file. Defaults to FormatCSV.
+	Format string
+	// Header is true if the first line of a CSV file contains
+	// the name of the columns.
+	Header bool
+	// Delimiter of the fields of a CSV file. Defaults to a comma.
+	Delimiter rune
+}
+
+// Validate returns an error if the options are invalid.
+func (o *Options) Validate() error {
+	switch o.Format {
+	case "", FormatCSV:
+	case FormatJSON:
+		if o.Header {
+			return errors.New("HEADER is only supported by the csv format")
+		}
+		if o.Delimiter != 0 {
+			return errors.New("DELIMITER is only supported by the csv format")
+		}
+	default:
+		return errors.Errorf("unknown format %q, expected csv or json", o.Format)
+	}
+
+	if o.Delimiter == '\r' || o.Delimiter == '\n' || o.Delimiter == '"' {
+		return errors.Errorf("invalid delimiter %q", o.Delimiter)
+	}
+
+	return nil
+}
+
+func (o *Options) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("FORMAT ")
+	if o.Format == "" {
+		sb.WriteString(FormatCSV)
+	} else {
+		sb.WriteString(o.Format)
+	}
+	if o.Header {
+		sb.WriteString(", HEADER")
+	}
+	if o.Delimiter != 0 {
+		fmt.Fprintf(&sb, ", DELIMITER '%c'", o.Delimiter)
+	}
+
+	return sb.String()
+}
+
+// A ReadOperator reads the rows of a table from a file.
+type ReadOperator struct {
+	stream.BaseOperator
+
+	TableName string
+	Path      string
+	// ColumnNames are the columns of the table present in the file, in order.
+	// If empty, CSV files use their header, or all the columns of the table.
+	ColumnNames []string
+	Options     Options
+
+	// line of the row being read
+	line int
+}
+
+// Read creates an operator that reads the rows of a table from a file.
+func Read(tableName, path string, columns []string, opts Options) *ReadOperator {
+	return &ReadOperator{TableName: tableName, Path: path, ColumnNames: columns, Options: opts}
+}
+
+// Line returns the line of the last row read from the file.
+// It is used to report where an error occurred in the file.
+func (op *ReadOperator) Line() int {
+	return op.line
+}
+
+func (op *ReadOperator) Iterator(in *environment.Environment) (stream.Iterator, error) {
+	tx := in.GetTx()
+
+	info, err := tx.Catalog.GetTableInfo(op.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := op.ColumnNames
+	if len(columns) == 0 {
+		for _, cc := range info.ColumnConstraints.Ordered {
+			columns = append(columns, cc.Column)
+		}
+	}
+
+	f, err := os.Open(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	op.line = 0
+	it := ReadIterator{
+		op:      op,
+		env:     in,
+		f:       f,
+		info:    info,
+		columns: columns,
+	}
+
+	if op.Options.Format == FormatJSON {
+		it.lines = bufio.NewReader(f)
+		return &it, nil
+	}
+
+	it.csv = csv.NewReader(bufio.NewReader(f))
+	if op.Options.Delimiter != 0 {
+		it.csv.Comma = op.Options.Delimiter
+	}
+
+	if op.Options.Header {
+		header, err := it.csv.Read()
+		if err != nil && !errors.Is(err, io.EOF) {
+			f.Close()
+			return nil, op.csvError(err)
+		}
+		op.line = 1
+
+		// the header names the columns, unless they are listed explicitly
+		if len(op.ColumnNames) == 0 && len(header) > 0 {
+			it.columns = header
+		}
+	}
+	it.csv.FieldsPerRecord = len(it.columns)
+
+	for _, c := range it.columns {
+		if info.ColumnConstraints.GetColumnConstraint(c) == nil {
+			f.Close()
+			return nil, errors.Errorf("table has no column %s", c)
+		}
+	}
+
+	return &it, nil
+}
+
+func (op *ReadOperator) Columns(env *environment.Environment) ([]string, error) {
+	return op.ColumnNames, nil
+}
+
+func (op *ReadOperator) String() string {
+	return fmt.Sprintf("file.Read(%q, %q, %s)", op.TableName, op.Path, op.Options.String())
+}
+
+type ReadIterator struct {
+	op      *ReadOperator
+	env     *environment.Environment
+	f       *os.File
+	info    *database.TableInfo
+	columns []string
+
+	csv   *csv.Reader
+	lines *bufio.Reader
+
+	cb  row.ColumnBuffer
+	row database.BasicRow
+	err error
+}
+
+func (it *ReadIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.cb.Reset()
+	if it.csv != nil {
+		it.err = it.readCSV()
+	} else {
+		it.err = it.readJSON()
+	}
+	if errors.Is(it.err, io.EOF) {
+		it.err = nil
+		return false
+	}
+
+	return it.err == nil
+}
+
+func (it *ReadIterator) readCSV() error {
+	record, err := it.csv.Read()
+	if err != nil {
+		return it.op.csvError(err)
+	}
+	it.op.line, _ = it.csv.FieldPos(0)
+
+	for i, field := range record {
+		c := it.columns[i]
+
+		// empty fields are NULL, except in text columns
+		if field == "" && it.info.ColumnConstraints.GetColumnConstraint(c).Type != types.TypeText {
+			it.cb.Add(c, types.NewNullValue())
+			continue
+		}
+
+		it.cb.Add(c, types.NewTextValue(field))
+	}
+
+	it.row.ResetWith(it.op.TableName, nil, &it.cb)
+	return nil
+}
+
+func (it *ReadIterator) readJSON() error {
+	for {
+		line, err := it.lines.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return err
+		}
+		it.op.line++
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := it.cb.UnmarshalJSON(line); err != nil {
+			return err
+		}
+
+		err = it.cb.Iterate(func(c string, _ types.Value) error {
+			if it.info.ColumnConstraints.GetColumnConstraint(c) == nil {
+				return errors.Errorf("table has no column %s", c)
+			}
+			if len(it.op.ColumnNames) > 0 && !slices.Contains(it.op.ColumnNames, c) {
+				return errors.Errorf("column %s is not copied", c)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		it.row.ResetWith(it.op.TableName, nil, &it.cb)
+		return nil
+	}
+}
+
+func (it *ReadIterator) Row() (database.Row, error) {
+	return &it.row, nil
+}
+
+func (it *ReadIterator) Error() error {
+	return it.err
+}
+
+func (it *ReadIterator) Close() error {
+	return it.f.Close()
+}
+
+// csvError removes the position from CSV errors,
+// which are reported with the line of the row being read.
+func (op *ReadOperator) csvError(err error) error {
+	var perr *csv.ParseError
+	if errors.As(err, &perr) {
+		op.line = perr.StartLine
+		return perr.Err
+	}
+
+	return err
+}
+
+// A Writer writes rows to a file.
+type Writer struct {
+	w       *bufio.Writer
+	csv     *csv.Writer
+	columns []string
+	record  []string
+}
+
+// NewWriter creates a writer of rows with the given columns.
+// If the options require a header, it is written immediately.
+func NewWriter(w io.Writer, columns []string, opts Options) (*Writer, error) {
+	fw := Writer{w: bufio.NewWriter(w), columns: columns}
+
+	if opts.Format == FormatJSON {
+		return &fw, nil
+	}
+
+	fw.csv = csv.NewWriter(fw.w)
+	if opts.Delimiter != 0 {
+		fw.csv.Comma = opts.Delimiter
+	}
+	if opts.Header {
+		if err := fw.csv.Write(columns); err != nil {
+			return nil, err
+		}
+	}
+
+	return &fw, nil
+}
+
+// WriteRow writes a row to the file.
+func (w *Writer) WriteRow(r database.Row) error {
+	if w.csv == nil {
+		data, err := row.MarshalJSON(r)
+		if err != nil {
+			return err
+		}
+		w.w.Write(data)
+		return w.w.WriteByte('\n')
+	}
+
+	w.record = w.record[:0]
+	err := r.Iterate(func(_ string, v types.Value) error {
+		switch v.Type() {
+		case types.TypeNull:
+			w.record = append(w.record, "")
+			return nil
+		case types.TypeTimestamp:
+			w.record = append(w.record, types.AsTime(v).Format(time.RFC3339Nano))
+			return nil
+		}
+
+		// values are written as they are cast from text,
+		// so that files can be copied back to a table
+		v, err := v.CastAs(types.TypeText)
+		if err != nil {
+			return err
+		}
+
+		w.record = append(w.record, types.AsString(v))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return w.csv.Write(w.record)
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (w *Writer) Flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	return w.w.Flush()
+}
@@ -93,6 +93,32 @@ func (it *ScanIterator) Close() error {
 }
 
 func (it *ScanIterator) Next() bool {
+	for it.next() {
+		if it.table.Info.TTLColumn == "" {
+			return true
+		}
+
+		// skip the rows that have expired but haven't been deleted yet
+		r, err := it.Row()
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		expired, err := it.table.IsExpired(r)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if !expired {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *ScanIterator) next() bool {
 	var r *tree.Range
 
 	if it.it == nil {
@@ -6,6 +6,7 @@ import (
 	"github.com/chaisql/chai/internal/database"
 	"github.com/chaisql/chai/internal/environment"
 	"github.com/chaisql/chai/internal/stream"
+	"github.com/chaisql/chai/internal/tree"
 	"github.com/chaisql/chai/internal/types"
 	"github.com/cockroachdb/errors"
 )
@@ -133,6 +134,16 @@ func (it *ValidateIterator) Next() bool {
 			return true
 		}
 
+		// expired rows don't conflict
+		deleted, err := it.deleteIfExpired(key)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if deleted {
+			return true
+		}
+
 		if it.onConflict == nil && !it.onConflictDoNothing {
 			it.err = &database.ConstraintViolationError{
 				Constraint: "UNIQUE",
@@ -187,3 +198,14 @@ func (it *ValidateIterator) Error() error {
 
 	return it.Iterator.Error()
 }
+
+// deleteIfExpired deletes the row with the given key if it has expired.
+func (it *ValidateIterator) deleteIfExpired(key *tree.Key) (bool, error) {
+	tx := it.env.GetTx()
+	table, err := tx.Catalog.GetTable(tx, it.info.Owner.TableName)
+	if err != nil {
+		return false, err
+	}
+
+	return table.DeleteIfExpired(key)
+}
@@ -156,6 +156,15 @@ func (it *GenerateKeyIterator) generateKey(r database.Row) (*tree.Key, error) {
 		return k, nil
 	}
 
+	// expired rows don't conflict
+	deleted, err := it.table.DeleteIfExpired(k)
+	if err != nil {
+		return nil, err
+	}
+	if deleted {
+		return k, nil
+	}
+
 	if it.onConflict == nil && !it.onConflictDoNothing {
 		return nil, &database.ConstraintViolationError{
 			Constraint: "PRIMARY KEY",
@@ -18,6 +18,9 @@ type ScanOperator struct {
 	// If set, the operator will scan this table.
 	// It not set, it will get the scan from the catalog.
 	Table *database.Table
+	// If set, the rows that have expired but haven't been
+	// deleted yet are returned, instead of being skipped.
+	IncludeExpired bool
 }
 
 // Scan creates an iterator that iterates over each object of the given table that match the given ranges.
@@ -53,9 +56,10 @@ func (op *ScanOperator) Iterator(in *environment.Environment) (stream.Iterator,
 	}
 
 	return &ScanIterator{
-		table:   table,
-		ranges:  ranges,
-		reverse: op.Reverse,
+		table:          table,
+		ranges:         ranges,
+		reverse:        op.Reverse,
+		includeExpired: op.IncludeExpired,
 	}, nil
 }
 
@@ -103,9 +107,10 @@ func (it *ScanOperator) String() string {
 }
 
 type ScanIterator struct {
-	table   *database.Table
-	ranges  []*database.Range
-	reverse bool
+	table          *database.Table
+	ranges         []*database.Range
+	reverse        bool
+	includeExpired bool
 
 	cursor int
 	it     *database.TableIterator
@@ -121,6 +126,32 @@ func (it *ScanIterator) Close() error {
 }
 
 func (it *ScanIterator) Next() bool {
+	for it.next() {
+		if it.includeExpired || it.table.Info.TTLColumn == "" {
+			return true
+		}
+
+		// skip the rows that have expired but haven't been deleted yet
+		r, err := it.it.Value()
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		expired, err := it.table.IsExpired(r)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		if !expired {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *ScanIterator) next() bool {
 	if it.it == nil {
 		it.it, it.err = it.table.Iterator(it.ranges[0])
 		if it.err != nil {
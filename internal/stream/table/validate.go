@@ -93,7 +93,7 @@ func (it *ValidateIterator) Next() bool {
 	}
 
 	// use the encoded row as the new row
-	it.eo.ResetWith(&it.table.Info.ColumnConstraints, it.buf)
+	it.eo.ResetWith(it.tx, &it.table.Info.ColumnConstraints, it.buf)
 	it.br.ResetWith(it.tableName, r.Key(), &it.eo)
 
 	// validate CHECK constraints if any
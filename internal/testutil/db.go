@@ -2,12 +2,15 @@ package testutil
 
 import (
 	"context"
+	"os"
 	"testing"
 
 	"github.com/chaisql/chai/internal/database"
 	"github.com/chaisql/chai/internal/database/catalogstore"
+	"github.com/chaisql/chai/internal/engine"
 	"github.com/chaisql/chai/internal/environment"
 	"github.com/chaisql/chai/internal/kv"
+	"github.com/chaisql/chai/internal/memkv"
 	"github.com/chaisql/chai/internal/query"
 	"github.com/chaisql/chai/internal/query/statement"
 	"github.com/chaisql/chai/internal/sql/parser"
@@ -15,15 +18,34 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func NewEngine(t testing.TB) *kv.PebbleEngine {
+// EngineEnv is the environment variable selecting the engine used by the tests.
+// If set to "memory", the in-memory B-tree engine is used instead of Pebble:
+//
+//	CHAI_TEST_ENGINE=memory go test ./...
+const EngineEnv = "CHAI_TEST_ENGINE"
+
+func useMemoryEngine() bool {
+	return os.Getenv(EngineEnv) == "memory"
+}
+
+func NewEngine(t testing.TB) engine.Engine {
 	t.Helper()
 
+	if useMemoryEngine() {
+		ng := memkv.NewEngine()
+		t.Cleanup(func() {
+			ng.Close()
+		})
+
+		return ng
+	}
+
 	st, err := kv.NewEngine(":memory:", kv.Options{
 		RollbackSegmentNamespace: int64(database.RollbackSegmentNamespace),
 		MaxBatchSize:             1 << 7,
 		MinTransientNamespace:    10_000,
 		MaxTransientNamespace:    11_000,
-	})
+	}, nil)
 	require.NoError(t, err)
 
 	t.Cleanup(func() {
@@ -48,7 +70,12 @@ func NewTestTree(t testing.TB, namespace tree.Namespace) *tree.Tree {
 func NewTestDB(t testing.TB) *database.Database {
 	t.Helper()
 
-	db, err := database.Open(":memory:", &database.Options{
+	path := ":memory:"
+	if useMemoryEngine() {
+		path = database.MemoryPath
+	}
+
+	db, err := database.Open(path, &database.Options{
 		CatalogLoader: catalogstore.LoadCatalog,
 	})
 	require.NoError(t, err)
@@ -71,6 +71,14 @@ func (p *ResultStream) parseKV() (string, expr.Expr, error) {
 		return "", nil, errors.New("expected IDENT or STRING")
 	}
 
+	// a key directly followed by a value without whitespace, like a:null,
+	// is scanned as a named parameter.
+	if tok, _, lit := p.ScanIgnoreWhitespace(); tok == scanner.NAMEDPARAM && lit[0] == ':' {
+		e, err := parser.NewParser(strings.NewReader(lit[1:])).ParseExpr()
+		return k, e, err
+	}
+	p.Unscan()
+
 	if err := p.ParseTokens(scanner.COLON); err != nil {
 		p.Unscan()
 		return "", nil, err
@@ -87,6 +87,11 @@ func (v ByteaValue) CastAs(target Type) (Value, error) {
 		return v, nil
 	case TypeText:
 		return NewTextValue(base64.StdEncoding.EncodeToString([]byte(v))), nil
+	case TypeUUID:
+		if len(v) != encoding.UUIDLen {
+			return nil, errors.Errorf("cannot cast bytea of length %d as uuid", len(v))
+		}
+		return NewUUIDValue([16]byte(v)), nil
 	}
 
 	return nil, errors.Errorf("cannot cast %q as %q", v.Type(), target)
@@ -22,6 +22,7 @@ func TestCastAs(t *testing.T) {
 	tsV := types.NewTimestampValue(now)
 	textV := types.NewTextValue("foo")
 	byteaV := types.NewByteaValue([]byte("asdine"))
+	uuidV := types.NewUUIDValue([16]byte{0xa0, 0xee, 0xbc, 0x99, 0x9c, 0x0b, 0x4e, 0xf8, 0xbb, 0x6d, 0x6b, 0xb9, 0xbd, 0x38, 0x0a, 0x11})
 
 	check := func(t *testing.T, targetType types.Type, tests []test) {
 		t.Helper()
@@ -110,4 +111,21 @@ func TestCastAs(t *testing.T) {
 			{byteaV, byteaV, false},
 		})
 	})
+
+	t.Run("uuid", func(t *testing.T) {
+		check(t, types.TypeUUID, []test{
+			{boolV, nil, true},
+			{integerV, nil, true},
+			{types.NewTextValue("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"), uuidV, false},
+			{types.NewTextValue("A0EEBC999C0B4EF8BB6D6BB9BD380A11"), uuidV, false},
+			{textV, nil, true},
+			{byteaV, nil, true},
+			{types.NewByteaValue(uuidV[:]), uuidV, false},
+			{uuidV, uuidV, false},
+		})
+
+		check(t, types.TypeText, []test{
+			{uuidV, types.NewTextValue("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"), false},
+		})
+	})
 }
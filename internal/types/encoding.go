@@ -19,6 +19,7 @@ var encodedTypeToTypeDefs = map[byte]TypeDefinition{
 	encoding.Float64Value: DoublePrecisionTypeDef{},
 	encoding.TextValue:    TextTypeDef{},
 	encoding.ByteaValue:   ByteaTypeDef{},
+	encoding.UUIDValue:    UUIDTypeDef{},
 }
 
 func DecodeValue(b []byte) (v Value, n int) {
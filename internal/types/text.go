@@ -20,7 +20,7 @@ func (TextTypeDef) Decode(src []byte) (Value, int) {
 }
 
 func (TextTypeDef) IsComparableWith(other Type) bool {
-	return other == TypeNull || other == TypeText || other == TypeBoolean || other == TypeInteger || other == TypeBigint || other == TypeDoublePrecision || other == TypeTimestamp || other == TypeBytea
+	return other == TypeNull || other == TypeText || other == TypeBoolean || other == TypeInteger || other == TypeBigint || other == TypeDoublePrecision || other == TypeTimestamp || other == TypeBytea || other == TypeUUID
 }
 
 func (t TextTypeDef) IsIndexComparableWith(other Type) bool {
@@ -134,6 +134,12 @@ func (v TextValue) CastAs(target Type) (Value, error) {
 		}
 
 		return NewByteaValue(b), nil
+	case TypeUUID:
+		u, err := ParseUUID(strings.TrimSpace(string(v)))
+		if err != nil {
+			return nil, fmt.Errorf(`cannot cast %q as uuid: %w`, v.V(), err)
+		}
+		return NewUUIDValue(u), nil
 	}
 
 	return nil, errors.Errorf("cannot cast %q as %q", v.Type(), target)
@@ -150,6 +156,8 @@ func (v TextValue) EQ(other Value) (bool, error) {
 			return false, err
 		}
 		return ts.Equal(AsTime(other)), nil
+	case TypeUUID:
+		return other.EQ(v)
 	default:
 		return false, nil
 	}
@@ -166,6 +174,8 @@ func (v TextValue) GT(other Value) (bool, error) {
 			return false, err
 		}
 		return ts.After(AsTime(other)), nil
+	case TypeUUID:
+		return other.LT(v)
 	default:
 		return false, nil
 	}
@@ -183,6 +193,8 @@ func (v TextValue) GTE(other Value) (bool, error) {
 		}
 		t2 := AsTime(other)
 		return t1.After(t2) || t1.Equal(t2), nil
+	case TypeUUID:
+		return other.LTE(v)
 	default:
 		return false, nil
 	}
@@ -199,6 +211,8 @@ func (v TextValue) LT(other Value) (bool, error) {
 			return false, err
 		}
 		return ts.Before(AsTime(other)), nil
+	case TypeUUID:
+		return other.GT(v)
 	default:
 		return false, nil
 	}
@@ -216,6 +230,8 @@ func (v TextValue) LTE(other Value) (bool, error) {
 		}
 		t2 := AsTime(other)
 		return t1.Before(t2) || t1.Equal(t2), nil
+	case TypeUUID:
+		return other.GTE(v)
 	default:
 		return false, nil
 	}
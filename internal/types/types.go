@@ -28,6 +28,7 @@ const (
 	TypeTimestamp
 	TypeText
 	TypeBytea
+	TypeUUID
 )
 
 func (t Type) Def() TypeDefinition {
@@ -48,6 +49,8 @@ func (t Type) Def() TypeDefinition {
 		return TextTypeDef{}
 	case TypeBytea:
 		return ByteaTypeDef{}
+	case TypeUUID:
+		return UUIDTypeDef{}
 	}
 
 	return nil
@@ -71,6 +74,8 @@ func (t Type) String() string {
 		return "bytea"
 	case TypeText:
 		return "text"
+	case TypeUUID:
+		return "uuid"
 	}
 
 	panic(fmt.Sprintf("unsupported type %#v", t))
@@ -94,6 +99,8 @@ func (t Type) MinEnctype() byte {
 		return encoding.TextValue
 	case TypeBytea:
 		return encoding.ByteaValue
+	case TypeUUID:
+		return encoding.UUIDValue
 	default:
 		panic(fmt.Sprintf("unsupported type %v", t))
 	}
@@ -117,6 +124,8 @@ func (t Type) MinEnctypeDesc() byte {
 		return encoding.DESC_TextValue
 	case TypeBytea:
 		return encoding.DESC_ByteaValue
+	case TypeUUID:
+		return encoding.DESC_UUIDValue
 	default:
 		panic(fmt.Sprintf("unsupported type %v", t))
 	}
@@ -140,6 +149,8 @@ func (t Type) MaxEnctype() byte {
 		return encoding.TextValue + 1
 	case TypeBytea:
 		return encoding.ByteaValue + 1
+	case TypeUUID:
+		return encoding.UUIDValue + 1
 	default:
 		panic(fmt.Sprintf("unsupported type %v", t))
 	}
@@ -161,6 +172,8 @@ func (t Type) MaxEnctypeDesc() byte {
 		return encoding.DESC_TextValue + 1
 	case TypeBytea:
 		return encoding.DESC_ByteaValue + 1
+	case TypeUUID:
+		return encoding.DESC_UUIDValue + 1
 	default:
 		panic(fmt.Sprintf("unsupported type %v", t))
 	}
@@ -0,0 +1,210 @@
+package types
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chaisql/chai/internal/encoding"
+	"github.com/cockroachdb/errors"
+)
+
+var _ TypeDefinition = UUIDTypeDef{}
+
+type UUIDTypeDef struct{}
+
+func (UUIDTypeDef) Decode(src []byte) (Value, int) {
+	x, n := encoding.DecodeUUID(src)
+	return NewUUIDValue(x), n
+}
+
+func (UUIDTypeDef) IsComparableWith(other Type) bool {
+	return other == TypeNull || other == TypeUUID || other == TypeText
+}
+
+func (UUIDTypeDef) IsIndexComparableWith(other Type) bool {
+	return other == TypeUUID || other == TypeText
+}
+
+var _ Value = NewUUIDValue([16]byte{})
+
+type UUIDValue [16]byte
+
+// NewUUIDValue returns a SQL UUID value.
+func NewUUIDValue(x [16]byte) UUIDValue {
+	return UUIDValue(x)
+}
+
+// NewRandomUUIDValue returns a random (version 4) UUID.
+func NewRandomUUIDValue() UUIDValue {
+	var u UUIDValue
+	_, _ = rand.Read(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return u
+}
+
+// NewTimeOrderedUUIDValue returns a version 7 UUID, whose first 48 bits
+// contain the number of milliseconds since the Unix epoch.
+// Version 7 UUIDs generated at different milliseconds are sorted by time,
+// which makes them well suited for primary keys.
+func NewTimeOrderedUUIDValue(t time.Time) UUIDValue {
+	var u UUIDValue
+	_, _ = rand.Read(u[6:])
+	ms := uint64(t.UnixMilli())
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(u[:6], ts[2:])
+	u[6] = (u[6] & 0x0f) | 0x70
+	u[8] = (u[8] & 0x3f) | 0x80
+	return u
+}
+
+// ParseUUID parses a UUID in its canonical form (xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx).
+// Upper case digits, surrounding braces and the hyphen-less form are also accepted.
+func ParseUUID(s string) ([16]byte, error) {
+	var u [16]byte
+
+	x := s
+	if len(x) > 2 && x[0] == '{' && x[len(x)-1] == '}' {
+		x = x[1 : len(x)-1]
+	}
+
+	switch len(x) {
+	case 36:
+		if x[8] != '-' || x[13] != '-' || x[18] != '-' || x[23] != '-' {
+			return u, errors.Errorf("invalid uuid %q", s)
+		}
+		x = x[0:8] + x[9:13] + x[14:18] + x[19:23] + x[24:]
+	case 32:
+	default:
+		return u, errors.Errorf("invalid uuid %q", s)
+	}
+
+	_, err := hex.Decode(u[:], []byte(x))
+	if err != nil {
+		return u, errors.Errorf("invalid uuid %q", s)
+	}
+
+	return u, nil
+}
+
+func (v UUIDValue) V() any {
+	return [16]byte(v)
+}
+
+func (v UUIDValue) Type() Type {
+	return TypeUUID
+}
+
+func (v UUIDValue) TypeDef() TypeDefinition {
+	return UUIDTypeDef{}
+}
+
+func (v UUIDValue) IsZero() (bool, error) {
+	return v == UUIDValue{}, nil
+}
+
+// Canonical returns the canonical textual representation of the UUID.
+func (v UUIDValue) Canonical() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], v[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], v[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], v[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], v[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], v[10:])
+	return string(buf[:])
+}
+
+func (v UUIDValue) String() string {
+	return "'" + v.Canonical() + "'"
+}
+
+func (v UUIDValue) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v UUIDValue) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(v.Canonical())), nil
+}
+
+func (v UUIDValue) Encode(dst []byte) ([]byte, error) {
+	return encoding.EncodeUUID(dst, v), nil
+}
+
+func (v UUIDValue) EncodeAsKey(dst []byte) ([]byte, error) {
+	return v.Encode(dst)
+}
+
+func (v UUIDValue) CastAs(target Type) (Value, error) {
+	switch target {
+	case TypeUUID:
+		return v, nil
+	case TypeText:
+		return NewTextValue(v.Canonical()), nil
+	case TypeBytea:
+		return NewByteaValue(bytes.Clone(v[:])), nil
+	}
+
+	return nil, errors.Errorf("cannot cast %q as %q", v.Type(), target)
+}
+
+// compare returns the result of the comparison between v and other,
+// or false if other is neither a UUID nor a text.
+func (v UUIDValue) compare(other Value) (int, bool, error) {
+	switch other.Type() {
+	case TypeUUID:
+		u := other.(UUIDValue)
+		return bytes.Compare(v[:], u[:]), true, nil
+	case TypeText:
+		u, err := ParseUUID(strings.TrimSpace(AsString(other)))
+		if err != nil {
+			return 0, false, err
+		}
+		return bytes.Compare(v[:], u[:]), true, nil
+	}
+
+	return 0, false, nil
+}
+
+func (v UUIDValue) EQ(other Value) (bool, error) {
+	cmp, ok, err := v.compare(other)
+	return ok && cmp == 0, err
+}
+
+func (v UUIDValue) GT(other Value) (bool, error) {
+	cmp, ok, err := v.compare(other)
+	return ok && cmp > 0, err
+}
+
+func (v UUIDValue) GTE(other Value) (bool, error) {
+	cmp, ok, err := v.compare(other)
+	return ok && cmp >= 0, err
+}
+
+func (v UUIDValue) LT(other Value) (bool, error) {
+	cmp, ok, err := v.compare(other)
+	return ok && cmp < 0, err
+}
+
+func (v UUIDValue) LTE(other Value) (bool, error) {
+	cmp, ok, err := v.compare(other)
+	return ok && cmp <= 0, err
+}
+
+func (v UUIDValue) Between(a, b Value) (bool, error) {
+	ok, err := v.GTE(a)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	return v.LTE(b)
+}
@@ -0,0 +1,341 @@
+// Package migrate applies versioned migration files to a database.
+//
+// Migrations are pairs of SQL files named NNN_name.up.sql and NNN_name.down.sql,
+// where NNN is the version of the migration. Migrations are applied in the order
+// of their versions, each in its own transaction, and the applied versions are
+// recorded in the __chai_migrations table along with the checksum of their up file.
+// Applying migrations fails if the up file of an applied migration has been edited.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// TableName is the name of the table recording the applied migrations.
+const TableName = "__chai_migrations"
+
+var (
+	fileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	nameRe = regexp.MustCompile(`^\w+$`)
+)
+
+// A Migration is a version of the schema of the database.
+type Migration struct {
+	Version int64
+	Name    string
+	// Up is the SQL applying the migration.
+	Up string
+	// Down is the SQL reverting the migration.
+	Down string
+
+	// hasDown is false if the migration has no down file
+	// and can't be reverted.
+	hasDown bool
+}
+
+// Checksum returns the checksum of the up file of the migration.
+func (m *Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// Load reads the migration files at the root of fsys, sorted by version.
+// Files that don't end with .sql are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	hasUp := make(map[int64]bool)
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".sql" {
+			continue
+		}
+
+		match := fileRe.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, errors.Errorf("invalid migration file name %q, expected NNN_name.up.sql or NNN_name.down.sql", e.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid migration version in %q", e.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, errors.Errorf("duplicate migration version %d: %s and %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(data)
+			hasUp[version] = true
+		} else {
+			m.Down = string(data)
+			m.hasDown = true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if !hasUp[m.Version] {
+			return nil, errors.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+// A Status describes a migration and whether it has been applied.
+type Status struct {
+	Version int64
+	Name    string
+	Applied bool
+	// AppliedAt is the time the migration was applied.
+	AppliedAt time.Time
+}
+
+// applied is a migration recorded in the migrations table.
+type applied struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Run applies the migrations of fsys that haven't been applied yet, in order.
+// Each migration is applied in its own transaction: if one fails,
+// the migrations applied before it are kept.
+func Run(ctx context.Context, db *sql.DB, fsys fs.FS) error {
+	_, err := Up(ctx, db, fsys, 0)
+	return err
+}
+
+// Up applies at most n migrations that haven't been applied yet, or all of them if n is 0.
+// It returns the applied migrations.
+func Up(ctx context.Context, db *sql.DB, fsys fs.FS, n int) ([]Migration, error) {
+	migrations, done, err := load(ctx, db, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var last int64 = -1
+	if len(done) > 0 {
+		last = done[len(done)-1].Version
+	}
+
+	var list []Migration
+	for _, m := range migrations {
+		if n > 0 && len(list) == n {
+			break
+		}
+		if slices.ContainsFunc(done, func(a applied) bool { return a.Version == m.Version }) {
+			continue
+		}
+		if m.Version < last {
+			return list, errors.Errorf("migration %d_%s is older than the last applied migration %d", m.Version, m.Name, last)
+		}
+
+		err = inTx(ctx, db, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, m.Up)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, "INSERT INTO "+TableName+" (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)",
+				m.Version, m.Name, m.Checksum(), time.Now().UTC())
+			return err
+		})
+		if err != nil {
+			return list, errors.Wrapf(err, "migration %d_%s", m.Version, m.Name)
+		}
+
+		list = append(list, m)
+	}
+
+	return list, nil
+}
+
+// Down reverts the last n applied migrations, in reverse order.
+// It returns the reverted migrations.
+func Down(ctx context.Context, db *sql.DB, fsys fs.FS, n int) ([]Migration, error) {
+	migrations, done, err := load(ctx, db, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Migration
+	for i := len(done) - 1; i >= 0 && len(list) < n; i-- {
+		idx := slices.IndexFunc(migrations, func(m Migration) bool { return m.Version == done[i].Version })
+		m := migrations[idx]
+		if !m.hasDown {
+			return list, errors.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+		}
+
+		err = inTx(ctx, db, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, m.Down)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, "DELETE FROM "+TableName+" WHERE version = $1", m.Version)
+			return err
+		})
+		if err != nil {
+			return list, errors.Wrapf(err, "migration %d_%s", m.Version, m.Name)
+		}
+
+		list = append(list, m)
+	}
+
+	return list, nil
+}
+
+// GetStatus returns the status of the migrations of fsys, sorted by version.
+func GetStatus(ctx context.Context, db *sql.DB, fsys fs.FS) ([]Status, error) {
+	migrations, done, err := load(ctx, db, fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Status, len(migrations))
+	for i, m := range migrations {
+		list[i] = Status{Version: m.Version, Name: m.Name}
+
+		idx := slices.IndexFunc(done, func(a applied) bool { return a.Version == m.Version })
+		if idx >= 0 {
+			list[i].Applied = true
+			list[i].AppliedAt = done[idx].AppliedAt
+		}
+	}
+
+	return list, nil
+}
+
+// Create creates the up and down files of a new migration in dir,
+// with a version following the one of the last migration.
+// It returns the paths of the created files.
+func Create(dir, name string) (up, down string, err error) {
+	if !nameRe.MatchString(name) {
+		return "", "", errors.Errorf("invalid migration name %q, expected letters, digits and underscores", name)
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return "", "", err
+	}
+
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	prefix := filepath.Join(dir, fmt.Sprintf("%03d_%s", version, name))
+	up, down = prefix+".up.sql", prefix+".down.sql"
+
+	for _, path := range []string{up, down} {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return "", "", err
+		}
+		if err := f.Close(); err != nil {
+			return "", "", err
+		}
+	}
+
+	return up, down, nil
+}
+
+// load reads the migrations of fsys and the applied migrations, sorted by version.
+// It returns an error if an applied migration is missing or has been edited.
+func load(ctx context.Context, db *sql.DB, fsys fs.FS) ([]Migration, []applied, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	_, err = db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+TableName+` (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+TableName+" ORDER BY version")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var done []applied
+	for rows.Next() {
+		var a applied
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, nil, err
+		}
+
+		idx := slices.IndexFunc(migrations, func(m Migration) bool { return m.Version == a.Version })
+		if idx < 0 {
+			return nil, nil, errors.Errorf("applied migration %d_%s is missing", a.Version, a.Name)
+		}
+		if migrations[idx].Checksum() != a.Checksum {
+			return nil, nil, errors.Errorf("applied migration %d_%s has been edited", a.Version, a.Name)
+		}
+
+		done = append(done, a)
+	}
+
+	return migrations, done, rows.Err()
+}
+
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,202 @@
+package migrate_test
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/chaisql/chai"
+	"github.com/chaisql/chai/migrate"
+	"github.com/stretchr/testify/require"
+)
+
+func file(s string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(s)}
+}
+
+func tables(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT name FROM __chai_catalog WHERE type = 'table' AND name NOT LIKE '__chai_%' ORDER BY name")
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	require.NoError(t, rows.Err())
+	return names
+}
+
+func TestRun(t *testing.T) {
+	fsys := fstest.MapFS{
+		"001_users.up.sql":    file("CREATE TABLE users(id INT PRIMARY KEY, name TEXT);"),
+		"001_users.down.sql":  file("DROP TABLE users;"),
+		"002_orders.up.sql":   file("CREATE TABLE orders(id INT PRIMARY KEY); CREATE INDEX ON orders(id);"),
+		"002_orders.down.sql": file("DROP TABLE orders;"),
+		"010_seed.up.sql":     file("INSERT INTO users VALUES (1, 'a');"),
+		"010_seed.down.sql":   file("DELETE FROM users;"),
+		"README.md":           file("not a migration"),
+		"subdir/003_x.up.sql": file("invalid"),
+	}
+
+	t.Run("Up and down", func(t *testing.T) {
+		db, err := sql.Open("chai", ":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		require.NoError(t, migrate.Run(t.Context(), db, fsys))
+		require.Equal(t, []string{"orders", "users"}, tables(t, db))
+
+		status, err := migrate.GetStatus(t.Context(), db, fsys)
+		require.NoError(t, err)
+		require.Len(t, status, 3)
+		for _, s := range status {
+			require.True(t, s.Applied)
+			require.False(t, s.AppliedAt.IsZero())
+		}
+
+		// running again is a no-op
+		list, err := migrate.Up(t.Context(), db, fsys, 0)
+		require.NoError(t, err)
+		require.Empty(t, list)
+
+		list, err = migrate.Down(t.Context(), db, fsys, 2)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		require.Equal(t, int64(10), list[0].Version)
+		require.Equal(t, int64(2), list[1].Version)
+		require.Equal(t, []string{"users"}, tables(t, db))
+
+		status, err = migrate.GetStatus(t.Context(), db, fsys)
+		require.NoError(t, err)
+		require.True(t, status[0].Applied)
+		require.False(t, status[1].Applied)
+		require.False(t, status[2].Applied)
+
+		list, err = migrate.Up(t.Context(), db, fsys, 1)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		require.Equal(t, "orders", list[0].Name)
+	})
+
+	t.Run("Failed migration", func(t *testing.T) {
+		db, err := sql.Open("chai", ":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		bad := fstest.MapFS{
+			"001_users.up.sql":  fsys["001_users.up.sql"],
+			"002_orders.up.sql": file("CREATE TABLE orders(id INT PRIMARY KEY); CREATE TABLE users(id INT PRIMARY KEY);"),
+		}
+
+		err = migrate.Run(t.Context(), db, bad)
+		require.ErrorContains(t, err, "migration 2_orders")
+
+		// the failed migration is rolled back, the previous one is kept
+		require.Equal(t, []string{"users"}, tables(t, db))
+		status, err := migrate.GetStatus(t.Context(), db, bad)
+		require.NoError(t, err)
+		require.True(t, status[0].Applied)
+		require.False(t, status[1].Applied)
+	})
+
+	t.Run("Edited migration", func(t *testing.T) {
+		db, err := sql.Open("chai", ":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		require.NoError(t, migrate.Run(t.Context(), db, fsys))
+
+		edited := fstest.MapFS{}
+		for k, v := range fsys {
+			edited[k] = v
+		}
+		edited["002_orders.up.sql"] = file("CREATE TABLE orders(id INT PRIMARY KEY);")
+
+		err = migrate.Run(t.Context(), db, edited)
+		require.ErrorContains(t, err, "applied migration 2_orders has been edited")
+
+		delete(edited, "002_orders.up.sql")
+		delete(edited, "002_orders.down.sql")
+		err = migrate.Run(t.Context(), db, edited)
+		require.ErrorContains(t, err, "applied migration 2_orders is missing")
+	})
+
+	t.Run("Out of order", func(t *testing.T) {
+		db, err := sql.Open("chai", ":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		require.NoError(t, migrate.Run(t.Context(), db, fsys))
+
+		late := fstest.MapFS{"005_late.up.sql": file("CREATE TABLE late(id INT PRIMARY KEY);")}
+		for k, v := range fsys {
+			late[k] = v
+		}
+		err = migrate.Run(t.Context(), db, late)
+		require.ErrorContains(t, err, "older than the last applied migration 10")
+	})
+
+	t.Run("No down migration", func(t *testing.T) {
+		db, err := sql.Open("chai", ":memory:")
+		require.NoError(t, err)
+		defer db.Close()
+
+		fsys := fstest.MapFS{"001_users.up.sql": fsys["001_users.up.sql"]}
+		require.NoError(t, migrate.Run(t.Context(), db, fsys))
+
+		_, err = migrate.Down(t.Context(), db, fsys, 1)
+		require.ErrorContains(t, err, "migration 1_users has no down file")
+	})
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		err  string
+	}{
+		{"Invalid name", fstest.MapFS{"users.sql": file("")}, `invalid migration file name "users.sql"`},
+		{"Duplicate version", fstest.MapFS{"1_a.up.sql": file(""), "001_b.up.sql": file("")}, "duplicate migration version 1"},
+		{"No up file", fstest.MapFS{"1_a.down.sql": file("")}, "migration 1_a has no up file"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := migrate.Load(test.fsys)
+			require.ErrorContains(t, err, test.err)
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "migrations")
+
+	up, down, err := migrate.Create(dir, "create_users")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "001_create_users.up.sql"), up)
+	require.Equal(t, filepath.Join(dir, "001_create_users.down.sql"), down)
+
+	up, _, err = migrate.Create(dir, "add_index")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "002_add_index.up.sql"), up)
+
+	_, err = os.Stat(up)
+	require.NoError(t, err)
+
+	_, _, err = migrate.Create(dir, "bad name")
+	require.Error(t, err)
+
+	// empty migrations can be applied
+	db, err := sql.Open("chai", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, migrate.Run(t.Context(), db, os.DirFS(dir)))
+}
@@ -0,0 +1,40 @@
+-- setup:
+CREATE TABLE test(a int primary key, b text, c int) WITH (compression = 'zstd');
+INSERT INTO test VALUES (1, 'GET /api/v1/users/1 HTTP/1.1 200', 1), (2, 'GET /api/v1/users/2 HTTP/1.1 404', 2), (3, NULL, 3);
+
+-- test: train compression dictionary
+ALTER TABLE test TRAIN COMPRESSION DICTIONARY;
+SELECT * FROM test;
+/* result:
+{
+  a: 1,
+  b: 'GET /api/v1/users/1 HTTP/1.1 200',
+  c: 1
+}
+{
+  a: 2,
+  b: 'GET /api/v1/users/2 HTTP/1.1 404',
+  c: 2
+}
+{
+  a: 3,
+  b: null,
+  c: 3
+}
+*/
+
+-- test: drop compression dictionary
+ALTER TABLE test TRAIN COMPRESSION DICTIONARY;
+INSERT INTO test VALUES (4, 'GET /api/v1/users/4 HTTP/1.1 200', 4);
+ALTER TABLE test DROP COMPRESSION DICTIONARY;
+SELECT b FROM test WHERE a = 4;
+/* result:
+{
+  b: 'GET /api/v1/users/4 HTTP/1.1 200'
+}
+*/
+
+-- test: table not compressed with zstd
+CREATE TABLE other(a int primary key, b text) WITH (compression = 'snappy');
+ALTER TABLE other TRAIN COMPRESSION DICTIONARY;
+-- error:
@@ -0,0 +1,74 @@
+-- setup:
+CREATE TABLE test(a int primary key, expires_at timestamp);
+INSERT INTO test VALUES (1, '2000-01-01'), (2, '2100-01-01'), (3, NULL);
+
+-- suite: no index
+
+-- suite: with index
+CREATE INDEX ON test(expires_at);
+
+-- test: expired rows are hidden
+ALTER TABLE test SET TTL expires_at;
+SELECT a FROM test;
+/* result:
+{
+  a: 2
+}
+{
+  a: 3
+}
+*/
+
+-- test: expired rows are hidden from index scans
+ALTER TABLE test SET TTL expires_at;
+SELECT a FROM test WHERE expires_at < '2200-01-01';
+/* result:
+{
+  a: 2
+}
+*/
+
+-- test: expired rows are hidden from primary key lookups
+ALTER TABLE test SET TTL expires_at;
+SELECT a FROM test WHERE a = 1;
+/* result:
+*/
+
+-- test: expired rows cannot be updated
+ALTER TABLE test SET TTL expires_at;
+UPDATE test SET expires_at = NULL;
+ALTER TABLE test DROP TTL;
+SELECT a, expires_at FROM test;
+/* result:
+{
+  a: 1,
+  expires_at: '2000-01-01T00:00:00Z'
+}
+{
+  a: 2,
+  expires_at: null
+}
+{
+  a: 3,
+  expires_at: null
+}
+*/
+
+-- test: drop ttl
+ALTER TABLE test SET TTL expires_at;
+ALTER TABLE test DROP TTL;
+SELECT name, sql FROM __chai_catalog WHERE type = 'table' AND name = 'test';
+/* result:
+{
+  name: 'test',
+  sql: 'CREATE TABLE test (a INTEGER NOT NULL, expires_at TIMESTAMP, CONSTRAINT test_pk PRIMARY KEY (a))'
+}
+*/
+
+-- test: not a timestamp
+ALTER TABLE test SET TTL a;
+-- error:
+
+-- test: unknown column
+ALTER TABLE test SET TTL b;
+-- error:
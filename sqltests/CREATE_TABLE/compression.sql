@@ -0,0 +1,73 @@
+-- test: table compression
+CREATE TABLE test(a int primary key, b text, c bytea) WITH (compression = 'zstd');
+SELECT name, sql FROM __chai_catalog WHERE type = 'table' AND name = 'test';
+/* result:
+{
+  name: 'test',
+  sql: 'CREATE TABLE test (a INTEGER NOT NULL, b TEXT, c BYTEA, CONSTRAINT test_pk PRIMARY KEY (a)) WITH (compression = \'zstd\')'
+}
+*/
+
+-- test: column compression
+CREATE TABLE test(a int primary key, b text COMPRESSION snappy, c bytea compression 'none') WITH (compression = 'zstd');
+SELECT name, sql FROM __chai_catalog WHERE type = 'table' AND name = 'test';
+/* result:
+{
+  name: 'test',
+  sql: 'CREATE TABLE test (a INTEGER NOT NULL, b TEXT COMPRESSION snappy, c BYTEA COMPRESSION none, CONSTRAINT test_pk PRIMARY KEY (a)) WITH (compression = \'zstd\')'
+}
+*/
+
+-- test: no compression
+CREATE TABLE test(a int primary key, b text) WITH (compression = 'none');
+SELECT name, sql FROM __chai_catalog WHERE type = 'table' AND name = 'test';
+/* result:
+{
+  name: 'test',
+  sql: 'CREATE TABLE test (a INTEGER NOT NULL, b TEXT, CONSTRAINT test_pk PRIMARY KEY (a))'
+}
+*/
+
+-- test: compressed values
+CREATE TABLE test(a int primary key, b text COMPRESSION snappy, c bytea, d text COMPRESSION none) WITH (compression = 'zstd');
+INSERT INTO test VALUES (1, 'aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa', '\xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa', 'aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa');
+INSERT INTO test VALUES (2, 'b', '\xbb', NULL);
+UPDATE test SET b = b || 'c' WHERE a = 1;
+SELECT * FROM test WHERE b = 'b' OR c = '\xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa';
+/* result:
+{
+  a: 1,
+  b: 'aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaac',
+  c: '\xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa',
+  d: 'aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa'
+}
+{
+  a: 2,
+  b: 'b',
+  c: '\xbb',
+  d: null
+}
+*/
+
+-- test: compressed indexed column
+CREATE TABLE test(a int primary key, b text) WITH (compression = 'snappy');
+CREATE INDEX ON test(b);
+INSERT INTO test VALUES (1, 'aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa'), (2, 'b');
+SELECT a FROM test WHERE b = 'aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa';
+/* result:
+{
+  a: 1
+}
+*/
+
+-- test: unknown compression
+CREATE TABLE test(a int primary key, b text) WITH (compression = 'lz4');
+-- error:
+
+-- test: unknown column compression
+CREATE TABLE test(a int primary key, b text COMPRESSION lz4);
+-- error:
+
+-- test: column compression on a non text column
+CREATE TABLE test(a int primary key, b int COMPRESSION zstd);
+-- error:
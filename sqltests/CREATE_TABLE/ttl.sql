@@ -0,0 +1,21 @@
+-- test: ttl column
+CREATE TABLE test(a int primary key, expires_at timestamp) WITH (ttl_column = expires_at);
+SELECT name, sql FROM __chai_catalog WHERE type = 'table' AND name = 'test';
+/* result:
+{
+  name: 'test',
+  sql: 'CREATE TABLE test (a INTEGER NOT NULL, expires_at TIMESTAMP, CONSTRAINT test_pk PRIMARY KEY (a)) WITH (ttl_column = expires_at)'
+}
+*/
+
+-- test: unknown column
+CREATE TABLE test(a int primary key) WITH (ttl_column = expires_at);
+-- error:
+
+-- test: not a timestamp
+CREATE TABLE test(a int primary key, expires_at int) WITH (ttl_column = expires_at);
+-- error:
+
+-- test: unknown option
+CREATE TABLE test(a int primary key, expires_at timestamp) WITH (foo = expires_at);
+-- error:
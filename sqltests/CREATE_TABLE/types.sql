@@ -151,3 +151,13 @@ SELECT name, sql FROM __chai_catalog WHERE type = 'table' AND name = 'test';
   "sql": 'CREATE TABLE test (pk INTEGER NOT NULL, a TEXT, CONSTRAINT test_pk PRIMARY KEY (pk))'
 }
 */
+
+-- test: UUID
+CREATE TABLE test (pk UUID PRIMARY KEY, a UUID DEFAULT gen_random_uuid());
+SELECT name, sql FROM __chai_catalog WHERE type = 'table' AND name = 'test';
+/* result:
+{
+  "name": 'test',
+  "sql": 'CREATE TABLE test (pk UUID NOT NULL, a UUID DEFAULT GEN_RANDOM_UUID(), CONSTRAINT test_pk PRIMARY KEY (pk))'
+}
+*/
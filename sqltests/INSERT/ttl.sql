@@ -0,0 +1,63 @@
+-- setup:
+CREATE TABLE test(a INT PRIMARY KEY, b TEXT UNIQUE, expires_at TIMESTAMP) WITH (ttl_column = expires_at);
+INSERT INTO test (a, b, expires_at) VALUES (1, 'foo', '2000-01-01'), (2, 'bar', '2100-01-01');
+
+-- test: expired primary key
+INSERT INTO test (a, b) VALUES (1, 'baz');
+SELECT a, b FROM test ORDER BY a;
+/* result:
+{
+    "a": 1,
+    "b": 'baz'
+}
+{
+    "a": 2,
+    "b": 'bar'
+}
+*/
+
+-- test: expired unique value
+INSERT INTO test (a, b) VALUES (3, 'foo');
+SELECT a, b FROM test ORDER BY a;
+/* result:
+{
+    "a": 2,
+    "b": 'bar'
+}
+{
+    "a": 3,
+    "b": 'foo'
+}
+*/
+
+-- test: expired row on conflict
+INSERT INTO test (a, b) VALUES (1, 'baz') ON CONFLICT DO NOTHING;
+SELECT a, b FROM test ORDER BY a;
+/* result:
+{
+    "a": 1,
+    "b": 'baz'
+}
+{
+    "a": 2,
+    "b": 'bar'
+}
+*/
+
+-- test: live primary key
+INSERT INTO test (a, b) VALUES (2, 'baz');
+-- error: PRIMARY KEY constraint error: [a]
+
+-- test: live unique value
+INSERT INTO test (a, b) VALUES (3, 'bar');
+-- error: UNIQUE constraint error: [b]
+
+-- test: update to an expired unique value
+UPDATE test SET b = 'foo' WHERE a = 2;
+SELECT a, b FROM test ORDER BY a;
+/* result:
+{
+    "a": 2,
+    "b": 'foo'
+}
+*/
@@ -0,0 +1,43 @@
+-- setup:
+CREATE TABLE test(
+    pk INT PRIMARY KEY,
+    a TEXT
+);
+
+INSERT INTO test (pk, a) VALUES (1, 'foo@example.com'), (2, 'bar'), (3, 'baz@example.org');
+
+-- test: =~
+SELECT pk FROM test WHERE a =~ '@example\\.(com|org)$';
+/* result:
+{
+    "pk": 1
+}
+{
+    "pk": 3
+}
+*/
+
+-- test: !~
+SELECT pk FROM test WHERE a !~ '@';
+/* result:
+{
+    "pk": 2
+}
+*/
+
+-- test: regexp_replace and split_part
+SELECT regexp_replace(a, '@.*$', '') AS name, split_part(a, '@', 2) AS domain FROM test WHERE pk = 1;
+/* result:
+{
+    "name": 'foo',
+    "domain": 'example.com'
+}
+*/
+
+-- test: replace
+SELECT replace(a, 'example', 'test') AS r FROM test WHERE pk = 3;
+/* result:
+{
+    "r": 'baz@test.org'
+}
+*/
@@ -0,0 +1,42 @@
+-- setup:
+CREATE TABLE test(a TEXT PRIMARY KEY, n BIGINT);
+
+INSERT INTO test (a, n) VALUES ('ab', 3);
+
+-- test: repeat
+SELECT repeat(a, n) AS r FROM test;
+/* result:
+{
+    "r": 'ababab'
+}
+*/
+
+-- test: repeat too large
+SELECT repeat('ab', 9223372036854775807);
+-- error: requested length too large
+
+-- test: repeat too large from a column
+UPDATE test SET n = 1073741824;
+SELECT repeat(a, n) FROM test;
+-- error: requested length too large
+
+-- test: pad
+SELECT lpad(a, 4, 'x') AS l, rpad(a, 4) AS r FROM test;
+/* result:
+{
+    "l": 'xxab',
+    "r": 'ab  '
+}
+*/
+
+-- test: lpad too large
+SELECT lpad('a', 9223372036854775807);
+-- error: requested length too large
+
+-- test: lpad too large for memory
+SELECT lpad('a', 1000000000, 'b');
+-- error: requested length too large
+
+-- test: rpad too large
+SELECT rpad('a', 9223372036854775807, 'b');
+-- error: requested length too large
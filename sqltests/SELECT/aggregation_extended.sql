@@ -0,0 +1,200 @@
+-- setup:
+CREATE TABLE test(pk INT PRIMARY KEY, g TEXT, x INT, s TEXT, b BOOL);
+INSERT INTO test (pk, g, x, s, b) VALUES
+    (1, 'a', 1, 'foo', true),
+    (2, 'a', 2, 'bar', true),
+    (3, 'a', 2, NULL, false),
+    (4, 'b', 4, 'baz', true),
+    (5, 'b', 10, 'qux', NULL),
+    (6, 'b', NULL, 'foo', true);
+
+-- test: string_agg
+SELECT string_agg(s, ',') AS r FROM test
+/* result:
+{"r": 'foo,bar,baz,qux,foo'}
+*/
+
+-- test: string_agg with ORDER BY
+SELECT string_agg(s, ', ' ORDER BY s DESC) AS r FROM test
+/* result:
+{"r": 'qux, foo, foo, baz, bar'}
+*/
+
+-- test: string_agg with multiple ORDER BY expressions
+SELECT string_agg(s, '' ORDER BY x DESC, pk) AS r FROM test
+/* result:
+{"r": 'quxbazbarfoofoo'}
+*/
+
+-- test: string_agg group by
+SELECT g, string_agg(s, '-' ORDER BY pk) AS r FROM test GROUP BY g
+/* result:
+{"g": 'a', "r": 'foo-bar'}
+{"g": 'b', "r": 'baz-qux-foo'}
+*/
+
+-- test: string_agg no rows
+SELECT string_agg(s, ',') AS r FROM test WHERE pk > 10
+/* result:
+{"r": null}
+*/
+
+-- test: string_agg name
+SELECT string_agg(s, ',' ORDER BY pk) FROM test WHERE pk = 1
+/* result:
+{"STRING_AGG(s, ',' ORDER BY pk)": 'foo'}
+*/
+
+-- test: bool_and, bool_or
+SELECT g, bool_and(b) AS a, bool_or(b) AS o FROM test GROUP BY g
+/* result:
+{"g": 'a', "a": false, "o": true}
+{"g": 'b', "a": true, "o": true}
+*/
+
+-- test: bool_and no rows
+SELECT bool_and(b) AS a, bool_or(b) AS o FROM test WHERE pk > 10
+/* result:
+{"a": null, "o": null}
+*/
+
+-- test: variance and stddev
+SELECT var_pop(x) AS vp, var_samp(x) AS vs, variance(x) AS v, stddev_pop(x) AS sp FROM test WHERE g = 'b'
+/* result:
+{"vp": 9.0, "vs": 18.0, "v": 18.0, "sp": 3.0}
+*/
+
+-- test: stddev with a single value
+SELECT stddev(x) AS s, stddev_samp(x) AS ss, stddev_pop(x) AS sp FROM test WHERE pk = 1
+/* result:
+{"s": null, "ss": null, "sp": 0.0}
+*/
+
+-- test: percentile_cont
+SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY x) AS p FROM test
+/* result:
+{"p": 2.0}
+*/
+
+-- test: percentile_cont interpolation
+SELECT percentile_cont(0.25) WITHIN GROUP (ORDER BY x) AS p, percentile_cont(0.75) WITHIN GROUP (ORDER BY x) AS q FROM test WHERE g = 'b'
+/* result:
+{"p": 5.5, "q": 8.5}
+*/
+
+-- test: percentile_disc
+SELECT percentile_disc(0.5) WITHIN GROUP (ORDER BY x) AS p, percentile_disc(0.5) WITHIN GROUP (ORDER BY x DESC) AS d FROM test
+/* result:
+{"p": 2, "d": 2}
+*/
+
+-- test: percentile_disc bounds
+SELECT percentile_disc(0) WITHIN GROUP (ORDER BY x) AS lo, percentile_disc(1) WITHIN GROUP (ORDER BY x) AS hi FROM test
+/* result:
+{"lo": 1, "hi": 10}
+*/
+
+-- test: percentile out of range
+SELECT percentile_cont(2) WITHIN GROUP (ORDER BY x) FROM test
+-- error:
+
+-- test: percentile of a NULL fraction
+SELECT percentile_cont(NULL) WITHIN GROUP (ORDER BY x) AS p, percentile_disc(NULL) WITHIN GROUP (ORDER BY x) AS d FROM test
+/* result:
+{"p": NULL, "d": NULL}
+*/
+
+-- test: percentile of a column
+SELECT percentile_cont(x) WITHIN GROUP (ORDER BY x) FROM test
+-- error: the fraction of percentile_cont must be a constant
+
+-- test: percentile_disc of a column
+SELECT percentile_disc(x / 10) WITHIN GROUP (ORDER BY x) FROM test
+-- error: the fraction of percentile_disc must be a constant
+
+-- test: percentile without WITHIN GROUP
+SELECT percentile_cont(0.5) FROM test
+-- error:
+
+-- test: mode
+SELECT mode() WITHIN GROUP (ORDER BY s) AS m, mode() WITHIN GROUP (ORDER BY x) AS n FROM test
+/* result:
+{"m": 'foo', "n": 2}
+*/
+
+-- test: mode group by
+SELECT g, mode() WITHIN GROUP (ORDER BY s DESC) AS m FROM test GROUP BY g
+/* result:
+{"g": 'a', "m": 'foo'}
+{"g": 'b', "m": 'qux'}
+*/
+
+-- test: COUNT(DISTINCT x)
+SELECT COUNT(DISTINCT x) AS c, COUNT(x) AS n FROM test
+/* result:
+{"c": 4, "n": 5}
+*/
+
+-- test: SUM(DISTINCT x)
+SELECT SUM(DISTINCT x) AS d, SUM(x) AS s FROM test
+/* result:
+{"d": 17, "s": 19}
+*/
+
+-- test: DISTINCT group by
+SELECT g, COUNT(DISTINCT s) AS c FROM test GROUP BY g
+/* result:
+{"g": 'a', "c": 2}
+{"g": 'b', "c": 3}
+*/
+
+-- test: DISTINCT name
+SELECT COUNT(DISTINCT x) FROM test
+/* result:
+{"COUNT(DISTINCT x)": 4}
+*/
+
+-- test: COUNT(DISTINCT *)
+SELECT COUNT(DISTINCT *) FROM test
+-- error:
+
+-- test: FILTER
+SELECT COUNT(*) AS n, COUNT(*) FILTER (WHERE x > 1) AS f, SUM(x) FILTER (WHERE b) AS s FROM test
+/* result:
+{"n": 6, "f": 4, "s": 7}
+*/
+
+-- test: FILTER group by
+SELECT g, COUNT(*) FILTER (WHERE b = true) AS c FROM test GROUP BY g
+/* result:
+{"g": 'a', "c": 2}
+{"g": 'b', "c": 2}
+*/
+
+-- test: FILTER with DISTINCT
+SELECT COUNT(DISTINCT x) FILTER (WHERE pk < 4) AS c FROM test
+/* result:
+{"c": 2}
+*/
+
+-- test: FILTER name
+SELECT COUNT(*) FILTER (WHERE x > 1) FROM test
+/* result:
+{"COUNT(*) FILTER (WHERE x > 1)": 4}
+*/
+
+-- test: FILTER on non-aggregate
+SELECT lower(s) FILTER (WHERE x > 1) FROM test
+-- error:
+
+-- test: ORDER BY on non-ordered aggregate
+SELECT COUNT(x ORDER BY x) FROM test
+-- error:
+
+-- test: filter and within are not reserved
+CREATE TABLE t2(filter INT PRIMARY KEY, within INT);
+INSERT INTO t2 (filter, within) VALUES (1, 2);
+SELECT filter, within FROM t2
+/* result:
+{"filter": 1, "within": 2}
+*/
@@ -0,0 +1,94 @@
+-- setup:
+CREATE TABLE test(pk INT PRIMARY KEY, a INT, b TEXT);
+INSERT INTO test (pk, a, b) VALUES (1, 1, 'foo'), (2, 5, 'bar'), (3, 12, NULL), (4, NULL, 'baz');
+
+-- test: searched CASE in projection
+SELECT pk, CASE WHEN a > 10 THEN 'big' WHEN a > 3 THEN 'medium' ELSE 'small' END AS size FROM test
+/* result:
+{"pk": 1, "size": 'small'}
+{"pk": 2, "size": 'medium'}
+{"pk": 3, "size": 'big'}
+{"pk": 4, "size": 'small'}
+*/
+
+-- test: simple CASE in projection
+SELECT pk, CASE b WHEN 'foo' THEN 1 WHEN 'bar' THEN 2 END AS n FROM test
+/* result:
+{"pk": 1, "n": 1}
+{"pk": 2, "n": 2}
+{"pk": 3, "n": null}
+{"pk": 4, "n": null}
+*/
+
+-- test: CASE name
+SELECT CASE a WHEN 1 THEN 'one' ELSE 'other' END FROM test WHERE pk = 1
+/* result:
+{"CASE a WHEN 1 THEN 'one' ELSE 'other' END": 'one'}
+*/
+
+-- test: CASE in WHERE
+SELECT pk FROM test WHERE CASE WHEN b IS NULL THEN false ELSE a < 10 END
+/* result:
+{"pk": 1}
+{"pk": 2}
+*/
+
+-- test: CASE in ORDER BY
+SELECT pk FROM test ORDER BY CASE WHEN a IS NULL THEN 0 ELSE a END DESC
+/* result:
+{"pk": 3}
+{"pk": 2}
+{"pk": 1}
+{"pk": 4}
+*/
+
+-- test: CASE with aggregate
+SELECT COUNT(*) AS n, CASE WHEN COUNT(*) > 3 THEN 'many' ELSE 'few' END AS c FROM test
+/* result:
+{"n": 4, "c": 'many'}
+*/
+
+-- test: mixed numeric types
+SELECT CASE WHEN a > 10 THEN 1.5 ELSE a END AS x FROM test WHERE pk = 1
+/* result:
+{"x": 1.0}
+*/
+
+-- test: mixed numeric literals
+SELECT CASE WHEN a = 1 THEN 1 ELSE 2.5 END AS x, typeof(CASE WHEN a = 1 THEN 1 ELSE 2.5 END) AS t FROM test WHERE pk = 1
+/* result:
+{"x": 1.0, "t": 'double precision'}
+*/
+
+-- test: no common type
+SELECT CASE WHEN a > 10 THEN 'big' ELSE a END FROM test
+-- error:
+
+-- test: no common type with null
+SELECT CASE WHEN a > 10 THEN NULL WHEN a > 5 THEN true ELSE b END FROM test
+-- error:
+
+-- test: NULLIF, GREATEST and LEAST with columns
+SELECT pk, NULLIF(b, 'foo') AS b, GREATEST(a, 5) AS g, LEAST(a, 5) AS l FROM test
+/* result:
+{"pk": 1, "b": null, "g": 5, "l": 1}
+{"pk": 2, "b": 'bar', "g": 5, "l": 5}
+{"pk": 3, "b": null, "g": 12, "l": 5}
+{"pk": 4, "b": 'baz', "g": 5, "l": 5}
+*/
+
+-- test: EXPLAIN folds constant branches
+EXPLAIN SELECT * FROM test WHERE CASE WHEN 1 > 2 THEN a = 1 WHEN b = 'foo' THEN true WHEN 2 > 1 THEN a > 3 ELSE false END
+/* result:
+{
+    "plan": 'table.Scan("test") | rows.Filter(CASE WHEN b = \'foo\' THEN true ELSE a > 3 END)'
+}
+*/
+
+-- test: EXPLAIN folds constant CASE
+EXPLAIN SELECT * FROM test WHERE a = CASE 2 WHEN 1 THEN 10 WHEN 2 THEN 20 END
+/* result:
+{
+    "plan": 'table.Scan("test") | rows.Filter(a = 20)'
+}
+*/
@@ -40,3 +40,13 @@ SELECT a, b FROM test;
     b: null
 }
 */
+
+-- test: IS NULL on typed column
+CREATE TABLE test (pk INT PRIMARY KEY, a INT);
+INSERT INTO test (pk, a) VALUES (1, null), (2, 2);
+SELECT pk FROM test WHERE a IS NULL;
+/* result:
+{
+    pk: 1
+}
+*/
@@ -0,0 +1,77 @@
+-- setup:
+CREATE TABLE test(id UUID PRIMARY KEY, a UUID DEFAULT uuidv7(), b TEXT);
+INSERT INTO test (id, b) VALUES
+    ('a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11', 'foo'),
+    ('6ba7b810-9dad-11d1-80b4-00c04fd430c8', 'bar'),
+    ('f47ac10b-58cc-4372-a567-0e02b2c3d479', 'baz');
+
+-- suite: no index
+
+-- suite: with index
+CREATE INDEX ON test(a);
+
+-- test: order by pk
+SELECT id, b FROM test ORDER BY id;
+/* result:
+{
+    id: '6ba7b810-9dad-11d1-80b4-00c04fd430c8',
+    b: 'bar'
+}
+{
+    id: 'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11',
+    b: 'foo'
+}
+{
+    id: 'f47ac10b-58cc-4372-a567-0e02b2c3d479',
+    b: 'baz'
+}
+*/
+
+-- test: lookup by text literal
+SELECT b FROM test WHERE id = 'F47AC10B-58CC-4372-A567-0E02B2C3D479';
+/* result:
+{
+    b: 'baz'
+}
+*/
+
+-- test: range
+SELECT b FROM test WHERE id > 'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11';
+/* result:
+{
+    b: 'baz'
+}
+*/
+
+-- test: default
+SELECT COUNT(*) AS c FROM test WHERE a IS NOT NULL;
+/* result:
+{
+    c: 3
+}
+*/
+
+-- test: typeof
+SELECT typeof(id) AS t, typeof(gen_random_uuid()) AS g FROM test LIMIT 1;
+/* result:
+{
+    t: 'uuid',
+    g: 'uuid'
+}
+*/
+
+-- test: duplicate
+INSERT INTO test (id, b) VALUES ('a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11', 'dup');
+-- error:
+
+-- test: invalid
+INSERT INTO test (id, b) VALUES ('not-a-uuid', 'foo');
+-- error:
+
+-- test: explain pk lookup
+EXPLAIN SELECT b FROM test WHERE id = 'F47AC10B-58CC-4372-A567-0E02B2C3D479';
+/* result:
+{
+    "plan": 'table.Scan("test", [{"min": (\'f47ac10b-58cc-4372-a567-0e02b2c3d479\'), "exact": true}]) | rows.Project(b)'
+}
+*/
@@ -0,0 +1,186 @@
+-- setup:
+CREATE TABLE test(a INT PRIMARY KEY, b TEXT);
+
+-- test: rollback to savepoint
+BEGIN;
+INSERT INTO test (a, b) VALUES (1, 'a');
+SAVEPOINT sp;
+INSERT INTO test (a, b) VALUES (2, 'b');
+UPDATE test SET b = 'c' WHERE a = 1;
+ROLLBACK TO SAVEPOINT sp;
+SELECT * FROM test;
+/* result:
+{
+    "a": 1,
+    "b": 'a'
+}
+*/
+
+-- test: savepoint can be rolled back to multiple times
+BEGIN;
+SAVEPOINT sp;
+INSERT INTO test (a, b) VALUES (1, 'a');
+ROLLBACK TO sp;
+INSERT INTO test (a, b) VALUES (2, 'b');
+ROLLBACK TO sp;
+INSERT INTO test (a, b) VALUES (3, 'c');
+SELECT * FROM test;
+/* result:
+{
+    "a": 3,
+    "b": 'c'
+}
+*/
+
+-- test: nested savepoints
+BEGIN;
+INSERT INTO test (a, b) VALUES (1, 'a');
+SAVEPOINT sp1;
+INSERT INTO test (a, b) VALUES (2, 'b');
+SAVEPOINT sp2;
+INSERT INTO test (a, b) VALUES (3, 'c');
+ROLLBACK TO sp2;
+SELECT * FROM test;
+/* result:
+{
+    "a": 1,
+    "b": 'a'
+}
+{
+    "a": 2,
+    "b": 'b'
+}
+*/
+
+-- test: rollback to outer savepoint
+BEGIN;
+INSERT INTO test (a, b) VALUES (1, 'a');
+SAVEPOINT sp1;
+INSERT INTO test (a, b) VALUES (2, 'b');
+SAVEPOINT sp2;
+INSERT INTO test (a, b) VALUES (3, 'c');
+ROLLBACK TO sp1;
+SELECT * FROM test;
+/* result:
+{
+    "a": 1,
+    "b": 'a'
+}
+*/
+
+-- test: rollback to destroyed savepoint
+BEGIN;
+SAVEPOINT sp1;
+SAVEPOINT sp2;
+ROLLBACK TO sp1;
+ROLLBACK TO sp2;
+-- error: savepoint "sp2" does not exist
+
+-- test: release keeps changes
+BEGIN;
+SAVEPOINT sp1;
+INSERT INTO test (a, b) VALUES (1, 'a');
+SAVEPOINT sp2;
+INSERT INTO test (a, b) VALUES (2, 'b');
+RELEASE SAVEPOINT sp2;
+SELECT * FROM test;
+/* result:
+{
+    "a": 1,
+    "b": 'a'
+}
+{
+    "a": 2,
+    "b": 'b'
+}
+*/
+
+-- test: rollback after release
+BEGIN;
+SAVEPOINT sp1;
+INSERT INTO test (a, b) VALUES (1, 'a');
+SAVEPOINT sp2;
+INSERT INTO test (a, b) VALUES (2, 'b');
+RELEASE sp2;
+ROLLBACK TO sp1;
+SELECT * FROM test;
+/* result:
+*/
+
+-- test: released savepoint
+BEGIN;
+SAVEPOINT sp;
+RELEASE sp;
+RELEASE sp;
+-- error: savepoint "sp" does not exist
+
+-- test: rollback schema changes
+BEGIN;
+SAVEPOINT sp;
+CREATE TABLE foo(a INT PRIMARY KEY);
+ROLLBACK TO sp;
+SELECT * FROM foo;
+-- error:
+
+-- test: commit after rollback to savepoint
+BEGIN;
+INSERT INTO test (a, b) VALUES (1, 'a');
+SAVEPOINT sp;
+INSERT INTO test (a, b) VALUES (2, 'b');
+ROLLBACK TO sp;
+COMMIT;
+SELECT * FROM test;
+/* result:
+{
+    "a": 1,
+    "b": 'a'
+}
+*/
+
+-- test: savepoint outside of a transaction
+SAVEPOINT sp;
+-- error: cannot create a savepoint with no active transaction
+
+-- test: rollback to savepoint outside of a transaction
+ROLLBACK TO SAVEPOINT sp;
+-- error: cannot rollback to a savepoint with no active transaction
+
+-- test: release outside of a transaction
+RELEASE SAVEPOINT sp;
+-- error: cannot release a savepoint with no active transaction
+
+-- test: rollback restores a table dropped before a savepoint
+INSERT INTO test (a, b) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+CREATE INDEX test_b_idx ON test(b);
+BEGIN;
+DROP TABLE test;
+SAVEPOINT sp;
+ROLLBACK;
+SELECT * FROM test WHERE b = 'b';
+/* result:
+{
+    "a": 2,
+    "b": 'b'
+}
+*/
+
+-- test: rollback to savepoint restores a dropped table
+INSERT INTO test (a, b) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+CREATE INDEX test_b_idx ON test(b);
+BEGIN;
+SAVEPOINT sp;
+DROP TABLE test;
+SAVEPOINT sp2;
+ROLLBACK TO sp;
+SELECT a FROM test WHERE b >= 'a';
+/* result:
+{
+    "a": 1
+}
+{
+    "a": 2
+}
+{
+    "a": 3
+}
+*/
@@ -0,0 +1,47 @@
+-- test: searched case
+> CASE WHEN 1 > 2 THEN 'a' WHEN 2 > 1 THEN 'b' ELSE 'c' END
+'b'
+
+-- test: searched case with else
+> CASE WHEN 1 > 2 THEN 'a' ELSE 'c' END
+'c'
+
+-- test: searched case without else
+> CASE WHEN 1 > 2 THEN 'a' END
+NULL
+
+-- test: searched case with null condition
+> CASE WHEN NULL THEN 'a' ELSE 'b' END
+'b'
+
+-- test: first matching branch wins
+> CASE WHEN true THEN 1 WHEN true THEN 2 END
+1
+
+-- test: simple case
+> CASE 2 WHEN 1 THEN 'one' WHEN 2 THEN 'two' ELSE 'many' END
+'two'
+
+-- test: simple case with else
+> CASE 3 WHEN 1 THEN 'one' WHEN 2 THEN 'two' ELSE 'many' END
+'many'
+
+-- test: simple case with null operand
+> CASE NULL WHEN NULL THEN 'null' ELSE 'not null' END
+'not null'
+
+-- test: simple case with expressions
+> CASE 1 + 1 WHEN 4 / 2 THEN 10 * 2 END
+20
+
+-- test: nested case
+> CASE WHEN true THEN CASE 1 WHEN 1 THEN 'inner' END ELSE 'outer' END
+'inner'
+
+-- test: missing WHEN
+! CASE ELSE 1 END
+'found ELSE'
+
+-- test: missing END
+! CASE WHEN true THEN 1
+'found EOF, expected END'
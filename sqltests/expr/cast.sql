@@ -127,6 +127,25 @@ false
 > CAST ('\x617364696e65' AS TEXT)
 'YXNkaW5l'
 
+-- test: source(UUID)
+> CAST ('A0EEBC99-9C0B-4EF8-BB6D-6BB9BD380A11' AS UUID)
+'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'
+
+> CAST (CAST ('a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11' AS UUID) AS TEXT)
+'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'
+
+> CAST ('{a0eebc999c0b4ef8bb6d6bb9bd380a11}' AS UUID)
+'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'
+
+! CAST ('a0eebc99' AS UUID)
+'cannot cast "a0eebc99" as uuid: invalid uuid "a0eebc99"'
+
+! CAST (CAST ('a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11' AS UUID) AS INT)
+'cannot cast "uuid" as "integer"'
+
+> CAST ('\xa0eebc999c0b4ef8bb6d6bb9bd380a11' AS UUID)
+'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'
+
 -- test: short form casts (::)
 -- Source: INT
 > 1::INTEGER
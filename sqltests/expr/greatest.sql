@@ -0,0 +1,31 @@
+-- test: greatest
+> GREATEST(1, 3, 2)
+3
+
+-- test: least
+> LEAST(1, 3, 2)
+1
+
+-- test: single argument
+> GREATEST(5)
+5
+
+-- test: mixed numeric types
+> GREATEST(1, 2.5, 2)
+2.5
+
+-- test: text
+> LEAST('b', 'a', 'c')
+'a'
+
+-- test: nulls are ignored
+> GREATEST(NULL, 1, NULL, 2)
+2
+
+-- test: all nulls
+> LEAST(NULL, NULL)
+NULL
+
+-- test: incompatible types
+! GREATEST(1, true)
+'GREATEST types integer and boolean cannot be matched'
@@ -0,0 +1,23 @@
+-- test: equal values
+> NULLIF(1, 1)
+NULL
+
+-- test: different values
+> NULLIF(1, 2)
+1
+
+-- test: different types
+> NULLIF(1, 1.0)
+NULL
+
+-- test: text
+> NULLIF('a', 'b')
+'a'
+
+-- test: null first argument
+> NULLIF(NULL, 1)
+NULL
+
+-- test: null second argument
+> NULLIF(1, NULL)
+1
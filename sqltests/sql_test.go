@@ -40,6 +40,15 @@ func TestSQL(t *testing.T) {
 		logger = log.New(os.Stderr, "[SQL TESTS] ", 0)
 	}
 
+	// run the tests against both the Pebble and the B-tree engines
+	for _, dsn := range []string{":memory:", "memory://"} {
+		t.Run(dsn, func(t *testing.T) {
+			testSQL(t, dsn)
+		})
+	}
+}
+
+func testSQL(t *testing.T, dsn string) {
 	err := filepath.Walk(".", func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -104,7 +113,7 @@ func TestSQL(t *testing.T) {
 
 						for _, test := range tests {
 							t.Run(test.Name, func(t *testing.T) {
-								db, err := sql.Open("chai", ":memory:")
+								db, err := sql.Open("chai", dsn)
 								require.NoError(t, err)
 								defer db.Close()
 